@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -14,14 +17,43 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/link-tracker/auth-service/internal/config"
 	"github.com/link-tracker/auth-service/internal/handler"
+	"github.com/link-tracker/auth-service/internal/mailer"
 	"github.com/link-tracker/auth-service/internal/middleware"
+	"github.com/link-tracker/auth-service/internal/model"
 	"github.com/link-tracker/auth-service/internal/repository"
 	"github.com/link-tracker/auth-service/internal/service"
+	"github.com/link-tracker/auth-service/internal/signing"
+	"github.com/link-tracker/auth-service/internal/throttle"
+	"github.com/link-tracker/auth-service/internal/userdata"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
 	cfg := config.Load()
 
+	// Tokens signed with a public secret could be forged by anyone
+	if err := cfg.JWT.CheckLegacySecret(); err != nil {
+		log.Fatalf("Invalid JWT configuration: %v", err)
+	}
+
+	keys, err := loadSigningKeys(&cfg.JWT, cfg.Development())
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+
+	trustedProxies, err := cfg.Server.TrustedProxyPrefixes()
+	if err != nil {
+		log.Fatalf("Invalid server configuration: %v", err)
+	}
+
+	// TOTP secrets encrypted with a public key could be read from a dump
+	if err := cfg.MFA.CheckEncryptionKey(); err != nil {
+		log.Fatalf("Invalid MFA configuration: %v", err)
+	}
+	if cfg.MFA.EncryptionKey == "" && !cfg.Development() {
+		log.Fatal("Invalid MFA configuration: MFA_ENCRYPTION_KEY is required unless APP_ENV=development")
+	}
+
 	// Database connection
 	dbPool, err := pgxpool.New(context.Background(), cfg.Database.DSN())
 	if err != nil {
@@ -35,16 +67,85 @@ func main() {
 	}
 	log.Println("Connected to database")
 
+	// Login throttling counters live in Redis so all instances share them,
+	// and fall back to memory while Redis is unreachable
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+		// Logins wait on these calls, so give up quickly
+		DialTimeout: time.Second,
+		ReadTimeout: 500 * time.Millisecond,
+	})
+	defer redisClient.Close()
+
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		log.Printf("Redis unavailable, login throttling uses in-memory counters: %v", err)
+	} else {
+		log.Println("Connected to Redis")
+	}
+	throttleStore := throttle.NewFallbackStore(throttle.NewRedisStore(redisClient), throttle.NewMemoryStore())
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(dbPool)
 	tokenRepo := repository.NewTokenRepository(dbPool)
+	sessionRepo := repository.NewSessionRepository(dbPool)
+	securityEventRepo := repository.NewSecurityEventRepository(dbPool)
+	workspaceRepo := repository.NewWorkspaceRepository(dbPool)
+	apiKeyRepo := repository.NewAPIKeyRepository(dbPool)
+	accountTokenRepo := repository.NewAccountTokenRepository(dbPool)
+	mfaRepo := repository.NewMFARepository(dbPool)
+	identityRepo := repository.NewIdentityRepository(dbPool)
+	oidcStateRepo := repository.NewOIDCStateRepository(dbPool)
+
+	// A temporary key cannot decrypt secrets stored under an earlier one,
+	// so it would silently lock enrolled users out
+	if cfg.MFA.EncryptionKey == "" {
+		stored, err := mfaRepo.HasSecrets(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to check MFA enrollments: %v", err)
+		}
+		if stored {
+			log.Fatal("Invalid MFA configuration: MFA_ENCRYPTION_KEY is not set but TOTP secrets are stored; set the key they were encrypted with, or in development delete them from user_mfa")
+		}
+		log.Println("WARNING: MFA_ENCRYPTION_KEY is not set, encrypting TOTP secrets with a temporary key; the service will not start again until they are deleted")
+		if cfg.MFA.EncryptionKey, err = temporaryKey(); err != nil {
+			log.Fatalf("Failed to generate MFA key: %v", err)
+		}
+	}
+
+	mail, err := mailer.New(cfg.Mailer)
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, tokenRepo, &cfg.JWT)
+	mfaService, err := service.NewMFAService(mfaRepo, userRepo, accountTokenRepo, &cfg.MFA)
+	if err != nil {
+		log.Fatalf("Failed to initialize MFA: %v", err)
+	}
+	loginGuard := service.NewLoginGuard(throttleStore, userRepo, securityEventRepo, mail, &cfg.Login)
+	authService := service.NewAuthService(userRepo, tokenRepo, sessionRepo, securityEventRepo, mfaService, loginGuard, keys, &cfg.JWT)
+	workspaceService := service.NewWorkspaceService(workspaceRepo, userRepo, mfaService)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+	accountService := service.NewAccountService(userRepo, sessionRepo, accountTokenRepo, mail, &cfg.Account)
+	oidcService := service.NewOIDCService(identityRepo, oidcStateRepo, userRepo, authService, &cfg.OIDC)
+	userData := userdata.NewClient(cfg.Services)
+	dataService := service.NewDataService(userRepo, workspaceRepo, identityRepo, apiKeyRepo, sessionRepo, securityEventRepo, mfaService, userData, throttleStore)
+	adminService := service.NewAdminService(userRepo, securityEventRepo, authService, apiKeyService, dataService, userData)
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(authService)
+	authHandler := handler.NewAuthHandler(authService, accountService)
+	accountHandler := handler.NewAccountHandler(accountService)
+	mfaHandler := handler.NewMFAHandler(mfaService, authService)
+	sessionHandler := handler.NewSessionHandler(authService)
+	oidcHandler := handler.NewOIDCHandler(oidcService)
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
 	healthHandler := handler.NewHealthHandler()
+	jwksHandler := handler.NewJWKSHandler(keys)
+	adminHandler := handler.NewAdminHandler(adminService, loginGuard)
+	dataHandler := handler.NewDataHandler(dataService)
 
 	// Setup router
 	r := chi.NewRouter()
@@ -53,7 +154,7 @@ func main() {
 	r.Use(chimiddleware.Logger)
 	r.Use(chimiddleware.Recoverer)
 	r.Use(chimiddleware.RequestID)
-	r.Use(chimiddleware.RealIP)
+	r.Use(middleware.RealIP(trustedProxies))
 	r.Use(chimiddleware.Timeout(30 * time.Second))
 	r.Use(corsMiddleware)
 
@@ -61,6 +162,9 @@ func main() {
 	r.Get("/health", healthHandler.Health)
 	r.Get("/ready", healthHandler.Ready)
 
+	// Public keys for verifying access tokens in other services
+	r.Get("/.well-known/jwks.json", jwksHandler.JWKS)
+
 	// API routes
 	r.Route("/api/v1/auth", func(r chi.Router) {
 		// Public routes
@@ -68,14 +172,68 @@ func main() {
 		r.Post("/login", authHandler.Login)
 		r.Post("/refresh", authHandler.Refresh)
 		r.Post("/logout", authHandler.Logout)
+		r.Post("/verify-email", accountHandler.VerifyEmail)
+		r.Post("/password/forgot", accountHandler.ForgotPassword)
+		r.Post("/password/reset", accountHandler.ResetPassword)
+		r.Post("/mfa/verify", mfaHandler.Verify)
+		r.Get("/oidc/providers", oidcHandler.Providers)
+		r.Post("/oidc/{provider}/start", oidcHandler.Start)
+		r.Post("/oidc/{provider}/callback", oidcHandler.Callback)
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(middleware.Auth(authService))
 			r.Get("/me", authHandler.Me)
+			r.Post("/verify-email/resend", accountHandler.ResendVerification)
+			r.Post("/password/change", authHandler.ChangePassword)
+			r.Get("/sessions", sessionHandler.List)
+			r.Delete("/sessions/{id}", sessionHandler.Revoke)
+			r.Get("/mfa", mfaHandler.Status)
+			r.Post("/mfa/totp/setup", mfaHandler.Setup)
+			r.Post("/mfa/totp/enable", mfaHandler.Enable)
+			r.Post("/mfa/totp/disable", mfaHandler.Disable)
+			r.Post("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+			r.Get("/account/export", dataHandler.ExportAccount)
+			r.Delete("/account", dataHandler.DeleteAccount)
 		})
 	})
 
+	r.Route("/api/v1/workspaces", func(r chi.Router) {
+		r.Use(middleware.Auth(authService))
+		r.Get("/", workspaceHandler.List)
+		r.Post("/", workspaceHandler.Create)
+		r.Get("/{id}", workspaceHandler.Get)
+		r.Put("/{id}", workspaceHandler.Update)
+		r.Delete("/{id}", workspaceHandler.Delete)
+		r.Get("/{id}/members", workspaceHandler.Members)
+		r.Post("/{id}/members", workspaceHandler.AddMember)
+		r.Put("/{id}/members/{userID}", workspaceHandler.UpdateMember)
+		r.Delete("/{id}/members/{userID}", workspaceHandler.RemoveMember)
+	})
+
+	// API keys are managed with a JWT only, so a key cannot issue new keys
+	r.Route("/api/v1/api-keys", func(r chi.Router) {
+		r.Use(middleware.Auth(authService))
+		r.Get("/", apiKeyHandler.List)
+		r.Post("/", apiKeyHandler.Create)
+		r.Delete("/{id}", apiKeyHandler.Revoke)
+	})
+
+	r.Route("/api/v1/admin", func(r chi.Router) {
+		r.Use(middleware.Auth(authService))
+		r.Use(middleware.RequireRole(model.RoleAdmin))
+		r.Get("/users", adminHandler.ListUsers)
+		r.Get("/users/{id}", adminHandler.GetUser)
+		r.Delete("/users/{id}", adminHandler.DeleteUser)
+		r.Put("/users/{id}/role", adminHandler.UpdateRole)
+		r.Post("/users/{id}/disable", adminHandler.DisableUser)
+		r.Post("/users/{id}/enable", adminHandler.EnableUser)
+		r.Post("/users/{id}/logout", adminHandler.LogoutUser)
+		r.Post("/users/{id}/unlock", adminHandler.UnlockUser)
+		r.Get("/users/{id}/usage", adminHandler.Usage)
+		r.Get("/users/{id}/export", adminHandler.ExportUser)
+	})
+
 	// Server setup
 	server := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
@@ -123,3 +281,31 @@ func corsMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// loadSigningKeys loads the keys from KeysDir. Only development may sign
+// with a temporary key: tokens issued by other instances or before a
+// restart would fail verification.
+func loadSigningKeys(cfg *config.JWTConfig, development bool) (*signing.KeySet, error) {
+	if cfg.KeysDir == "" {
+		if !development {
+			return nil, errors.New("JWT_KEYS_DIR is required unless APP_ENV=development")
+		}
+		log.Println("WARNING: JWT_KEYS_DIR is not set, signing with a temporary key; tokens will not survive a restart")
+		return signing.Generate()
+	}
+
+	keys, err := signing.Load(cfg.KeysDir, cfg.ActiveKeyID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Signing access tokens with key %s (%s)", keys.Active().ID, keys.Active().Algorithm())
+	return keys, nil
+}
+
+func temporaryKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
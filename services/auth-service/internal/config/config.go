@@ -1,22 +1,42 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// Config is the service configuration. Env is the deployment environment
+// from APP_ENV, "production" unless set; only "development" allows settings
+// that are unsafe in production, such as temporary keys.
 type Config struct {
+	Env      string
 	Server   ServerConfig
 	Database DatabaseConfig
 	Redis    RedisConfig
 	JWT      JWTConfig
+	Account  AccountConfig
+	Mailer   MailerConfig
+	MFA      MFAConfig
+	Login    LoginConfig
+	OIDC     OIDCConfig
+	Services ServicesConfig
 }
 
+// ServerConfig configures the HTTP server. TrustedProxies lists the
+// addresses or CIDR ranges of reverse proxies whose X-Real-IP header is
+// taken as the client address; requests from anywhere else are identified
+// by their own address, so clients can't pick the IP that login throttling
+// counts against.
 type ServerConfig struct {
-	Port         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
+	Port           string
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -35,18 +55,178 @@ type RedisConfig struct {
 	DB       int
 }
 
+// JWTConfig configures access tokens. They are signed with the keys in
+// KeysDir (see signing.Load), which is required outside development; in
+// development a key is generated at startup when it is empty. LegacySecret accepts HS256 tokens
+// issued before asymmetric signing and should be empty once they expired.
 type JWTConfig struct {
-	Secret           string
-	AccessTokenTTL   time.Duration
-	RefreshTokenTTL  time.Duration
+	KeysDir         string
+	ActiveKeyID     string
+	LegacySecret    string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// AccountConfig configures email verification and password reset.
+type AccountConfig struct {
+	AppURL         string // frontend base URL used in email links
+	VerifyTokenTTL time.Duration
+	ResetTokenTTL  time.Duration
+}
+
+// MailerConfig selects how emails are sent. Driver "smtp" sends through
+// the SMTP server; "capture" keeps messages in memory and logs them, for
+// local development and tests.
+type MailerConfig struct {
+	Driver   string
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// MFAConfig configures TOTP two-factor authentication. TOTP secrets are
+// encrypted with a key derived from EncryptionKey; changing it invalidates
+// all enrollments. It is required outside development; in development a
+// key is generated at startup as long as no secrets are stored yet.
+type MFAConfig struct {
+	Issuer        string
+	EncryptionKey string
+	ChallengeTTL  time.Duration
+}
+
+// LoginConfig throttles failed logins per account (email) and per IP.
+// After the free attempts every failure blocks the key for BackoffBase,
+// doubling up to BackoffMax; at the lockout threshold the key is blocked
+// for LockoutDuration. Failures are forgotten Window after the first one.
+type LoginConfig struct {
+	FreeAttempts       int64
+	LockoutThreshold   int64
+	IPFreeAttempts     int64
+	IPLockoutThreshold int64
+	BackoffBase        time.Duration
+	BackoffMax         time.Duration
+	LockoutDuration    time.Duration
+	Window             time.Duration
+}
+
+// ServicesConfig holds the base URLs of the internal ports of the services
+// that own user data. The admin and account endpoints call them directly,
+// bypassing nginx.
+type ServicesConfig struct {
+	BacklinkURL string
+	HealthURL   string
+	IndexURL    string
+	Timeout     time.Duration
+}
+
+// OIDCConfig configures sign-in with OpenID Connect providers. The
+// providers are listed in OIDC_PROVIDERS (e.g. "google,yandex") and read
+// their settings from OIDC_<NAME>_* variables; google and yandex have
+// defaults for everything except the client credentials. RedirectURL is the
+// frontend page providers send the user back to; "{provider}" in it is
+// replaced with the provider name.
+type OIDCConfig struct {
+	RedirectURL string
+	StateTTL    time.Duration
+	Providers   []OIDCProviderConfig
+}
+
+// OIDCProviderConfig describes one provider. Endpoints are discovered from
+// Issuer unless set explicitly, which is needed for plain OAuth 2.0
+// providers such as Yandex. The claim names pick the user's ID, email and
+// name from the ID token or the userinfo response. TrustEmail treats the
+// email as verified for providers that do not send a verification claim.
+type OIDCProviderConfig struct {
+	Name        string
+	DisplayName string
+
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+
+	// UserInfoAuthScheme is the Authorization scheme for the userinfo
+	// request, "Bearer" unless the provider wants another
+	UserInfoAuthScheme string
+
+	SubjectClaim       string
+	EmailClaim         string
+	EmailVerifiedClaim string
+	NameClaim          string
+	TrustEmail         bool
+}
+
+// oidcPresets are the defaults of well-known providers.
+var oidcPresets = map[string]OIDCProviderConfig{
+	"google": {
+		DisplayName: "Google",
+		Issuer:      "https://accounts.google.com",
+		Scopes:      []string{"openid", "email", "profile"},
+	},
+	"yandex": {
+		DisplayName:        "Yandex",
+		Scopes:             []string{"login:email", "login:info"},
+		AuthURL:            "https://oauth.yandex.ru/authorize",
+		TokenURL:           "https://oauth.yandex.ru/token",
+		UserInfoURL:        "https://login.yandex.ru/info?format=json",
+		UserInfoAuthScheme: "OAuth",
+		SubjectClaim:       "id",
+		EmailClaim:         "default_email",
+		NameClaim:          "real_name",
+		TrustEmail:         true,
+	},
+}
+
+func loadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		preset := oidcPresets[name]
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		scopes := strings.Join(preset.Scopes, " ")
+		if len(preset.Scopes) == 0 {
+			scopes = "openid email profile"
+		}
+
+		providers = append(providers, OIDCProviderConfig{
+			Name:               name,
+			DisplayName:        getEnv(prefix+"DISPLAY_NAME", orDefault(preset.DisplayName, name)),
+			Issuer:             getEnv(prefix+"ISSUER", preset.Issuer),
+			ClientID:           getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret:       getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:             strings.Fields(getEnv(prefix+"SCOPES", scopes)),
+			AuthURL:            getEnv(prefix+"AUTH_URL", preset.AuthURL),
+			TokenURL:           getEnv(prefix+"TOKEN_URL", preset.TokenURL),
+			UserInfoURL:        getEnv(prefix+"USERINFO_URL", preset.UserInfoURL),
+			UserInfoAuthScheme: getEnv(prefix+"USERINFO_AUTH_SCHEME", orDefault(preset.UserInfoAuthScheme, "Bearer")),
+			SubjectClaim:       getEnv(prefix+"SUBJECT_CLAIM", orDefault(preset.SubjectClaim, "sub")),
+			EmailClaim:         getEnv(prefix+"EMAIL_CLAIM", orDefault(preset.EmailClaim, "email")),
+			EmailVerifiedClaim: getEnv(prefix+"EMAIL_VERIFIED_CLAIM", "email_verified"),
+			NameClaim:          getEnv(prefix+"NAME_CLAIM", orDefault(preset.NameClaim, "name")),
+			TrustEmail:         getBoolEnv(prefix+"TRUST_EMAIL", preset.TrustEmail),
+		})
+	}
+	return providers
 }
 
 func Load() *Config {
 	return &Config{
+		Env: getEnv("APP_ENV", "production"),
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 10*time.Second),
-			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 10*time.Second),
+			Port:           getEnv("SERVER_PORT", "8080"),
+			ReadTimeout:    getDurationEnv("SERVER_READ_TIMEOUT", 10*time.Second),
+			WriteTimeout:   getDurationEnv("SERVER_WRITE_TIMEOUT", 10*time.Second),
+			TrustedProxies: getListEnv("TRUSTED_PROXIES"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -63,11 +243,118 @@ func Load() *Config {
 			DB:       getIntEnv("REDIS_DB", 0),
 		},
 		JWT: JWTConfig{
-			Secret:          getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+			KeysDir:         getEnv("JWT_KEYS_DIR", ""),
+			ActiveKeyID:     getEnv("JWT_ACTIVE_KEY_ID", ""),
+			LegacySecret:    getEnv("JWT_SECRET", ""),
 			AccessTokenTTL:  getDurationEnv("JWT_ACCESS_TTL", 15*time.Minute),
 			RefreshTokenTTL: getDurationEnv("JWT_REFRESH_TTL", 7*24*time.Hour),
 		},
+		Account: AccountConfig{
+			AppURL:         getEnv("APP_URL", "http://localhost:3000"),
+			VerifyTokenTTL: getDurationEnv("EMAIL_VERIFY_TTL", 48*time.Hour),
+			ResetTokenTTL:  getDurationEnv("PASSWORD_RESET_TTL", time.Hour),
+		},
+		Mailer: MailerConfig{
+			Driver:   getEnv("MAILER_DRIVER", "capture"),
+			Host:     getEnv("SMTP_HOST", "localhost"),
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("MAIL_FROM", "Link Tracker <no-reply@localhost>"),
+		},
+		MFA: MFAConfig{
+			Issuer:        getEnv("MFA_ISSUER", "Link Tracker"),
+			EncryptionKey: getEnv("MFA_ENCRYPTION_KEY", ""),
+			ChallengeTTL:  getDurationEnv("MFA_CHALLENGE_TTL", 5*time.Minute),
+		},
+		Login: LoginConfig{
+			FreeAttempts:       int64(getIntEnv("LOGIN_FREE_ATTEMPTS", 3)),
+			LockoutThreshold:   int64(getIntEnv("LOGIN_LOCKOUT_THRESHOLD", 10)),
+			IPFreeAttempts:     int64(getIntEnv("LOGIN_IP_FREE_ATTEMPTS", 20)),
+			IPLockoutThreshold: int64(getIntEnv("LOGIN_IP_LOCKOUT_THRESHOLD", 100)),
+			BackoffBase:        getDurationEnv("LOGIN_BACKOFF_BASE", time.Second),
+			BackoffMax:         getDurationEnv("LOGIN_BACKOFF_MAX", 5*time.Minute),
+			LockoutDuration:    getDurationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			Window:             getDurationEnv("LOGIN_ATTEMPT_WINDOW", time.Hour),
+		},
+		OIDC: OIDCConfig{
+			RedirectURL: getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/oidc/{provider}/callback"),
+			StateTTL:    getDurationEnv("OIDC_STATE_TTL", 10*time.Minute),
+			Providers:   loadOIDCProviders(),
+		},
+		Services: ServicesConfig{
+			BacklinkURL: getEnv("BACKLINK_SERVICE_URL", "http://localhost:9082"),
+			IndexURL:    getEnv("INDEX_SERVICE_URL", "http://localhost:9083"),
+			HealthURL:   getEnv("HEALTH_SERVICE_URL", "http://localhost:9084"),
+			Timeout:     getDurationEnv("SERVICES_TIMEOUT", 10*time.Second),
+		},
+	}
+}
+
+// defaultSecrets are HS256 secrets that shipped as config defaults and are
+// therefore public.
+var defaultSecrets = map[string]bool{
+	"your-secret-key":                      true,
+	"your-secret-key-change-in-production": true,
+	"dev-secret-change-in-production":      true,
+}
+
+// CheckLegacySecret rejects a legacy secret that is a published default or
+// too short. An empty secret turns HS256 off and is fine.
+func (c *JWTConfig) CheckLegacySecret() error {
+	if c.LegacySecret == "" {
+		return nil
+	}
+	if defaultSecrets[c.LegacySecret] {
+		return errors.New("JWT_SECRET is set to a published default; remove it or set a real secret")
+	}
+	if len(c.LegacySecret) < 32 {
+		return fmt.Errorf("JWT_SECRET must be at least 32 bytes, got %d", len(c.LegacySecret))
+	}
+	return nil
+}
+
+// TrustedProxyPrefixes parses TrustedProxies. A bare address stands for
+// itself.
+func (c *ServerConfig) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, v := range c.TrustedProxies {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
 	}
+	return prefixes, nil
+}
+
+// Development reports whether the service runs in local development.
+func (c *Config) Development() bool {
+	return c.Env == "development"
+}
+
+// CheckEncryptionKey rejects an MFA key that is a published default or too
+// short. An empty key is checked by the caller, which knows whether
+// secrets are stored.
+func (c *MFAConfig) CheckEncryptionKey() error {
+	if c.EncryptionKey == "" {
+		return nil
+	}
+	if c.EncryptionKey == "dev-mfa-key-change-in-production" {
+		return errors.New("MFA_ENCRYPTION_KEY is set to a published default; remove it or set a real key")
+	}
+	if len(c.EncryptionKey) < 32 {
+		return fmt.Errorf("MFA_ENCRYPTION_KEY must be at least 32 bytes, got %d", len(c.EncryptionKey))
+	}
+	return nil
 }
 
 func (c *DatabaseConfig) DSN() string {
@@ -85,6 +372,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getListEnv splits a comma-separated variable, dropping empty items.
+func getListEnv(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getIntEnv(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intVal, err := strconv.Atoi(value); err == nil {
@@ -102,3 +400,19 @@ func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+func getBoolEnv(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func orDefault(value, defaultValue string) string {
+	if value != "" {
+		return value
+	}
+	return defaultValue
+}
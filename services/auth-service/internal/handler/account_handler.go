@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/link-tracker/auth-service/internal/middleware"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/service"
+)
+
+type AccountHandler struct {
+	accountService *service.AccountService
+}
+
+func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+// ResendVerification emails a new verification link to the current user
+// POST /api/v1/auth/verify-email/resend
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	if err := h.accountService.ResendVerification(r.Context(), userID); err != nil {
+		if errors.Is(err, service.ErrEmailAlreadyVerified) {
+			respondError(w, http.StatusConflict, err.Error(), "ALREADY_VERIFIED")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "failed to send verification email", "INTERNAL_ERROR")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.MessageResponse{Message: "verification email sent"})
+}
+
+// VerifyEmail confirms an email address with the emailed token
+// POST /api/v1/auth/verify-email
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req model.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.Token == "" {
+		respondError(w, http.StatusBadRequest, "token is required", "VALIDATION_ERROR")
+		return
+	}
+
+	if err := h.accountService.VerifyEmail(r.Context(), req.Token); err != nil {
+		respondAccountTokenError(w, err, "failed to verify email")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.MessageResponse{Message: "email verified"})
+}
+
+// ForgotPassword emails a password reset link. It answers the same way
+// whether or not the email is registered.
+// POST /api/v1/auth/password/forgot
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req model.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.Email == "" {
+		respondError(w, http.StatusBadRequest, "email is required", "VALIDATION_ERROR")
+		return
+	}
+
+	if err := h.accountService.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to send password reset email", "INTERNAL_ERROR")
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, model.MessageResponse{
+		Message: "if the email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password with the emailed token
+// POST /api/v1/auth/password/reset
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req model.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.Token == "" {
+		respondError(w, http.StatusBadRequest, "token is required", "VALIDATION_ERROR")
+		return
+	}
+	if err := validatePassword(req.Password); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+
+	if err := h.accountService.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		respondAccountTokenError(w, err, "failed to reset password")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.MessageResponse{Message: "password has been reset"})
+}
+
+// respondAccountTokenError maps the errors of emailed token endpoints.
+func respondAccountTokenError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidToken):
+		respondError(w, http.StatusBadRequest, "invalid or already used token", "INVALID_TOKEN")
+	case errors.Is(err, service.ErrTokenExpired):
+		respondError(w, http.StatusBadRequest, "token expired", "TOKEN_EXPIRED")
+	default:
+		respondError(w, http.StatusInternalServerError, message, "INTERNAL_ERROR")
+	}
+}
@@ -0,0 +1,387 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/link-tracker/auth-service/internal/middleware"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/repository"
+	"github.com/link-tracker/auth-service/internal/service"
+)
+
+// AdminHandler serves user management for admins. Routes are guarded by
+// middleware.RequireRole.
+type AdminHandler struct {
+	adminService *service.AdminService
+	loginGuard   *service.LoginGuard
+}
+
+func NewAdminHandler(adminService *service.AdminService, loginGuard *service.LoginGuard) *AdminHandler {
+	return &AdminHandler{
+		adminService: adminService,
+		loginGuard:   loginGuard,
+	}
+}
+
+// ListUsers lists and searches users, newest first
+// GET /api/v1/admin/users
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filters := &model.UserFilters{
+		Query:   query.Get("q"),
+		Role:    model.Role(query.Get("role")),
+		Status:  model.UserStatus(query.Get("status")),
+		Page:    1,
+		PerPage: 20,
+	}
+	if v := query.Get("page"); v != "" {
+		if page, err := strconv.Atoi(v); err == nil {
+			filters.Page = page
+		}
+	}
+	if v := query.Get("per_page"); v != "" {
+		if perPage, err := strconv.Atoi(v); err == nil {
+			filters.PerPage = perPage
+		}
+	}
+
+	if filters.Status != "" && filters.Status != model.UserStatusActive && filters.Status != model.UserStatusDisabled {
+		respondError(w, http.StatusBadRequest, "status must be one of: active, disabled", "VALIDATION_ERROR")
+		return
+	}
+	if filters.Role != "" && filters.Role != model.RoleUser && filters.Role != model.RoleAdmin {
+		respondError(w, http.StatusBadRequest, service.ErrInvalidUserRole.Error(), "VALIDATION_ERROR")
+		return
+	}
+
+	// Any cursor parameter, even an empty one for the first page, switches
+	// to cursor pagination
+	if query.Has("cursor") {
+		h.listUsersCursor(w, r, filters)
+		return
+	}
+
+	users, total, err := h.adminService.ListUsers(r.Context(), filters)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list users", "INTERNAL_ERROR")
+		return
+	}
+
+	data := make([]model.AdminUserResponse, 0, len(users))
+	for _, user := range users {
+		data = append(data, adminUserResponse(user))
+	}
+
+	respondJSON(w, http.StatusOK, model.UserListResponse{
+		Data:       data,
+		Page:       filters.Page,
+		PerPage:    filters.PerPage,
+		Total:      total,
+		TotalPages: int((total + int64(filters.PerPage) - 1) / int64(filters.PerPage)),
+	})
+}
+
+func (h *AdminHandler) listUsersCursor(w http.ResponseWriter, r *http.Request, filters *model.UserFilters) {
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		var after model.UserCursor
+		if err := decodeCursor(v, &after); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		filters.After = &after
+	}
+
+	users, next, err := h.adminService.ListUsersCursor(r.Context(), filters)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			respondError(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "failed to list users", "INTERNAL_ERROR")
+		return
+	}
+
+	resp := model.UserCursorListResponse{
+		Data:    make([]model.AdminUserResponse, 0, len(users)),
+		PerPage: filters.PerPage,
+		HasMore: next != nil,
+	}
+	for _, user := range users {
+		resp.Data = append(resp.Data, adminUserResponse(user))
+	}
+	if next != nil {
+		if resp.NextCursor, err = encodeCursor(next); err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to list users", "INTERNAL_ERROR")
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// encodeCursor turns a list position into an opaque URL-safe string, in the
+// format the other services use.
+func encodeCursor(position interface{}) (string, error) {
+	b, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor reads a cursor produced by encodeCursor into position. The
+// decoded values are untrusted.
+func decodeCursor(cursor string, position interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return service.ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, position); err != nil {
+		return service.ErrInvalidCursor
+	}
+	return nil
+}
+
+// GetUser returns a user
+// GET /api/v1/admin/users/{id}
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminService.GetUser(r.Context(), userID)
+	if err != nil {
+		respondAdminError(w, err, "failed to get user")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, adminUserResponse(user))
+}
+
+// UpdateRole changes the role of a user
+// PUT /api/v1/admin/users/{id}/role
+func (h *AdminHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	user, err := h.adminService.UpdateRole(r.Context(), adminID, userID, req.Role)
+	if err != nil {
+		respondAdminError(w, err, "failed to update role")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, adminUserResponse(user))
+}
+
+// DisableUser blocks a user from signing in and revokes their sessions
+// POST /api/v1/admin/users/{id}/disable
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+// EnableUser lets a disabled user sign in again
+// POST /api/v1/admin/users/{id}/enable
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *AdminHandler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	adminID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminService.SetDisabled(r.Context(), adminID, userID, disabled)
+	if err != nil {
+		respondAdminError(w, err, "failed to update user")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, adminUserResponse(user))
+}
+
+// LogoutUser revokes all sessions of a user
+// POST /api/v1/admin/users/{id}/logout
+func (h *AdminHandler) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.LogoutUser(r.Context(), adminID, userID); err != nil {
+		respondAdminError(w, err, "failed to log out user")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.MessageResponse{Message: "all sessions revoked"})
+}
+
+// DeleteUser deletes a user and their data in all services
+// DELETE /api/v1/admin/users/{id}
+func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.DeleteUser(r.Context(), adminID, userID); err != nil {
+		if !respondDeletionError(w, err) {
+			respondAdminError(w, err, "failed to delete user")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExportUser downloads everything stored about a user as JSON or, with
+// ?format=zip, as a ZIP archive
+// GET /api/v1/admin/users/{id}/export
+func (h *AdminHandler) ExportUser(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	format, ok := parseExportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.adminService.ExportUser(r.Context(), adminID, userID)
+	if err != nil {
+		if !respondExportError(w, err) {
+			respondAdminError(w, err, "failed to export user")
+		}
+		return
+	}
+
+	writeExport(w, export, format)
+}
+
+// Usage counts the projects, backlinks, sites and platforms of a user
+// GET /api/v1/admin/users/{id}/usage
+func (h *AdminHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	usage, err := h.adminService.Usage(r.Context(), userID)
+	if err != nil {
+		respondAdminError(w, err, "failed to get usage")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, usage)
+}
+
+// UnlockUser lifts a login lockout of a user
+// POST /api/v1/admin/users/{id}/unlock
+func (h *AdminHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	userID, ok := parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.loginGuard.Unlock(r.Context(), adminID, userID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			respondError(w, http.StatusNotFound, "user not found", "USER_NOT_FOUND")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "failed to unlock user", "INTERNAL_ERROR")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.MessageResponse{Message: "user unlocked"})
+}
+
+func parseUserID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid user id", "INVALID_ID")
+		return 0, false
+	}
+	return userID, true
+}
+
+// respondAdminError maps the errors shared by admin endpoints.
+func respondAdminError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		respondError(w, http.StatusNotFound, "user not found", "USER_NOT_FOUND")
+	case errors.Is(err, service.ErrInvalidUserRole):
+		respondError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+	case errors.Is(err, service.ErrCannotModifySelf):
+		respondError(w, http.StatusConflict, err.Error(), "CANNOT_MODIFY_SELF")
+	case errors.Is(err, service.ErrLastAdmin):
+		respondError(w, http.StatusConflict, err.Error(), "LAST_ADMIN")
+	case errors.Is(err, service.ErrServicesUnavailable):
+		respondError(w, http.StatusBadGateway, err.Error(), "SERVICES_UNAVAILABLE")
+	default:
+		respondError(w, http.StatusInternalServerError, message, "INTERNAL_ERROR")
+	}
+}
+
+func adminUserResponse(user *model.User) model.AdminUserResponse {
+	response := model.AdminUserResponse{
+		UserResponse: userResponse(user),
+		Disabled:     user.DisabledAt != nil,
+		UpdatedAt:    user.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if user.DisabledAt != nil {
+		disabledAt := user.DisabledAt.Format("2006-01-02T15:04:05Z")
+		response.DisabledAt = &disabledAt
+	}
+	return response
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/link-tracker/auth-service/internal/middleware"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/repository"
+	"github.com/link-tracker/auth-service/internal/service"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+// List returns the API keys of the current user without the keys themselves
+// GET /api/v1/api-keys
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	keys, err := h.apiKeyService.List(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list api keys", "INTERNAL_ERROR")
+		return
+	}
+
+	data := make([]model.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		data[i] = apiKeyResponse(key)
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+// Create issues an API key. The key is only returned in this response.
+// POST /api/v1/api-keys
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	var req model.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	key, raw, err := h.apiKeyService.Create(r.Context(), userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAPIKey):
+			respondError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		case errors.Is(err, service.ErrTooManyAPIKeys):
+			respondError(w, http.StatusConflict, err.Error(), "TOO_MANY_API_KEYS")
+		default:
+			respondError(w, http.StatusInternalServerError, "failed to create api key", "INTERNAL_ERROR")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, model.CreatedAPIKeyResponse{
+		APIKeyResponse: apiKeyResponse(key),
+		Key:            raw,
+	})
+}
+
+// Revoke deletes an API key
+// DELETE /api/v1/api-keys/{id}
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid api key id", "INVALID_ID")
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(r.Context(), userID, id); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			respondError(w, http.StatusNotFound, "api key not found", "NOT_FOUND")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "failed to revoke api key", "INTERNAL_ERROR")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiKeyResponse(key *model.APIKey) model.APIKeyResponse {
+	return model.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  formatOptionalTime(key.ExpiresAt),
+		LastUsedAt: formatOptionalTime(key.LastUsedAt),
+		CreatedAt:  key.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format("2006-01-02T15:04:05Z")
+	return &s
+}
@@ -3,7 +3,11 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/link-tracker/auth-service/internal/middleware"
 	"github.com/link-tracker/auth-service/internal/model"
@@ -12,11 +16,15 @@ import (
 )
 
 type AuthHandler struct {
-	authService *service.AuthService
+	authService    *service.AuthService
+	accountService *service.AccountService
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *service.AuthService, accountService *service.AccountService) *AuthHandler {
+	return &AuthHandler{
+		authService:    authService,
+		accountService: accountService,
+	}
 }
 
 // Register handles user registration
@@ -43,13 +51,13 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusCreated, model.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		Name:      user.Name,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	})
+	// The account works without a verified address, so a mail failure must
+	// not fail registration; the user can ask for the link again
+	if err := h.accountService.SendVerification(r.Context(), user); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	respondJSON(w, http.StatusCreated, userResponse(user))
 }
 
 // Login handles user authentication
@@ -66,12 +74,19 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authResponse, err := h.authService.Login(r.Context(), &req)
+	authResponse, err := h.authService.Login(r.Context(), &req, clientInfo(r))
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			respondError(w, http.StatusUnauthorized, "invalid email or password", "INVALID_CREDENTIALS")
 			return
 		}
+		if errors.Is(err, service.ErrAccountDisabled) {
+			respondError(w, http.StatusForbidden, err.Error(), "ACCOUNT_DISABLED")
+			return
+		}
+		if respondThrottled(w, err) {
+			return
+		}
 		respondError(w, http.StatusInternalServerError, "failed to login", "INTERNAL_ERROR")
 		return
 	}
@@ -79,6 +94,19 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, authResponse)
 }
 
+// respondThrottled answers 429 with Retry-After if err is a ThrottledError
+// and reports whether it did.
+func respondThrottled(w http.ResponseWriter, err error) bool {
+	var throttled *service.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	respondError(w, http.StatusTooManyRequests, "too many failed login attempts, try again later", "TOO_MANY_ATTEMPTS")
+	return true
+}
+
 // Refresh handles token refresh
 // POST /api/v1/auth/refresh
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
@@ -93,12 +121,20 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authResponse, err := h.authService.RefreshTokens(r.Context(), req.RefreshToken)
+	authResponse, err := h.authService.RefreshTokens(r.Context(), req.RefreshToken, clientInfo(r))
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidToken) {
 			respondError(w, http.StatusUnauthorized, "invalid refresh token", "INVALID_TOKEN")
 			return
 		}
+		if errors.Is(err, service.ErrTokenReused) {
+			respondError(w, http.StatusUnauthorized, "refresh token was already used, the session has been revoked", "TOKEN_REUSED")
+			return
+		}
+		if errors.Is(err, service.ErrAccountDisabled) {
+			respondError(w, http.StatusForbidden, err.Error(), "ACCOUNT_DISABLED")
+			return
+		}
 		respondError(w, http.StatusInternalServerError, "failed to refresh tokens", "INTERNAL_ERROR")
 		return
 	}
@@ -125,13 +161,7 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondJSON(w, http.StatusOK, model.UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		Name:      user.Name,
-		Role:      user.Role,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z"),
-	})
+	respondJSON(w, http.StatusOK, userResponse(user))
 }
 
 // Logout handles user logout
@@ -158,16 +188,84 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func validateRegisterRequest(req *model.RegisterRequest) error {
-	if req.Email == "" {
-		return errors.New("email is required")
+// ChangePassword changes the password and signs out other sessions
+// POST /api/v1/auth/password/change
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	var req model.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.CurrentPassword == "" {
+		respondError(w, http.StatusBadRequest, "current_password is required", "VALIDATION_ERROR")
+		return
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+		return
+	}
+
+	authResponse, err := h.authService.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword, clientInfo(r))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			respondError(w, http.StatusUnauthorized, "current password is incorrect", "INVALID_CREDENTIALS")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "failed to change password", "INTERNAL_ERROR")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, authResponse)
+}
+
+// clientInfo describes the client for session listings. RemoteAddr already
+// holds the real IP when the request came through the proxy.
+func clientInfo(r *http.Request) model.ClientInfo {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return model.ClientInfo{
+		IPAddress: ip,
+		UserAgent: r.UserAgent(),
+	}
+}
+
+func userResponse(user *model.User) model.UserResponse {
+	return model.UserResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Name:          user.Name,
+		Role:          user.Role,
+		CreatedAt:     user.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	}
-	if req.Password == "" {
+}
+
+func validatePassword(password string) error {
+	if password == "" {
 		return errors.New("password is required")
 	}
-	if len(req.Password) < 8 {
+	if len(password) < 8 {
 		return errors.New("password must be at least 8 characters")
 	}
+	return nil
+}
+
+func validateRegisterRequest(req *model.RegisterRequest) error {
+	if req.Email == "" {
+		return errors.New("email is required")
+	}
+	if err := validatePassword(req.Password); err != nil {
+		return err
+	}
 	if req.Name == "" {
 		return errors.New("name is required")
 	}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/link-tracker/auth-service/internal/middleware"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/service"
+)
+
+const (
+	exportFormatJSON = "json"
+	exportFormatZIP  = "zip"
+)
+
+// DataHandler serves the export and deletion of the caller's own account.
+type DataHandler struct {
+	dataService *service.DataService
+}
+
+func NewDataHandler(dataService *service.DataService) *DataHandler {
+	return &DataHandler{dataService: dataService}
+}
+
+// ExportAccount downloads everything stored about the caller as JSON or,
+// with ?format=zip, as a ZIP archive
+// GET /api/v1/auth/account/export
+func (h *DataHandler) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	format, ok := parseExportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.dataService.ExportAccount(r.Context(), userID)
+	if err != nil {
+		if !respondExportError(w, err) {
+			respondDataError(w, err, "failed to export account")
+		}
+		return
+	}
+
+	writeExport(w, export, format)
+}
+
+// DeleteAccount deletes the caller's account and their data in all services
+// DELETE /api/v1/auth/account
+func (h *DataHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	var req model.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	err := h.dataService.DeleteAccount(r.Context(), userID, req.Password, req.Code)
+	if err != nil {
+		if !respondDeletionError(w, err) {
+			respondDataError(w, err, "failed to delete account")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseExportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		return exportFormatJSON, true
+	case exportFormatJSON, exportFormatZIP:
+		return format, true
+	default:
+		respondError(w, http.StatusBadRequest, "format must be one of: json, zip", "VALIDATION_ERROR")
+		return "", false
+	}
+}
+
+// writeExport sends an export as one JSON document or as a ZIP archive with
+// account.json and a file per service.
+func writeExport(w http.ResponseWriter, export *model.AccountExport, format string) {
+	filename := fmt.Sprintf("account-%d-%s.%s", export.Account.User.ID, export.ExportedAt.Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if format == exportFormatJSON {
+		respondJSON(w, http.StatusOK, export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.WriteHeader(http.StatusOK)
+
+	// The status is sent, so a failure can only cut the archive short
+	if err := writeExportZIP(w, export); err != nil {
+		log.Printf("failed to write export of user %d: %v", export.Account.User.ID, err)
+	}
+}
+
+func writeExportZIP(w http.ResponseWriter, export *model.AccountExport) error {
+	account, err := json.MarshalIndent(struct {
+		ExportedAt time.Time          `json:"exported_at"`
+		Account    *model.AccountData `json:"account"`
+	}{export.ExportedAt, export.Account}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	if err := addZIPFile(archive, "account.json", export.ExportedAt, account); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(export.Services))
+	for name := range export.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := addZIPFile(archive, name+".json", export.ExportedAt, export.Services[name]); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func addZIPFile(archive *zip.Writer, name string, modified time.Time, data []byte) error {
+	f, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// respondExportError answers errors particular to exports and reports
+// whether it did.
+func respondExportError(w http.ResponseWriter, err error) bool {
+	var throttled *service.ExportThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		respondError(w, http.StatusTooManyRequests, "an export was started recently, try again later", "EXPORT_THROTTLED")
+	case errors.Is(err, service.ErrExportTooLarge):
+		respondError(w, http.StatusUnprocessableEntity, err.Error(), "EXPORT_TOO_LARGE")
+	default:
+		return false
+	}
+	return true
+}
+
+// respondDeletionError answers a deletion that some services failed and
+// reports whether it did.
+func respondDeletionError(w http.ResponseWriter, err error) bool {
+	var incomplete *service.DeletionIncompleteError
+	if !errors.As(err, &incomplete) {
+		return false
+	}
+	respondJSON(w, http.StatusBadGateway, model.DeletionIncompleteResponse{
+		Error:   "the account is disabled, but some services failed to delete its data; retry the deletion",
+		Code:    "DELETION_INCOMPLETE",
+		Deleted: incomplete.Deleted,
+	})
+	return true
+}
+
+func respondDataError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		respondError(w, http.StatusUnauthorized, "password is incorrect", "INVALID_CREDENTIALS")
+	case errors.Is(err, service.ErrInvalidMFACode):
+		respondError(w, http.StatusUnauthorized, err.Error(), "INVALID_MFA_CODE")
+	case errors.Is(err, service.ErrLastAdmin):
+		respondError(w, http.StatusConflict, err.Error(), "LAST_ADMIN")
+	case errors.Is(err, service.ErrServicesUnavailable):
+		respondError(w, http.StatusBadGateway, err.Error(), "SERVICES_UNAVAILABLE")
+	default:
+		respondError(w, http.StatusInternalServerError, message, "INTERNAL_ERROR")
+	}
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/link-tracker/auth-service/internal/signing"
+)
+
+type JWKSHandler struct {
+	keys *signing.KeySet
+}
+
+func NewJWKSHandler(keys *signing.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// JWKS publishes the public keys access tokens are signed with
+// GET /.well-known/jwks.json
+func (h *JWKSHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	// Verifiers refetch on unknown key IDs, so a short max-age is enough
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondJSON(w, http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/link-tracker/auth-service/internal/middleware"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/service"
+)
+
+type MFAHandler struct {
+	mfaService  *service.MFAService
+	authService *service.AuthService
+}
+
+func NewMFAHandler(mfaService *service.MFAService, authService *service.AuthService) *MFAHandler {
+	return &MFAHandler{
+		mfaService:  mfaService,
+		authService: authService,
+	}
+}
+
+// Status returns whether the current user has MFA enabled
+// GET /api/v1/auth/mfa
+func (h *MFAHandler) Status(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	status, err := h.mfaService.Status(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get mfa status", "INTERNAL_ERROR")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, status)
+}
+
+// Setup starts TOTP enrollment and returns the secret for the authenticator app
+// POST /api/v1/auth/mfa/totp/setup
+func (h *MFAHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	setup, err := h.mfaService.Setup(r.Context(), userID)
+	if err != nil {
+		respondMFAError(w, err, "failed to start mfa setup")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, setup)
+}
+
+// Enable confirms TOTP enrollment and returns recovery codes
+// POST /api/v1/auth/mfa/totp/enable
+func (h *MFAHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	var req model.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.Code == "" {
+		respondError(w, http.StatusBadRequest, "code is required", "VALIDATION_ERROR")
+		return
+	}
+
+	codes, err := h.mfaService.Enable(r.Context(), userID, req.Code)
+	if err != nil {
+		respondMFAError(w, err, "failed to enable mfa")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable turns MFA off
+// POST /api/v1/auth/mfa/totp/disable
+func (h *MFAHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	var req model.DisableMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.Password == "" || req.Code == "" {
+		respondError(w, http.StatusBadRequest, "password and code are required", "VALIDATION_ERROR")
+		return
+	}
+
+	if err := h.mfaService.Disable(r.Context(), userID, req.Password, req.Code); err != nil {
+		respondMFAError(w, err, "failed to disable mfa")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.MessageResponse{Message: "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes
+// POST /api/v1/auth/mfa/recovery-codes
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	var req model.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.Code == "" {
+		respondError(w, http.StatusBadRequest, "code is required", "VALIDATION_ERROR")
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		respondMFAError(w, err, "failed to regenerate recovery codes")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Verify completes a login that returned mfa_required
+// POST /api/v1/auth/mfa/verify
+func (h *MFAHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	var req model.MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.MFAToken == "" || req.Code == "" {
+		respondError(w, http.StatusBadRequest, "mfa_token and code are required", "VALIDATION_ERROR")
+		return
+	}
+
+	authResponse, err := h.authService.VerifyMFA(r.Context(), req.MFAToken, req.Code, clientInfo(r))
+	if err != nil {
+		if respondThrottled(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrInvalidToken):
+			respondError(w, http.StatusUnauthorized, "invalid or used mfa token, log in again", "INVALID_TOKEN")
+		case errors.Is(err, service.ErrTokenExpired):
+			respondError(w, http.StatusUnauthorized, "mfa token expired, log in again", "TOKEN_EXPIRED")
+		default:
+			respondMFAError(w, err, "failed to verify mfa code")
+		}
+		return
+	}
+
+	respondJSON(w, http.StatusOK, authResponse)
+}
+
+// respondMFAError maps the errors shared by MFA endpoints.
+func respondMFAError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode):
+		respondError(w, http.StatusUnauthorized, err.Error(), "INVALID_MFA_CODE")
+	case errors.Is(err, service.ErrInvalidCredentials):
+		respondError(w, http.StatusUnauthorized, "password is incorrect", "INVALID_CREDENTIALS")
+	case errors.Is(err, service.ErrAccountDisabled):
+		respondError(w, http.StatusForbidden, err.Error(), "ACCOUNT_DISABLED")
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		respondError(w, http.StatusConflict, err.Error(), "MFA_ALREADY_ENABLED")
+	case errors.Is(err, service.ErrMFANotEnabled):
+		respondError(w, http.StatusConflict, err.Error(), "MFA_NOT_ENABLED")
+	case errors.Is(err, service.ErrMFASetupNotStarted):
+		respondError(w, http.StatusConflict, err.Error(), "MFA_SETUP_NOT_STARTED")
+	case errors.Is(err, service.ErrMFARequiredByWorkspace):
+		respondError(w, http.StatusConflict, err.Error(), "MFA_REQUIRED")
+	default:
+		respondError(w, http.StatusInternalServerError, message, "INTERNAL_ERROR")
+	}
+}
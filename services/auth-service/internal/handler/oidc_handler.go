@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/service"
+)
+
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService}
+}
+
+// Providers lists the identity providers users can sign in with
+// GET /api/v1/auth/oidc/providers
+func (h *OIDCHandler) Providers(w http.ResponseWriter, r *http.Request) {
+	providers := h.oidcService.Providers()
+	response := make([]model.OIDCProviderResponse, 0, len(providers))
+	for _, provider := range providers {
+		response = append(response, model.OIDCProviderResponse{
+			Name:        provider.Name(),
+			DisplayName: provider.DisplayName(),
+		})
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// Start begins a sign-in with a provider
+// POST /api/v1/auth/oidc/{provider}/start
+func (h *OIDCHandler) Start(w http.ResponseWriter, r *http.Request) {
+	authURL, err := h.oidcService.Start(r.Context(), chi.URLParam(r, "provider"))
+	if err != nil {
+		respondOIDCError(w, err, "failed to start sign-in")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.OIDCStartResponse{AuthorizationURL: authURL})
+}
+
+// Callback completes a sign-in with the code and state the provider
+// redirected the user back with
+// POST /api/v1/auth/oidc/{provider}/callback
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	var req model.OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.Code == "" || req.State == "" {
+		respondError(w, http.StatusBadRequest, "code and state are required", "VALIDATION_ERROR")
+		return
+	}
+
+	authResponse, err := h.oidcService.Callback(r.Context(), chi.URLParam(r, "provider"), req.Code, req.State, clientInfo(r))
+	if err != nil {
+		if respondThrottled(w, err) {
+			return
+		}
+		respondOIDCError(w, err, "failed to sign in")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, authResponse)
+}
+
+func respondOIDCError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrUnknownProvider):
+		respondError(w, http.StatusNotFound, err.Error(), "UNKNOWN_PROVIDER")
+	case errors.Is(err, service.ErrInvalidOIDCState):
+		respondError(w, http.StatusBadRequest, err.Error(), "INVALID_STATE")
+	case errors.Is(err, service.ErrOIDCLoginFailed):
+		respondError(w, http.StatusUnauthorized, err.Error(), "OIDC_LOGIN_FAILED")
+	case errors.Is(err, service.ErrEmailNotVerified):
+		respondError(w, http.StatusForbidden, err.Error(), "EMAIL_NOT_VERIFIED")
+	case errors.Is(err, service.ErrAccountDisabled):
+		respondError(w, http.StatusForbidden, err.Error(), "ACCOUNT_DISABLED")
+	case errors.Is(err, service.ErrProviderUnavailable):
+		respondError(w, http.StatusBadGateway, err.Error(), "PROVIDER_UNAVAILABLE")
+	default:
+		respondError(w, http.StatusInternalServerError, fallback, "INTERNAL_ERROR")
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/link-tracker/auth-service/internal/middleware"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/service"
+)
+
+type SessionHandler struct {
+	authService *service.AuthService
+}
+
+func NewSessionHandler(authService *service.AuthService) *SessionHandler {
+	return &SessionHandler{authService: authService}
+}
+
+// List returns the signed-in devices of the current user
+// GET /api/v1/auth/sessions
+func (h *SessionHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list sessions", "INTERNAL_ERROR")
+		return
+	}
+
+	currentID, _ := middleware.GetSessionID(r.Context())
+	response := make([]model.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionResponse(session, currentID))
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// Revoke signs a device out
+// DELETE /api/v1/auth/sessions/{id}
+func (h *SessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid session id", "INVALID_ID")
+		return
+	}
+
+	if err := h.authService.RevokeSession(r.Context(), userID, id); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			respondError(w, http.StatusNotFound, "session not found", "NOT_FOUND")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "failed to revoke session", "INTERNAL_ERROR")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sessionResponse(session *model.Session, currentID int64) model.SessionResponse {
+	return model.SessionResponse{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IPAddress,
+		Current:    session.ID == currentID,
+		CreatedAt:  session.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		LastUsedAt: session.LastUsedAt.Format("2006-01-02T15:04:05Z"),
+		ExpiresAt:  session.ExpiresAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
@@ -0,0 +1,332 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/link-tracker/auth-service/internal/middleware"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/repository"
+	"github.com/link-tracker/auth-service/internal/service"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *service.WorkspaceService
+}
+
+func NewWorkspaceHandler(workspaceService *service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{workspaceService: workspaceService}
+}
+
+// List returns the workspaces of the current user
+// GET /api/v1/workspaces
+func (h *WorkspaceHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	workspaces, err := h.workspaceService.List(r.Context(), userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to list workspaces", "INTERNAL_ERROR")
+		return
+	}
+
+	data := make([]model.WorkspaceResponse, len(workspaces))
+	for i, ws := range workspaces {
+		data[i] = workspaceResponse(ws)
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+// Create creates a workspace owned by the current user
+// POST /api/v1/workspaces
+func (h *WorkspaceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	var req model.CreateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		respondError(w, http.StatusBadRequest, "name is required", "VALIDATION_ERROR")
+		return
+	}
+
+	workspace, err := h.workspaceService.Create(r.Context(), userID, &req)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to create workspace", "INTERNAL_ERROR")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, workspaceResponse(workspace))
+}
+
+// Get returns a workspace
+// GET /api/v1/workspaces/{id}
+func (h *WorkspaceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid workspace id", "INVALID_ID")
+		return
+	}
+
+	workspace, err := h.workspaceService.Get(r.Context(), userID, id)
+	if err != nil {
+		respondWorkspaceError(w, err, "failed to get workspace")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, workspaceResponse(workspace))
+}
+
+// Update renames a workspace or changes whether it requires MFA
+// PUT /api/v1/workspaces/{id}
+func (h *WorkspaceHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid workspace id", "INVALID_ID")
+		return
+	}
+
+	var req model.UpdateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" && req.RequireMFA == nil {
+		respondError(w, http.StatusBadRequest, "name or require_mfa is required", "VALIDATION_ERROR")
+		return
+	}
+
+	workspace, err := h.workspaceService.Update(r.Context(), userID, id, &req)
+	if err != nil {
+		respondWorkspaceError(w, err, "failed to update workspace")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, workspaceResponse(workspace))
+}
+
+// Delete deletes a workspace
+// DELETE /api/v1/workspaces/{id}
+func (h *WorkspaceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid workspace id", "INVALID_ID")
+		return
+	}
+
+	if err := h.workspaceService.Delete(r.Context(), userID, id); err != nil {
+		respondWorkspaceError(w, err, "failed to delete workspace")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Members lists the members of a workspace
+// GET /api/v1/workspaces/{id}/members
+func (h *WorkspaceHandler) Members(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid workspace id", "INVALID_ID")
+		return
+	}
+
+	members, err := h.workspaceService.Members(r.Context(), userID, id)
+	if err != nil {
+		respondWorkspaceError(w, err, "failed to list members")
+		return
+	}
+
+	data := make([]model.WorkspaceMemberResponse, len(members))
+	for i, m := range members {
+		data[i] = memberResponse(m)
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
+
+// AddMember adds a registered user to a workspace
+// POST /api/v1/workspaces/{id}/members
+func (h *WorkspaceHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid workspace id", "INVALID_ID")
+		return
+	}
+
+	var req model.AddMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.Email == "" {
+		respondError(w, http.StatusBadRequest, "email is required", "VALIDATION_ERROR")
+		return
+	}
+
+	member, err := h.workspaceService.AddMember(r.Context(), userID, id, &req)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			respondError(w, http.StatusNotFound, "no user with this email", "USER_NOT_FOUND")
+			return
+		}
+		if errors.Is(err, repository.ErrMemberExists) {
+			respondError(w, http.StatusConflict, err.Error(), "MEMBER_EXISTS")
+			return
+		}
+		respondWorkspaceError(w, err, "failed to add member")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, memberResponse(member))
+}
+
+// UpdateMember changes the role of a member
+// PUT /api/v1/workspaces/{id}/members/{userID}
+func (h *WorkspaceHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid workspace id", "INVALID_ID")
+		return
+	}
+	memberID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid user id", "INVALID_ID")
+		return
+	}
+
+	var req model.UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if err := h.workspaceService.UpdateMember(r.Context(), userID, id, memberID, req.Role); err != nil {
+		respondWorkspaceError(w, err, "failed to update member")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.MessageResponse{Message: "member updated"})
+}
+
+// RemoveMember removes a member, or lets the current user leave
+// DELETE /api/v1/workspaces/{id}/members/{userID}
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid workspace id", "INVALID_ID")
+		return
+	}
+	memberID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid user id", "INVALID_ID")
+		return
+	}
+
+	if err := h.workspaceService.RemoveMember(r.Context(), userID, id, memberID); err != nil {
+		respondWorkspaceError(w, err, "failed to remove member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWorkspaceError maps the errors shared by workspace endpoints.
+func respondWorkspaceError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrWorkspaceNotFound):
+		respondError(w, http.StatusNotFound, "workspace not found", "NOT_FOUND")
+	case errors.Is(err, repository.ErrMemberNotFound):
+		respondError(w, http.StatusNotFound, "member not found", "NOT_FOUND")
+	case errors.Is(err, service.ErrForbidden):
+		respondError(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
+	case errors.Is(err, repository.ErrLastOwner):
+		respondError(w, http.StatusConflict, err.Error(), "LAST_OWNER")
+	case errors.Is(err, service.ErrInvalidRole):
+		respondError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+	case errors.Is(err, service.ErrMFANotEnabled):
+		respondError(w, http.StatusConflict, "enable two-factor authentication before requiring it", "MFA_NOT_ENABLED")
+	default:
+		respondError(w, http.StatusInternalServerError, message, "INTERNAL_ERROR")
+	}
+}
+
+func workspaceResponse(ws *model.Workspace) model.WorkspaceResponse {
+	return model.WorkspaceResponse{
+		ID:         ws.ID,
+		Name:       ws.Name,
+		Role:       ws.Role,
+		RequireMFA: ws.RequireMFA,
+		CreatedAt:  ws.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func memberResponse(m *model.WorkspaceMember) model.WorkspaceMemberResponse {
+	return model.WorkspaceMemberResponse{
+		UserID:     m.UserID,
+		Email:      m.Email,
+		Name:       m.Name,
+		Role:       m.Role,
+		MFAEnabled: m.MFAEnabled,
+		CreatedAt:  m.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// captureLimit bounds memory use of a long-running capture mailer.
+const captureLimit = 100
+
+// CaptureMailer keeps messages in memory instead of sending them and logs
+// them, so links can be followed in local development and tests.
+type CaptureMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewCaptureMailer() *CaptureMailer {
+	return &CaptureMailer{}
+}
+
+func (m *CaptureMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	m.messages = append(m.messages, msg)
+	if len(m.messages) > captureLimit {
+		m.messages = m.messages[len(m.messages)-captureLimit:]
+	}
+	m.mu.Unlock()
+
+	log.Printf("mailer: captured %q to %s\n%s", msg.Subject, msg.To, msg.Body)
+	return nil
+}
+
+// Messages returns the captured messages, oldest first.
+func (m *CaptureMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
+
+// Last returns the most recent message sent to the address.
+func (m *CaptureMailer) Last(to string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i], true
+		}
+	}
+	return Message{}, false
+}
@@ -0,0 +1,34 @@
+// Package mailer sends transactional emails such as verification and
+// password reset links.
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/link-tracker/auth-service/internal/config"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by cfg.Driver.
+func New(cfg config.MailerConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg), nil
+	case "capture":
+		return NewCaptureMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer driver %q", cfg.Driver)
+	}
+}
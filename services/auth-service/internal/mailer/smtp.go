@@ -0,0 +1,68 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/link-tracker/auth-service/internal/config"
+)
+
+// SMTPMailer sends messages through an SMTP server, using STARTTLS when the
+// server offers it.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+	host string
+}
+
+func NewSMTPMailer(cfg config.MailerConfig) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(cfg.Host, cfg.Port),
+		from: cfg.From,
+		host: cfg.Host,
+	}
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return m
+}
+
+// Send delivers msg. net/smtp has no context support, so ctx is only
+// checked before connecting.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+
+	if err := smtp.SendMail(m.addr, m.auth, from.Address, []string{to.Address}, buf.Bytes()); err != nil {
+		return fmt.Errorf("send mail via %s: %w", m.host, err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/link-tracker/auth-service/internal/model"
 	"github.com/link-tracker/auth-service/internal/service"
 )
 
@@ -14,6 +15,8 @@ const (
 	UserIDKey contextKey = "user_id"
 	EmailKey  contextKey = "email"
 	RoleKey   contextKey = "role"
+
+	SessionIDKey contextKey = "session_id"
 )
 
 func Auth(authService *service.AuthService) func(http.Handler) http.Handler {
@@ -44,12 +47,27 @@ func Auth(authService *service.AuthService) func(http.Handler) http.Handler {
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 			ctx = context.WithValue(ctx, EmailKey, claims.Email)
 			ctx = context.WithValue(ctx, RoleKey, claims.Role)
+			ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// RequireRole rejects requests whose access token lacks role. Use it after
+// Auth.
+func RequireRole(role model.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if current, _ := GetRole(r.Context()); current != role {
+				http.Error(w, `{"error":"forbidden","code":"FORBIDDEN"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func GetUserID(ctx context.Context) (int64, bool) {
 	userID, ok := ctx.Value(UserIDKey).(int64)
 	return userID, ok
@@ -59,3 +77,15 @@ func GetEmail(ctx context.Context) (string, bool) {
 	email, ok := ctx.Value(EmailKey).(string)
 	return email, ok
 }
+
+func GetRole(ctx context.Context) (model.Role, bool) {
+	role, ok := ctx.Value(RoleKey).(model.Role)
+	return role, ok
+}
+
+// GetSessionID returns the session of the access token; tokens issued
+// before sessions existed have none.
+func GetSessionID(ctx context.Context) (int64, bool) {
+	sessionID, ok := ctx.Value(SessionIDKey).(int64)
+	return sessionID, ok && sessionID != 0
+}
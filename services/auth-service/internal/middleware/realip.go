@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP replaces the remote address of requests coming from a trusted
+// proxy with the client address the proxy put in X-Real-IP. Forwarding
+// headers of other requests are ignored: anyone connecting directly could
+// set them to any address.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isTrusted(r.RemoteAddr, trusted) {
+				if ip, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+					r.RemoteAddr = ip.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isTrusted(remoteAddr string, trusted []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
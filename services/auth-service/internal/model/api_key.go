@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// APIKeyPrefix starts every API key. It must match the prefix the other
+// services check in shared/go/pkg/apikey.
+const APIKeyPrefix = "lt_"
+
+// APIKeyScopes are the scopes a key can be given. A write scope includes
+// read access to the same resource.
+var APIKeyScopes = []string{
+	"backlinks:read", "backlinks:write",
+	"sites:read", "sites:write",
+	"platforms:read", "platforms:write",
+}
+
+func ValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // first characters of the key, to recognize it
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -17,20 +17,239 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// MFACodeRequest carries a code from the authenticator app or, where
+// accepted, a recovery code.
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type DisableMFARequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// DeleteAccountRequest confirms the deletion of the caller's account. The
+// password is required when the account has one, the code when MFA is
+// enabled.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// UpdateWorkspaceRequest changes the fields that are set.
+type UpdateWorkspaceRequest struct {
+	Name       string `json:"name,omitempty"`
+	RequireMFA *bool  `json:"require_mfa,omitempty"`
+}
+
+// AddMemberRequest adds an existing user, found by email, to a workspace.
+type AddMemberRequest struct {
+	Email string        `json:"email"`
+	Role  WorkspaceRole `json:"role"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role Role `json:"role"`
+}
+
+type UpdateMemberRequest struct {
+	Role WorkspaceRole `json:"role"`
+}
+
+// CreateAPIKeyRequest creates an API key. Keys without ExpiresInDays do not
+// expire.
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int     `json:"expires_in_days,omitempty"`
+}
+
+// OIDCCallbackRequest carries the parameters the provider redirected the
+// user back with.
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
 // Response DTOs
 
+// AuthResponse is returned by login and token endpoints. When the user has
+// MFA enabled, login returns only MFARequired and MFAToken, which is
+// exchanged for tokens at /auth/mfa/verify; ExpiresIn is then the lifetime
+// of the MFA token.
 type AuthResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 	ExpiresIn    int64  `json:"expires_in"`
+
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+	// MFASetupRequired tells a user without MFA that a workspace requires it
+	MFASetupRequired bool `json:"mfa_setup_required,omitempty"`
+}
+
+// SessionResponse is a signed-in device. Current marks the session of the
+// access token used for the request.
+type SessionResponse struct {
+	ID         int64  `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IPAddress  string `json:"ip_address"`
+	Current    bool   `json:"current"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+type OIDCProviderResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// OIDCStartResponse is where to send the user to sign in with a provider.
+type OIDCStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type MFASetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesResponse lists new recovery codes. They are shown once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
 }
 
 type UserResponse struct {
-	ID        int64  `json:"id"`
-	Email     string `json:"email"`
-	Name      string `json:"name"`
-	Role      Role   `json:"role"`
-	CreatedAt string `json:"created_at"`
+	ID            int64  `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Role          Role   `json:"role"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// AdminUserResponse is a user as admins see it.
+type AdminUserResponse struct {
+	UserResponse
+	Disabled   bool    `json:"disabled"`
+	DisabledAt *string `json:"disabled_at"`
+	UpdatedAt  string  `json:"updated_at"`
+}
+
+// UserListResponse is a page of users, in the pagination format of the
+// other services.
+type UserListResponse struct {
+	Data       []AdminUserResponse `json:"data"`
+	Page       int                 `json:"page"`
+	PerPage    int                 `json:"per_page"`
+	Total      int64               `json:"total"`
+	TotalPages int                 `json:"total_pages"`
+}
+
+// UserCursorListResponse is a page of users in cursor pagination, in the
+// format of the other services. NextCursor is empty on the last page.
+type UserCursorListResponse struct {
+	Data       []AdminUserResponse `json:"data"`
+	PerPage    int                 `json:"per_page"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+	HasMore    bool                `json:"has_more"`
+}
+
+// UserUsageResponse counts what a user created across services. Counts are
+// null when the service that owns them is listed in Unavailable.
+type UserUsageResponse struct {
+	UserID      int64    `json:"user_id"`
+	Projects    *int64   `json:"projects"`
+	Backlinks   *int64   `json:"backlinks"`
+	Sites       *int64   `json:"sites"`
+	Platforms   *int64   `json:"platforms"`
+	Unavailable []string `json:"unavailable,omitempty"`
+}
+
+// Merge copies the counts that are set in other.
+func (u *UserUsageResponse) Merge(other *UserUsageResponse) {
+	if other.Projects != nil {
+		u.Projects = other.Projects
+	}
+	if other.Backlinks != nil {
+		u.Backlinks = other.Backlinks
+	}
+	if other.Sites != nil {
+		u.Sites = other.Sites
+	}
+	if other.Platforms != nil {
+		u.Platforms = other.Platforms
+	}
+}
+
+// DeletionIncompleteResponse answers a user deletion that some services
+// failed. The user is disabled and signed out; Deleted counts what the other
+// services deleted and lists the failed ones as unavailable.
+type DeletionIncompleteResponse struct {
+	Error   string             `json:"error"`
+	Code    string             `json:"code"`
+	Deleted *UserUsageResponse `json:"deleted"`
+}
+
+type WorkspaceResponse struct {
+	ID         int64         `json:"id"`
+	Name       string        `json:"name"`
+	Role       WorkspaceRole `json:"role,omitempty"`
+	RequireMFA bool          `json:"require_mfa"`
+	CreatedAt  string        `json:"created_at"`
+}
+
+type WorkspaceMemberResponse struct {
+	UserID     int64         `json:"user_id"`
+	Email      string        `json:"email"`
+	Name       string        `json:"name"`
+	Role       WorkspaceRole `json:"role"`
+	MFAEnabled bool          `json:"mfa_enabled"`
+	CreatedAt  string        `json:"created_at"`
+}
+
+type APIKeyResponse struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse is the only response that contains the key itself.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
 }
 
 type MessageResponse struct {
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AccountExport is everything stored about a user: their account in
+// auth-service and the data they added to the other services, keyed by
+// service name.
+type AccountExport struct {
+	ExportedAt time.Time                  `json:"exported_at"`
+	Account    *AccountData               `json:"account"`
+	Services   map[string]json.RawMessage `json:"services"`
+}
+
+// AccountData is what auth-service stores about a user. Secrets such as
+// password and key hashes are left out.
+type AccountData struct {
+	User           *User            `json:"user"`
+	MFAEnabled     bool             `json:"mfa_enabled"`
+	Identities     []*UserIdentity  `json:"identities"`
+	Workspaces     []*Workspace     `json:"workspaces"`
+	APIKeys        []*APIKey        `json:"api_keys"`
+	Sessions       []*Session       `json:"sessions"`
+	SecurityEvents []*SecurityEvent `json:"security_events"`
+}
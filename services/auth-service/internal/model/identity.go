@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// UserIdentity links an account at an OpenID Connect provider to a user.
+type UserIdentity struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	Provider    string    `json:"provider"`
+	Subject     string    `json:"subject"`
+	Email       string    `json:"email"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// OIDCState is a pending authorization request, kept between the redirect
+// to the provider and the callback.
+type OIDCState struct {
+	StateHash    string    `json:"-"`
+	Provider     string    `json:"provider"`
+	Nonce        string    `json:"-"`
+	CodeVerifier string    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
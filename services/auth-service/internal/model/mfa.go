@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// UserMFA is the TOTP enrollment of a user. It stays pending, with a nil
+// EnabledAt, until the first code from the authenticator app is confirmed.
+type UserMFA struct {
+	UserID       int64      `json:"user_id"`
+	TOTPSecret   string     `json:"-"` // encrypted
+	EnabledAt    *time.Time `json:"enabled_at"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// MFAStatus describes the second factor of the current user.
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+	// RequiredByWorkspace is set when a workspace of the user requires MFA
+	RequiredByWorkspace bool `json:"required_by_workspace"`
+}
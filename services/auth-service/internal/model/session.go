@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// Session is a signed-in device: the family of refresh tokens issued from
+// one login.
+type Session struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+
+	// ExpiresAt is when the current refresh token expires
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ClientInfo describes the client a request came from.
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+type SecurityEventType string
+
+const (
+	// SecurityEventTokenReuse is a rotated refresh token presented again,
+	// which means it was copied; the session is revoked
+	SecurityEventTokenReuse SecurityEventType = "refresh_token_reuse"
+
+	// SecurityEventAccountLocked and SecurityEventIPBlocked are logins
+	// blocked after too many failed attempts
+	SecurityEventAccountLocked   SecurityEventType = "account_locked"
+	SecurityEventAccountUnlocked SecurityEventType = "account_unlocked"
+	SecurityEventIPBlocked       SecurityEventType = "ip_blocked"
+
+	// Admin actions on a user; details name the admin. Events of a deleted
+	// user go with it, so the deletion is recorded without a user, and
+	// without an admin when users delete their own account
+	SecurityEventUserDisabled    SecurityEventType = "user_disabled"
+	SecurityEventUserEnabled     SecurityEventType = "user_enabled"
+	SecurityEventRoleChanged     SecurityEventType = "role_changed"
+	SecurityEventSessionsRevoked SecurityEventType = "sessions_revoked"
+	SecurityEventUserDeleted     SecurityEventType = "user_deleted"
+
+	// SecurityEventDataExported is an account export, by the user or, with
+	// admin_id in details, by an admin
+	SecurityEventDataExported SecurityEventType = "data_exported"
+)
+
+type SecurityEvent struct {
+	ID        int64             `json:"id"`
+	UserID    *int64            `json:"user_id"`
+	Type      SecurityEventType `json:"type"`
+	IPAddress string            `json:"ip_address"`
+	UserAgent string            `json:"user_agent"`
+	Details   map[string]any    `json:"details"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -17,12 +17,70 @@ type User struct {
 	Role         Role      `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// DisabledAt is set while an admin has disabled the user
+	DisabledAt *time.Time `json:"disabled_at"`
 }
 
-type RefreshToken struct {
+// UserStatus filters users by whether they are disabled.
+type UserStatus string
+
+const (
+	UserStatusActive   UserStatus = "active"
+	UserStatusDisabled UserStatus = "disabled"
+)
+
+// UserFilters selects users for the admin user list. Query matches email
+// and name.
+type UserFilters struct {
+	Query   string
+	Role    Role
+	Status  UserStatus
+	Page    int
+	PerPage int
+
+	// After continues a cursor-paginated list after the given position.
+	After *UserCursor
+}
+
+// UserCursor is the position after the last user of a page in cursor
+// pagination. Users are listed newest first.
+type UserCursor struct {
+	CreatedAt time.Time `json:"t"`
 	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	TokenHash string    `json:"-"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
+}
+
+// RefreshToken belongs to a session. Refreshing rotates it: the token is
+// marked rotated and a child token is issued in the same session.
+type RefreshToken struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	SessionID int64      `json:"session_id"`
+	ParentID  *int64     `json:"parent_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TokenPurpose is what a single-use account token was issued for.
+type TokenPurpose string
+
+const (
+	TokenVerifyEmail   TokenPurpose = "verify_email"
+	TokenResetPassword TokenPurpose = "reset_password"
+	TokenMFALogin      TokenPurpose = "mfa_login"
+)
+
+// AccountToken is a single-use token sent by email.
+type AccountToken struct {
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"user_id"`
+	Purpose   TokenPurpose `json:"purpose"`
+	TokenHash string       `json:"-"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    *time.Time   `json:"used_at"`
+	Attempts  int          `json:"attempts"`
+	CreatedAt time.Time    `json:"created_at"`
 }
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// WorkspaceRole is a member's role in a workspace. It applies to every
+// project, site and platform of the workspace.
+type WorkspaceRole string
+
+const (
+	WorkspaceOwner  WorkspaceRole = "owner"
+	WorkspaceEditor WorkspaceRole = "editor"
+	WorkspaceViewer WorkspaceRole = "viewer"
+)
+
+func (r WorkspaceRole) Valid() bool {
+	return r == WorkspaceOwner || r == WorkspaceEditor || r == WorkspaceViewer
+}
+
+type Workspace struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	CreatedBy  *int64    `json:"created_by"`
+	RequireMFA bool      `json:"require_mfa"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Role is the role of the user the workspace was loaded for
+	Role WorkspaceRole `json:"role,omitempty"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID int64         `json:"workspace_id"`
+	UserID      int64         `json:"user_id"`
+	Email       string        `json:"email"`
+	Name        string        `json:"name"`
+	Role        WorkspaceRole `json:"role"`
+	MFAEnabled  bool          `json:"mfa_enabled"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
@@ -0,0 +1,324 @@
+// Package oidc signs users in with external OpenID Connect providers using
+// the authorization code flow with PKCE. Plain OAuth 2.0 providers without
+// ID tokens work too, given explicit endpoints and a userinfo URL.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/link-tracker/auth-service/internal/config"
+)
+
+// maxResponseSize limits the responses read from a provider.
+const maxResponseSize = 1 << 20
+
+var (
+	// ErrUnavailable is returned when the provider cannot be reached or
+	// answers with a server error.
+	ErrUnavailable = errors.New("identity provider unavailable")
+	// ErrRejected is returned when the provider refuses the code or returns
+	// an identity that does not pass validation.
+	ErrRejected = errors.New("identity provider rejected the login")
+)
+
+// Identity is the provider account a user signed in with.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type endpoints struct {
+	Issuer      string `json:"issuer"`
+	AuthURL     string `json:"authorization_endpoint"`
+	TokenURL    string `json:"token_endpoint"`
+	UserInfoURL string `json:"userinfo_endpoint"`
+}
+
+// Provider is a configured identity provider. Endpoints that are not
+// configured are discovered from the issuer on first use.
+type Provider struct {
+	cfg         config.OIDCProviderConfig
+	redirectURL string
+	client      *http.Client
+
+	mu        sync.Mutex
+	endpoints *endpoints
+}
+
+// NewProvider creates a provider. "{provider}" in redirectURL is replaced
+// with the provider name.
+func NewProvider(cfg config.OIDCProviderConfig, redirectURL string) *Provider {
+	return &Provider{
+		cfg:         cfg,
+		redirectURL: strings.ReplaceAll(redirectURL, "{provider}", cfg.Name),
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) DisplayName() string {
+	return p.cfg.DisplayName
+}
+
+// AuthCodeURL returns the URL the user is sent to for signing in. The
+// verifier is kept until the callback; only its S256 challenge is sent.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	ep, err := p.resolve(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.redirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(ep.AuthURL, "?") {
+		separator = "&"
+	}
+	return ep.AuthURL + separator + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the identity from the
+// ID token, completed from the userinfo endpoint when the token is missing
+// or lacks the email.
+//
+// The ID token comes straight from the token endpoint over TLS, so its
+// signature is not checked (OpenID Connect Core 3.1.3.7); its issuer,
+// audience, expiry and nonce are.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	ep, err := p.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.do(req, &token); err != nil {
+		if token.Error != "" {
+			return nil, fmt.Errorf("%w: %s: %s", ErrRejected, token.Error, token.ErrorDescription)
+		}
+		return nil, err
+	}
+
+	claims := map[string]any{}
+	if token.IDToken != "" {
+		claims, err = p.validateIDToken(ep, token.IDToken, nonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if (token.IDToken == "" || claimString(claims, p.cfg.EmailClaim) == "") && ep.UserInfoURL != "" {
+		info, err := p.userInfo(ctx, ep.UserInfoURL, token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		// The userinfo response must describe the user of the ID token
+		if sub := claimString(claims, p.cfg.SubjectClaim); sub != "" && claimString(info, p.cfg.SubjectClaim) != sub {
+			return nil, fmt.Errorf("%w: userinfo subject does not match the ID token", ErrRejected)
+		}
+		for k, v := range info {
+			claims[k] = v
+		}
+	}
+
+	identity := &Identity{
+		Subject:       claimString(claims, p.cfg.SubjectClaim),
+		Email:         claimString(claims, p.cfg.EmailClaim),
+		EmailVerified: p.cfg.TrustEmail || claimBool(claims, p.cfg.EmailVerifiedClaim),
+		Name:          claimString(claims, p.cfg.NameClaim),
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: no %q claim", ErrRejected, p.cfg.SubjectClaim)
+	}
+	if identity.Email == "" {
+		identity.EmailVerified = false
+	}
+
+	return identity, nil
+}
+
+func (p *Provider) validateIDToken(ep *endpoints, idToken, nonce string) (map[string]any, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser(jwt.WithJSONNumber()).ParseUnverified(idToken, claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed ID token: %v", ErrRejected, err)
+	}
+
+	validator := jwt.NewValidator(
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err := validator.Validate(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	if ep.Issuer != "" && claimString(claims, "iss") != ep.Issuer {
+		return nil, fmt.Errorf("%w: unexpected ID token issuer", ErrRejected)
+	}
+	if claimString(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("%w: ID token nonce mismatch", ErrRejected)
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) userInfo(ctx context.Context, userInfoURL, accessToken string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, userInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", p.cfg.UserInfoAuthScheme+" "+accessToken)
+
+	info := map[string]any{}
+	if err := p.do(req, &info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// resolve returns the endpoints, running discovery if any is missing.
+// Failed discovery is retried on the next call.
+func (p *Provider) resolve(ctx context.Context) (*endpoints, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.endpoints != nil {
+		return p.endpoints, nil
+	}
+
+	ep := &endpoints{
+		Issuer:      p.cfg.Issuer,
+		AuthURL:     p.cfg.AuthURL,
+		TokenURL:    p.cfg.TokenURL,
+		UserInfoURL: p.cfg.UserInfoURL,
+	}
+
+	if ep.AuthURL == "" || ep.TokenURL == "" {
+		if ep.Issuer == "" {
+			return nil, fmt.Errorf("provider %s: no issuer or endpoints configured", p.cfg.Name)
+		}
+
+		discoveryURL := strings.TrimSuffix(ep.Issuer, "/") + "/.well-known/openid-configuration"
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var discovered endpoints
+		if err := p.do(req, &discovered); err != nil {
+			return nil, err
+		}
+		if discovered.Issuer != ep.Issuer {
+			return nil, fmt.Errorf("%w: discovery returned issuer %q", ErrUnavailable, discovered.Issuer)
+		}
+
+		if ep.AuthURL == "" {
+			ep.AuthURL = discovered.AuthURL
+		}
+		if ep.TokenURL == "" {
+			ep.TokenURL = discovered.TokenURL
+		}
+		if ep.UserInfoURL == "" {
+			ep.UserInfoURL = discovered.UserInfoURL
+		}
+	}
+
+	p.endpoints = ep
+	return ep, nil
+}
+
+// do sends the request and decodes the JSON response into v, which is also
+// filled on 4xx responses so OAuth error fields can be read.
+func (p *Provider) do(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("%w: %s returned %d", ErrUnavailable, req.URL.Host, resp.StatusCode)
+	}
+
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize))
+	decoder.UseNumber()
+	decodeErr := decoder.Decode(v)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned %d", ErrRejected, req.URL.Host, resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("%w: invalid response from %s: %v", ErrUnavailable, req.URL.Host, decodeErr)
+	}
+	return nil
+}
+
+// claimString returns a string or numeric claim as a string; providers
+// such as Yandex send numeric user IDs.
+func claimString(claims map[string]any, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+// claimBool accepts true and "true"; some providers send booleans as
+// strings.
+func claimBool(claims map[string]any, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
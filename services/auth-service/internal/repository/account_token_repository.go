@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/auth-service/internal/model"
+)
+
+var (
+	ErrAccountTokenNotFound = errors.New("account token not found or already used")
+	ErrAccountTokenExpired  = errors.New("account token expired")
+)
+
+type AccountTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAccountTokenRepository(db *pgxpool.Pool) *AccountTokenRepository {
+	return &AccountTokenRepository{db: db}
+}
+
+// Create stores a token and invalidates unused tokens of the same user and
+// purpose, so only the latest emailed link works.
+func (r *AccountTokenRepository) Create(ctx context.Context, token *model.AccountToken) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `DELETE FROM account_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	if _, err := tx.Exec(ctx, query, token.UserID, token.Purpose); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO account_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(ctx, query,
+		token.UserID,
+		token.Purpose,
+		token.TokenHash,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Consume marks a token as used and returns its user. A token can be
+// consumed once; an expired token is used up as well.
+func (r *AccountTokenRepository) Consume(ctx context.Context, purpose model.TokenPurpose, tokenHash string) (int64, error) {
+	query := `
+		UPDATE account_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL
+		RETURNING user_id, expires_at
+	`
+
+	var userID int64
+	var expiresAt time.Time
+	err := r.db.QueryRow(ctx, query, tokenHash, purpose).Scan(&userID, &expiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrAccountTokenNotFound
+		}
+		return 0, err
+	}
+
+	if time.Now().After(expiresAt) {
+		return 0, ErrAccountTokenExpired
+	}
+
+	return userID, nil
+}
+
+// Get returns an unused token without consuming it, for flows that check
+// something else before the token is spent.
+func (r *AccountTokenRepository) Get(ctx context.Context, purpose model.TokenPurpose, tokenHash string) (*model.AccountToken, error) {
+	query := `
+		SELECT id, user_id, purpose, expires_at, used_at, attempts, created_at
+		FROM account_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL
+	`
+
+	token := &model.AccountToken{TokenHash: tokenHash}
+	err := r.db.QueryRow(ctx, query, tokenHash, purpose).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.Attempts,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountTokenNotFound
+		}
+		return nil, err
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrAccountTokenExpired
+	}
+
+	return token, nil
+}
+
+// RecordFailedAttempt counts a wrong answer for a token and uses the token
+// up after maxAttempts.
+func (r *AccountTokenRepository) RecordFailedAttempt(ctx context.Context, id int64, maxAttempts int) error {
+	query := `
+		UPDATE account_tokens
+		SET attempts = attempts + 1,
+			used_at = CASE WHEN attempts + 1 >= $2 THEN NOW() ELSE used_at END
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, query, id, maxAttempts)
+	return err
+}
+
+// DeleteByUserID removes unused tokens of a purpose, e.g. pending reset links
+// once the password was changed.
+func (r *AccountTokenRepository) DeleteByUserID(ctx context.Context, userID int64, purpose model.TokenPurpose) error {
+	query := `DELETE FROM account_tokens WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	_, err := r.db.Exec(ctx, query, userID, purpose)
+	return err
+}
+
+func (r *AccountTokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM account_tokens WHERE expires_at < NOW()`
+	result, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
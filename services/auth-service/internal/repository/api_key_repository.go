@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/auth-service/internal/model"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, key_prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		key.Scopes,
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+}
+
+// ListByUser returns the keys of a user, newest first.
+func (r *APIKeyRepository) ListByUser(ctx context.Context, userID int64) ([]*model.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, key_prefix, scopes, expires_at, last_used_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		key := &model.APIKey{}
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			&key.Scopes,
+			&key.ExpiresAt,
+			&key.LastUsedAt,
+			&key.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepository) CountByUser(ctx context.Context, userID int64) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM api_keys WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+// Delete revokes a key of userID.
+func (r *APIKeyRepository) Delete(ctx context.Context, id, userID int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// DeleteByUser revokes all keys of userID.
+func (r *APIKeyRepository) DeleteByUser(ctx context.Context, userID int64) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM api_keys WHERE user_id = $1`, userID)
+	return err
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/auth-service/internal/model"
+)
+
+var (
+	ErrIdentityNotFound  = errors.New("identity not found")
+	ErrIdentityLinked    = errors.New("identity is already linked")
+	ErrOIDCStateNotFound = errors.New("oidc state not found")
+	ErrOIDCStateExpired  = errors.New("oidc state expired")
+)
+
+type IdentityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIdentityRepository(db *pgxpool.Pool) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// Link links the identity to its user and marks the user's email verified,
+// in one transaction. If the email was not verified yet, whoever registered
+// the address never proved they own it, and the provider says someone else
+// does: their password and sessions are dropped as well. Link reports
+// whether it did.
+func (r *IdentityRepository) Link(ctx context.Context, identity *model.UserIdentity) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var unverified bool
+	err = tx.QueryRow(ctx, `SELECT email_verified_at IS NULL FROM users WHERE id = $1 FOR UPDATE`, identity.UserID).Scan(&unverified)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrUserNotFound
+		}
+		return false, err
+	}
+
+	if unverified {
+		if _, err := tx.Exec(ctx, `UPDATE users SET password_hash = '', email_verified_at = NOW() WHERE id = $1`, identity.UserID); err != nil {
+			return false, err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM sessions WHERE user_id = $1`, identity.UserID); err != nil {
+			return false, err
+		}
+	}
+
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, last_login_at, created_at
+	`
+	err = tx.QueryRow(ctx, query,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+	).Scan(&identity.ID, &identity.LastLoginAt, &identity.CreatedAt)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return false, ErrIdentityLinked
+		}
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return unverified, nil
+}
+
+func (r *IdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, last_login_at, created_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+
+	identity := &model.UserIdentity{}
+	err := r.db.QueryRow(ctx, query, provider, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.LastLoginAt,
+		&identity.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+// ListByUser returns the provider accounts linked to a user.
+func (r *IdentityRepository) ListByUser(ctx context.Context, userID int64) ([]*model.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, last_login_at, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*model.UserIdentity
+	for rows.Next() {
+		identity := &model.UserIdentity{}
+		err := rows.Scan(
+			&identity.ID,
+			&identity.UserID,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.LastLoginAt,
+			&identity.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+// TouchLogin records a sign-in with the identity and the email the provider
+// reported for it.
+func (r *IdentityRepository) TouchLogin(ctx context.Context, id int64, email string) error {
+	query := `
+		UPDATE user_identities
+		SET last_login_at = NOW(), email = $2
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, query, id, email)
+	return err
+}
+
+type OIDCStateRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOIDCStateRepository(db *pgxpool.Pool) *OIDCStateRepository {
+	return &OIDCStateRepository{db: db}
+}
+
+func (r *OIDCStateRepository) Create(ctx context.Context, state *model.OIDCState) error {
+	query := `
+		INSERT INTO oidc_states (state_hash, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		state.StateHash,
+		state.Provider,
+		state.Nonce,
+		state.CodeVerifier,
+		state.ExpiresAt,
+	)
+	return err
+}
+
+// Consume deletes a pending request of the provider and returns it, so
+// that a state can be used once.
+func (r *OIDCStateRepository) Consume(ctx context.Context, provider, stateHash string) (*model.OIDCState, error) {
+	query := `
+		DELETE FROM oidc_states
+		WHERE state_hash = $1 AND provider = $2
+		RETURNING state_hash, provider, nonce, code_verifier, expires_at
+	`
+
+	state := &model.OIDCState{}
+	err := r.db.QueryRow(ctx, query, stateHash, provider).Scan(
+		&state.StateHash,
+		&state.Provider,
+		&state.Nonce,
+		&state.CodeVerifier,
+		&state.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOIDCStateNotFound
+		}
+		return nil, err
+	}
+
+	if time.Now().After(state.ExpiresAt) {
+		return nil, ErrOIDCStateExpired
+	}
+
+	return state, nil
+}
+
+func (r *OIDCStateRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM oidc_states WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/auth-service/internal/model"
+)
+
+var (
+	ErrMFANotFound       = errors.New("mfa enrollment not found")
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+)
+
+type MFARepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMFARepository(db *pgxpool.Pool) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// HasSecrets reports whether any user has a TOTP secret, enabled or pending.
+func (r *MFARepository) HasSecrets(ctx context.Context) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM user_mfa)`).Scan(&exists)
+	return exists, err
+}
+
+func (r *MFARepository) Get(ctx context.Context, userID int64) (*model.UserMFA, error) {
+	query := `
+		SELECT user_id, totp_secret, enabled_at, last_used_step, created_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+
+	mfa := &model.UserMFA{}
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&mfa.UserID,
+		&mfa.TOTPSecret,
+		&mfa.EnabledAt,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFANotFound
+		}
+		return nil, err
+	}
+
+	return mfa, nil
+}
+
+// SavePending stores a new secret for a user who has not enabled MFA yet,
+// replacing an unfinished setup.
+func (r *MFARepository) SavePending(ctx context.Context, userID int64, secret string) error {
+	query := `
+		INSERT INTO user_mfa (user_id, totp_secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET totp_secret = EXCLUDED.totp_secret, last_used_step = 0, created_at = NOW()
+		WHERE user_mfa.enabled_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// Enable finishes the setup with the step of the confirmed code and stores
+// the hashes of the first recovery codes.
+func (r *MFARepository) Enable(ctx context.Context, userID, step int64, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE user_mfa
+		SET enabled_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`
+	result, err := tx.Exec(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseStep records a TOTP step as used. It reports false when the step, or
+// a later one, was used already, so a code cannot be replayed.
+func (r *MFARepository) UseStep(ctx context.Context, userID, step int64) (bool, error) {
+	query := `UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+	result, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It reports false
+// when no such code exists.
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM mfa_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		)
+	`
+	result, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// ReplaceRecoveryCodes invalidates all recovery codes of the user and stores
+// new ones.
+func (r *MFARepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *MFARepository) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	var n int
+	err := r.db.QueryRow(ctx, query, userID).Scan(&n)
+	return n, err
+}
+
+// Delete turns MFA off and removes the recovery codes.
+func (r *MFARepository) Delete(ctx context.Context, userID int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RequiredByWorkspace reports whether any workspace of the user requires
+// MFA.
+func (r *MFARepository) RequiredByWorkspace(ctx context.Context, userID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM workspace_members m
+			JOIN workspaces w ON w.id = m.workspace_id
+			WHERE m.user_id = $1 AND w.require_mfa
+		)
+	`
+	var required bool
+	err := r.db.QueryRow(ctx, query, userID).Scan(&required)
+	return required, err
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(ctx, query, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
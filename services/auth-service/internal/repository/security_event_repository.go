@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/auth-service/internal/model"
+)
+
+type SecurityEventRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSecurityEventRepository(db *pgxpool.Pool) *SecurityEventRepository {
+	return &SecurityEventRepository{db: db}
+}
+
+func (r *SecurityEventRepository) Create(ctx context.Context, event *model.SecurityEvent) error {
+	query := `
+		INSERT INTO security_events (user_id, event_type, ip_address, user_agent, details)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	details := event.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+
+	return r.db.QueryRow(ctx, query,
+		event.UserID,
+		event.Type,
+		event.IPAddress,
+		event.UserAgent,
+		details,
+	).Scan(&event.ID, &event.CreatedAt)
+}
+
+// ListByUser returns the security events of a user, newest first.
+func (r *SecurityEventRepository) ListByUser(ctx context.Context, userID int64) ([]*model.SecurityEvent, error) {
+	query := `
+		SELECT id, user_id, event_type, ip_address, user_agent, details, created_at
+		FROM security_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.SecurityEvent
+	for rows.Next() {
+		event := &model.SecurityEvent{}
+		err := rows.Scan(
+			&event.ID,
+			&event.UserID,
+			&event.Type,
+			&event.IPAddress,
+			&event.UserAgent,
+			&event.Details,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
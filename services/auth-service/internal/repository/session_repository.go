@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/auth-service/internal/model"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *model.Session) error {
+	query := `
+		INSERT INTO sessions (user_id, user_agent, ip_address)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, last_used_at
+	`
+
+	return r.db.QueryRow(ctx, query,
+		session.UserID,
+		session.UserAgent,
+		session.IPAddress,
+	).Scan(&session.ID, &session.CreatedAt, &session.LastUsedAt)
+}
+
+// Touch records that the session was refreshed from the given client.
+func (r *SessionRepository) Touch(ctx context.Context, id int64, client model.ClientInfo) error {
+	query := `
+		UPDATE sessions
+		SET last_used_at = NOW(), ip_address = $2, user_agent = $3
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, query, id, client.IPAddress, client.UserAgent)
+	return err
+}
+
+// ListActiveByUser returns the sessions of a user that still have a valid
+// refresh token, most recently used first.
+func (r *SessionRepository) ListActiveByUser(ctx context.Context, userID int64) ([]*model.Session, error) {
+	query := `
+		SELECT s.id, s.user_id, s.user_agent, s.ip_address, s.created_at, s.last_used_at, t.expires_at
+		FROM sessions s
+		JOIN refresh_tokens t ON t.session_id = s.id AND t.rotated_at IS NULL
+		WHERE s.user_id = $1 AND t.expires_at > NOW()
+		ORDER BY s.last_used_at DESC, s.id DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*model.Session
+	for rows.Next() {
+		session := &model.Session{}
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// Delete revokes a session of the user together with its refresh tokens.
+func (r *SessionRepository) Delete(ctx context.Context, userID, id int64) error {
+	query := `DELETE FROM sessions WHERE id = $1 AND user_id = $2`
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepository) DeleteByUserID(ctx context.Context, userID int64) error {
+	query := `DELETE FROM sessions WHERE user_id = $1`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
+// DeleteExpired removes sessions without a valid refresh token.
+func (r *SessionRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `
+		DELETE FROM sessions s
+		WHERE NOT EXISTS (
+			SELECT 1 FROM refresh_tokens t
+			WHERE t.session_id = s.id AND t.rotated_at IS NULL AND t.expires_at > NOW()
+		)
+	`
+	result, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
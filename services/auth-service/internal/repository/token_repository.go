@@ -25,13 +25,15 @@ func NewTokenRepository(db *pgxpool.Pool) *TokenRepository {
 
 func (r *TokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
 	query := `
-		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
-		VALUES ($1, $2, $3)
+		INSERT INTO refresh_tokens (user_id, session_id, parent_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at
 	`
 
 	err := r.db.QueryRow(ctx, query,
 		token.UserID,
+		token.SessionID,
+		token.ParentID,
 		token.TokenHash,
 		token.ExpiresAt,
 	).Scan(&token.ID, &token.CreatedAt)
@@ -39,9 +41,11 @@ func (r *TokenRepository) Create(ctx context.Context, token *model.RefreshToken)
 	return err
 }
 
+// GetByTokenHash returns the token, including rotated ones; callers check
+// RotatedAt.
 func (r *TokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
 	query := `
-		SELECT id, user_id, token_hash, expires_at, created_at
+		SELECT id, user_id, session_id, parent_id, token_hash, expires_at, rotated_at, created_at
 		FROM refresh_tokens
 		WHERE token_hash = $1
 	`
@@ -50,8 +54,11 @@ func (r *TokenRepository) GetByTokenHash(ctx context.Context, tokenHash string)
 	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
 		&token.ID,
 		&token.UserID,
+		&token.SessionID,
+		&token.ParentID,
 		&token.TokenHash,
 		&token.ExpiresAt,
+		&token.RotatedAt,
 		&token.CreatedAt,
 	)
 
@@ -69,16 +76,15 @@ func (r *TokenRepository) GetByTokenHash(ctx context.Context, tokenHash string)
 	return token, nil
 }
 
-func (r *TokenRepository) DeleteByTokenHash(ctx context.Context, tokenHash string) error {
-	query := `DELETE FROM refresh_tokens WHERE token_hash = $1`
-	_, err := r.db.Exec(ctx, query, tokenHash)
-	return err
-}
-
-func (r *TokenRepository) DeleteByUserID(ctx context.Context, userID int64) error {
-	query := `DELETE FROM refresh_tokens WHERE user_id = $1`
-	_, err := r.db.Exec(ctx, query, userID)
-	return err
+// MarkRotated marks the token as rotated. It returns false if the token was
+// already rotated, for example by a concurrent refresh.
+func (r *TokenRepository) MarkRotated(ctx context.Context, id int64) (bool, error) {
+	query := `UPDATE refresh_tokens SET rotated_at = NOW() WHERE id = $1 AND rotated_at IS NULL`
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
 }
 
 func (r *TokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -48,7 +50,7 @@ func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 
 func (r *UserRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
 	query := `
-		SELECT id, email, password_hash, name, role, created_at, updated_at
+		SELECT id, email, password_hash, name, role, created_at, updated_at, email_verified_at, disabled_at
 		FROM users
 		WHERE id = $1
 	`
@@ -62,6 +64,8 @@ func (r *UserRepository) GetByID(ctx context.Context, id int64) (*model.User, er
 		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.EmailVerifiedAt,
+		&user.DisabledAt,
 	)
 
 	if err != nil {
@@ -76,7 +80,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id int64) (*model.User, er
 
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	query := `
-		SELECT id, email, password_hash, name, role, created_at, updated_at
+		SELECT id, email, password_hash, name, role, created_at, updated_at, email_verified_at, disabled_at
 		FROM users
 		WHERE email = $1
 	`
@@ -90,6 +94,8 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.U
 		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.EmailVerifiedAt,
+		&user.DisabledAt,
 	)
 
 	if err != nil {
@@ -127,6 +133,208 @@ func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
 	return nil
 }
 
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	result, err := r.db.Exec(ctx, `UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// MarkEmailVerified records that the user confirmed their address. An
+// earlier verification time is kept.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// userListColumns are the columns scanned by scanUsers.
+const userListColumns = `id, email, password_hash, name, role, created_at, updated_at, email_verified_at, disabled_at`
+
+// userConditions returns the WHERE condition selecting the users that match
+// filters, and its arguments.
+func userConditions(filters *model.UserFilters) (string, []any) {
+	where := []string{"TRUE"}
+	args := []any{}
+
+	if filters.Query != "" {
+		args = append(args, containsPattern(filters.Query))
+		where = append(where, fmt.Sprintf("(email ILIKE $%d OR name ILIKE $%d)", len(args), len(args)))
+	}
+	if filters.Role != "" {
+		args = append(args, filters.Role)
+		where = append(where, fmt.Sprintf("role = $%d", len(args)))
+	}
+	switch filters.Status {
+	case model.UserStatusActive:
+		where = append(where, "disabled_at IS NULL")
+	case model.UserStatusDisabled:
+		where = append(where, "disabled_at IS NOT NULL")
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// List returns a page of users matching the filters, newest first, and the
+// number of matching users.
+func (r *UserRepository) List(ctx context.Context, filters *model.UserFilters) ([]*model.User, int64, error) {
+	condition, args := userConditions(filters)
+
+	var total int64
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE "+condition, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filters.PerPage, (filters.Page-1)*filters.PerPage)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM users
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, userListColumns, condition, len(args)-1, len(args))
+
+	users, err := r.queryUsers(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// ListAfter returns up to filters.PerPage users matching the filters after
+// filters.After, newest first, and whether more follow. It neither counts
+// the matching users nor skips rows with OFFSET.
+func (r *UserRepository) ListAfter(ctx context.Context, filters *model.UserFilters) ([]*model.User, bool, error) {
+	condition, args := userConditions(filters)
+
+	if filters.After != nil {
+		args = append(args, filters.After.CreatedAt, filters.After.ID)
+		condition += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+	args = append(args, filters.PerPage+1)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM users
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, userListColumns, condition, len(args))
+
+	users, err := r.queryUsers(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(users) > filters.PerPage {
+		return users[:filters.PerPage], true, nil
+	}
+	return users, false, nil
+}
+
+// queryUsers runs a query selecting userListColumns.
+func (r *UserRepository) queryUsers(ctx context.Context, query string, args ...any) ([]*model.User, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*model.User
+	for rows.Next() {
+		user := &model.User{}
+		err := rows.Scan(
+			&user.ID,
+			&user.Email,
+			&user.PasswordHash,
+			&user.Name,
+			&user.Role,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.EmailVerifiedAt,
+			&user.DisabledAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// SetDisabled disables or re-enables a user. Disabling an already disabled
+// user keeps the original time.
+func (r *UserRepository) SetDisabled(ctx context.Context, userID int64, disabled bool) error {
+	query := `UPDATE users SET disabled_at = NULL WHERE id = $1`
+	if disabled {
+		query = `UPDATE users SET disabled_at = COALESCE(disabled_at, NOW()) WHERE id = $1`
+	}
+
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, userID int64, role model.Role) error {
+	result, err := r.db.Exec(ctx, `UPDATE users SET role = $1 WHERE id = $2`, role, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// CountAdmins returns the number of admins who are not disabled.
+func (r *UserRepository) CountAdmins(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE role = $1 AND disabled_at IS NULL`, model.RoleAdmin).Scan(&count)
+	return count, err
+}
+
+// Delete removes a user. Sessions, tokens, keys and memberships go with it.
+func (r *UserRepository) Delete(ctx context.Context, userID int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// containsPattern is an ILIKE pattern matching s anywhere, with the
+// wildcards in s taken literally.
+func containsPattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
 func isDuplicateKeyError(err error) bool {
 	return err != nil && err.Error() != "" &&
 		(contains(err.Error(), "duplicate key") || contains(err.Error(), "23505"))
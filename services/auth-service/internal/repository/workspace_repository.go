@@ -0,0 +1,351 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/auth-service/internal/model"
+)
+
+var (
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	ErrMemberNotFound    = errors.New("workspace member not found")
+	ErrMemberExists      = errors.New("user is already a workspace member")
+	ErrLastOwner         = errors.New("workspace must keep at least one owner")
+)
+
+type WorkspaceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWorkspaceRepository(db *pgxpool.Pool) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+// Create inserts the workspace and makes its creator the owner.
+func (r *WorkspaceRepository) Create(ctx context.Context, workspace *model.Workspace) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO workspaces (name, created_by)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRow(ctx, query, workspace.Name, workspace.CreatedBy).
+		Scan(&workspace.ID, &workspace.CreatedAt, &workspace.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, workspace.ID, *workspace.CreatedBy, model.WorkspaceOwner); err != nil {
+		return err
+	}
+	workspace.Role = model.WorkspaceOwner
+
+	return tx.Commit(ctx)
+}
+
+func (r *WorkspaceRepository) GetByID(ctx context.Context, id int64) (*model.Workspace, error) {
+	query := `
+		SELECT id, name, created_by, require_mfa, created_at, updated_at
+		FROM workspaces
+		WHERE id = $1
+	`
+
+	workspace := &model.Workspace{}
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&workspace.ID,
+		&workspace.Name,
+		&workspace.CreatedBy,
+		&workspace.RequireMFA,
+		&workspace.CreatedAt,
+		&workspace.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWorkspaceNotFound
+		}
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// ListByUser returns the workspaces userID is a member of with their role.
+func (r *WorkspaceRepository) ListByUser(ctx context.Context, userID int64) ([]*model.Workspace, error) {
+	query := `
+		SELECT w.id, w.name, w.created_by, w.require_mfa, w.created_at, w.updated_at, m.role
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = $1
+		ORDER BY w.name, w.id
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []*model.Workspace
+	for rows.Next() {
+		workspace := &model.Workspace{}
+		err := rows.Scan(
+			&workspace.ID,
+			&workspace.Name,
+			&workspace.CreatedBy,
+			&workspace.RequireMFA,
+			&workspace.CreatedAt,
+			&workspace.UpdatedAt,
+			&workspace.Role,
+		)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+
+	return workspaces, rows.Err()
+}
+
+func (r *WorkspaceRepository) Update(ctx context.Context, workspace *model.Workspace) error {
+	query := `
+		UPDATE workspaces
+		SET name = $1, require_mfa = $2
+		WHERE id = $3
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query, workspace.Name, workspace.RequireMFA, workspace.ID).Scan(&workspace.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWorkspaceNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM workspaces WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrWorkspaceNotFound
+	}
+
+	return nil
+}
+
+// GetRole returns the role of userID in the workspace, or ErrMemberNotFound.
+// Members who lost access because the workspace requires MFA they have not
+// enabled count as non-members, as they do in the other services.
+func (r *WorkspaceRepository) GetRole(ctx context.Context, workspaceID, userID int64) (model.WorkspaceRole, error) {
+	query := `SELECT role FROM active_workspace_members WHERE workspace_id = $1 AND user_id = $2`
+
+	var role model.WorkspaceRole
+	err := r.db.QueryRow(ctx, query, workspaceID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrMemberNotFound
+		}
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID int64) ([]*model.WorkspaceMember, error) {
+	query := `
+		SELECT m.workspace_id, m.user_id, u.email, u.name, m.role, f.enabled_at IS NOT NULL, m.created_at
+		FROM workspace_members m
+		JOIN users u ON u.id = m.user_id
+		LEFT JOIN user_mfa f ON f.user_id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY m.created_at, m.user_id
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*model.WorkspaceMember
+	for rows.Next() {
+		member := &model.WorkspaceMember{}
+		err := rows.Scan(
+			&member.WorkspaceID,
+			&member.UserID,
+			&member.Email,
+			&member.Name,
+			&member.Role,
+			&member.MFAEnabled,
+			&member.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func (r *WorkspaceRepository) AddMember(ctx context.Context, member *model.WorkspaceMember) error {
+	query := `
+		INSERT INTO workspace_members (workspace_id, user_id, role)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRow(ctx, query, member.WorkspaceID, member.UserID, member.Role).Scan(&member.CreatedAt)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return ErrMemberExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+// UpdateMemberRole changes the role of a member. Demoting the only owner
+// fails with ErrLastOwner.
+func (r *WorkspaceRepository) UpdateMemberRole(ctx context.Context, workspaceID, userID int64, role model.WorkspaceRole) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if role != model.WorkspaceOwner {
+		if err := checkNotLastOwner(ctx, tx, workspaceID, userID); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE workspace_members SET role = $1 WHERE workspace_id = $2 AND user_id = $3`
+	result, err := tx.Exec(ctx, query, role, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RemoveMember removes a member. Removing the only owner fails with
+// ErrLastOwner.
+func (r *WorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkNotLastOwner(ctx, tx, workspaceID, userID); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`
+	result, err := tx.Exec(ctx, query, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+
+	return tx.Commit(ctx)
+}
+
+// checkNotLastOwner fails with ErrLastOwner when userID is the only owner of
+// the workspace. The owner rows stay locked until the transaction ends, so
+// two owners demoting each other at the same time can't both succeed.
+func checkNotLastOwner(ctx context.Context, tx pgx.Tx, workspaceID, userID int64) error {
+	rows, err := tx.Query(ctx, `
+		SELECT user_id FROM workspace_members
+		WHERE workspace_id = $1 AND role = $2
+		FOR UPDATE
+	`, workspaceID, model.WorkspaceOwner)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var owners []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		owners = append(owners, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(owners) == 1 && owners[0] == userID {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// ReleaseUser prepares the workspaces of a user for the deletion of their
+// account. In every workspace with other members, the heir becomes owner if
+// they are not one yet: the longest-standing other owner or, without one,
+// the longest-standing other member, preferring users who are not disabled.
+// Workspaces without other members are deleted. The other services pick the
+// same heir when they hand over the user's data in shared workspaces, which
+// happens first; see DataService.DeleteUser.
+func (r *WorkspaceRepository) ReleaseUser(ctx context.Context, userID int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE workspace_members m
+		SET role = $2
+		FROM (
+			SELECT DISTINCT ON (o.workspace_id) o.workspace_id, o.user_id
+			FROM workspace_members o
+			JOIN users u ON u.id = o.user_id
+			WHERE o.user_id <> $1
+				AND o.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1)
+			ORDER BY o.workspace_id, u.disabled_at IS NULL DESC, o.role = $2 DESC, o.created_at, o.user_id
+		) heir
+		WHERE m.workspace_id = heir.workspace_id AND m.user_id = heir.user_id AND m.role <> $2
+	`, userID, model.WorkspaceOwner)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM workspaces w
+		WHERE EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = w.id AND user_id = $1)
+			AND NOT EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = w.id AND user_id <> $1)
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
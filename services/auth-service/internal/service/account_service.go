@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/link-tracker/auth-service/internal/config"
+	"github.com/link-tracker/auth-service/internal/mailer"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrEmailAlreadyVerified = errors.New("email already verified")
+
+// AccountService handles the emailed flows: address verification and
+// password reset.
+type AccountService struct {
+	userRepo         *repository.UserRepository
+	sessionRepo      *repository.SessionRepository
+	accountTokenRepo *repository.AccountTokenRepository
+	mailer           mailer.Mailer
+	cfg              *config.AccountConfig
+}
+
+func NewAccountService(
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	accountTokenRepo *repository.AccountTokenRepository,
+	mailer mailer.Mailer,
+	cfg *config.AccountConfig,
+) *AccountService {
+	return &AccountService{
+		userRepo:         userRepo,
+		sessionRepo:      sessionRepo,
+		accountTokenRepo: accountTokenRepo,
+		mailer:           mailer,
+		cfg:              cfg,
+	}
+}
+
+// SendVerification emails a verification link to the user. Earlier links
+// stop working.
+func (s *AccountService) SendVerification(ctx context.Context, user *model.User) error {
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.issueToken(ctx, user.ID, model.TokenVerifyEmail, s.cfg.VerifyTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your Link Tracker email address by opening this link:\n\n%s\n\nThe link expires in %s.\n",
+			user.Name, s.link("/verify-email", token), formatTTL(s.cfg.VerifyTokenTTL)),
+	})
+}
+
+func (s *AccountService) ResendVerification(ctx context.Context, userID int64) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.consumeToken(ctx, model.TokenVerifyEmail, token)
+	if err != nil {
+		return err
+	}
+
+	return s.userRepo.MarkEmailVerified(ctx, userID)
+}
+
+// RequestPasswordReset emails a reset link. Unknown addresses are ignored
+// without an error so the endpoint does not reveal which emails exist.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := s.issueToken(ctx, user.ID, model.TokenResetPassword, s.cfg.ResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your Link Tracker account. To choose a new password, open this link:\n\n%s\n\nThe link expires in %s. If you did not ask for a reset, ignore this email.\n",
+			user.Name, s.link("/reset-password", token), formatTTL(s.cfg.ResetTokenTTL)),
+	})
+}
+
+// ResetPassword sets a new password and signs the user out everywhere.
+// Following the emailed link also proves the address, so it is marked
+// verified.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	userID, err := s.consumeToken(ctx, model.TokenResetPassword, token)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, userID, string(hashedPassword)); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.DeleteByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.userRepo.MarkEmailVerified(ctx, userID)
+}
+
+func (s *AccountService) issueToken(ctx context.Context, userID int64, purpose model.TokenPurpose, ttl time.Duration) (string, error) {
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.accountTokenRepo.Create(ctx, &model.AccountToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *AccountService) consumeToken(ctx context.Context, purpose model.TokenPurpose, token string) (int64, error) {
+	userID, err := s.accountTokenRepo.Consume(ctx, purpose, hashToken(token))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrAccountTokenNotFound):
+			return 0, ErrInvalidToken
+		case errors.Is(err, repository.ErrAccountTokenExpired):
+			return 0, ErrTokenExpired
+		}
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (s *AccountService) link(path, token string) string {
+	return strings.TrimRight(s.cfg.AppURL, "/") + path + "?token=" + token
+}
+
+// formatTTL renders a token lifetime for an email, e.g. "1 hour".
+func formatTTL(ttl time.Duration) string {
+	if ttl >= 24*time.Hour && ttl%(24*time.Hour) == 0 {
+		return plural(int(ttl/(24*time.Hour)), "day")
+	}
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		return plural(int(ttl/time.Hour), "hour")
+	}
+	return plural(int(ttl.Round(time.Minute)/time.Minute), "minute")
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
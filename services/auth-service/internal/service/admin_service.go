@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/repository"
+	"github.com/link-tracker/auth-service/internal/userdata"
+)
+
+// maxUsersPerPage limits the page size of the admin user list.
+const maxUsersPerPage = 100
+
+var (
+	ErrCannotModifySelf = errors.New("admins cannot disable, delete or demote themselves")
+	ErrLastAdmin        = errors.New("at least one active admin must remain")
+	ErrInvalidUserRole  = errors.New("role must be one of: user, admin")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+)
+
+// AdminService manages users for admins. Every change is recorded as a
+// security event naming the admin.
+type AdminService struct {
+	userRepo      *repository.UserRepository
+	eventRepo     *repository.SecurityEventRepository
+	authService   *AuthService
+	apiKeyService *APIKeyService
+	dataService   *DataService
+	userData      *userdata.Client
+}
+
+func NewAdminService(
+	userRepo *repository.UserRepository,
+	eventRepo *repository.SecurityEventRepository,
+	authService *AuthService,
+	apiKeyService *APIKeyService,
+	dataService *DataService,
+	userData *userdata.Client,
+) *AdminService {
+	return &AdminService{
+		userRepo:      userRepo,
+		eventRepo:     eventRepo,
+		authService:   authService,
+		apiKeyService: apiKeyService,
+		dataService:   dataService,
+		userData:      userData,
+	}
+}
+
+// ListUsers returns a page of users and the number of matching users.
+// Out-of-range paging is clamped.
+func (s *AdminService) ListUsers(ctx context.Context, filters *model.UserFilters) ([]*model.User, int64, error) {
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.PerPage < 1 || filters.PerPage > maxUsersPerPage {
+		filters.PerPage = 20
+	}
+	return s.userRepo.List(ctx, filters)
+}
+
+// ListUsersCursor returns a page of users after filters.After and the
+// cursor of the next page, nil on the last one. Cursors are not signed, so
+// the position is checked before it reaches the query.
+func (s *AdminService) ListUsersCursor(ctx context.Context, filters *model.UserFilters) ([]*model.User, *model.UserCursor, error) {
+	if c := filters.After; c != nil && (c.ID <= 0 || c.CreatedAt.IsZero()) {
+		return nil, nil, ErrInvalidCursor
+	}
+	if filters.PerPage < 1 || filters.PerPage > maxUsersPerPage {
+		filters.PerPage = 20
+	}
+
+	users, hasMore, err := s.userRepo.ListAfter(ctx, filters)
+	if err != nil || !hasMore {
+		return users, nil, err
+	}
+	last := users[len(users)-1]
+	return users, &model.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
+
+func (s *AdminService) GetUser(ctx context.Context, userID int64) (*model.User, error) {
+	return s.userRepo.GetByID(ctx, userID)
+}
+
+// SetDisabled disables or re-enables a user. Disabling revokes all sessions
+// and API keys; access tokens already issued stay valid until they expire.
+func (s *AdminService) SetDisabled(ctx context.Context, adminID, userID int64, disabled bool) (*model.User, error) {
+	if disabled && adminID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if disabled && user.Role == model.RoleAdmin {
+		if err := checkNotLastAdmin(ctx, s.userRepo, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.userRepo.SetDisabled(ctx, userID, disabled); err != nil {
+		return nil, err
+	}
+
+	eventType := model.SecurityEventUserEnabled
+	if disabled {
+		if err := s.authService.LogoutAll(ctx, userID); err != nil {
+			return nil, err
+		}
+		if err := s.apiKeyService.RevokeAll(ctx, userID); err != nil {
+			return nil, err
+		}
+		eventType = model.SecurityEventUserDisabled
+	}
+	s.record(ctx, &model.SecurityEvent{
+		UserID:  &userID,
+		Type:    eventType,
+		Details: map[string]any{"admin_id": adminID},
+	})
+
+	return s.userRepo.GetByID(ctx, userID)
+}
+
+// UpdateRole changes the role of a user. It is in access tokens issued
+// from the next refresh on.
+func (s *AdminService) UpdateRole(ctx context.Context, adminID, userID int64, role model.Role) (*model.User, error) {
+	if role != model.RoleUser && role != model.RoleAdmin {
+		return nil, ErrInvalidUserRole
+	}
+	if adminID == userID && role != model.RoleAdmin {
+		return nil, ErrCannotModifySelf
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	if user.Role == model.RoleAdmin {
+		if err := checkNotLastAdmin(ctx, s.userRepo, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, &model.SecurityEvent{
+		UserID: &userID,
+		Type:   model.SecurityEventRoleChanged,
+		Details: map[string]any{
+			"admin_id": adminID,
+			"from":     user.Role,
+			"to":       role,
+		},
+	})
+
+	return s.userRepo.GetByID(ctx, userID)
+}
+
+// LogoutUser revokes all sessions of a user.
+func (s *AdminService) LogoutUser(ctx context.Context, adminID, userID int64) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.authService.LogoutAll(ctx, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, &model.SecurityEvent{
+		UserID:  &userID,
+		Type:    model.SecurityEventSessionsRevoked,
+		Details: map[string]any{"admin_id": adminID},
+	})
+	return nil
+}
+
+// DeleteUser deletes a user with their data in all services.
+func (s *AdminService) DeleteUser(ctx context.Context, adminID, userID int64) error {
+	if adminID == userID {
+		return ErrCannotModifySelf
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Role == model.RoleAdmin {
+		if err := checkNotLastAdmin(ctx, s.userRepo, user); err != nil {
+			return err
+		}
+	}
+
+	deleted, err := s.dataService.DeleteUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("admin %d deleted user %d", adminID, userID)
+	s.record(ctx, &model.SecurityEvent{
+		Type: model.SecurityEventUserDeleted,
+		Details: map[string]any{
+			"admin_id": adminID,
+			"user_id":  userID,
+			"deleted":  deleted,
+		},
+	})
+	return nil
+}
+
+// ExportUser exports everything stored about a user.
+func (s *AdminService) ExportUser(ctx context.Context, adminID, userID int64) (*model.AccountExport, error) {
+	export, err := s.dataService.Export(ctx, adminID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, &model.SecurityEvent{
+		UserID:  &userID,
+		Type:    model.SecurityEventDataExported,
+		Details: map[string]any{"admin_id": adminID},
+	})
+	return export, nil
+}
+
+// Usage counts what a user created in the other services.
+func (s *AdminService) Usage(ctx context.Context, userID int64) (*model.UserUsageResponse, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.userData.Usage(ctx, userID), nil
+}
+
+// checkNotLastAdmin fails if user is the only active admin.
+func checkNotLastAdmin(ctx context.Context, userRepo *repository.UserRepository, user *model.User) error {
+	if user.DisabledAt != nil {
+		return nil
+	}
+
+	admins, err := userRepo.CountAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+func (s *AdminService) record(ctx context.Context, event *model.SecurityEvent) {
+	if err := s.eventRepo.Create(ctx, event); err != nil {
+		log.Printf("failed to record security event %s: %v", event.Type, err)
+	}
+}
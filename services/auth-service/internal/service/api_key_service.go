@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/repository"
+)
+
+const (
+	maxAPIKeysPerUser    = 25
+	maxAPIKeyExpiryDays  = 365
+	apiKeyRandomBytes    = 24
+	apiKeyDisplayedChars = 8 // after the prefix
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid api key request")
+	ErrTooManyAPIKeys = fmt.Errorf("at most %d api keys per user", maxAPIKeysPerUser)
+)
+
+type APIKeyService struct {
+	apiKeyRepo *repository.APIKeyRepository
+}
+
+func NewAPIKeyService(apiKeyRepo *repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{apiKeyRepo: apiKeyRepo}
+}
+
+// Create issues a key for userID. The key is returned once; only its hash is
+// stored.
+func (s *APIKeyService) Create(ctx context.Context, userID int64, req *model.CreateAPIKeyRequest) (*model.APIKey, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, "", fmt.Errorf("%w: name is required and must be at most 100 characters", ErrInvalidAPIKey)
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays != nil {
+		days := *req.ExpiresInDays
+		if days < 1 || days > maxAPIKeyExpiryDays {
+			return nil, "", fmt.Errorf("%w: expires_in_days must be between 1 and %d", ErrInvalidAPIKey, maxAPIKeyExpiryDays)
+		}
+		t := time.Now().AddDate(0, 0, days)
+		expiresAt = &t
+	}
+
+	count, err := s.apiKeyRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, "", ErrTooManyAPIKeys
+	}
+
+	random, err := generateRandomToken(apiKeyRandomBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	raw := model.APIKeyPrefix + random
+
+	key := &model.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:len(model.APIKeyPrefix)+apiKeyDisplayedChars],
+		KeyHash:   hashToken(raw),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, raw, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID int64) ([]*model.APIKey, error) {
+	return s.apiKeyRepo.ListByUser(ctx, userID)
+}
+
+// Revoke deletes a key; requests made with it fail immediately.
+func (s *APIKeyService) Revoke(ctx context.Context, userID, keyID int64) error {
+	return s.apiKeyRepo.Delete(ctx, keyID, userID)
+}
+
+// RevokeAll deletes every key of userID.
+func (s *APIKeyService) RevokeAll(ctx context.Context, userID int64) error {
+	return s.apiKeyRepo.DeleteByUser(ctx, userID)
+}
+
+// normalizeScopes validates scopes and drops duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKey)
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !model.ValidAPIKeyScope(scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q, expected one of: %s",
+				ErrInvalidAPIKey, scope, strings.Join(model.APIKeyScopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+
+	return result, nil
+}
@@ -6,12 +6,14 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/link-tracker/auth-service/internal/config"
 	"github.com/link-tracker/auth-service/internal/model"
 	"github.com/link-tracker/auth-service/internal/repository"
+	"github.com/link-tracker/auth-service/internal/signing"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -19,30 +21,50 @@ var (
 	ErrInvalidCredentials = errors.New("invalid email or password")
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrTokenExpired       = errors.New("token expired")
+	ErrTokenReused        = errors.New("refresh token was already used")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrAccountDisabled    = errors.New("account is disabled")
 )
 
 type Claims struct {
 	UserID int64      `json:"user_id"`
 	Email  string     `json:"email"`
 	Role   model.Role `json:"role"`
+	// SessionID is the session the token was issued for
+	SessionID int64 `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	tokenRepo *repository.TokenRepository
-	cfg       *config.JWTConfig
+	userRepo    *repository.UserRepository
+	tokenRepo   *repository.TokenRepository
+	sessionRepo *repository.SessionRepository
+	eventRepo   *repository.SecurityEventRepository
+	mfaService  *MFAService
+	loginGuard  *LoginGuard
+	keys        *signing.KeySet
+	cfg         *config.JWTConfig
 }
 
 func NewAuthService(
 	userRepo *repository.UserRepository,
 	tokenRepo *repository.TokenRepository,
+	sessionRepo *repository.SessionRepository,
+	eventRepo *repository.SecurityEventRepository,
+	mfaService *MFAService,
+	loginGuard *LoginGuard,
+	keys *signing.KeySet,
 	cfg *config.JWTConfig,
 ) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		tokenRepo: tokenRepo,
-		cfg:       cfg,
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		sessionRepo: sessionRepo,
+		eventRepo:   eventRepo,
+		mfaService:  mfaService,
+		loginGuard:  loginGuard,
+		keys:        keys,
+		cfg:         cfg,
 	}
 }
 
@@ -66,26 +88,118 @@ func (s *AuthService) Register(ctx context.Context, req *model.RegisterRequest)
 	return user, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, req *model.LoginRequest) (*model.AuthResponse, error) {
+// Login checks the password. Attempts are throttled by the login guard,
+// which returns a ThrottledError while the account or IP is blocked.
+func (s *AuthService) Login(ctx context.Context, req *model.LoginRequest, client model.ClientInfo) (*model.AuthResponse, error) {
+	attempt, err := s.loginGuard.Attempt(ctx, req.Email, client)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
+			s.loginGuard.Failed(ctx, attempt, nil)
 			return nil, ErrInvalidCredentials
 		}
+		s.loginGuard.Passed(ctx, attempt)
 		return nil, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		s.loginGuard.Failed(ctx, attempt, user)
 		return nil, ErrInvalidCredentials
 	}
+	s.loginGuard.Passed(ctx, attempt)
 
-	return s.generateTokenPair(ctx, user)
+	return s.CompleteLogin(ctx, user, client)
 }
 
-func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*model.AuthResponse, error) {
-	tokenHash := hashToken(refreshToken)
+// CompleteLogin signs in a user who has proven who they are, with a
+// password or an identity provider.
+func (s *AuthService) CompleteLogin(ctx context.Context, user *model.User, client model.ClientInfo) (*model.AuthResponse, error) {
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	// With MFA the password only earns a challenge, exchanged for tokens
+	// in VerifyMFA. Wrong codes count as failed logins and the account's
+	// failures are forgotten only once the code is right, so signing in
+	// again does not buy more guesses at the code. A locked account gets
+	// no new challenge.
+	mfaEnabled, err := s.mfaService.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaEnabled {
+		if err := s.loginGuard.CheckAccount(ctx, user.Email); err != nil {
+			return nil, err
+		}
+		mfaToken, err := s.mfaService.StartChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &model.AuthResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			ExpiresIn:   int64(s.mfaService.ChallengeTTL().Seconds()),
+		}, nil
+	}
+
+	s.loginGuard.Succeeded(ctx, user.Email)
+
+	authResponse, err := s.generateTokenPair(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	authResponse.MFASetupRequired, err = s.mfaService.RequiredByWorkspace(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return authResponse, nil
+}
+
+// VerifyMFA completes a login that returned mfa_required. Wrong codes are
+// throttled by the login guard like wrong passwords, on top of the attempt
+// limit of each challenge.
+func (s *AuthService) VerifyMFA(ctx context.Context, mfaToken, code string, client model.ClientInfo) (*model.AuthResponse, error) {
+	userID, err := s.mfaService.ChallengeUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt, err := s.loginGuard.Attempt(ctx, user.Email, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.mfaService.CompleteChallenge(ctx, mfaToken, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.loginGuard.Failed(ctx, attempt, user)
+		} else {
+			s.loginGuard.Passed(ctx, attempt)
+		}
+		return nil, err
+	}
+
+	s.loginGuard.Passed(ctx, attempt)
+	s.loginGuard.Succeeded(ctx, user.Email)
+
+	return s.generateTokenPair(ctx, user, client)
+}
 
-	storedToken, err := s.tokenRepo.GetByTokenHash(ctx, tokenHash)
+// RefreshTokens rotates a refresh token within its session. A token that
+// was already rotated has been copied, so the whole session is revoked and
+// a security event is recorded.
+func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string, client model.ClientInfo) (*model.AuthResponse, error) {
+	storedToken, err := s.tokenRepo.GetByTokenHash(ctx, hashToken(refreshToken))
 	if err != nil {
 		if errors.Is(err, repository.ErrTokenNotFound) || errors.Is(err, repository.ErrTokenExpired) {
 			return nil, ErrInvalidToken
@@ -93,26 +207,74 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*
 		return nil, err
 	}
 
-	// Delete old refresh token
-	if err := s.tokenRepo.DeleteByTokenHash(ctx, tokenHash); err != nil {
+	if storedToken.RotatedAt != nil {
+		return nil, s.revokeReusedSession(ctx, storedToken, client)
+	}
+
+	rotated, err := s.tokenRepo.MarkRotated(ctx, storedToken.ID)
+	if err != nil {
 		return nil, err
 	}
+	if !rotated {
+		// Another request rotated the token between the read and the update
+		return nil, s.revokeReusedSession(ctx, storedToken, client)
+	}
 
 	user, err := s.userRepo.GetByID(ctx, storedToken.UserID)
 	if err != nil {
 		return nil, err
 	}
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	if err := s.sessionRepo.Touch(ctx, storedToken.SessionID, client); err != nil {
+		return nil, err
+	}
 
-	return s.generateTokenPair(ctx, user)
+	return s.issueTokens(ctx, user, storedToken.SessionID, &storedToken.ID)
 }
 
+func (s *AuthService) revokeReusedSession(ctx context.Context, token *model.RefreshToken, client model.ClientInfo) error {
+	if err := s.sessionRepo.Delete(ctx, token.UserID, token.SessionID); err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		return err
+	}
+
+	log.Printf("security: refresh token %d of user %d reused from %s, session %d revoked",
+		token.ID, token.UserID, client.IPAddress, token.SessionID)
+
+	// The session is already revoked, so a failed write only loses the record
+	event := &model.SecurityEvent{
+		UserID:    &token.UserID,
+		Type:      model.SecurityEventTokenReuse,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Details: map[string]any{
+			"session_id": token.SessionID,
+			"token_id":   token.ID,
+		},
+	}
+	if err := s.eventRepo.Create(ctx, event); err != nil {
+		log.Printf("failed to record security event for user %d: %v", token.UserID, err)
+	}
+
+	return ErrTokenReused
+}
+
+// ValidateAccessToken accepts tokens signed with any key of the key set,
+// and HS256 tokens while a legacy secret is configured.
 func (s *AuthService) ValidateAccessToken(tokenString string) (*Claims, error) {
+	methods := s.keys.Algorithms()
+	if s.cfg.LegacySecret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return []byte(s.cfg.LegacySecret), nil
 		}
-		return []byte(s.cfg.Secret), nil
-	})
+		return s.keys.Keyfunc(token)
+	}, jwt.WithValidMethods(methods))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -133,18 +295,97 @@ func (s *AuthService) GetUserByID(ctx context.Context, userID int64) (*model.Use
 	return s.userRepo.GetByID(ctx, userID)
 }
 
+// Logout revokes the session of the refresh token. Unknown and expired
+// tokens are ignored.
 func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
-	tokenHash := hashToken(refreshToken)
-	return s.tokenRepo.DeleteByTokenHash(ctx, tokenHash)
+	storedToken, err := s.tokenRepo.GetByTokenHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenNotFound) || errors.Is(err, repository.ErrTokenExpired) {
+			return nil
+		}
+		return err
+	}
+
+	err = s.sessionRepo.Delete(ctx, storedToken.UserID, storedToken.SessionID)
+	if err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// ListSessions returns the signed-in devices of a user.
+func (s *AuthService) ListSessions(ctx context.Context, userID int64) ([]*model.Session, error) {
+	return s.sessionRepo.ListActiveByUser(ctx, userID)
+}
+
+// RevokeSession signs a device out. Access tokens already issued for it stay
+// valid until they expire.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID int64) error {
+	if err := s.sessionRepo.Delete(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of a signed-in user. All sessions
+// are revoked and the caller gets a new token pair, so other devices are
+// signed out while the current one stays signed in.
+func (s *AuthService) ChangePassword(ctx context.Context, userID int64, currentPassword, newPassword string, client model.ClientInfo) (*model.AuthResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, userID, string(hashedPassword)); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.DeleteByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.generateTokenPair(ctx, user, client)
 }
 
 func (s *AuthService) LogoutAll(ctx context.Context, userID int64) error {
-	return s.tokenRepo.DeleteByUserID(ctx, userID)
+	return s.sessionRepo.DeleteByUserID(ctx, userID)
+}
+
+// generateTokenPair starts a new session for the client. Disabled users
+// get no tokens.
+func (s *AuthService) generateTokenPair(ctx context.Context, user *model.User, client model.ClientInfo) (*model.AuthResponse, error) {
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+
+	session := &model.Session{
+		UserID:    user.ID,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user, session.ID, nil)
 }
 
-func (s *AuthService) generateTokenPair(ctx context.Context, user *model.User) (*model.AuthResponse, error) {
+// issueTokens issues a token pair in a session; parentID is the refresh
+// token being rotated, if any.
+func (s *AuthService) issueTokens(ctx context.Context, user *model.User, sessionID int64, parentID *int64) (*model.AuthResponse, error) {
 	// Generate access token
-	accessToken, err := s.generateAccessToken(user)
+	accessToken, err := s.generateAccessToken(user, sessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -159,6 +400,8 @@ func (s *AuthService) generateTokenPair(ctx context.Context, user *model.User) (
 	tokenHash := hashToken(refreshToken)
 	storedToken := &model.RefreshToken{
 		UserID:    user.ID,
+		SessionID: sessionID,
+		ParentID:  parentID,
 		TokenHash: tokenHash,
 		ExpiresAt: time.Now().Add(s.cfg.RefreshTokenTTL),
 	}
@@ -174,11 +417,12 @@ func (s *AuthService) generateTokenPair(ctx context.Context, user *model.User) (
 	}, nil
 }
 
-func (s *AuthService) generateAccessToken(user *model.User) (string, error) {
+func (s *AuthService) generateAccessToken(user *model.User, sessionID int64) (string, error) {
 	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.cfg.AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -186,8 +430,7 @@ func (s *AuthService) generateAccessToken(user *model.User) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.cfg.Secret))
+	return s.keys.Sign(claims)
 }
 
 func generateRandomToken(length int) (string, error) {
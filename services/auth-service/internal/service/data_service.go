@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/repository"
+	"github.com/link-tracker/auth-service/internal/throttle"
+	"github.com/link-tracker/auth-service/internal/userdata"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// exportInterval is how often a caller may start an export. Exports are
+// built in memory from all services, so they are too costly to repeat.
+const exportInterval = 5 * time.Minute
+
+var (
+	// ErrServicesUnavailable means a service holding user data failed an
+	// export or deletion. Deletions fail with a DeletionIncompleteError,
+	// which wraps it.
+	ErrServicesUnavailable = userdata.ErrUnavailable
+
+	// ErrExportTooLarge means the data of a service exceeds the export
+	// size limit.
+	ErrExportTooLarge = userdata.ErrTooLarge
+)
+
+// ExportThrottledError is returned when the caller started an export less
+// than exportInterval ago.
+type ExportThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ExportThrottledError) Error() string {
+	return "an export was started recently"
+}
+
+// DeletionIncompleteError is returned when services failed to delete the
+// data of a user. The user stays disabled, without sessions and API keys,
+// and nothing else is deleted from auth-service, so the deletion can be
+// retried. Deleted counts what the other services deleted and lists the
+// failed ones as unavailable.
+type DeletionIncompleteError struct {
+	Deleted *model.UserUsageResponse
+}
+
+func (e *DeletionIncompleteError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrServicesUnavailable, strings.Join(e.Deleted.Unavailable, ", "))
+}
+
+func (e *DeletionIncompleteError) Unwrap() error {
+	return ErrServicesUnavailable
+}
+
+// DataService exports and deletes accounts together with the data users
+// added to the other services, which only store a bare user_id.
+type DataService struct {
+	userRepo      *repository.UserRepository
+	workspaceRepo *repository.WorkspaceRepository
+	identityRepo  *repository.IdentityRepository
+	apiKeyRepo    *repository.APIKeyRepository
+	sessionRepo   *repository.SessionRepository
+	eventRepo     *repository.SecurityEventRepository
+	mfaService    *MFAService
+	userData      *userdata.Client
+	throttle      throttle.Store
+}
+
+func NewDataService(
+	userRepo *repository.UserRepository,
+	workspaceRepo *repository.WorkspaceRepository,
+	identityRepo *repository.IdentityRepository,
+	apiKeyRepo *repository.APIKeyRepository,
+	sessionRepo *repository.SessionRepository,
+	eventRepo *repository.SecurityEventRepository,
+	mfaService *MFAService,
+	userData *userdata.Client,
+	throttle throttle.Store,
+) *DataService {
+	return &DataService{
+		userRepo:      userRepo,
+		workspaceRepo: workspaceRepo,
+		identityRepo:  identityRepo,
+		apiKeyRepo:    apiKeyRepo,
+		sessionRepo:   sessionRepo,
+		eventRepo:     eventRepo,
+		mfaService:    mfaService,
+		userData:      userData,
+		throttle:      throttle,
+	}
+}
+
+// ExportAccount exports the caller's own account.
+func (s *DataService) ExportAccount(ctx context.Context, userID int64) (*model.AccountExport, error) {
+	export, err := s.Export(ctx, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.record(ctx, &model.SecurityEvent{
+		UserID: &userID,
+		Type:   model.SecurityEventDataExported,
+	})
+	return export, nil
+}
+
+// Export gathers everything stored about a user. A caller may start one
+// export per exportInterval.
+func (s *DataService) Export(ctx context.Context, callerID, userID int64) (*model.AccountExport, error) {
+	if err := s.claimExport(ctx, callerID); err != nil {
+		return nil, err
+	}
+
+	account, err := s.accountData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := s.userData.Export(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AccountExport{
+		ExportedAt: time.Now().UTC(),
+		Account:    account,
+		Services:   services,
+	}, nil
+}
+
+// claimExport returns an ExportThrottledError if the caller started an
+// export less than exportInterval ago. Store errors let the export through.
+func (s *DataService) claimExport(ctx context.Context, callerID int64) error {
+	key := fmt.Sprintf("export:user:%d", callerID)
+	claimed, err := s.throttle.Claim(ctx, key, exportInterval)
+	if err != nil {
+		log.Printf("failed to throttle export of user %d: %v", callerID, err)
+		return nil
+	}
+	if claimed {
+		return nil
+	}
+
+	retryAfter, err := s.throttle.BlockedFor(ctx, key)
+	if err != nil || retryAfter <= 0 {
+		retryAfter = exportInterval
+	}
+	return &ExportThrottledError{RetryAfter: retryAfter}
+}
+
+func (s *DataService) accountData(ctx context.Context, userID int64) (*model.AccountData, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &model.AccountData{User: user}
+	if data.MFAEnabled, err = s.mfaService.Enabled(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.Identities, err = s.identityRepo.ListByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.Workspaces, err = s.workspaceRepo.ListByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.APIKeys, err = s.apiKeyRepo.ListByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.Sessions, err = s.sessionRepo.ListActiveByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.SecurityEvents, err = s.eventRepo.ListByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// DeleteAccount deletes the caller's own account after checking their
+// password, when the account has one, and an MFA code, when MFA is enabled.
+func (s *DataService) DeleteAccount(ctx context.Context, userID int64, password, code string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+			return ErrInvalidCredentials
+		}
+	}
+
+	enabled, err := s.mfaService.Enabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		if err := s.mfaService.Verify(ctx, userID, code); err != nil {
+			return err
+		}
+	}
+
+	if user.Role == model.RoleAdmin {
+		if err := checkNotLastAdmin(ctx, s.userRepo, user); err != nil {
+			return err
+		}
+	}
+
+	deleted, err := s.DeleteUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("user %d deleted their account", userID)
+	s.record(ctx, &model.SecurityEvent{
+		Type: model.SecurityEventUserDeleted,
+		Details: map[string]any{
+			"user_id": userID,
+			"deleted": deleted,
+		},
+	})
+	return nil
+}
+
+// DeleteUser disables a user and revokes their sessions and API keys, so
+// they can't add data while it is deleted, then deletes their data in the
+// other services and their account with memberships. Workspaces the user was the
+// only owner of are handed over to another member; workspaces without
+// other members are deleted. The services hand over what the user created
+// in shared workspaces to the heir ReleaseUser picks, as the records would
+// otherwise belong to a deleted user. It returns what the services deleted,
+// or a DeletionIncompleteError when some of them failed.
+func (s *DataService) DeleteUser(ctx context.Context, user *model.User) (*model.UserUsageResponse, error) {
+	if err := s.userRepo.SetDisabled(ctx, user.ID, true); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.apiKeyRepo.DeleteByUser(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.userData.DeleteData(ctx, user.ID)
+	if errors.Is(err, userdata.ErrUnavailable) {
+		log.Printf("user %d is disabled, deleting their data is incomplete: %v", user.ID, err)
+		return nil, &DeletionIncompleteError{Deleted: deleted}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.workspaceRepo.ReleaseUser(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.Delete(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+func (s *DataService) record(ctx context.Context, event *model.SecurityEvent) {
+	if err := s.eventRepo.Create(ctx, event); err != nil {
+		log.Printf("failed to record security event %s: %v", event.Type, err)
+	}
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/link-tracker/auth-service/internal/config"
+	"github.com/link-tracker/auth-service/internal/mailer"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/repository"
+	"github.com/link-tracker/auth-service/internal/throttle"
+)
+
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// ThrottledError is returned while logins for the account or the IP are
+// blocked.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// LoginGuard throttles password and MFA code guessing. Failures are counted
+// per email, whether or not an account exists, and per IP; see
+// config.LoginConfig. Store errors never fail a login: throttling is
+// skipped instead.
+type LoginGuard struct {
+	store     throttle.Store
+	userRepo  *repository.UserRepository
+	eventRepo *repository.SecurityEventRepository
+	mailer    mailer.Mailer
+	cfg       *config.LoginConfig
+}
+
+func NewLoginGuard(
+	store throttle.Store,
+	userRepo *repository.UserRepository,
+	eventRepo *repository.SecurityEventRepository,
+	mailer mailer.Mailer,
+	cfg *config.LoginConfig,
+) *LoginGuard {
+	return &LoginGuard{
+		store:     store,
+		userRepo:  userRepo,
+		eventRepo: eventRepo,
+		mailer:    mailer,
+		cfg:       cfg,
+	}
+}
+
+// LoginAttempt is an attempt counted by Attempt. It is settled with Failed
+// or Passed once the password or code was checked.
+type LoginAttempt struct {
+	email      string
+	client     model.ClientInfo
+	failures   int64
+	ipFailures int64
+}
+
+// Check returns a ThrottledError if logins for the email or the client's
+// IP are blocked. It counts nothing.
+func (g *LoginGuard) Check(ctx context.Context, email string, client model.ClientInfo) error {
+	return g.check(ctx, g.keys(email, client))
+}
+
+// CheckAccount is Check for the account alone.
+func (g *LoginGuard) CheckAccount(ctx context.Context, email string) error {
+	return g.check(ctx, []string{accountKey(email)})
+}
+
+func (g *LoginGuard) check(ctx context.Context, keys []string) error {
+	var retryAfter time.Duration
+	for _, key := range keys {
+		d, err := g.store.BlockedFor(ctx, key)
+		if err != nil {
+			log.Printf("login guard: failed to check %s: %v", key, err)
+			continue
+		}
+		if d > retryAfter {
+			retryAfter = d
+		}
+	}
+
+	if retryAfter > 0 {
+		return &ThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Attempt counts an attempt as failed before the password or code is
+// checked, so concurrent requests can't all slip through before the first
+// failure is recorded. Past the free attempts each attempt must take the
+// backoff block of the key; concurrent ones that find it taken get a
+// ThrottledError and stay counted.
+func (g *LoginGuard) Attempt(ctx context.Context, email string, client model.ClientInfo) (*LoginAttempt, error) {
+	if err := g.Check(ctx, email, client); err != nil {
+		return nil, err
+	}
+
+	a := &LoginAttempt{email: email, client: client}
+	var err error
+	a.failures, err = g.count(ctx, accountKey(email), g.cfg.FreeAttempts, g.cfg.LockoutThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if client.IPAddress != "" {
+		a.ipFailures, err = g.count(ctx, ipKey(client.IPAddress), g.cfg.IPFreeAttempts, g.cfg.IPLockoutThreshold)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Failed settles a failed attempt. user is nil when no account has the
+// email. The failure is already counted; this records lockouts.
+func (g *LoginGuard) Failed(ctx context.Context, a *LoginAttempt, user *model.User) {
+	if a.failures == g.cfg.LockoutThreshold {
+		g.accountLocked(ctx, user, a.client, a.failures)
+	}
+
+	if a.ipFailures == g.cfg.IPLockoutThreshold {
+		log.Printf("security: ip %s blocked after %d failed logins", a.client.IPAddress, a.ipFailures)
+		g.record(ctx, &model.SecurityEvent{
+			Type:      model.SecurityEventIPBlocked,
+			IPAddress: a.client.IPAddress,
+			UserAgent: a.client.UserAgent,
+			Details:   map[string]any{"failures": a.ipFailures},
+		})
+	}
+}
+
+// Passed settles an attempt whose password or code was right: it no longer
+// counts, and the account block it took is lifted so the login can go on.
+// A block of the IP stays, so one valid account cannot be used to lift it.
+func (g *LoginGuard) Passed(ctx context.Context, a *LoginAttempt) {
+	key := accountKey(a.email)
+	if err := g.store.Forgive(ctx, key); err != nil {
+		log.Printf("login guard: failed to forgive %s: %v", key, err)
+	}
+	if err := g.store.Unblock(ctx, key); err != nil {
+		log.Printf("login guard: failed to unblock %s: %v", key, err)
+	}
+
+	if a.client.IPAddress == "" {
+		return
+	}
+	key = ipKey(a.client.IPAddress)
+	if err := g.store.Forgive(ctx, key); err != nil {
+		log.Printf("login guard: failed to forgive %s: %v", key, err)
+	}
+}
+
+// Succeeded forgets the failures of the account. Failures of the IP are
+// kept, so one valid account cannot be used to reset them.
+func (g *LoginGuard) Succeeded(ctx context.Context, email string) {
+	if err := g.store.Reset(ctx, accountKey(email)); err != nil {
+		log.Printf("login guard: failed to reset account counter: %v", err)
+	}
+}
+
+// Unlock lifts a lockout of the user's account before it expires.
+func (g *LoginGuard) Unlock(ctx context.Context, adminID, userID int64) error {
+	user, err := g.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := g.store.Reset(ctx, accountKey(user.Email)); err != nil {
+		return err
+	}
+
+	g.record(ctx, &model.SecurityEvent{
+		UserID:  &user.ID,
+		Type:    model.SecurityEventAccountUnlocked,
+		Details: map[string]any{"admin_id": adminID},
+	})
+	return nil
+}
+
+// count counts an attempt for key and, past the free attempts, takes the
+// block for the backoff delay. It returns the number of attempts counted
+// in the window.
+func (g *LoginGuard) count(ctx context.Context, key string, free, threshold int64) (int64, error) {
+	failures, err := g.store.Fail(ctx, key, g.cfg.Window)
+	if err != nil {
+		log.Printf("login guard: failed to count failure for %s: %v", key, err)
+		return 0, nil
+	}
+
+	d := g.delay(failures, free, threshold)
+	if d == 0 {
+		return failures, nil
+	}
+	claimed, err := g.store.Claim(ctx, key, d)
+	if err != nil {
+		log.Printf("login guard: failed to block %s: %v", key, err)
+		return failures, nil
+	}
+	if !claimed {
+		retryAfter, err := g.store.BlockedFor(ctx, key)
+		if err != nil || retryAfter <= 0 {
+			retryAfter = g.cfg.BackoffBase
+		}
+		return failures, &ThrottledError{RetryAfter: retryAfter}
+	}
+	return failures, nil
+}
+
+// delay returns how long a key is blocked after its n-th failure: nothing
+// for free attempts, then BackoffBase doubling up to BackoffMax, and
+// LockoutDuration from the threshold on.
+func (g *LoginGuard) delay(n, free, threshold int64) time.Duration {
+	if n >= threshold {
+		return g.cfg.LockoutDuration
+	}
+	if n <= free {
+		return 0
+	}
+
+	// Cap the shift; the result is capped by BackoffMax anyway
+	shift := n - free - 1
+	if shift > 30 {
+		shift = 30
+	}
+	d := g.cfg.BackoffBase << shift
+	if d > g.cfg.BackoffMax {
+		d = g.cfg.BackoffMax
+	}
+	return d
+}
+
+// accountLocked records the lockout and tells the owner, if the account
+// exists.
+func (g *LoginGuard) accountLocked(ctx context.Context, user *model.User, client model.ClientInfo, failures int64) {
+	if user == nil {
+		return
+	}
+
+	log.Printf("security: account of user %d locked after %d failed logins, last from %s", user.ID, failures, client.IPAddress)
+	g.record(ctx, &model.SecurityEvent{
+		UserID:    &user.ID,
+		Type:      model.SecurityEventAccountLocked,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Details:   map[string]any{"failures": failures},
+	})
+
+	err := g.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account was locked",
+		Body: fmt.Sprintf("Hi %s,\n\nSigning in to your Link Tracker account was blocked for %s after %d failed attempts. The last attempt came from %s.\n\nIf this was you, wait and try again or reset your password. If it was not, someone may be guessing your password: choose a strong one and turn on two-factor authentication.\n",
+			user.Name, formatTTL(g.cfg.LockoutDuration), failures, client.IPAddress),
+	})
+	if err != nil {
+		log.Printf("failed to send lockout email to user %d: %v", user.ID, err)
+	}
+}
+
+// record saves a security event; losing one must not fail the request.
+func (g *LoginGuard) record(ctx context.Context, event *model.SecurityEvent) {
+	if err := g.eventRepo.Create(ctx, event); err != nil {
+		log.Printf("failed to record security event %s: %v", event.Type, err)
+	}
+}
+
+func (g *LoginGuard) keys(email string, client model.ClientInfo) []string {
+	keys := []string{accountKey(email)}
+	if client.IPAddress != "" {
+		keys = append(keys, ipKey(client.IPAddress))
+	}
+	return keys
+}
+
+func accountKey(email string) string {
+	return "login:account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "login:ip:" + ip
+}
@@ -0,0 +1,414 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/link-tracker/auth-service/internal/config"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/repository"
+	"github.com/link-tracker/auth-service/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	recoveryCodeCount = 10
+
+	// maxMFAAttempts is the number of wrong codes after which a login
+	// challenge is used up and the user has to enter the password again
+	maxMFAAttempts = 5
+)
+
+var (
+	ErrMFAAlreadyEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled          = errors.New("two-factor authentication is not enabled")
+	ErrMFASetupNotStarted     = errors.New("two-factor setup has not been started")
+	ErrInvalidMFACode         = errors.New("invalid authentication code")
+	ErrMFARequiredByWorkspace = errors.New("a workspace you belong to requires two-factor authentication")
+)
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type MFAService struct {
+	mfaRepo          *repository.MFARepository
+	userRepo         *repository.UserRepository
+	accountTokenRepo *repository.AccountTokenRepository
+	cfg              *config.MFAConfig
+	gcm              cipher.AEAD
+}
+
+func NewMFAService(
+	mfaRepo *repository.MFARepository,
+	userRepo *repository.UserRepository,
+	accountTokenRepo *repository.AccountTokenRepository,
+	cfg *config.MFAConfig,
+) (*MFAService, error) {
+	key := sha256.Sum256([]byte(cfg.EncryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAService{
+		mfaRepo:          mfaRepo,
+		userRepo:         userRepo,
+		accountTokenRepo: accountTokenRepo,
+		cfg:              cfg,
+		gcm:              gcm,
+	}, nil
+}
+
+func (s *MFAService) Status(ctx context.Context, userID int64) (*model.MFAStatus, error) {
+	status := &model.MFAStatus{}
+
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		status.Enabled = true
+		if status.RecoveryCodesLeft, err = s.mfaRepo.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if status.RequiredByWorkspace, err = s.mfaRepo.RequiredByWorkspace(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func (s *MFAService) Enabled(ctx context.Context, userID int64) (bool, error) {
+	mfa, err := s.mfaRepo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return mfa.EnabledAt != nil, nil
+}
+
+func (s *MFAService) RequiredByWorkspace(ctx context.Context, userID int64) (bool, error) {
+	return s.mfaRepo.RequiredByWorkspace(ctx, userID)
+}
+
+// Setup starts enrollment with a new secret. MFA is not enabled until Enable
+// confirms a code generated from it.
+func (s *MFAService) Setup(ctx context.Context, userID int64) (*model.MFASetupResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.SavePending(ctx, userID, sealed); err != nil {
+		if errors.Is(err, repository.ErrMFAAlreadyEnabled) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &model.MFASetupResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.cfg.Issuer, user.Email, secret),
+	}, nil
+}
+
+// Enable confirms the setup with a code from the authenticator app and
+// returns the first recovery codes.
+func (s *MFAService) Enable(ctx context.Context, userID int64, code string) ([]string, error) {
+	mfa, err := s.mfaRepo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			return nil, ErrMFASetupNotStarted
+		}
+		return nil, err
+	}
+	if mfa.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := s.open(mfa.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.Enable(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrMFAAlreadyEnabled) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns MFA off after checking the password and a code. Members of
+// a workspace that requires MFA cannot turn it off.
+func (s *MFAService) Disable(ctx context.Context, userID int64, password, code string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	required, err := s.mfaRepo.RequiredByWorkspace(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredByWorkspace
+	}
+
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+
+	return s.mfaRepo.Delete(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a code
+// from the authenticator app.
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := s.verifyTOTP(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify accepts a current code from the authenticator app or an unused
+// recovery code. Each code works once.
+func (s *MFAService) Verify(ctx context.Context, userID int64, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(ctx, userID, code)
+	}
+
+	if _, err := s.enabledMFA(ctx, userID); err != nil {
+		return err
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// StartChallenge issues the short-lived token that login returns instead
+// of a token pair when MFA is enabled.
+func (s *MFAService) StartChallenge(ctx context.Context, userID int64) (string, error) {
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.accountTokenRepo.Create(ctx, &model.AccountToken{
+		UserID:    userID,
+		Purpose:   model.TokenMFALogin,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.cfg.ChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ChallengeUser returns the user a pending login challenge was issued to.
+func (s *MFAService) ChallengeUser(ctx context.Context, mfaToken string) (int64, error) {
+	challenge, err := s.challenge(ctx, hashToken(mfaToken))
+	if err != nil {
+		return 0, err
+	}
+	return challenge.UserID, nil
+}
+
+// CompleteChallenge checks the code for a login challenge and returns the
+// user who passed it. Wrong codes count against the challenge.
+func (s *MFAService) CompleteChallenge(ctx context.Context, mfaToken, code string) (int64, error) {
+	tokenHash := hashToken(mfaToken)
+
+	challenge, err := s.challenge(ctx, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.Verify(ctx, challenge.UserID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if err := s.accountTokenRepo.RecordFailedAttempt(ctx, challenge.ID, maxMFAAttempts); err != nil {
+				return 0, err
+			}
+		}
+		return 0, err
+	}
+
+	// Consuming fails if a concurrent request used the challenge first
+	if _, err := s.accountTokenRepo.Consume(ctx, model.TokenMFALogin, tokenHash); err != nil {
+		if errors.Is(err, repository.ErrAccountTokenNotFound) || errors.Is(err, repository.ErrAccountTokenExpired) {
+			return 0, ErrInvalidToken
+		}
+		return 0, err
+	}
+
+	return challenge.UserID, nil
+}
+
+func (s *MFAService) challenge(ctx context.Context, tokenHash string) (*model.AccountToken, error) {
+	challenge, err := s.accountTokenRepo.Get(ctx, model.TokenMFALogin, tokenHash)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrAccountTokenNotFound):
+			return nil, ErrInvalidToken
+		case errors.Is(err, repository.ErrAccountTokenExpired):
+			return nil, ErrTokenExpired
+		}
+		return nil, err
+	}
+	return challenge, nil
+}
+
+func (s *MFAService) ChallengeTTL() time.Duration {
+	return s.cfg.ChallengeTTL
+}
+
+func (s *MFAService) verifyTOTP(ctx context.Context, userID int64, code string) error {
+	mfa, err := s.enabledMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	secret, err := s.open(mfa.TOTPSecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	used, err := s.mfaRepo.UseStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func (s *MFAService) enabledMFA(ctx context.Context, userID int64) (*model.UserMFA, error) {
+	mfa, err := s.mfaRepo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			return nil, ErrMFANotEnabled
+		}
+		return nil, err
+	}
+	if mfa.EnabledAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+	return mfa, nil
+}
+
+// seal encrypts a TOTP secret for storage.
+func (s *MFAService) seal(secret string) (string, error) {
+	nonce := make([]byte, s.gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *MFAService) open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < s.gcm.NonceSize() {
+		return "", errors.New("stored totp secret is corrupt")
+	}
+
+	nonce, ciphertext := data[:s.gcm.NonceSize()], data[s.gcm.NonceSize():]
+	secret, err := s.gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// generateRecoveryCodes returns codes formatted as "xxxx-xxxx" and their
+// hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode makes codes typed with spaces, dashes or capitals
+// match.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/link-tracker/auth-service/internal/config"
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/oidc"
+	"github.com/link-tracker/auth-service/internal/repository"
+)
+
+var (
+	ErrUnknownProvider     = errors.New("unknown identity provider")
+	ErrInvalidOIDCState    = errors.New("sign-in request is invalid or expired")
+	ErrOIDCLoginFailed     = errors.New("identity provider did not confirm the sign-in")
+	ErrProviderUnavailable = errors.New("identity provider is unavailable")
+	ErrEmailNotVerified    = errors.New("identity provider did not confirm the email address")
+)
+
+// OIDCService signs users in with external identity providers. A provider
+// account is linked to the user with the same email once the provider
+// confirms the address; without such a user, one is created.
+type OIDCService struct {
+	providers    []*oidc.Provider
+	identityRepo *repository.IdentityRepository
+	stateRepo    *repository.OIDCStateRepository
+	userRepo     *repository.UserRepository
+	authService  *AuthService
+	cfg          *config.OIDCConfig
+}
+
+func NewOIDCService(
+	identityRepo *repository.IdentityRepository,
+	stateRepo *repository.OIDCStateRepository,
+	userRepo *repository.UserRepository,
+	authService *AuthService,
+	cfg *config.OIDCConfig,
+) *OIDCService {
+	providers := make([]*oidc.Provider, 0, len(cfg.Providers))
+	for _, providerCfg := range cfg.Providers {
+		providers = append(providers, oidc.NewProvider(providerCfg, cfg.RedirectURL))
+	}
+
+	return &OIDCService{
+		providers:    providers,
+		identityRepo: identityRepo,
+		stateRepo:    stateRepo,
+		userRepo:     userRepo,
+		authService:  authService,
+		cfg:          cfg,
+	}
+}
+
+// Providers returns the configured providers in configuration order.
+func (s *OIDCService) Providers() []*oidc.Provider {
+	return s.providers
+}
+
+// Start begins a sign-in and returns the provider URL to send the user to.
+// The state, nonce and PKCE verifier are stored until the callback.
+func (s *OIDCService) Start(ctx context.Context, providerName string) (string, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := generateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", providerError(providerName, err)
+	}
+
+	pending := &model.OIDCState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(s.cfg.StateTTL),
+	}
+	if err := s.stateRepo.Create(ctx, pending); err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// Callback completes a sign-in with the code the provider returned and
+// signs the user in like a password login, including the MFA challenge.
+func (s *OIDCService) Callback(ctx context.Context, providerName, code, state string, client model.ClientInfo) (*model.AuthResponse, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.stateRepo.Consume(ctx, providerName, hashToken(state))
+	if err != nil {
+		if errors.Is(err, repository.ErrOIDCStateNotFound) || errors.Is(err, repository.ErrOIDCStateExpired) {
+			return nil, ErrInvalidOIDCState
+		}
+		return nil, err
+	}
+
+	identity, err := provider.Exchange(ctx, code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		return nil, providerError(providerName, err)
+	}
+
+	user, err := s.resolveUser(ctx, providerName, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.CompleteLogin(ctx, user, client)
+}
+
+// resolveUser returns the user linked to the identity, linking it first if
+// needed. Disabled users are refused before anything is changed.
+func (s *OIDCService) resolveUser(ctx context.Context, providerName string, identity *oidc.Identity) (*model.User, error) {
+	linked, err := s.identityRepo.GetByProviderSubject(ctx, providerName, identity.Subject)
+	if err == nil {
+		return s.linkedUser(ctx, linked, identity)
+	}
+	if !errors.Is(err, repository.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	// Linking by an address the provider has not confirmed would let anyone
+	// sign in as its owner
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		if user.DisabledAt != nil {
+			return nil, ErrAccountDisabled
+		}
+	case errors.Is(err, repository.ErrUserNotFound):
+		user = &model.User{
+			Email: identity.Email,
+			Name:  identity.Name,
+			Role:  model.RoleUser,
+		}
+		if user.Name == "" {
+			user.Name, _, _ = strings.Cut(identity.Email, "@")
+		}
+		// No password: the user signs in with the provider, or sets one
+		// with a password reset link
+		err := s.userRepo.Create(ctx, user)
+		if errors.Is(err, repository.ErrUserAlreadyExists) {
+			// A concurrent callback, or a registration, created the user
+			// first; link to that user instead
+			user, err = s.userRepo.GetByEmail(ctx, identity.Email)
+			if err == nil && user.DisabledAt != nil {
+				return nil, ErrAccountDisabled
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	link := &model.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	tookOver, err := s.identityRepo.Link(ctx, link)
+	if errors.Is(err, repository.ErrIdentityLinked) {
+		// A concurrent first callback for the same provider account linked
+		// it first; sign in through that link
+		linked, err := s.identityRepo.GetByProviderSubject(ctx, providerName, identity.Subject)
+		if err != nil {
+			return nil, err
+		}
+		return s.linkedUser(ctx, linked, identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tookOver {
+		user.PasswordHash = ""
+	}
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	log.Printf("linked %s account %s to user %d", providerName, identity.Subject, user.ID)
+	return user, nil
+}
+
+// linkedUser returns the user a provider account is linked to and records
+// the sign-in.
+func (s *OIDCService) linkedUser(ctx context.Context, linked *model.UserIdentity, identity *oidc.Identity) (*model.User, error) {
+	user, err := s.userRepo.GetByID(ctx, linked.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	if err := s.identityRepo.TouchLogin(ctx, linked.ID, identity.Email); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *OIDCService) provider(name string) (*oidc.Provider, error) {
+	for _, provider := range s.providers {
+		if provider.Name() == name {
+			return provider, nil
+		}
+	}
+	return nil, ErrUnknownProvider
+}
+
+// providerError maps provider failures to service errors. Details are
+// logged only, as they may describe the provider configuration.
+func providerError(providerName string, err error) error {
+	log.Printf("oidc %s: %v", providerName, err)
+	if errors.Is(err, oidc.ErrRejected) {
+		return ErrOIDCLoginFailed
+	}
+	return ErrProviderUnavailable
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/link-tracker/auth-service/internal/model"
+	"github.com/link-tracker/auth-service/internal/repository"
+)
+
+var (
+	ErrForbidden   = errors.New("insufficient workspace role")
+	ErrInvalidRole = errors.New("role must be one of: owner, editor, viewer")
+)
+
+type WorkspaceService struct {
+	workspaceRepo *repository.WorkspaceRepository
+	userRepo      *repository.UserRepository
+	mfaService    *MFAService
+}
+
+func NewWorkspaceService(workspaceRepo *repository.WorkspaceRepository, userRepo *repository.UserRepository, mfaService *MFAService) *WorkspaceService {
+	return &WorkspaceService{
+		workspaceRepo: workspaceRepo,
+		userRepo:      userRepo,
+		mfaService:    mfaService,
+	}
+}
+
+// Create makes a workspace owned by userID.
+func (s *WorkspaceService) Create(ctx context.Context, userID int64, req *model.CreateWorkspaceRequest) (*model.Workspace, error) {
+	workspace := &model.Workspace{
+		Name:      strings.TrimSpace(req.Name),
+		CreatedBy: &userID,
+	}
+
+	if err := s.workspaceRepo.Create(ctx, workspace); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+func (s *WorkspaceService) List(ctx context.Context, userID int64) ([]*model.Workspace, error) {
+	return s.workspaceRepo.ListByUser(ctx, userID)
+}
+
+// Get returns a workspace to any of its members.
+func (s *WorkspaceService) Get(ctx context.Context, userID, workspaceID int64) (*model.Workspace, error) {
+	role, err := s.authorize(ctx, workspaceID, userID, model.WorkspaceViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.workspaceRepo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	workspace.Role = role
+
+	return workspace, nil
+}
+
+func (s *WorkspaceService) Update(ctx context.Context, userID, workspaceID int64, req *model.UpdateWorkspaceRequest) (*model.Workspace, error) {
+	workspace, err := s.Get(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if workspace.Role != model.WorkspaceOwner {
+		return nil, ErrForbidden
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		workspace.Name = name
+	}
+
+	// Members without MFA lose access to the workspace's resources while it
+	// is required. The owner turning it on must have MFA so they keep theirs.
+	if req.RequireMFA != nil {
+		if *req.RequireMFA && !workspace.RequireMFA {
+			enabled, err := s.mfaService.Enabled(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+			if !enabled {
+				return nil, ErrMFANotEnabled
+			}
+		}
+		workspace.RequireMFA = *req.RequireMFA
+	}
+
+	if err := s.workspaceRepo.Update(ctx, workspace); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// Delete removes the workspace. Its projects, sites and platforms go back
+// to the members who created them.
+func (s *WorkspaceService) Delete(ctx context.Context, userID, workspaceID int64) error {
+	if _, err := s.authorize(ctx, workspaceID, userID, model.WorkspaceOwner); err != nil {
+		return err
+	}
+
+	return s.workspaceRepo.Delete(ctx, workspaceID)
+}
+
+func (s *WorkspaceService) Members(ctx context.Context, userID, workspaceID int64) ([]*model.WorkspaceMember, error) {
+	if _, err := s.authorize(ctx, workspaceID, userID, model.WorkspaceViewer); err != nil {
+		return nil, err
+	}
+
+	return s.workspaceRepo.ListMembers(ctx, workspaceID)
+}
+
+// AddMember adds a registered user to the workspace. Only owners manage
+// members.
+func (s *WorkspaceService) AddMember(ctx context.Context, userID, workspaceID int64, req *model.AddMemberRequest) (*model.WorkspaceMember, error) {
+	if !req.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if _, err := s.authorize(ctx, workspaceID, userID, model.WorkspaceOwner); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(req.Email))
+	if err != nil {
+		return nil, err
+	}
+
+	member := &model.WorkspaceMember{
+		WorkspaceID: workspaceID,
+		UserID:      user.ID,
+		Email:       user.Email,
+		Name:        user.Name,
+		Role:        req.Role,
+	}
+	if err := s.workspaceRepo.AddMember(ctx, member); err != nil {
+		return nil, err
+	}
+	if member.MFAEnabled, err = s.mfaService.Enabled(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (s *WorkspaceService) UpdateMember(ctx context.Context, userID, workspaceID, memberID int64, role model.WorkspaceRole) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+	if _, err := s.authorize(ctx, workspaceID, userID, model.WorkspaceOwner); err != nil {
+		return err
+	}
+
+	return s.workspaceRepo.UpdateMemberRole(ctx, workspaceID, memberID, role)
+}
+
+// RemoveMember removes a member. Owners remove anyone; other members may
+// only leave.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberID int64) error {
+	required := model.WorkspaceOwner
+	if memberID == userID {
+		required = model.WorkspaceViewer
+	}
+	if _, err := s.authorize(ctx, workspaceID, userID, required); err != nil {
+		return err
+	}
+
+	return s.workspaceRepo.RemoveMember(ctx, workspaceID, memberID)
+}
+
+// authorize returns the role of userID in the workspace, or ErrForbidden
+// when it is below required. Non-members get ErrWorkspaceNotFound so that
+// workspace ids are not disclosed.
+func (s *WorkspaceService) authorize(ctx context.Context, workspaceID, userID int64, required model.WorkspaceRole) (model.WorkspaceRole, error) {
+	role, err := s.workspaceRepo.GetRole(ctx, workspaceID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			return "", repository.ErrWorkspaceNotFound
+		}
+		return "", err
+	}
+
+	if roleRank(role) < roleRank(required) {
+		return "", ErrForbidden
+	}
+
+	return role, nil
+}
+
+func roleRank(role model.WorkspaceRole) int {
+	switch role {
+	case model.WorkspaceOwner:
+		return 3
+	case model.WorkspaceEditor:
+		return 2
+	case model.WorkspaceViewer:
+		return 1
+	}
+	return 0
+}
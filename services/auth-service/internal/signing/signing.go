@@ -0,0 +1,235 @@
+// Package signing holds the private keys access tokens are signed with and
+// publishes their public halves as a JSON Web Key Set, so other services can
+// verify tokens without being able to issue them.
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key accepted for RS256.
+const minRSABits = 2048
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key is a private signing key. Its ID is sent as the "kid" header of
+// every token it signs.
+type Key struct {
+	ID     string
+	method jwt.SigningMethod
+	signer crypto.Signer
+}
+
+// Algorithm returns the JWS algorithm of the key: RS256 or EdDSA.
+func (k *Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+// KeySet signs with its active key and verifies with any of its keys, so a
+// key can be rotated out while tokens it signed are still valid.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// Load reads every *.pem file in dir as a private key named after the file.
+// RSA keys sign with RS256 and Ed25519 keys with EdDSA. activeID selects
+// the signing key; it may be empty when dir holds a single key.
+//
+// To rotate, add the new key file and make it active; remove the old file
+// once tokens signed with it have expired.
+func Load(dir, activeID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.pem keys in %s", dir)
+	}
+	sort.Strings(paths)
+
+	set := &KeySet{keys: make(map[string]*Key, len(paths))}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := parseKey(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		set.keys[id] = key
+	}
+
+	switch {
+	case activeID != "":
+		set.active = set.keys[activeID]
+		if set.active == nil {
+			return nil, fmt.Errorf("active key %q not found in %s", activeID, dir)
+		}
+	case len(set.keys) == 1:
+		set.active = set.keys[strings.TrimSuffix(filepath.Base(paths[0]), ".pem")]
+	default:
+		return nil, errors.New("several keys found; set the active key id")
+	}
+
+	return set, nil
+}
+
+// Generate returns a set with a new Ed25519 key that lives only as long as
+// the process. Tokens stop verifying after a restart, so it is meant for
+// local development.
+func Generate() (*KeySet, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: "dev-" + hex.EncodeToString(b), method: jwt.SigningMethodEdDSA, signer: private}
+	return &KeySet{active: key, keys: map[string]*Key{key.ID: key}}, nil
+}
+
+// Active returns the key new tokens are signed with.
+func (s *KeySet) Active() *Key {
+	return s.active
+}
+
+// Sign signs claims with the active key.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.method, claims)
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.signer)
+}
+
+// Keyfunc verifies tokens signed by any key of the set. Use it with
+// jwt.WithValidMethods so the algorithm must match the key.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, ErrUnknownKey
+	}
+	return key.signer.Public(), nil
+}
+
+// Algorithms returns the algorithms of the keys in the set.
+func (s *KeySet) Algorithms() []string {
+	seen := map[string]bool{}
+	var algs []string
+	for _, key := range s.keys {
+		if alg := key.Algorithm(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set, ordered by ID.
+func (s *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		key := s.keys[id]
+		jwk := JWK{Kid: id, Use: "sig", Alg: key.Algorithm()}
+
+		switch public := key.signer.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func parseKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var private any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSABits)
+		}
+		return &Key{ID: id, method: jwt.SigningMethodRS256, signer: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, method: jwt.SigningMethodEdDSA, signer: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+}
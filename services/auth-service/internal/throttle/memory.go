@@ -0,0 +1,120 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many writes pass between removals of expired entries.
+const sweepEvery = 1000
+
+type memoryEntry struct {
+	failures     int64
+	failuresTill time.Time
+	blockedTill  time.Time
+}
+
+// MemoryStore keeps counters in process memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	writes  int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*memoryEntry{}}
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, key string, window time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e := s.entry(key)
+	if now.After(e.failuresTill) {
+		e.failures = 0
+		e.failuresTill = now.Add(window)
+	}
+	e.failures++
+	return e.failures, nil
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, key string, d time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e := s.entry(key)
+	if now.Before(e.blockedTill) {
+		return false, nil
+	}
+	e.blockedTill = now.Add(d)
+	return true, nil
+}
+
+func (s *MemoryStore) Forgive(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.failures > 0 && time.Now().Before(e.failuresTill) {
+		e.failures--
+	}
+	return nil
+}
+
+func (s *MemoryStore) Unblock(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.blockedTill = time.Time{}
+	}
+	return nil
+}
+
+func (s *MemoryStore) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return 0, nil
+	}
+	if d := time.Until(e.blockedTill); d > 0 {
+		return d, nil
+	}
+	return 0, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// entry returns the entry of key, creating it. Callers hold s.mu.
+func (s *MemoryStore) entry(key string) *memoryEntry {
+	s.writes++
+	if s.writes%sweepEvery == 0 {
+		s.sweep()
+	}
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+	return e
+}
+
+func (s *MemoryStore) sweep() {
+	now := time.Now()
+	for key, e := range s.entries {
+		if now.After(e.failuresTill) && now.After(e.blockedTill) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package throttle
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces throttle keys in a shared Redis.
+const keyPrefix = "auth:throttle:"
+
+// forgiveScript decrements a counter that exists and is positive. A plain
+// DECR would recreate an expired counter at -1 without an expiry.
+var forgiveScript = redis.NewScript(`
+local n = tonumber(redis.call("GET", KEYS[1]) or "0")
+if n > 0 then
+	return redis.call("DECR", KEYS[1])
+end
+return 0
+`)
+
+// RedisStore keeps counters in Redis, shared by all instances.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Fail(ctx context.Context, key string, window time.Duration) (int64, error) {
+	failuresKey := keyPrefix + key + ":failures"
+
+	pipe := s.client.TxPipeline()
+	incr := pipe.Incr(ctx, failuresKey)
+	// NX keeps the expiry of the first failure, so the window does not slide
+	pipe.ExpireNX(ctx, failuresKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (s *RedisStore) Claim(ctx context.Context, key string, d time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, keyPrefix+key+":blocked", 1, d).Result()
+}
+
+func (s *RedisStore) Forgive(ctx context.Context, key string) error {
+	return forgiveScript.Run(ctx, s.client, []string{keyPrefix + key + ":failures"}).Err()
+}
+
+func (s *RedisStore) Unblock(ctx context.Context, key string) error {
+	return s.client.Del(ctx, keyPrefix+key+":blocked").Err()
+}
+
+func (s *RedisStore) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.PTTL(ctx, keyPrefix+key+":blocked").Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL is negative for missing keys
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, keyPrefix+key+":failures", keyPrefix+key+":blocked").Err()
+}
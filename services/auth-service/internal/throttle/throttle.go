@@ -0,0 +1,130 @@
+// Package throttle counts failed attempts per key and blocks keys for a
+// while, in Redis so that all auth-service instances share the counters,
+// or in memory when Redis is unavailable.
+package throttle
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Store keeps failure counters and blocks.
+type Store interface {
+	// Fail records a failure for key and returns the number of failures
+	// since the first one, which is forgotten window after it happened.
+	Fail(ctx context.Context, key string, window time.Duration) (int64, error)
+
+	// Claim blocks key for d unless it is already blocked, and reports
+	// whether it did. Of concurrent callers only one succeeds.
+	Claim(ctx context.Context, key string, d time.Duration) (bool, error)
+
+	// Forgive takes back one failure of key, if any is counted.
+	Forgive(ctx context.Context, key string) error
+
+	// Unblock lifts the block of key and keeps its failures.
+	Unblock(ctx context.Context, key string) error
+
+	// BlockedFor returns how long key stays blocked, or 0.
+	BlockedFor(ctx context.Context, key string) (time.Duration, error)
+
+	// Reset forgets the failures and block of key.
+	Reset(ctx context.Context, key string) error
+}
+
+// primaryRetryInterval is how long the fallback is used after primary
+// fails, so requests do not each wait for Redis to time out.
+const primaryRetryInterval = 30 * time.Second
+
+// FallbackStore uses primary and switches to fallback when it fails, so an
+// unreachable Redis weakens throttling to a single instance instead of
+// turning it off.
+type FallbackStore struct {
+	primary  Store
+	fallback Store
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+func NewFallbackStore(primary, fallback Store) *FallbackStore {
+	return &FallbackStore{primary: primary, fallback: fallback}
+}
+
+func (s *FallbackStore) Fail(ctx context.Context, key string, window time.Duration) (int64, error) {
+	if s.primaryUp() {
+		n, err := s.primary.Fail(ctx, key, window)
+		if err == nil {
+			return n, nil
+		}
+		s.primaryFailed(err)
+	}
+	return s.fallback.Fail(ctx, key, window)
+}
+
+func (s *FallbackStore) Claim(ctx context.Context, key string, d time.Duration) (bool, error) {
+	if s.primaryUp() {
+		ok, err := s.primary.Claim(ctx, key, d)
+		if err == nil {
+			return ok, nil
+		}
+		s.primaryFailed(err)
+	}
+	return s.fallback.Claim(ctx, key, d)
+}
+
+// Forgive and Unblock apply to both stores, like Reset.
+func (s *FallbackStore) Forgive(ctx context.Context, key string) error {
+	if s.primaryUp() {
+		if err := s.primary.Forgive(ctx, key); err != nil {
+			s.primaryFailed(err)
+		}
+	}
+	return s.fallback.Forgive(ctx, key)
+}
+
+func (s *FallbackStore) Unblock(ctx context.Context, key string) error {
+	if s.primaryUp() {
+		if err := s.primary.Unblock(ctx, key); err != nil {
+			s.primaryFailed(err)
+		}
+	}
+	return s.fallback.Unblock(ctx, key)
+}
+
+func (s *FallbackStore) BlockedFor(ctx context.Context, key string) (time.Duration, error) {
+	if s.primaryUp() {
+		d, err := s.primary.BlockedFor(ctx, key)
+		if err == nil {
+			return d, nil
+		}
+		s.primaryFailed(err)
+	}
+	return s.fallback.BlockedFor(ctx, key)
+}
+
+// Reset clears both stores, since failures may have been counted in
+// either.
+func (s *FallbackStore) Reset(ctx context.Context, key string) error {
+	if s.primaryUp() {
+		if err := s.primary.Reset(ctx, key); err != nil {
+			s.primaryFailed(err)
+		}
+	}
+	return s.fallback.Reset(ctx, key)
+}
+
+func (s *FallbackStore) primaryUp() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().After(s.downUntil)
+}
+
+func (s *FallbackStore) primaryFailed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.downUntil = time.Now().Add(primaryRetryInterval)
+	log.Printf("throttle: redis unavailable, using in-memory counters for %s: %v", primaryRetryInterval, err)
+}
@@ -0,0 +1,126 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890", in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last 6 digits.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		step, ok := Validate(rfcSecret, tt.code, time.Unix(tt.unix, 0))
+		if !ok {
+			t.Errorf("Validate(%q) at %d rejected a valid code", tt.code, tt.unix)
+			continue
+		}
+		if want := tt.unix / Period; step != want {
+			t.Errorf("Validate(%q) at %d = step %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const step = 40000000
+	code := generate(key, step)
+	start := time.Unix(step*Period, 0)
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   bool
+	}{
+		{"same step", 0, true},
+		{"end of step", Period*time.Second - time.Second, true},
+		{"one step later", Period * time.Second, true},
+		{"one step earlier", -Period * time.Second, true},
+		{"two steps later", 2 * Period * time.Second, false},
+		{"two steps earlier", -Period*time.Second - time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Validate(rfcSecret, code, start.Add(tt.offset))
+			if ok != tt.want {
+				t.Fatalf("Validate() ok = %v, want %v", ok, tt.want)
+			}
+			if ok && got != step {
+				t.Errorf("Validate() step = %d, want %d", got, step)
+			}
+		})
+	}
+}
+
+// A code stays valid for the whole window, so replay protection relies on
+// Validate returning the step the code belongs to rather than the current
+// one: MFARepository.UseStep accepts each step once.
+func TestValidateReturnsCodeStepForReplayCheck(t *testing.T) {
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const step = 40000000
+	code := generate(key, step)
+
+	var steps []int64
+	for _, offset := range []time.Duration{-Period * time.Second, 0, Period * time.Second} {
+		got, ok := Validate(rfcSecret, code, time.Unix(step*Period, 0).Add(offset))
+		if !ok {
+			t.Fatalf("Validate() rejected the code %v from its step", offset)
+		}
+		steps = append(steps, got)
+	}
+	for _, got := range steps {
+		if got != step {
+			t.Errorf("Validate() steps = %v, want all %d", steps, step)
+			break
+		}
+	}
+}
+
+func TestValidateRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(59, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"wrong code", rfcSecret, "287083"},
+		{"short code", rfcSecret, "28708"},
+		{"long code", rfcSecret, "2870820"},
+		{"empty code", rfcSecret, ""},
+		{"invalid secret", "not base32!", "287082"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.code, now); ok {
+				t.Errorf("Validate(%q, %q) accepted", tt.secret, tt.code)
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsLowercaseSecret(t *testing.T) {
+	if _, ok := Validate("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", time.Unix(59, 0)); !ok {
+		t.Error("Validate() rejected a lowercase secret")
+	}
+}
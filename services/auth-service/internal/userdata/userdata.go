@@ -0,0 +1,233 @@
+// Package userdata calls the services that own user data: projects and
+// backlinks in backlink-service, sites in health-service and platforms in
+// index-service. The services serve these routes on an internal port that
+// is neither published nor proxied, so only auth-service can reach them.
+package userdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/link-tracker/auth-service/internal/config"
+	"github.com/link-tracker/auth-service/internal/model"
+)
+
+// maxResponseSize limits the responses read from a service, and
+// maxExportSize the exports of all services together, which are held in
+// memory while the export is written. Each service gets an equal share.
+const (
+	maxResponseSize = 1 << 20
+	maxExportSize   = 48 << 20
+)
+
+var (
+	// ErrUnavailable is returned when a service fails an export or
+	// deletion. The error names the services.
+	ErrUnavailable = errors.New("user data services unavailable")
+
+	// ErrTooLarge is returned when the data of a service exceeds its share
+	// of maxExportSize.
+	ErrTooLarge = fmt.Errorf("export exceeds %d MiB", maxExportSize>>20)
+)
+
+// userPath is the internal route of a service for a user's data.
+func userPath(userID int64, action string) string {
+	return fmt.Sprintf("/internal/v1/users/%d/%s", userID, action)
+}
+
+type service struct {
+	name    string
+	baseURL string
+}
+
+type Client struct {
+	services []service
+	client   *http.Client
+}
+
+func NewClient(cfg config.ServicesConfig) *Client {
+	return &Client{
+		services: []service{
+			{name: "backlink-service", baseURL: strings.TrimSuffix(cfg.BacklinkURL, "/")},
+			{name: "health-service", baseURL: strings.TrimSuffix(cfg.HealthURL, "/")},
+			{name: "index-service", baseURL: strings.TrimSuffix(cfg.IndexURL, "/")},
+		},
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Usage counts what a user created in every service. Counts of services
+// that fail are left nil and the services are listed as unavailable, so
+// one service being down does not hide the others.
+func (c *Client) Usage(ctx context.Context, userID int64) *model.UserUsageResponse {
+	usage := &model.UserUsageResponse{UserID: userID}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, svc := range c.services {
+		wg.Add(1)
+		go func(svc service) {
+			defer wg.Done()
+
+			var counts model.UserUsageResponse
+			err := c.get(ctx, svc, userPath(userID, "usage"), &counts)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("userdata: usage of user %d from %s: %v", userID, svc.name, err)
+				usage.Unavailable = append(usage.Unavailable, svc.name)
+				return
+			}
+			usage.Merge(&counts)
+		}(svc)
+	}
+	wg.Wait()
+
+	return usage
+}
+
+// Export fetches the data a user added to every service, keyed by service
+// name. Unlike Usage it fails when any service does, as an export missing
+// a service would look complete.
+func (c *Client) Export(ctx context.Context, userID int64) (map[string]json.RawMessage, error) {
+	exports := make(map[string]json.RawMessage, len(c.services))
+
+	var unavailable []string
+	var tooLarge bool
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, svc := range c.services {
+		wg.Add(1)
+		go func(svc service) {
+			defer wg.Done()
+
+			data, err := c.export(ctx, svc, userPath(userID, "export"), maxExportSize/int64(len(c.services)))
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("userdata: export of user %d from %s: %v", userID, svc.name, err)
+				if errors.Is(err, ErrTooLarge) {
+					tooLarge = true
+					return
+				}
+				unavailable = append(unavailable, svc.name)
+				return
+			}
+			exports[svc.name] = data
+		}(svc)
+	}
+	wg.Wait()
+
+	if tooLarge {
+		return nil, ErrTooLarge
+	}
+	if len(unavailable) > 0 {
+		return nil, unavailableError(unavailable)
+	}
+	return exports, nil
+}
+
+// DeleteData deletes the data a user added in every service and returns
+// what was deleted. It calls every service even when one fails; the counts
+// are then returned with the failed services listed as unavailable, along
+// with the error. Deleting is idempotent, so a failed deletion is retried as
+// a whole.
+func (c *Client) DeleteData(ctx context.Context, userID int64) (*model.UserUsageResponse, error) {
+	deleted := &model.UserUsageResponse{UserID: userID}
+
+	var unavailable []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, svc := range c.services {
+		wg.Add(1)
+		go func(svc service) {
+			defer wg.Done()
+
+			var counts model.UserUsageResponse
+			err := c.do(ctx, http.MethodDelete, svc, userPath(userID, "data"), maxResponseSize, func(body io.Reader) error {
+				return json.NewDecoder(body).Decode(&counts)
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("userdata: deleting data of user %d in %s: %v", userID, svc.name, err)
+				unavailable = append(unavailable, svc.name)
+				return
+			}
+			deleted.Merge(&counts)
+		}(svc)
+	}
+	wg.Wait()
+
+	if len(unavailable) > 0 {
+		err := unavailableError(unavailable)
+		deleted.Unavailable = unavailable
+		return deleted, err
+	}
+	return deleted, nil
+}
+
+func (c *Client) get(ctx context.Context, svc service, path string, v any) error {
+	return c.do(ctx, http.MethodGet, svc, path, maxResponseSize, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(v)
+	})
+}
+
+// export reads an export as is, so it is not decoded and encoded again.
+func (c *Client) export(ctx context.Context, svc service, path string, limit int64) (json.RawMessage, error) {
+	var data []byte
+	err := c.do(ctx, http.MethodGet, svc, path, limit+1, func(body io.Reader) error {
+		var err error
+		data, err = io.ReadAll(body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: %d MiB from %s", ErrTooLarge, limit>>20, svc.name)
+	}
+	if !json.Valid(data) {
+		return nil, errors.New("export is not valid JSON")
+	}
+	return data, nil
+}
+
+// do sends a request and passes the body of a 200 response, read up to
+// limit bytes, to read.
+func (c *Client) do(ctx context.Context, method string, svc service, path string, limit int64, read func(io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, method, svc.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", svc.name, resp.StatusCode)
+	}
+
+	return read(io.LimitReader(resp.Body, limit))
+}
+
+func unavailableError(services []string) error {
+	sort.Strings(services)
+	return fmt.Errorf("%w: %s", ErrUnavailable, strings.Join(services, ", "))
+}
@@ -0,0 +1,7 @@
+-- Drop view and trigger first
+DROP VIEW IF EXISTS active_workspace_members;
+DROP TRIGGER IF EXISTS update_workspaces_updated_at ON workspaces;
+
+-- Drop tables (workspace_members first due to foreign key)
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
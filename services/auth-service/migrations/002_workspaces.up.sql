@@ -0,0 +1,34 @@
+-- Workspaces let a team share projects, sites and platforms. Other services
+-- read workspace_members to authorize access, so apply this migration before
+-- theirs.
+CREATE TABLE IF NOT EXISTS workspaces (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create workspace_members table
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id BIGINT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+-- Membership is looked up by user on every authorized request
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members(user_id);
+
+-- Trigger to auto-update updated_at
+CREATE TRIGGER update_workspaces_updated_at
+    BEFORE UPDATE ON workspaces
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Memberships that grant access. Other services authorize against this view
+-- instead of workspace_members, so later migrations can narrow it.
+CREATE OR REPLACE VIEW active_workspace_members AS
+SELECT workspace_id, user_id, role, created_at
+FROM workspace_members;
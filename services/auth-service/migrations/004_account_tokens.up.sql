@@ -0,0 +1,18 @@
+-- Email verification and password reset. Tokens are single-use and stored
+-- hashed like refresh tokens.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Create account_tokens table
+CREATE TABLE IF NOT EXISTS account_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+    token_hash VARCHAR(255) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes for account_tokens
+CREATE INDEX IF NOT EXISTS idx_account_tokens_user_id ON account_tokens(user_id, purpose);
+CREATE INDEX IF NOT EXISTS idx_account_tokens_expires_at ON account_tokens(expires_at);
@@ -0,0 +1,16 @@
+-- Restore the view from 002_workspaces before dropping what it depends on
+CREATE OR REPLACE VIEW active_workspace_members AS
+SELECT workspace_id, user_id, role, created_at
+FROM workspace_members;
+
+ALTER TABLE workspaces DROP COLUMN IF EXISTS require_mfa;
+
+DELETE FROM account_tokens WHERE purpose = 'mfa_login';
+ALTER TABLE account_tokens DROP COLUMN IF EXISTS attempts;
+ALTER TABLE account_tokens DROP CONSTRAINT IF EXISTS account_tokens_purpose_check;
+ALTER TABLE account_tokens ADD CONSTRAINT account_tokens_purpose_check
+    CHECK (purpose IN ('verify_email', 'reset_password'));
+
+-- Drop tables
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,41 @@
+-- TOTP two-factor authentication with recovery codes, and workspaces that
+-- require it from their members.
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    totp_secret TEXT NOT NULL,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create mfa_recovery_codes table
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(255) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+
+-- Login challenges between the password and the second factor
+ALTER TABLE account_tokens DROP CONSTRAINT IF EXISTS account_tokens_purpose_check;
+ALTER TABLE account_tokens ADD CONSTRAINT account_tokens_purpose_check
+    CHECK (purpose IN ('verify_email', 'reset_password', 'mfa_login'));
+ALTER TABLE account_tokens ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+
+ALTER TABLE workspaces ADD COLUMN IF NOT EXISTS require_mfa BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Memberships that grant access. Members of a workspace that requires MFA
+-- keep their membership but lose access until they enable it. Services
+-- authorize against this view instead of workspace_members.
+CREATE OR REPLACE VIEW active_workspace_members AS
+SELECT m.workspace_id, m.user_id, m.role, m.created_at
+FROM workspace_members m
+JOIN workspaces w ON w.id = m.workspace_id
+WHERE NOT w.require_mfa
+   OR EXISTS (
+       SELECT 1 FROM user_mfa f
+       WHERE f.user_id = m.user_id AND f.enabled_at IS NOT NULL
+   );
@@ -0,0 +1,11 @@
+-- Drop security events
+DROP TABLE IF EXISTS security_events;
+
+-- Drop token families
+DROP INDEX IF EXISTS idx_refresh_tokens_session_id;
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS rotated_at,
+    DROP COLUMN IF EXISTS parent_id,
+    DROP COLUMN IF EXISTS session_id;
+
+DROP TABLE IF EXISTS sessions;
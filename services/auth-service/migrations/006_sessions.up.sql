@@ -0,0 +1,37 @@
+-- A session is a family of refresh tokens: login starts it and every
+-- refresh rotates its token. Rotated tokens are kept so that replaying one
+-- is detected and revokes the whole family.
+CREATE TABLE IF NOT EXISTS sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- Tokens issued before sessions existed have no family; their users sign
+-- in again
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+    ADD COLUMN session_id BIGINT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    ADD COLUMN parent_id BIGINT REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    ADD COLUMN rotated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+
+-- Security-relevant events of an account, such as a replayed refresh token
+CREATE TABLE IF NOT EXISTS security_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_user_id ON security_events(user_id, created_at DESC);
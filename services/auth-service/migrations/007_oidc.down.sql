@@ -0,0 +1,3 @@
+-- Drop tables
+DROP TABLE IF EXISTS oidc_states;
+DROP TABLE IF EXISTS user_identities;
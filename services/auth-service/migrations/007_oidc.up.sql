@@ -0,0 +1,27 @@
+-- Sign-in with external OpenID Connect providers. An identity links a
+-- provider account to a user; users created through a provider have an
+-- empty password hash until they set a password with a reset link.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    last_login_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- Pending authorization requests, looked up by the hashed state parameter
+CREATE TABLE IF NOT EXISTS oidc_states (
+    state_hash VARCHAR(255) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    nonce VARCHAR(255) NOT NULL,
+    code_verifier VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_states_expires_at ON oidc_states(expires_at);
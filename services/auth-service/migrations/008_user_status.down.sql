@@ -0,0 +1,3 @@
+-- Drop user status
+DROP INDEX IF EXISTS idx_users_created_at;
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
@@ -0,0 +1,6 @@
+-- Admins can disable users. A disabled user cannot sign in or refresh
+-- tokens; NULL means the user is active.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE;
+
+-- The admin user list shows the newest users first
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at DESC, id DESC);
@@ -0,0 +1,10 @@
+-- Restore the view from 005_mfa
+CREATE OR REPLACE VIEW active_workspace_members AS
+SELECT m.workspace_id, m.user_id, m.role, m.created_at
+FROM workspace_members m
+JOIN workspaces w ON w.id = m.workspace_id
+WHERE NOT w.require_mfa
+   OR EXISTS (
+       SELECT 1 FROM user_mfa f
+       WHERE f.user_id = m.user_id AND f.enabled_at IS NOT NULL
+   );
@@ -0,0 +1,13 @@
+-- Disabled users keep their workspace memberships but lose the access they
+-- grant, like members without MFA in workspaces that require it.
+CREATE OR REPLACE VIEW active_workspace_members AS
+SELECT m.workspace_id, m.user_id, m.role, m.created_at
+FROM workspace_members m
+JOIN workspaces w ON w.id = m.workspace_id
+JOIN users u ON u.id = m.user_id
+WHERE u.disabled_at IS NULL
+  AND (NOT w.require_mfa
+   OR EXISTS (
+       SELECT 1 FROM user_mfa f
+       WHERE f.user_id = m.user_id AND f.enabled_at IS NOT NULL
+   ));
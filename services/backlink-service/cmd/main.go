@@ -16,12 +16,19 @@ import (
 	"github.com/link-tracker/backlink-service/internal/handler"
 	"github.com/link-tracker/backlink-service/internal/repository"
 	"github.com/link-tracker/backlink-service/internal/service"
+	"github.com/link-tracker/shared/pkg/apikey"
+	"github.com/link-tracker/shared/pkg/jwks"
 	"github.com/link-tracker/shared/pkg/middleware"
 )
 
 func main() {
 	cfg := config.Load()
 
+	// Tokens signed with a public secret could be forged by anyone
+	if err := middleware.CheckLegacySecret(cfg.JWT.LegacySecret); err != nil {
+		log.Fatalf("Invalid JWT configuration: %v", err)
+	}
+
 	// Database connection
 	dbPool, err := pgxpool.New(context.Background(), cfg.Database.DSN())
 	if err != nil {
@@ -38,19 +45,56 @@ func main() {
 	// Initialize repositories
 	projectRepo := repository.NewProjectRepository(dbPool)
 	backlinkRepo := repository.NewBacklinkRepository(dbPool)
+	discoveryRepo := repository.NewDiscoveryRepository(dbPool)
+	importRepo := repository.NewImportRepository(dbPool)
+
+	// Discovery runs do not survive a restart
+	if n, err := discoveryRepo.FailInterrupted(context.Background()); err != nil {
+		log.Printf("Failed to reset interrupted discovery runs: %v", err)
+	} else if n > 0 {
+		log.Printf("Marked %d interrupted discovery runs as failed", n)
+	}
+
+	// Compute canonical URLs and keys of links and discovered links that
+	// lack them
+	go func() {
+		n, err := service.BackfillCanonical(context.Background(), backlinkRepo, projectRepo, nil)
+		if err != nil {
+			log.Printf("Canonical URL backfill failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Canonical URL backfill: %d links processed", n)
+		}
+
+		n, err = service.BackfillDiscoveredCanonical(context.Background(), discoveryRepo, projectRepo)
+		if err != nil {
+			log.Printf("Discovered link canonical URL backfill failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Discovered link canonical URL backfill: %d links processed", n)
+		}
+	}()
 
 	// Initialize services
-	projectService := service.NewProjectService(projectRepo)
+	projectService := service.NewProjectService(projectRepo, backlinkRepo)
 	backlinkService := service.NewBacklinkService(backlinkRepo, projectRepo)
+	discoveryService := service.NewDiscoveryService(discoveryRepo, backlinkRepo, projectRepo, cfg.Discovery)
+	importService := service.NewImportService(importRepo, backlinkRepo, projectRepo)
+	accountService := service.NewAccountService(projectRepo, backlinkRepo, discoveryRepo)
 
 	// Initialize handlers
 	healthHandler := handler.NewHealthHandler()
 	projectHandler := handler.NewProjectHandler(projectService)
 	backlinkHandler := handler.NewBacklinkHandler(backlinkService)
+	discoveryHandler := handler.NewDiscoveryHandler(discoveryService)
+	importHandler := handler.NewImportHandler(importService)
+	adminHandler := handler.NewAdminHandler(projectService)
+	accountHandler := handler.NewAccountHandler(accountService)
 
 	// JWT middleware config
 	jwtMiddleware := middleware.JWTAuth(middleware.JWTConfig{
-		Secret: cfg.JWT.Secret,
+		Keys:         jwks.NewCache(cfg.JWT.JWKSURL),
+		LegacySecret: cfg.JWT.LegacySecret,
+		APIKeys:      apikey.NewStore(dbPool),
+		Resource:     apikey.ResourceBacklinks,
 	})
 
 	// Setup router
@@ -79,6 +123,17 @@ func main() {
 			r.Get("/{id}", projectHandler.Get)
 			r.Put("/{id}", projectHandler.Update)
 			r.Delete("/{id}", projectHandler.Delete)
+			r.Get("/{id}/stats", projectHandler.Stats)
+			r.Get("/{id}/members", projectHandler.Members)
+			r.Post("/{id}/members", projectHandler.Share)
+			r.Delete("/{id}/members/{userID}", projectHandler.Unshare)
+			r.Get("/{id}/spend", backlinkHandler.SpendReport)
+			r.Get("/{id}/lost-paid-links", backlinkHandler.LostPaidLinks)
+			r.Get("/{id}/duplicates", backlinkHandler.Duplicates)
+			r.Post("/{id}/discovery", discoveryHandler.Start)
+			r.Get("/{id}/discovery/{runID}", discoveryHandler.GetRun)
+			r.Get("/{id}/discovered", discoveryHandler.ListDiscovered)
+			r.Post("/{id}/discovered/{linkID}/adopt", discoveryHandler.Adopt)
 		})
 
 		// Backlinks
@@ -88,12 +143,29 @@ func main() {
 			r.Post("/bulk", backlinkHandler.BulkCreate)
 			r.Delete("/bulk", backlinkHandler.BulkDelete)
 			r.Post("/import", backlinkHandler.Import)
+			r.Post("/import/preview", importHandler.Preview)
+			r.Post("/import/{importID}/commit", importHandler.Commit)
 			r.Get("/{id}", backlinkHandler.Get)
 			r.Put("/{id}", backlinkHandler.Update)
 			r.Delete("/{id}", backlinkHandler.Delete)
+			r.Post("/{id}/check", backlinkHandler.Check)
 		})
 	})
 
+	// Internal routes, called by auth-service for the account export and
+	// deletion and the admin usage. They take the user from the path and
+	// have no authentication, so they are served on a port that is neither
+	// published nor proxied.
+	internal := chi.NewRouter()
+	internal.Use(chimiddleware.Logger)
+	internal.Use(chimiddleware.Recoverer)
+	internal.Use(chimiddleware.RequestID)
+	internal.Route("/internal/v1/users/{id}", func(r chi.Router) {
+		r.Get("/usage", adminHandler.Usage)
+		r.Get("/export", accountHandler.Export)
+		r.Delete("/data", accountHandler.DeleteData)
+	})
+
 	// Server setup
 	server := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
@@ -101,6 +173,12 @@ func main() {
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 	}
+	internalServer := &http.Server{
+		Addr:         ":" + cfg.Server.InternalPort,
+		Handler:      internal,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
 
 	// Graceful shutdown
 	go func() {
@@ -109,6 +187,12 @@ func main() {
 			log.Fatalf("Server failed: %v", err)
 		}
 	}()
+	go func() {
+		log.Printf("Starting internal server on port %s", cfg.Server.InternalPort)
+		if err := internalServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Internal server failed: %v", err)
+		}
+	}()
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -120,6 +204,9 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if err := internalServer.Shutdown(ctx); err != nil {
+		log.Printf("Internal server forced to shutdown: %v", err)
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
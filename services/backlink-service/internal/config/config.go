@@ -7,13 +7,18 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	Discovery DiscoveryConfig
 }
 
+// ServerConfig holds the public port and InternalPort, which serves the
+// routes auth-service calls for user data. InternalPort must not be
+// published or proxied.
 type ServerConfig struct {
 	Port         string
+	InternalPort string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 }
@@ -27,14 +32,31 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+// JWTConfig points at the key set auth-service publishes. LegacySecret
+// accepts HS256 tokens from before asymmetric signing and should be empty
+// once they have expired.
 type JWTConfig struct {
-	Secret string
+	JWKSURL      string
+	LegacySecret string
+}
+
+// DiscoveryConfig limits crawls of donor domains looking for untracked links.
+type DiscoveryConfig struct {
+	MaxDepth     int
+	MaxPages     int // per donor domain
+	MaxDomains   int // per run
+	RequestDelay time.Duration
+	RunTimeout   time.Duration
+	Workers      int // runs crawled at the same time
+	QueueSize    int // runs waiting for a worker
+	MaxUserRuns  int // queued and running runs per user
 }
 
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
 			Port:         getEnv("SERVER_PORT", "8081"),
+			InternalPort: getEnv("INTERNAL_PORT", "9082"),
 			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 10*time.Second),
 			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 10*time.Second),
 		},
@@ -47,7 +69,18 @@ func Load() *Config {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
+			JWKSURL:      getEnv("JWKS_URL", "http://localhost:8080/.well-known/jwks.json"),
+			LegacySecret: getEnv("JWT_SECRET", ""),
+		},
+		Discovery: DiscoveryConfig{
+			MaxDepth:     getIntEnv("DISCOVERY_MAX_DEPTH", 2),
+			MaxPages:     getIntEnv("DISCOVERY_MAX_PAGES", 200),
+			MaxDomains:   getIntEnv("DISCOVERY_MAX_DOMAINS", 50),
+			RequestDelay: getDurationEnv("DISCOVERY_REQUEST_DELAY", 500*time.Millisecond),
+			RunTimeout:   getDurationEnv("DISCOVERY_RUN_TIMEOUT", time.Hour),
+			Workers:      getIntEnv("DISCOVERY_WORKERS", 4),
+			QueueSize:    getIntEnv("DISCOVERY_QUEUE_SIZE", 100),
+			MaxUserRuns:  getIntEnv("DISCOVERY_MAX_USER_RUNS", 2),
 		},
 	}
 }
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/link-tracker/backlink-service/internal/service"
+	"github.com/link-tracker/shared/pkg/response"
+)
+
+// AccountHandler serves the account export and deletion auth-service
+// aggregates. Routes are only served on the internal port.
+type AccountHandler struct {
+	accountService *service.AccountService
+}
+
+func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
+	return &AccountHandler{accountService: accountService}
+}
+
+// Export handles GET /internal/v1/users/{id}/export
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := accountUserID(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.accountService.Export(r.Context(), userID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "failed to export data", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, export)
+}
+
+// DeleteData handles DELETE /internal/v1/users/{id}/data
+func (h *AccountHandler) DeleteData(w http.ResponseWriter, r *http.Request) {
+	userID, ok := accountUserID(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.accountService.DeleteData(r.Context(), userID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "failed to delete data", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, deleted)
+}
+
+// accountUserID returns the user in the path. It writes the error response
+// when the id is invalid.
+func accountUserID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid user id", "INVALID_ID")
+		return 0, false
+	}
+	return userID, true
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -14,6 +15,9 @@ import (
 	"github.com/link-tracker/shared/pkg/response"
 )
 
+// maxBulkBacklinks limits the items of a bulk create request.
+const maxBulkBacklinks = 1000
+
 type BacklinkHandler struct {
 	backlinkService *service.BacklinkService
 }
@@ -55,6 +59,65 @@ func (h *BacklinkHandler) List(w http.ResponseWriter, r *http.Request) {
 	if v := r.URL.Query().Get("target_url"); v != "" {
 		filters.TargetURL = &v
 	}
+	if v := r.URL.Query().Get("vendor"); v != "" {
+		filters.Vendor = &v
+	}
+	if v := r.URL.Query().Get("donor_indexable"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			filters.DonorIndexable = &b
+		}
+	}
+	if v := r.URL.Query().Get("donor_canonical_ok"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			filters.DonorCanonicalOK = &b
+		}
+	}
+	if v := r.URL.Query().Get("donor_robots_allowed"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			filters.DonorRobotsAllowed = &b
+		}
+	}
+	if v := r.URL.Query().Get("donor_in_sitemap"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			filters.DonorInSitemap = &b
+		}
+	}
+	if v := r.URL.Query().Get("max_outbound_links"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			filters.MaxOutboundLinks = &n
+		}
+	}
+	if v := r.URL.Query().Get("placement_region"); v != "" {
+		region := model.Region(v)
+		filters.PlacementRegion = &region
+	}
+	if v := r.URL.Query().Get("placement_hidden"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			filters.PlacementHidden = &b
+		}
+	}
+	if v := r.URL.Query().Get("anchor_match"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			filters.AnchorMatch = &b
+		}
+	}
+	if v := r.URL.Query().Get("created_from"); v != "" {
+		filters.CreatedFrom = &v
+	}
+	if v := r.URL.Query().Get("created_to"); v != "" {
+		filters.CreatedTo = &v
+	}
+	if v := r.URL.Query().Get("checked_from"); v != "" {
+		filters.CheckedFrom = &v
+	}
+	if v := r.URL.Query().Get("checked_to"); v != "" {
+		filters.CheckedTo = &v
+	}
+	if v := r.URL.Query().Get("q"); v != "" {
+		filters.Search = &v
+	}
+	filters.SortBy = model.BacklinkSort(r.URL.Query().Get("sort_by"))
+	filters.Order = model.SortOrder(r.URL.Query().Get("order"))
 	if v := r.URL.Query().Get("page"); v != "" {
 		if page, err := strconv.Atoi(v); err == nil && page > 0 {
 			filters.Page = page
@@ -66,14 +129,21 @@ func (h *BacklinkHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Any cursor parameter, even an empty one for the first page, switches
+	// to cursor pagination
+	if r.URL.Query().Has("cursor") {
+		h.listCursor(w, r, userID, filters)
+		return
+	}
+
 	backlinks, total, err := h.backlinkService.List(r.Context(), userID, filters)
 	if err != nil {
 		if errors.Is(err, service.ErrUnauthorized) {
 			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
 			return
 		}
-		if errors.Is(err, service.ErrProjectRequired) {
-			response.Error(w, http.StatusBadRequest, "project_id query parameter is required", "VALIDATION_ERROR")
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
 			return
 		}
 		response.Error(w, http.StatusInternalServerError, "failed to list backlinks", "INTERNAL_ERROR")
@@ -88,6 +158,50 @@ func (h *BacklinkHandler) List(w http.ResponseWriter, r *http.Request) {
 	response.Paginated(w, data, filters.Page, filters.PerPage, total)
 }
 
+func (h *BacklinkHandler) listCursor(w http.ResponseWriter, r *http.Request, userID int64, filters *model.BacklinkFilters) {
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		var after model.BacklinkCursor
+		if err := response.DecodeCursor(v, &after); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		filters.After = &after
+	}
+
+	page, err := h.backlinkService.ListCursor(r.Context(), userID, filters, model.TotalMode(r.URL.Query().Get("total")))
+	if err != nil {
+		if errors.Is(err, response.ErrInvalidCursor) {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list backlinks", "INTERNAL_ERROR")
+		return
+	}
+
+	var next string
+	if page.Next != nil {
+		if next, err = response.EncodeCursor(page.Next); err != nil {
+			response.Error(w, http.StatusInternalServerError, "failed to list backlinks", "INTERNAL_ERROR")
+			return
+		}
+	}
+
+	data := make([]model.BacklinkResponse, len(page.Backlinks))
+	for i, b := range page.Backlinks {
+		data[i] = model.BacklinkToResponse(b)
+	}
+
+	response.CursorPaginated(w, data, filters.PerPage, next, page.Total, page.TotalEstimated)
+}
+
 // Create handles POST /api/v1/backlinks
 func (h *BacklinkHandler) Create(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
@@ -107,16 +221,28 @@ func (h *BacklinkHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	backlink, err := h.backlinkService.Create(r.Context(), userID, &req)
+	backlink, created, err := h.backlinkService.Create(r.Context(), userID, &req)
 	if err != nil {
 		if errors.Is(err, service.ErrUnauthorized) {
 			response.Error(w, http.StatusForbidden, "access denied to project", "FORBIDDEN")
 			return
 		}
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
+		if errors.Is(err, repository.ErrDuplicateBacklink) {
+			response.Error(w, http.StatusConflict, err.Error(), "CONFLICT")
+			return
+		}
 		response.Error(w, http.StatusInternalServerError, "failed to create backlink", "INTERNAL_ERROR")
 		return
 	}
 
+	if !created {
+		response.JSON(w, http.StatusOK, model.BacklinkToResponse(backlink))
+		return
+	}
 	response.Created(w, model.BacklinkToResponse(backlink))
 }
 
@@ -181,6 +307,14 @@ func (h *BacklinkHandler) Update(w http.ResponseWriter, r *http.Request) {
 			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
 			return
 		}
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
+		if errors.Is(err, repository.ErrDuplicateBacklink) {
+			response.Error(w, http.StatusConflict, err.Error(), "CONFLICT")
+			return
+		}
 		response.Error(w, http.StatusInternalServerError, "failed to update backlink", "INTERNAL_ERROR")
 		return
 	}
@@ -219,6 +353,236 @@ func (h *BacklinkHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	response.NoContent(w)
 }
 
+// Check handles POST /api/v1/backlinks/:id/check
+func (h *BacklinkHandler) Check(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid backlink id", "INVALID_ID")
+		return
+	}
+
+	backlink, err := h.backlinkService.Check(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrBacklinkNotFound) {
+			response.Error(w, http.StatusNotFound, "backlink not found", "NOT_FOUND")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to check backlink", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, model.BacklinkToResponse(backlink))
+}
+
+// SpendReport handles GET /api/v1/projects/:id/spend
+func (h *BacklinkHandler) SpendReport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+
+	filters := &model.SpendFilters{}
+	if v := r.URL.Query().Get("from"); v != "" {
+		filters.From = &v
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		filters.To = &v
+	}
+
+	report, err := h.backlinkService.SpendReport(r.Context(), userID, projectID, filters)
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to build spend report", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, report)
+}
+
+// LostPaidLinks handles GET /api/v1/projects/:id/lost-paid-links
+func (h *BacklinkHandler) LostPaidLinks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+
+	page, perPage := 1, 20
+	if v := r.URL.Query().Get("page"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if v := r.URL.Query().Get("per_page"); v != "" {
+		if pp, err := strconv.Atoi(v); err == nil && pp > 0 && pp <= 100 {
+			perPage = pp
+		}
+	}
+
+	// Any cursor parameter, even an empty one for the first page, switches
+	// to cursor pagination
+	if r.URL.Query().Has("cursor") {
+		h.lostPaidLinksCursor(w, r, userID, projectID, perPage)
+		return
+	}
+
+	data, total, err := h.backlinkService.LostPaidLinks(r.Context(), userID, projectID, page, perPage)
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list lost paid links", "INTERNAL_ERROR")
+		return
+	}
+
+	response.Paginated(w, data, page, perPage, total)
+}
+
+func (h *BacklinkHandler) lostPaidLinksCursor(w http.ResponseWriter, r *http.Request, userID, projectID int64, perPage int) {
+	var after *model.TimeCursor
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		after = &model.TimeCursor{}
+		if err := response.DecodeCursor(v, after); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+	}
+
+	data, next, err := h.backlinkService.LostPaidLinksCursor(r.Context(), userID, projectID, after, perPage)
+	if err != nil {
+		if errors.Is(err, response.ErrInvalidCursor) {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list lost paid links", "INTERNAL_ERROR")
+		return
+	}
+
+	var nextCursor string
+	if next != nil {
+		if nextCursor, err = response.EncodeCursor(next); err != nil {
+			response.Error(w, http.StatusInternalServerError, "failed to list lost paid links", "INTERNAL_ERROR")
+			return
+		}
+	}
+	response.CursorPaginated(w, data, perPage, nextCursor, nil, false)
+}
+
+// Duplicates handles GET /api/v1/projects/:id/duplicates
+func (h *BacklinkHandler) Duplicates(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+
+	page, perPage := 1, 20
+	if v := r.URL.Query().Get("page"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if v := r.URL.Query().Get("per_page"); v != "" {
+		if pp, err := strconv.Atoi(v); err == nil && pp > 0 && pp <= 100 {
+			perPage = pp
+		}
+	}
+
+	// Any cursor parameter, even an empty one for the first page, switches
+	// to cursor pagination
+	if r.URL.Query().Has("cursor") {
+		h.duplicatesCursor(w, r, userID, projectID, perPage)
+		return
+	}
+
+	groups, total, err := h.backlinkService.Duplicates(r.Context(), userID, projectID, page, perPage)
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list duplicates", "INTERNAL_ERROR")
+		return
+	}
+
+	response.Paginated(w, groups, page, perPage, total)
+}
+
+func (h *BacklinkHandler) duplicatesCursor(w http.ResponseWriter, r *http.Request, userID, projectID int64, perPage int) {
+	var after *model.DuplicateCursor
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		after = &model.DuplicateCursor{}
+		if err := response.DecodeCursor(v, after); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+	}
+
+	groups, next, err := h.backlinkService.DuplicatesCursor(r.Context(), userID, projectID, after, perPage)
+	if err != nil {
+		if errors.Is(err, response.ErrInvalidCursor) {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list duplicates", "INTERNAL_ERROR")
+		return
+	}
+
+	var nextCursor string
+	if next != nil {
+		if nextCursor, err = response.EncodeCursor(next); err != nil {
+			response.Error(w, http.StatusInternalServerError, "failed to list duplicates", "INTERNAL_ERROR")
+			return
+		}
+	}
+	response.CursorPaginated(w, groups, perPage, nextCursor, nil, false)
+}
+
 // BulkCreate handles POST /api/v1/backlinks/bulk
 func (h *BacklinkHandler) BulkCreate(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
@@ -238,8 +602,8 @@ func (h *BacklinkHandler) BulkCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.Backlinks) > 100 {
-		response.Error(w, http.StatusBadRequest, "maximum 100 backlinks per request", "VALIDATION_ERROR")
+	if len(req.Backlinks) > maxBulkBacklinks {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("maximum %d backlinks per request", maxBulkBacklinks), "VALIDATION_ERROR")
 		return
 	}
 
@@ -249,6 +613,10 @@ func (h *BacklinkHandler) BulkCreate(w http.ResponseWriter, r *http.Request) {
 			response.Error(w, http.StatusForbidden, "access denied to one or more projects", "FORBIDDEN")
 			return
 		}
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
 		response.Error(w, http.StatusInternalServerError, "failed to create backlinks", "INTERNAL_ERROR")
 		return
 	}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/backlink-service/internal/repository"
+	"github.com/link-tracker/backlink-service/internal/service"
+	"github.com/link-tracker/shared/pkg/middleware"
+	"github.com/link-tracker/shared/pkg/response"
+)
+
+type DiscoveryHandler struct {
+	discoveryService *service.DiscoveryService
+}
+
+func NewDiscoveryHandler(discoveryService *service.DiscoveryService) *DiscoveryHandler {
+	return &DiscoveryHandler{discoveryService: discoveryService}
+}
+
+// Start handles POST /api/v1/projects/:id/discovery
+func (h *DiscoveryHandler) Start(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+
+	var req model.StartDiscoveryRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+			return
+		}
+	}
+
+	run, err := h.discoveryService.Start(r.Context(), userID, projectID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		if errors.Is(err, service.ErrNoTargetDomains) || errors.Is(err, service.ErrNoDonorDomains) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
+		if errors.Is(err, service.ErrTooManyDiscoveries) {
+			response.Error(w, http.StatusTooManyRequests, err.Error(), "TOO_MANY_RUNS")
+			return
+		}
+		if errors.Is(err, service.ErrDiscoveryQueueFull) {
+			response.Error(w, http.StatusServiceUnavailable, err.Error(), "QUEUE_FULL")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to start discovery", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusAccepted, run)
+}
+
+// GetRun handles GET /api/v1/projects/:id/discovery/:runID
+func (h *DiscoveryHandler) GetRun(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+	runID, err := strconv.ParseInt(chi.URLParam(r, "runID"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid run id", "INVALID_ID")
+		return
+	}
+
+	run, err := h.discoveryService.GetRun(r.Context(), userID, projectID, runID)
+	if err != nil {
+		if errors.Is(err, repository.ErrDiscoveryRunNotFound) {
+			response.Error(w, http.StatusNotFound, "discovery run not found", "NOT_FOUND")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to get discovery run", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, run)
+}
+
+// ListDiscovered handles GET /api/v1/projects/:id/discovered
+func (h *DiscoveryHandler) ListDiscovered(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+
+	page, perPage := 1, 20
+	if v := r.URL.Query().Get("page"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if v := r.URL.Query().Get("per_page"); v != "" {
+		if pp, err := strconv.Atoi(v); err == nil && pp > 0 && pp <= 100 {
+			perPage = pp
+		}
+	}
+
+	// Any cursor parameter, even an empty one for the first page, switches
+	// to cursor pagination
+	if r.URL.Query().Has("cursor") {
+		h.listUntrackedCursor(w, r, userID, projectID, perPage)
+		return
+	}
+
+	links, total, err := h.discoveryService.ListUntracked(r.Context(), userID, projectID, page, perPage)
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list discovered links", "INTERNAL_ERROR")
+		return
+	}
+
+	if links == nil {
+		links = []*model.DiscoveredLink{}
+	}
+	response.Paginated(w, links, page, perPage, total)
+}
+
+func (h *DiscoveryHandler) listUntrackedCursor(w http.ResponseWriter, r *http.Request, userID, projectID int64, perPage int) {
+	var after *model.TimeCursor
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		after = &model.TimeCursor{}
+		if err := response.DecodeCursor(v, after); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+	}
+
+	links, next, err := h.discoveryService.ListUntrackedCursor(r.Context(), userID, projectID, after, perPage)
+	if err != nil {
+		if errors.Is(err, response.ErrInvalidCursor) {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list discovered links", "INTERNAL_ERROR")
+		return
+	}
+
+	var nextCursor string
+	if next != nil {
+		if nextCursor, err = response.EncodeCursor(next); err != nil {
+			response.Error(w, http.StatusInternalServerError, "failed to list discovered links", "INTERNAL_ERROR")
+			return
+		}
+	}
+	if links == nil {
+		links = []*model.DiscoveredLink{}
+	}
+	response.CursorPaginated(w, links, perPage, nextCursor, nil, false)
+}
+
+// Adopt handles POST /api/v1/projects/:id/discovered/:linkID/adopt
+func (h *DiscoveryHandler) Adopt(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+	linkID, err := strconv.ParseInt(chi.URLParam(r, "linkID"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid discovered link id", "INVALID_ID")
+		return
+	}
+
+	backlink, err := h.discoveryService.Adopt(r.Context(), userID, projectID, linkID)
+	if err != nil {
+		if errors.Is(err, repository.ErrDiscoveredLinkNotFound) {
+			response.Error(w, http.StatusNotFound, "discovered link not found", "NOT_FOUND")
+			return
+		}
+		if errors.Is(err, repository.ErrDiscoveredLinkAdopted) {
+			response.Error(w, http.StatusConflict, "discovered link already adopted", "CONFLICT")
+			return
+		}
+		if errors.Is(err, repository.ErrDuplicateBacklink) {
+			response.Error(w, http.StatusConflict, err.Error(), "CONFLICT")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to adopt discovered link", "INTERNAL_ERROR")
+		return
+	}
+
+	response.Created(w, model.BacklinkToResponse(backlink))
+}
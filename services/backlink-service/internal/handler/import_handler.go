@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/backlink-service/internal/repository"
+	"github.com/link-tracker/backlink-service/internal/service"
+	"github.com/link-tracker/shared/pkg/middleware"
+	"github.com/link-tracker/shared/pkg/response"
+)
+
+// maxImportFileSize limits uploaded export files.
+const maxImportFileSize = 10 << 20
+
+type ImportHandler struct {
+	importService *service.ImportService
+}
+
+func NewImportHandler(importService *service.ImportService) *ImportHandler {
+	return &ImportHandler{importService: importService}
+}
+
+// Preview handles POST /api/v1/backlinks/import/preview
+//
+// Expects multipart/form-data with "file", "project_id" and optionally
+// "format" and "default_target_url".
+func (h *ImportHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid multipart form or file larger than 10MB", "INVALID_REQUEST")
+		return
+	}
+
+	projectID, err := strconv.ParseInt(r.FormValue("project_id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "project_id is required", "VALIDATION_ERROR")
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "file is required", "VALIDATION_ERROR")
+		return
+	}
+	defer file.Close()
+
+	preview, err := h.importService.Preview(r.Context(), userID, projectID, r.FormValue("format"), r.FormValue("default_target_url"), file)
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied to project", "FORBIDDEN")
+			return
+		}
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to parse import", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, preview)
+}
+
+// Commit handles POST /api/v1/backlinks/import/:importID/commit
+func (h *ImportHandler) Commit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	importID, err := strconv.ParseInt(chi.URLParam(r, "importID"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid import id", "INVALID_ID")
+		return
+	}
+
+	// The body is optional; an empty one uses the default conflict mode.
+	var req model.CommitImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	result, err := h.importService.Commit(r.Context(), userID, importID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
+		if errors.Is(err, repository.ErrImportNotFound) {
+			response.Error(w, http.StatusNotFound, "import not found", "NOT_FOUND")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		if errors.Is(err, repository.ErrImportCommitted) {
+			response.Error(w, http.StatusConflict, "import already committed", "CONFLICT")
+			return
+		}
+		if errors.Is(err, service.ErrImportExpired) {
+			response.Error(w, http.StatusGone, err.Error(), "EXPIRED")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to commit import", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
@@ -30,6 +30,13 @@ func (h *ProjectHandler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Any cursor parameter, even an empty one for the first page, switches
+	// to cursor pagination
+	if r.URL.Query().Has("cursor") {
+		h.listCursor(w, r, userID)
+		return
+	}
+
 	projects, err := h.projectService.List(r.Context(), userID)
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, "failed to list projects", "INTERNAL_ERROR")
@@ -44,6 +51,47 @@ func (h *ProjectHandler) List(w http.ResponseWriter, r *http.Request) {
 	response.JSON(w, http.StatusOK, data)
 }
 
+func (h *ProjectHandler) listCursor(w http.ResponseWriter, r *http.Request, userID int64) {
+	var after *model.TimeCursor
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		after = &model.TimeCursor{}
+		if err := response.DecodeCursor(v, after); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+	}
+	perPage := 20
+	if v := r.URL.Query().Get("per_page"); v != "" {
+		if pp, err := strconv.Atoi(v); err == nil && pp > 0 && pp <= 100 {
+			perPage = pp
+		}
+	}
+
+	projects, next, err := h.projectService.ListCursor(r.Context(), userID, after, perPage)
+	if err != nil {
+		if errors.Is(err, response.ErrInvalidCursor) {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list projects", "INTERNAL_ERROR")
+		return
+	}
+
+	var nextCursor string
+	if next != nil {
+		if nextCursor, err = response.EncodeCursor(next); err != nil {
+			response.Error(w, http.StatusInternalServerError, "failed to list projects", "INTERNAL_ERROR")
+			return
+		}
+	}
+
+	data := make([]model.ProjectResponse, len(projects))
+	for i, p := range projects {
+		data[i] = model.ProjectToResponse(p)
+	}
+	response.CursorPaginated(w, data, perPage, nextCursor, nil, false)
+}
+
 // Create handles POST /api/v1/projects
 func (h *ProjectHandler) Create(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
@@ -65,6 +113,14 @@ func (h *ProjectHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	project, err := h.projectService.Create(r.Context(), userID, &req)
 	if err != nil {
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "workspace access denied", "FORBIDDEN")
+			return
+		}
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
 		response.Error(w, http.StatusInternalServerError, "failed to create project", "INTERNAL_ERROR")
 		return
 	}
@@ -133,6 +189,10 @@ func (h *ProjectHandler) Update(w http.ResponseWriter, r *http.Request) {
 			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
 			return
 		}
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
 		response.Error(w, http.StatusInternalServerError, "failed to update project", "INTERNAL_ERROR")
 		return
 	}
@@ -170,3 +230,162 @@ func (h *ProjectHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	response.NoContent(w)
 }
+
+// Stats handles GET /api/v1/projects/:id/stats
+func (h *ProjectHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+
+	weeks, _ := strconv.Atoi(r.URL.Query().Get("weeks"))
+
+	stats, err := h.projectService.Stats(r.Context(), userID, id, weeks)
+	if err != nil {
+		if errors.Is(err, repository.ErrProjectNotFound) {
+			response.Error(w, http.StatusNotFound, "project not found", "NOT_FOUND")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to build project stats", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, stats)
+}
+
+// Members handles GET /api/v1/projects/:id/members
+func (h *ProjectHandler) Members(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+
+	members, err := h.projectService.Members(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrProjectNotFound) {
+			response.Error(w, http.StatusNotFound, "project not found", "NOT_FOUND")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list project members", "INTERNAL_ERROR")
+		return
+	}
+
+	data := make([]model.ProjectMemberResponse, len(members))
+	for i, m := range members {
+		data[i] = model.ProjectMemberToResponse(m)
+	}
+
+	response.JSON(w, http.StatusOK, data)
+}
+
+// Share handles POST /api/v1/projects/:id/members
+func (h *ProjectHandler) Share(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+
+	var req model.ShareProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
+		return
+	}
+
+	if req.Email == "" {
+		response.Error(w, http.StatusBadRequest, "email is required", "VALIDATION_ERROR")
+		return
+	}
+
+	member, err := h.projectService.Share(r.Context(), userID, id, &req)
+	if err != nil {
+		if errors.Is(err, repository.ErrProjectNotFound) {
+			response.Error(w, http.StatusNotFound, "project not found", "NOT_FOUND")
+			return
+		}
+		if errors.Is(err, repository.ErrUserNotFound) {
+			response.Error(w, http.StatusNotFound, "no user with this email", "USER_NOT_FOUND")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		if errors.Is(err, service.ErrValidation) {
+			response.Error(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to share project", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, model.ProjectMemberToResponse(member))
+}
+
+// Unshare handles DELETE /api/v1/projects/:id/members/:userID
+func (h *ProjectHandler) Unshare(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid project id", "INVALID_ID")
+		return
+	}
+	memberID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid user id", "INVALID_ID")
+		return
+	}
+
+	err = h.projectService.Unshare(r.Context(), userID, id, memberID)
+	if err != nil {
+		if errors.Is(err, repository.ErrProjectNotFound) {
+			response.Error(w, http.StatusNotFound, "project not found", "NOT_FOUND")
+			return
+		}
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			response.Error(w, http.StatusNotFound, "project is not shared with this user", "NOT_FOUND")
+			return
+		}
+		if errors.Is(err, service.ErrUnauthorized) {
+			response.Error(w, http.StatusForbidden, "access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to unshare project", "INTERNAL_ERROR")
+		return
+	}
+
+	response.NoContent(w)
+}
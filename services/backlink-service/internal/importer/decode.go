@@ -0,0 +1,46 @@
+package importer
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// decode converts an export file to UTF-8. Ahrefs writes UTF-16 with a BOM,
+// older Yandex.Webmaster and Excel-saved files are often Windows-1251, the
+// rest are UTF-8 with or without a BOM.
+func decode(data []byte) ([]byte, error) {
+	var enc encoding.Encoding
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return data[3:], nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		enc = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case utf8.Valid(data):
+		return data, nil
+	default:
+		enc = charmap.Windows1251
+	}
+	return enc.NewDecoder().Bytes(data)
+}
+
+// sniffDelimiter picks the separator that occurs most often in the header.
+func sniffDelimiter(data []byte) rune {
+	header := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		header = data[:i]
+	}
+
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', ';', '\t'} {
+		if n := bytes.Count(header, []byte(string(d))); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
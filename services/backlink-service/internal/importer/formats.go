@@ -0,0 +1,109 @@
+package importer
+
+import "strings"
+
+type Format string
+
+const (
+	FormatAhrefs   Format = "ahrefs"
+	FormatSemrush  Format = "semrush"
+	FormatMajestic Format = "majestic"
+	FormatGSC      Format = "gsc"
+	FormatYandex   Format = "yandex"
+)
+
+// columns lists the header names a vendor uses for each field, lowercased.
+// Flag columns hold TRUE/FALSE-like values; linkType holds a free-form link
+// type such as "nofollow".
+type columns struct {
+	source    []string
+	target    []string
+	anchor    []string
+	nofollow  []string
+	sponsored []string
+	ugc       []string
+	linkType  []string
+}
+
+var formats = map[Format]columns{
+	FormatAhrefs: {
+		source:    []string{"referring page url", "referring page"},
+		target:    []string{"target url", "link url"},
+		anchor:    []string{"anchor", "anchor text"},
+		nofollow:  []string{"nofollow"},
+		sponsored: []string{"sponsored"},
+		ugc:       []string{"ugc"},
+		linkType:  []string{"link type", "type"},
+	},
+	FormatSemrush: {
+		source:    []string{"source url"},
+		target:    []string{"target url"},
+		anchor:    []string{"anchor"},
+		nofollow:  []string{"nofollow"},
+		sponsored: []string{"sponsored"},
+		ugc:       []string{"ugc"},
+	},
+	FormatMajestic: {
+		source:   []string{"sourceurl", "source url"},
+		target:   []string{"targeturl", "target url"},
+		anchor:   []string{"anchortext", "anchor text"},
+		nofollow: []string{"flagnofollow", "flag no follow"},
+		linkType: []string{"linktype", "link type"},
+	},
+	FormatGSC: {
+		source: []string{"linking page", "ссылающаяся страница"},
+		target: []string{"target page", "целевая страница"},
+	},
+	FormatYandex: {
+		source: []string{"source_url", "url источника", "source url"},
+		target: []string{"destination_url", "url назначения", "destination url"},
+		anchor: []string{"anchor", "текст ссылки"},
+	},
+}
+
+// detectOrder matters: several vendors share "source url"/"target url", so the
+// more distinctive layouts are tried first.
+var detectOrder = []Format{FormatAhrefs, FormatMajestic, FormatYandex, FormatGSC, FormatSemrush}
+
+// Formats returns the supported export formats.
+func Formats() []Format {
+	return []Format{FormatAhrefs, FormatSemrush, FormatMajestic, FormatGSC, FormatYandex}
+}
+
+func ParseFormat(s string) (Format, bool) {
+	f := Format(strings.ToLower(strings.TrimSpace(s)))
+	_, ok := formats[f]
+	return f, ok
+}
+
+// detect picks the first format whose source column is present and, for
+// formats sharing column names, whose distinctive columns are present too.
+func detect(header map[string]int) (Format, bool) {
+	for _, f := range detectOrder {
+		cols := formats[f]
+		if findColumn(header, cols.source) < 0 {
+			continue
+		}
+		switch f {
+		case FormatMajestic:
+			if findColumn(header, cols.nofollow) < 0 && findColumn(header, []string{"sourceurl"}) < 0 {
+				continue
+			}
+		case FormatYandex:
+			if findColumn(header, cols.target) < 0 {
+				continue
+			}
+		}
+		return f, true
+	}
+	return "", false
+}
+
+func findColumn(header map[string]int, names []string) int {
+	for _, n := range names {
+		if i, ok := header[n]; ok {
+			return i
+		}
+	}
+	return -1
+}
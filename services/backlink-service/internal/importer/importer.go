@@ -0,0 +1,186 @@
+// Package importer parses backlink exports of third-party SEO tools into
+// rows that map onto CreateBacklinkRequest.
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+)
+
+// MaxRows limits the number of data rows accepted from one file.
+const MaxRows = 10000
+
+var (
+	ErrUnknownFormat = errors.New("unrecognized export format")
+	ErrEmptyFile     = errors.New("file has no data rows")
+	ErrTooManyRows   = fmt.Errorf("file has more than %d rows", MaxRows)
+)
+
+// Row is one link read from an export. Line is the 1-based line in the file,
+// Error is set when the row cannot be imported.
+type Row struct {
+	Line       int            `json:"line"`
+	SourceURL  string         `json:"source_url"`
+	TargetURL  string         `json:"target_url"`
+	AnchorText string         `json:"anchor_text"`
+	LinkType   model.LinkType `json:"link_type"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Parse reads an export. With an empty format the vendor is detected from
+// the header. defaultTarget fills the target for exports that lack one, such
+// as the Search Console "latest links" report.
+func Parse(r io.Reader, format Format, defaultTarget string) ([]Row, Format, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := decode(raw)
+	if err != nil {
+		return nil, "", err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = sniffDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	headerRecord, err := reader.Read()
+	if err == io.EOF {
+		return nil, "", ErrEmptyFile
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	header := make(map[string]int, len(headerRecord))
+	for i, h := range headerRecord {
+		header[strings.ToLower(strings.Trim(strings.TrimSpace(h), "\""))] = i
+	}
+
+	if format == "" {
+		var ok bool
+		if format, ok = detect(header); !ok {
+			return nil, "", ErrUnknownFormat
+		}
+	}
+	cols, ok := formats[format]
+	if !ok {
+		return nil, "", ErrUnknownFormat
+	}
+
+	idx := struct{ source, target, anchor, nofollow, sponsored, ugc, linkType int }{
+		source:    findColumn(header, cols.source),
+		target:    findColumn(header, cols.target),
+		anchor:    findColumn(header, cols.anchor),
+		nofollow:  findColumn(header, cols.nofollow),
+		sponsored: findColumn(header, cols.sponsored),
+		ugc:       findColumn(header, cols.ugc),
+		linkType:  findColumn(header, cols.linkType),
+	}
+	if idx.source < 0 {
+		return nil, "", fmt.Errorf("%w: no source URL column for %s", ErrUnknownFormat, format)
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, "", err
+			}
+			rows = append(rows, Row{Line: parseErr.Line, Error: "malformed CSV row"})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+		if len(rows) >= MaxRows {
+			return nil, "", ErrTooManyRows
+		}
+
+		row := Row{
+			Line:       line,
+			SourceURL:  field(record, idx.source),
+			TargetURL:  field(record, idx.target),
+			AnchorText: field(record, idx.anchor),
+			LinkType:   linkType(record, idx.nofollow, idx.sponsored, idx.ugc, idx.linkType),
+		}
+		if row.TargetURL == "" {
+			row.TargetURL = defaultTarget
+		}
+		row.Error = validate(&row)
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, "", ErrEmptyFile
+	}
+	return rows, format, nil
+}
+
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func isBlank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func flag(record []string, i int) bool {
+	switch strings.ToLower(field(record, i)) {
+	case "true", "yes", "1", "+", "y":
+		return true
+	}
+	return false
+}
+
+func linkType(record []string, nofollow, sponsored, ugc, typeCol int) model.LinkType {
+	t := strings.ToLower(field(record, typeCol))
+	switch {
+	case flag(record, sponsored) || strings.Contains(t, "sponsored"):
+		return model.LinkTypeSponsored
+	case flag(record, ugc) || strings.Contains(t, "ugc"):
+		return model.LinkTypeUGC
+	case flag(record, nofollow) || strings.Contains(t, "nofollow"):
+		return model.LinkTypeNoFollow
+	}
+	return model.LinkTypeDoFollow
+}
+
+func validate(row *Row) string {
+	if !isHTTPURL(row.SourceURL) {
+		return "invalid source URL"
+	}
+	if row.TargetURL == "" {
+		return "target URL is missing"
+	}
+	if !isHTTPURL(row.TargetURL) {
+		return "invalid target URL"
+	}
+	return ""
+}
+
+func isHTTPURL(s string) bool {
+	lower := strings.ToLower(s)
+	return (strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")) && len(s) > len("https://")
+}
@@ -0,0 +1,221 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestParseDetectsFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format Format
+		want   Row
+	}{
+		{
+			name:   "ahrefs",
+			data:   "Referring page URL,Target URL,Anchor,Nofollow,Sponsored,UGC\nhttps://donor.com/a,https://site.com/,Site,false,true,false\n",
+			format: FormatAhrefs,
+			want:   Row{Line: 2, SourceURL: "https://donor.com/a", TargetURL: "https://site.com/", AnchorText: "Site", LinkType: model.LinkTypeSponsored},
+		},
+		{
+			name:   "semrush",
+			data:   "Source url;Target url;Anchor;Nofollow\nhttps://donor.com/b;https://site.com/x;x;true\n",
+			format: FormatSemrush,
+			want:   Row{Line: 2, SourceURL: "https://donor.com/b", TargetURL: "https://site.com/x", AnchorText: "x", LinkType: model.LinkTypeNoFollow},
+		},
+		{
+			name:   "majestic",
+			data:   "SourceURL,TargetURL,AnchorText,FlagNoFollow\nhttps://donor.com/c,https://site.com/,anchor,-\n",
+			format: FormatMajestic,
+			want:   Row{Line: 2, SourceURL: "https://donor.com/c", TargetURL: "https://site.com/", AnchorText: "anchor", LinkType: model.LinkTypeDoFollow},
+		},
+		{
+			name:   "yandex",
+			data:   "\"URL источника\"\t\"URL назначения\"\t\"Текст ссылки\"\nhttps://донор.рф/\thttps://site.com/\tссылка\n",
+			format: FormatYandex,
+			want:   Row{Line: 2, SourceURL: "https://донор.рф/", TargetURL: "https://site.com/", AnchorText: "ссылка", LinkType: model.LinkTypeDoFollow},
+		},
+		{
+			name:   "search console",
+			data:   "Linking page,Target page\nhttps://donor.com/d,https://site.com/e\n",
+			format: FormatGSC,
+			want:   Row{Line: 2, SourceURL: "https://donor.com/d", TargetURL: "https://site.com/e", LinkType: model.LinkTypeDoFollow},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, format, err := Parse(strings.NewReader(tt.data), "", "")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if format != tt.format {
+				t.Errorf("Parse() format = %q, want %q", format, tt.format)
+			}
+			if len(rows) != 1 || rows[0] != tt.want {
+				t.Errorf("Parse() rows = %+v, want [%+v]", rows, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLinkType(t *testing.T) {
+	tests := []struct {
+		linkType string
+		want     model.LinkType
+	}{
+		{"", model.LinkTypeDoFollow},
+		{"dofollow", model.LinkTypeDoFollow},
+		{"nofollow", model.LinkTypeNoFollow},
+		{`"NoFollow, UGC"`, model.LinkTypeUGC},
+		{"sponsored nofollow", model.LinkTypeSponsored},
+	}
+
+	for _, tt := range tests {
+		data := "Referring page URL,Target URL,Link type\nhttps://donor.com/,https://site.com/," + tt.linkType + "\n"
+		rows, _, err := Parse(strings.NewReader(data), FormatAhrefs, "")
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.linkType, err)
+		}
+		if got := rows[0].LinkType; got != tt.want {
+			t.Errorf("Parse(%q) link type = %q, want %q", tt.linkType, got, tt.want)
+		}
+	}
+}
+
+func TestParseRowErrors(t *testing.T) {
+	data := strings.Join([]string{
+		"Linking page,Target page",
+		"https://donor.com/ok,https://site.com/",
+		"",
+		"donor.com/relative,https://site.com/",
+		"https://donor.com/no-target,",
+		"https://donor.com/bad-target,site.com",
+	}, "\n")
+
+	rows, _, err := Parse(strings.NewReader(data), FormatGSC, "")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []struct {
+		line int
+		err  string
+	}{
+		{2, ""},
+		{4, "invalid source URL"},
+		{5, "target URL is missing"},
+		{6, "invalid target URL"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Parse() returned %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i, w := range want {
+		if rows[i].Line != w.line || rows[i].Error != w.err {
+			t.Errorf("row %d = line %d error %q, want line %d error %q", i, rows[i].Line, rows[i].Error, w.line, w.err)
+		}
+	}
+}
+
+func TestParseDefaultTarget(t *testing.T) {
+	data := "Linking page\nhttps://donor.com/\n"
+	rows, _, err := Parse(strings.NewReader(data), FormatGSC, "https://site.com/")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if rows[0].TargetURL != "https://site.com/" || rows[0].Error != "" {
+		t.Errorf("Parse() row = %+v, want the default target", rows[0])
+	}
+}
+
+func TestParseEncodings(t *testing.T) {
+	const text = "Source url;Target url;Anchor\nhttps://donor.com/;https://site.com/;Купить окна\n"
+
+	utf16, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp1251, err := charmap.Windows1251.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"utf-8", []byte(text)},
+		{"utf-8 with BOM", append([]byte{0xEF, 0xBB, 0xBF}, text...)},
+		{"utf-16 with BOM", utf16},
+		{"windows-1251", cp1251},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, format, err := Parse(bytes.NewReader(tt.data), "", "")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if format != FormatSemrush {
+				t.Errorf("Parse() format = %q, want %q", format, FormatSemrush)
+			}
+			if len(rows) != 1 || rows[0].AnchorText != "Купить окна" {
+				t.Errorf("Parse() rows = %+v, want anchor %q", rows, "Купить окна")
+			}
+		})
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	tooMany := "Linking page,Target page\n" + strings.Repeat("https://donor.com/,https://site.com/\n", MaxRows+1)
+
+	tests := []struct {
+		name   string
+		data   string
+		format Format
+		want   error
+	}{
+		{"empty", "", "", ErrEmptyFile},
+		{"header only", "Linking page,Target page\n", "", ErrEmptyFile},
+		{"blank rows only", "Linking page,Target page\n,\n\n", "", ErrEmptyFile},
+		{"unknown header", "foo,bar\n1,2\n", "", ErrUnknownFormat},
+		{"source column missing", "Target URL\nhttps://site.com/\n", FormatAhrefs, ErrUnknownFormat},
+		{"too many rows", tooMany, "", ErrTooManyRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Parse(strings.NewReader(tt.data), tt.format, ""); !errors.Is(err, tt.want) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Format
+		ok   bool
+	}{
+		{"ahrefs", FormatAhrefs, true},
+		{" GSC ", FormatGSC, true},
+		{"Yandex", FormatYandex, true},
+		{"moz", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseFormat(tt.s)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
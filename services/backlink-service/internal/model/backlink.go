@@ -1,6 +1,11 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/urlcanon"
+)
 
 type LinkStatus string
 
@@ -21,23 +26,159 @@ const (
 	LinkTypeUGC       LinkType = "ugc"
 )
 
+// IsValid reports whether t is a known link type.
+func (t LinkType) IsValid() bool {
+	switch t {
+	case LinkTypeDoFollow, LinkTypeNoFollow, LinkTypeSponsored, LinkTypeUGC:
+		return true
+	}
+	return false
+}
+
+// ConflictMode decides what creating a link does when the project already
+// tracks a link with the same canonical source and target URLs.
+type ConflictMode string
+
+const (
+	ConflictError  ConflictMode = "error"  // reject the new link
+	ConflictSkip   ConflictMode = "skip"   // keep the existing link as is
+	ConflictUpdate ConflictMode = "update" // apply the new fields to the existing link
+)
+
+// IsValid reports whether m is a known mode. Empty means ConflictError.
+func (m ConflictMode) IsValid() bool {
+	switch m {
+	case "", ConflictError, ConflictSkip, ConflictUpdate:
+		return true
+	}
+	return false
+}
+
 type Project struct {
-	ID            int64     `json:"id"`
-	Name          string    `json:"name"`
-	UserID        int64     `json:"user_id"`
-	GoogleSheetID *string   `json:"google_sheet_id,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID             int64           `json:"id"`
+	Name           string          `json:"name"`
+	UserID         int64           `json:"user_id"`
+	GoogleSheetID  *string         `json:"google_sheet_id,omitempty"`
+	URLRules       *urlcanon.Rules `json:"url_rules,omitempty"` // nil uses urlcanon.DefaultRules
+	AnchorSettings *AnchorSettings `json:"anchor_settings,omitempty"`
+	WorkspaceID    *int64          `json:"workspace_id,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+
+	// Role is the role of the user the project was loaded for. Projects in
+	// a workspace belong to it; UserID is then only their creator.
+	Role models.MemberRole `json:"-"`
+}
+
+// ProjectMember is a user a project is shared with directly.
+type ProjectMember struct {
+	ProjectID int64             `json:"project_id"`
+	UserID    int64             `json:"user_id"`
+	Email     string            `json:"email"`
+	Name      string            `json:"name"`
+	Role      models.MemberRole `json:"role"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Rules returns the URL equivalence rules of the project.
+func (p *Project) Rules() urlcanon.Rules {
+	if p.URLRules == nil {
+		return urlcanon.DefaultRules()
+	}
+	return *p.URLRules
+}
+
+// Anchors returns the anchor distribution settings of the project with
+// default thresholds filled in.
+func (p *Project) Anchors() AnchorSettings {
+	var settings AnchorSettings
+	if p.AnchorSettings != nil {
+		settings = *p.AnchorSettings
+	}
+	settings.Thresholds = settings.Thresholds.WithDefaults()
+	return settings
 }
 
 type Backlink struct {
-	ID            int64      `json:"id"`
-	ProjectID     int64      `json:"project_id"`
-	SourceURL     string     `json:"source_url"`
-	TargetURL     string     `json:"target_url"`
-	AnchorText    string     `json:"anchor_text"`
-	Status        LinkStatus `json:"status"`
-	LinkType      LinkType   `json:"link_type"`
-	HTTPStatus    *int       `json:"http_status,omitempty"`
-	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
+	ID            int64       `json:"id"`
+	ProjectID     int64       `json:"project_id"`
+	SourceURL     string      `json:"source_url"`
+	TargetURL     string      `json:"target_url"`
+	AnchorText    string      `json:"anchor_text"`
+	Status        LinkStatus  `json:"status"`
+	LinkType      LinkType    `json:"link_type"`
+	HTTPStatus    *int        `json:"http_status,omitempty"`
+	LastCheckedAt *time.Time  `json:"last_checked_at,omitempty"`
+	DonorAudit    *DonorAudit `json:"donor_audit,omitempty"`
+	Placement     *Placement  `json:"placement,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+
+	// Canonical URLs under the project's URL rules, used for matching,
+	// deduplication and exact URL filters.
+	SourceURLCanonical string `json:"-"`
+	TargetURLCanonical string `json:"-"`
+
+	// SourceDomain is the source host without "www.", used for sorting.
+	SourceDomain string `json:"-"`
+
+	// LinkKey is unique within a project. Links that duplicated another one
+	// before uniqueness was enforced have no key.
+	LinkKey string `json:"-"`
+
+	// Commercial terms for purchased links
+	Vendor    *string    `json:"vendor,omitempty"`
+	Price     *float64   `json:"price,omitempty"`
+	Currency  *string    `json:"currency,omitempty"`
+	PlacedAt  *time.Time `json:"placed_at,omitempty"`
+	PaidUntil *time.Time `json:"paid_until,omitempty"`
+	LostAt    *time.Time `json:"lost_at,omitempty"`
+}
+
+// IsLost reports whether the status means the link no longer works.
+func (s LinkStatus) IsLost() bool {
+	return s == LinkStatusRemoved || s == LinkStatusBroken
+}
+
+// DonorAudit describes how much the source page is worth as a link donor.
+// Robots and sitemap fields are nil when robots.txt or the sitemap could not
+// be fetched.
+type DonorAudit struct {
+	Indexable       bool      `json:"indexable"`
+	HasNoindex      bool      `json:"has_noindex"`
+	CanonicalURL    *string   `json:"canonical_url,omitempty"`
+	CanonicalOK     bool      `json:"canonical_ok"`
+	RobotsGooglebot *bool     `json:"robots_googlebot_allowed"`
+	RobotsYandex    *bool     `json:"robots_yandex_allowed"`
+	OutboundLinks   int       `json:"outbound_links"`
+	InSitemap       *bool     `json:"in_sitemap"`
+	AuditedAt       time.Time `json:"audited_at"`
+}
+
+// Region is the part of the donor page a link sits in.
+type Region string
+
+const (
+	RegionContent    Region = "content"
+	RegionHeader     Region = "header"
+	RegionFooter     Region = "footer"
+	RegionNavigation Region = "navigation"
+	RegionSidebar    Region = "sidebar"
+	RegionComments   Region = "comments"
+)
+
+// Placement describes where the tracked link was found on the donor page.
+// It is nil when the link was not found by the last check.
+type Placement struct {
+	Region          Region  `json:"region"`
+	Context         string  `json:"context"`          // sentence around the link
+	Position        int     `json:"position"`         // 1-based among the page's links
+	TotalLinks      int     `json:"total_links"`      // links on the page
+	PositionPercent int     `json:"position_percent"` // share of page text before the link
+	IsImage         bool    `json:"is_image"`
+	ImageAlt        *string `json:"image_alt,omitempty"`
+	Hidden          bool    `json:"hidden"`
+	HiddenBy        *string `json:"hidden_by,omitempty"` // e.g. "style display:none"
+	Anchor          string  `json:"anchor"`              // anchor text found on the page
+	// AnchorMatch compares Anchor with the expected anchor text, ignoring
+	// case, punctuation and ё/е. Nil when no anchor text is expected.
+	AnchorMatch *bool `json:"anchor_match,omitempty"`
 }
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+type DiscoveryStatus string
+
+const (
+	DiscoveryStatusRunning DiscoveryStatus = "running"
+	DiscoveryStatusDone    DiscoveryStatus = "done"
+	DiscoveryStatusFailed  DiscoveryStatus = "failed"
+)
+
+// DiscoveryRun is one crawl of donor domains looking for links to the
+// project's target domains.
+type DiscoveryRun struct {
+	ID            int64           `json:"id"`
+	ProjectID     int64           `json:"project_id"`
+	Status        DiscoveryStatus `json:"status"`
+	TargetDomains []string        `json:"target_domains"`
+	DonorDomains  []string        `json:"donor_domains"`
+	MaxDepth      int             `json:"max_depth"`
+	MaxPages      int             `json:"max_pages"`
+	PagesCrawled  int             `json:"pages_crawled"`
+	LinksFound    int             `json:"links_found"`
+	Error         *string         `json:"error,omitempty"`
+	StartedAt     time.Time       `json:"started_at"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+}
+
+// DiscoveredLink is a link to a target domain found by a crawl. It stays
+// untracked until adopted into the project as a Backlink.
+type DiscoveredLink struct {
+	ID                int64     `json:"id"`
+	ProjectID         int64     `json:"project_id"`
+	RunID             int64     `json:"run_id"`
+	SourceURL         string    `json:"source_url"`
+	TargetURL         string    `json:"target_url"`
+	AnchorText        string    `json:"anchor_text"`
+	LinkType          LinkType  `json:"link_type"`
+	AdoptedBacklinkID *int64    `json:"adopted_backlink_id,omitempty"`
+	DiscoveredAt      time.Time `json:"discovered_at"`
+
+	SourceURLCanonical string `json:"-"`
+	TargetURLCanonical string `json:"-"`
+}
+
+// StartDiscoveryRequest overrides the domains and limits of a run. Empty
+// target domains default to the hosts of the project's target URLs, empty
+// donor domains to the hosts of its source URLs.
+type StartDiscoveryRequest struct {
+	TargetDomains []string `json:"target_domains,omitempty"`
+	DonorDomains  []string `json:"donor_domains,omitempty"`
+	MaxDepth      *int     `json:"max_depth,omitempty"`
+	MaxPages      *int     `json:"max_pages,omitempty"`
+}
@@ -1,13 +1,28 @@
 package model
 
+import (
+	"time"
+
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/urlcanon"
+)
+
 // Request DTOs
 
 type CreateBacklinkRequest struct {
-	ProjectID  int64      `json:"project_id"`
-	SourceURL  string     `json:"source_url"`
-	TargetURL  string     `json:"target_url"`
-	AnchorText string     `json:"anchor_text"`
-	LinkType   LinkType   `json:"link_type"`
+	ProjectID  int64    `json:"project_id"`
+	SourceURL  string   `json:"source_url"`
+	TargetURL  string   `json:"target_url"`
+	AnchorText string   `json:"anchor_text"`
+	LinkType   LinkType `json:"link_type"`
+
+	Vendor    *string  `json:"vendor,omitempty"`
+	Price     *float64 `json:"price,omitempty"`
+	Currency  *string  `json:"currency,omitempty"`
+	PlacedAt  *string  `json:"placed_at,omitempty"`  // YYYY-MM-DD
+	PaidUntil *string  `json:"paid_until,omitempty"` // YYYY-MM-DD
+
+	OnConflict ConflictMode `json:"on_conflict,omitempty"`
 }
 
 type UpdateBacklinkRequest struct {
@@ -16,10 +31,18 @@ type UpdateBacklinkRequest struct {
 	AnchorText *string     `json:"anchor_text,omitempty"`
 	Status     *LinkStatus `json:"status,omitempty"`
 	LinkType   *LinkType   `json:"link_type,omitempty"`
+
+	Vendor    *string  `json:"vendor,omitempty"`
+	Price     *float64 `json:"price,omitempty"`
+	Currency  *string  `json:"currency,omitempty"`
+	PlacedAt  *string  `json:"placed_at,omitempty"`
+	PaidUntil *string  `json:"paid_until,omitempty"`
 }
 
 type BulkCreateBacklinksRequest struct {
-	Backlinks []CreateBacklinkRequest `json:"backlinks"`
+	Backlinks  []CreateBacklinkRequest `json:"backlinks"`
+	OnConflict ConflictMode            `json:"on_conflict,omitempty"` // per-item on_conflict is ignored
+	Atomic     bool                    `json:"atomic,omitempty"`      // all or nothing instead of best effort
 }
 
 type BulkDeleteBacklinksRequest struct {
@@ -33,54 +56,247 @@ type ImportFromSheetsRequest struct {
 }
 
 type CreateProjectRequest struct {
-	Name          string  `json:"name"`
-	GoogleSheetID *string `json:"google_sheet_id,omitempty"`
+	Name           string          `json:"name"`
+	GoogleSheetID  *string         `json:"google_sheet_id,omitempty"`
+	URLRules       *urlcanon.Rules `json:"url_rules,omitempty"`
+	AnchorSettings *AnchorSettings `json:"anchor_settings,omitempty"`
+	WorkspaceID    *int64          `json:"workspace_id,omitempty"`
 }
 
 type UpdateProjectRequest struct {
-	Name          *string `json:"name,omitempty"`
-	GoogleSheetID *string `json:"google_sheet_id,omitempty"`
+	Name           *string         `json:"name,omitempty"`
+	GoogleSheetID  *string         `json:"google_sheet_id,omitempty"`
+	URLRules       *urlcanon.Rules `json:"url_rules,omitempty"`
+	AnchorSettings *AnchorSettings `json:"anchor_settings,omitempty"`
+	WorkspaceID    *int64          `json:"workspace_id,omitempty"` // 0 moves the project out of its workspace
+}
+
+// ShareProjectRequest shares a project with a registered user.
+type ShareProjectRequest struct {
+	Email string            `json:"email"`
+	Role  models.MemberRole `json:"role"` // editor or viewer
 }
 
 // Query parameters
 
 type BacklinkFilters struct {
-	ProjectID  *int64      `json:"project_id,omitempty"`
-	Status     *LinkStatus `json:"status,omitempty"`
-	LinkType   *LinkType   `json:"link_type,omitempty"`
-	SourceURL  *string     `json:"source_url,omitempty"`
-	TargetURL  *string     `json:"target_url,omitempty"`
-	Page       int         `json:"page"`
-	PerPage    int         `json:"per_page"`
+	ProjectID *int64      `json:"project_id,omitempty"`
+	Status    *LinkStatus `json:"status,omitempty"`
+	LinkType  *LinkType   `json:"link_type,omitempty"`
+	SourceURL *string     `json:"source_url,omitempty"`
+	TargetURL *string     `json:"target_url,omitempty"`
+	Vendor    *string     `json:"vendor,omitempty"`
+	Page      int         `json:"page"`
+	PerPage   int         `json:"per_page"`
+
+	// Donor audit filters
+	DonorIndexable     *bool `json:"donor_indexable,omitempty"`
+	DonorCanonicalOK   *bool `json:"donor_canonical_ok,omitempty"`
+	DonorRobotsAllowed *bool `json:"donor_robots_allowed,omitempty"`
+	DonorInSitemap     *bool `json:"donor_in_sitemap,omitempty"`
+	MaxOutboundLinks   *int  `json:"max_outbound_links,omitempty"`
+
+	// Placement filters
+	PlacementRegion *Region `json:"placement_region,omitempty"`
+	PlacementHidden *bool   `json:"placement_hidden,omitempty"`
+	AnchorMatch     *bool   `json:"anchor_match,omitempty"`
+
+	// Date ranges, YYYY-MM-DD in UTC, both ends inclusive
+	CreatedFrom *string `json:"created_from,omitempty"`
+	CreatedTo   *string `json:"created_to,omitempty"`
+	CheckedFrom *string `json:"checked_from,omitempty"`
+	CheckedTo   *string `json:"checked_to,omitempty"`
+
+	// Search matches a substring of the source URL, target URL, anchor text
+	// or vendor.
+	Search *string `json:"q,omitempty"`
+
+	SortBy BacklinkSort `json:"sort_by,omitempty"`
+	Order  SortOrder    `json:"order,omitempty"`
+
+	// Set by the service when no project is given: the list then covers all
+	// projects of the user.
+	UserID *int64 `json:"-"`
+
+	// Set by the service when source_url/target_url is a full URL: the
+	// filter then matches all variants of that URL instead of a substring.
+	// Across projects there is one canonical form per distinct URL rules.
+	SourceURLCanonical []string `json:"-"`
+	TargetURLCanonical []string `json:"-"`
+
+	// Set by the service from the date ranges; the upper bounds are
+	// exclusive.
+	CreatedAfter  *time.Time `json:"-"`
+	CreatedBefore *time.Time `json:"-"`
+	CheckedAfter  *time.Time `json:"-"`
+	CheckedBefore *time.Time `json:"-"`
+
+	// After continues a cursor-paginated list after the given position.
+	After *BacklinkCursor `json:"-"`
+}
+
+// BacklinkCursor is the position after the last link of a page in cursor
+// pagination: the sort the page used, the link's sort value and its id.
+// Value is nil for links sorting last, e.g. never checked ones.
+type BacklinkCursor struct {
+	SortBy BacklinkSort `json:"s"`
+	Order  SortOrder    `json:"o"`
+	Value  *string      `json:"v"`
+	ID     int64        `json:"id"`
+}
+
+// TotalMode says how a cursor-paginated list counts its rows.
+type TotalMode string
+
+const (
+	TotalNone     TotalMode = "none"
+	TotalEstimate TotalMode = "estimate" // from planner statistics
+	TotalExact    TotalMode = "exact"
+)
+
+// BacklinkCursorPage is a page of a cursor-paginated list. Next is nil on
+// the last page.
+type BacklinkCursorPage struct {
+	Backlinks      []*Backlink
+	Next           *BacklinkCursor
+	Total          *int64
+	TotalEstimated bool
+}
+
+// TimeCursor is the position after the last row of a page in lists sorted
+// newest first by a timestamp and then by id: projects by creation, lost
+// paid links by loss and discovered links by discovery.
+type TimeCursor struct {
+	At time.Time `json:"t"`
+	ID int64     `json:"id"`
+}
+
+// DuplicateCursor is the position after the last group of a page of
+// duplicates. Groups are sorted largest first, then by their oldest link.
+type DuplicateCursor struct {
+	Count int   `json:"n"`
+	ID    int64 `json:"id"`
+}
+
+type BacklinkSort string
+
+const (
+	SortCreatedAt     BacklinkSort = "created_at"
+	SortLastCheckedAt BacklinkSort = "last_checked_at"
+	SortStatus        BacklinkSort = "status"
+	SortDomain        BacklinkSort = "domain" // source domain
+)
+
+type SortOrder string
+
+const (
+	OrderAsc  SortOrder = "asc"
+	OrderDesc SortOrder = "desc"
+)
+
+type SpendFilters struct {
+	From *string `json:"from,omitempty"` // YYYY-MM-DD, inclusive
+	To   *string `json:"to,omitempty"`   // YYYY-MM-DD, inclusive
 }
 
 // Response DTOs
 
 type BacklinkResponse struct {
-	ID            int64      `json:"id"`
-	ProjectID     int64      `json:"project_id"`
-	SourceURL     string     `json:"source_url"`
-	TargetURL     string     `json:"target_url"`
-	AnchorText    string     `json:"anchor_text"`
-	Status        LinkStatus `json:"status"`
-	LinkType      LinkType   `json:"link_type"`
-	HTTPStatus    *int       `json:"http_status,omitempty"`
-	LastCheckedAt *string    `json:"last_checked_at,omitempty"`
-	CreatedAt     string     `json:"created_at"`
+	ID            int64       `json:"id"`
+	ProjectID     int64       `json:"project_id"`
+	SourceURL     string      `json:"source_url"`
+	TargetURL     string      `json:"target_url"`
+	AnchorText    string      `json:"anchor_text"`
+	Status        LinkStatus  `json:"status"`
+	LinkType      LinkType    `json:"link_type"`
+	HTTPStatus    *int        `json:"http_status,omitempty"`
+	LastCheckedAt *string     `json:"last_checked_at,omitempty"`
+	DonorAudit    *DonorAudit `json:"donor_audit,omitempty"`
+	Placement     *Placement  `json:"placement,omitempty"`
+	Vendor        *string     `json:"vendor,omitempty"`
+	Price         *float64    `json:"price,omitempty"`
+	Currency      *string     `json:"currency,omitempty"`
+	PlacedAt      *string     `json:"placed_at,omitempty"`
+	PaidUntil     *string     `json:"paid_until,omitempty"`
+	LostAt        *string     `json:"lost_at,omitempty"`
+	CreatedAt     string      `json:"created_at"`
 }
 
 type ProjectResponse struct {
-	ID            int64   `json:"id"`
-	Name          string  `json:"name"`
-	UserID        int64   `json:"user_id"`
-	GoogleSheetID *string `json:"google_sheet_id,omitempty"`
-	CreatedAt     string  `json:"created_at"`
+	ID             int64             `json:"id"`
+	Name           string            `json:"name"`
+	UserID         int64             `json:"user_id"`
+	GoogleSheetID  *string           `json:"google_sheet_id,omitempty"`
+	URLRules       urlcanon.Rules    `json:"url_rules"`
+	AnchorSettings AnchorSettings    `json:"anchor_settings"`
+	WorkspaceID    *int64            `json:"workspace_id,omitempty"`
+	Role           models.MemberRole `json:"role"`
+	CreatedAt      string            `json:"created_at"`
+}
+
+// UserUsageResponse counts what a user created, for admins.
+type UserUsageResponse struct {
+	UserID    int64 `json:"user_id"`
+	Projects  int64 `json:"projects"`
+	Backlinks int64 `json:"backlinks"`
+}
+
+// AccountExport is everything a user added to backlink-service, for the
+// account export.
+type AccountExport struct {
+	UserID     int64            `json:"user_id"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Projects   []*ProjectExport `json:"projects"`
+}
+
+// ProjectExport is a project with its links and discovery runs.
+type ProjectExport struct {
+	*Project
+	Backlinks     []*Backlink     `json:"backlinks"`
+	DiscoveryRuns []*DiscoveryRun `json:"discovery_runs"`
 }
 
+type ProjectMemberResponse struct {
+	UserID    int64             `json:"user_id"`
+	Email     string            `json:"email"`
+	Name      string            `json:"name"`
+	Role      models.MemberRole `json:"role"`
+	CreatedAt string            `json:"created_at"`
+}
+
+// BulkOperationResponse keeps Errors as plain messages for existing
+// clients; ErrorDetails has the same errors with the failed item.
 type BulkOperationResponse struct {
-	Success int   `json:"success"`
-	Failed  int   `json:"failed"`
-	Errors  []string `json:"errors,omitempty"`
+	Success      int         `json:"success"`
+	Failed       int         `json:"failed"`
+	Updated      int         `json:"updated,omitempty"` // included in success
+	Skipped      int         `json:"skipped,omitempty"`
+	Errors       []string    `json:"errors,omitempty"`
+	ErrorDetails []BulkError `json:"error_details,omitempty"`
+}
+
+// AddError records a failed item.
+func (r *BulkOperationResponse) AddError(e BulkError) {
+	r.Errors = append(r.Errors, e.Message)
+	r.ErrorDetails = append(r.ErrorDetails, e)
+}
+
+// BulkError reports a failed item by its position in the request.
+type BulkError struct {
+	Index   int    `json:"index"`
+	Line    int    `json:"line,omitempty"` // file line for imports
+	URL     string `json:"url,omitempty"`
+	Message string `json:"message"`
+}
+
+// DuplicateGroup is a set of links of a project that share canonical source
+// and target URLs.
+type DuplicateGroup struct {
+	SourceURLCanonical string             `json:"source_url_canonical"`
+	TargetURLCanonical string             `json:"target_url_canonical"`
+	Count              int                `json:"count"`
+	PrimaryID          *int64             `json:"primary_id,omitempty"` // link holding the unique key
+	Backlinks          []BacklinkResponse `json:"backlinks"`
 }
 
 func BacklinkToResponse(b *Backlink) BacklinkResponse {
@@ -93,21 +309,52 @@ func BacklinkToResponse(b *Backlink) BacklinkResponse {
 		Status:     b.Status,
 		LinkType:   b.LinkType,
 		HTTPStatus: b.HTTPStatus,
+		DonorAudit: b.DonorAudit,
+		Placement:  b.Placement,
+		Vendor:     b.Vendor,
+		Price:      b.Price,
+		Currency:   b.Currency,
 		CreatedAt:  b.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	}
 	if b.LastCheckedAt != nil {
 		formatted := b.LastCheckedAt.Format("2006-01-02T15:04:05Z")
 		resp.LastCheckedAt = &formatted
 	}
+	if b.PlacedAt != nil {
+		formatted := b.PlacedAt.Format("2006-01-02")
+		resp.PlacedAt = &formatted
+	}
+	if b.PaidUntil != nil {
+		formatted := b.PaidUntil.Format("2006-01-02")
+		resp.PaidUntil = &formatted
+	}
+	if b.LostAt != nil {
+		formatted := b.LostAt.Format("2006-01-02T15:04:05Z")
+		resp.LostAt = &formatted
+	}
 	return resp
 }
 
 func ProjectToResponse(p *Project) ProjectResponse {
 	return ProjectResponse{
-		ID:            p.ID,
-		Name:          p.Name,
-		UserID:        p.UserID,
-		GoogleSheetID: p.GoogleSheetID,
-		CreatedAt:     p.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		ID:             p.ID,
+		Name:           p.Name,
+		UserID:         p.UserID,
+		GoogleSheetID:  p.GoogleSheetID,
+		URLRules:       p.Rules(),
+		AnchorSettings: p.Anchors(),
+		WorkspaceID:    p.WorkspaceID,
+		Role:           p.Role,
+		CreatedAt:      p.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+}
+
+func ProjectMemberToResponse(m *ProjectMember) ProjectMemberResponse {
+	return ProjectMemberResponse{
+		UserID:    m.UserID,
+		Email:     m.Email,
+		Name:      m.Name,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	}
 }
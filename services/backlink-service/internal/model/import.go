@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+type ImportRowStatus string
+
+const (
+	ImportRowNew       ImportRowStatus = "new"
+	ImportRowDuplicate ImportRowStatus = "duplicate"
+	ImportRowInvalid   ImportRowStatus = "invalid"
+)
+
+// ImportRow is one parsed line of an uploaded export.
+type ImportRow struct {
+	Line       int             `json:"line"`
+	SourceURL  string          `json:"source_url"`
+	TargetURL  string          `json:"target_url"`
+	AnchorText string          `json:"anchor_text"`
+	LinkType   LinkType        `json:"link_type"`
+	Status     ImportRowStatus `json:"status"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// BacklinkImport is a parsed export waiting to be committed.
+type BacklinkImport struct {
+	ID          int64
+	ProjectID   int64
+	UserID      int64
+	Format      string
+	Rows        []ImportRow
+	CreatedAt   time.Time
+	CommittedAt *time.Time
+}
+
+// ImportPreview summarizes what committing an import would do. Rows holds
+// at most the first PreviewRows lines.
+type ImportPreview struct {
+	ImportID   int64       `json:"import_id"`
+	ProjectID  int64       `json:"project_id"`
+	Format     string      `json:"format"`
+	TotalRows  int         `json:"total_rows"`
+	NewRows    int         `json:"new_rows"`
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Rows       []ImportRow `json:"rows"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+}
+
+// CommitImportRequest is the optional body of an import commit.
+type CommitImportRequest struct {
+	OnConflict ConflictMode `json:"on_conflict,omitempty"`
+	Atomic     bool         `json:"atomic,omitempty"`
+}
@@ -0,0 +1,38 @@
+package model
+
+// SpendTotal is money spent in one currency.
+type SpendTotal struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+	Links    int     `json:"links"`
+}
+
+type VendorSpend struct {
+	Vendor string `json:"vendor"`
+	SpendTotal
+}
+
+type MonthlySpend struct {
+	Month string `json:"month"` // YYYY-MM
+	SpendTotal
+}
+
+// SpendReport sums link prices of a project by placement date. Amounts in
+// different currencies are never added together.
+type SpendReport struct {
+	ProjectID int64          `json:"project_id"`
+	From      *string        `json:"from,omitempty"`
+	To        *string        `json:"to,omitempty"`
+	Totals    []SpendTotal   `json:"totals"`
+	ByVendor  []VendorSpend  `json:"by_vendor"`
+	ByMonth   []MonthlySpend `json:"by_month"`
+}
+
+// LostPaidLinkResponse is a paid link that disappeared before its paid-until
+// date. RefundEstimate is the price share of the unused term and is only set
+// when the placement date is known.
+type LostPaidLinkResponse struct {
+	BacklinkResponse
+	UnusedDays     int      `json:"unused_days"`
+	RefundEstimate *float64 `json:"refund_estimate,omitempty"`
+}
@@ -0,0 +1,112 @@
+package model
+
+// AnchorCategory groups anchor texts by how they relate to the project's
+// keywords and brand.
+type AnchorCategory string
+
+const (
+	AnchorExact   AnchorCategory = "exact"   // equals a keyword
+	AnchorPartial AnchorCategory = "partial" // contains a keyword
+	AnchorBrand   AnchorCategory = "brand"   // contains a brand name
+	AnchorURL     AnchorCategory = "url"     // a bare URL or domain
+	AnchorGeneric AnchorCategory = "generic" // "click here", "подробнее", empty
+	AnchorOther   AnchorCategory = "other"
+)
+
+// AnchorCategories lists the categories in report order.
+var AnchorCategories = []AnchorCategory{AnchorExact, AnchorPartial, AnchorBrand, AnchorURL, AnchorGeneric, AnchorOther}
+
+// AnchorSettings configure the anchor distribution of a project. The second
+// level label of each target domain always counts as a brand name.
+type AnchorSettings struct {
+	Keywords   []string         `json:"keywords"`
+	Brands     []string         `json:"brands"`
+	Generic    []string         `json:"generic,omitempty"` // in addition to the built-in list
+	Thresholds AnchorThresholds `json:"thresholds"`
+}
+
+// AnchorThresholds are the link shares, in percent, above which the anchor
+// profile looks over-optimized. Zero uses the default.
+type AnchorThresholds struct {
+	ExactMaxPercent        float64 `json:"exact_max_percent"`
+	PartialMaxPercent      float64 `json:"partial_max_percent"`
+	SingleAnchorMaxPercent float64 `json:"single_anchor_max_percent"`
+}
+
+// DefaultAnchorThresholds are used for thresholds a project leaves unset.
+func DefaultAnchorThresholds() AnchorThresholds {
+	return AnchorThresholds{
+		ExactMaxPercent:        10,
+		PartialMaxPercent:      30,
+		SingleAnchorMaxPercent: 20,
+	}
+}
+
+// WithDefaults returns t with unset thresholds taken from the defaults.
+func (t AnchorThresholds) WithDefaults() AnchorThresholds {
+	d := DefaultAnchorThresholds()
+	if t.ExactMaxPercent == 0 {
+		t.ExactMaxPercent = d.ExactMaxPercent
+	}
+	if t.PartialMaxPercent == 0 {
+		t.PartialMaxPercent = d.PartialMaxPercent
+	}
+	if t.SingleAnchorMaxPercent == 0 {
+		t.SingleAnchorMaxPercent = d.SingleAnchorMaxPercent
+	}
+	return t
+}
+
+// ProjectStats summarizes the links of a project.
+type ProjectStats struct {
+	ProjectID              int64              `json:"project_id"`
+	Total                  int                `json:"total"`
+	ByStatus               map[LinkStatus]int `json:"by_status"`
+	ByLinkType             map[LinkType]int   `json:"by_link_type"`
+	ReferringDomains       int                `json:"referring_domains"`
+	ActiveReferringDomains int                `json:"active_referring_domains"` // with an active or nofollow link
+	Weekly                 []WeeklyLinks      `json:"weekly"`
+	Anchors                AnchorDistribution `json:"anchors"`
+}
+
+// WeeklyLinks counts links gained and lost in a week starting on Monday.
+// Links count as gained by placement date, falling back to creation date,
+// and as lost by the date of the loss that still holds.
+type WeeklyLinks struct {
+	Week   string `json:"week"` // YYYY-MM-DD
+	Gained int    `json:"gained"`
+	Lost   int    `json:"lost"`
+}
+
+// AnchorCount is the number of links sharing an anchor text, or an anchor
+// and target domain as loaded from the repository.
+type AnchorCount struct {
+	Anchor       string         `json:"anchor"`
+	TargetDomain string         `json:"-"`
+	Category     AnchorCategory `json:"category"`
+	Links        int            `json:"links"`
+	Percent      float64        `json:"percent"`
+}
+
+type AnchorGroup struct {
+	Category AnchorCategory `json:"category"`
+	Links    int            `json:"links"`
+	Percent  float64        `json:"percent"`
+}
+
+// AnchorWarning reports a share above its over-optimization threshold.
+type AnchorWarning struct {
+	Code      string  `json:"code"`
+	Message   string  `json:"message"`
+	Percent   float64 `json:"percent"`
+	Threshold float64 `json:"threshold"`
+}
+
+// AnchorDistribution covers links that are not lost.
+type AnchorDistribution struct {
+	Total      int              `json:"total"`
+	Groups     []AnchorGroup    `json:"groups"`
+	TopAnchors []AnchorCount    `json:"top_anchors"`
+	Warnings   []AnchorWarning  `json:"warnings"`
+	Thresholds AnchorThresholds `json:"thresholds"`
+}
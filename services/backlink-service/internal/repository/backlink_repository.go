@@ -2,19 +2,75 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/link-tracker/backlink-service/internal/model"
 )
 
 var (
-	ErrBacklinkNotFound = errors.New("backlink not found")
+	ErrBacklinkNotFound  = errors.New("backlink not found")
+	ErrDuplicateBacklink = errors.New("backlink already exists in project")
 )
 
+// BatchError identifies the link whose statement failed in BulkWrite.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// TxHook runs inside a write transaction, before its statements, so that
+// its changes commit or roll back together with them.
+type TxHook func(ctx context.Context, tx pgx.Tx) error
+
+// execer is satisfied by both the pool and a transaction.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// bulkBatchSize is how many statements BulkWrite sends per round trip.
+const bulkBatchSize = 1000
+
+const (
+	insertBacklinkQuery = `
+		INSERT INTO backlinks (project_id, source_url, target_url, anchor_text, status, link_type,
+		                       vendor, price, currency, placed_at, paid_until,
+		                       source_url_canonical, target_url_canonical, link_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	updateBacklinkQuery = `
+		UPDATE backlinks
+		SET source_url = $1, target_url = $2, anchor_text = $3, status = $4, link_type = $5,
+		    vendor = $6, price = $7, currency = $8, placed_at = $9, paid_until = $10, lost_at = $11,
+		    source_url_canonical = $12, target_url_canonical = $13, link_key = $14
+		WHERE id = $15
+	`
+)
+
+const backlinkColumns = `id, project_id, source_url, target_url, anchor_text, status, link_type, http_status, last_checked_at,
+	donor_indexable, donor_has_noindex, donor_canonical_url, donor_canonical_ok, donor_robots_googlebot,
+	donor_robots_yandex, donor_outbound_links, donor_in_sitemap, donor_audited_at, created_at,
+	vendor, price, currency, placed_at, paid_until, lost_at,
+	source_url_canonical, target_url_canonical, link_key,
+	placement_region, placement_context, placement_position, placement_total_links, placement_position_pct,
+	placement_is_image, placement_image_alt, placement_hidden, placement_hidden_by,
+	placement_anchor, placement_anchor_match, source_domain`
+
 type BacklinkRepository struct {
 	db *pgxpool.Pool
 }
@@ -23,46 +79,72 @@ func NewBacklinkRepository(db *pgxpool.Pool) *BacklinkRepository {
 	return &BacklinkRepository{db: db}
 }
 
+// Create inserts a link. It fails with ErrDuplicateBacklink when the project
+// already has a link with the same key.
 func (r *BacklinkRepository) Create(ctx context.Context, backlink *model.Backlink) error {
+	query := insertBacklinkQuery + ` RETURNING id, created_at`
+
+	err := r.db.QueryRow(ctx, query, insertArgs(backlink)...).Scan(&backlink.ID, &backlink.CreatedAt)
+
+	if isUniqueViolation(err) {
+		return ErrDuplicateBacklink
+	}
+	return err
+}
+
+// GetByLinkKey returns the link of a project holding key.
+func (r *BacklinkRepository) GetByLinkKey(ctx context.Context, projectID int64, key string) (*model.Backlink, error) {
 	query := `
-		INSERT INTO backlinks (project_id, source_url, target_url, anchor_text, status, link_type)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at
+		SELECT ` + backlinkColumns + `
+		FROM backlinks
+		WHERE project_id = $1 AND link_key = $2
 	`
 
-	err := r.db.QueryRow(ctx, query,
-		backlink.ProjectID,
-		backlink.SourceURL,
-		backlink.TargetURL,
-		backlink.AnchorText,
-		backlink.Status,
-		backlink.LinkType,
-	).Scan(&backlink.ID, &backlink.CreatedAt)
+	backlink, err := scanBacklink(r.db.QueryRow(ctx, query, projectID, key))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBacklinkNotFound
+		}
+		return nil, err
+	}
 
-	return err
+	return backlink, nil
+}
+
+// GetByLinkKeys returns the links of a project holding any of keys, by key.
+func (r *BacklinkRepository) GetByLinkKeys(ctx context.Context, projectID int64, keys []string) (map[string]*model.Backlink, error) {
+	query := `
+		SELECT ` + backlinkColumns + `
+		FROM backlinks
+		WHERE project_id = $1 AND link_key = ANY($2)
+	`
+
+	rows, err := r.db.Query(ctx, query, projectID, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	backlinks := make(map[string]*model.Backlink)
+	for rows.Next() {
+		backlink, err := scanBacklink(rows)
+		if err != nil {
+			return nil, err
+		}
+		backlinks[backlink.LinkKey] = backlink
+	}
+
+	return backlinks, rows.Err()
 }
 
 func (r *BacklinkRepository) GetByID(ctx context.Context, id int64) (*model.Backlink, error) {
 	query := `
-		SELECT id, project_id, source_url, target_url, anchor_text, status, link_type, http_status, last_checked_at, created_at
+		SELECT ` + backlinkColumns + `
 		FROM backlinks
 		WHERE id = $1
 	`
 
-	backlink := &model.Backlink{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&backlink.ID,
-		&backlink.ProjectID,
-		&backlink.SourceURL,
-		&backlink.TargetURL,
-		&backlink.AnchorText,
-		&backlink.Status,
-		&backlink.LinkType,
-		&backlink.HTTPStatus,
-		&backlink.LastCheckedAt,
-		&backlink.CreatedAt,
-	)
-
+	backlink, err := scanBacklink(r.db.QueryRow(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrBacklinkNotFound
@@ -73,7 +155,9 @@ func (r *BacklinkRepository) GetByID(ctx context.Context, id int64) (*model.Back
 	return backlink, nil
 }
 
-func (r *BacklinkRepository) List(ctx context.Context, filters *model.BacklinkFilters) ([]*model.Backlink, int64, error) {
+// listConditions builds the WHERE conditions of a list query. Arguments are
+// numbered from 1.
+func listConditions(filters *model.BacklinkFilters) ([]string, []interface{}) {
 	var conditions []string
 	var args []interface{}
 	argNum := 1
@@ -84,6 +168,12 @@ func (r *BacklinkRepository) List(ctx context.Context, filters *model.BacklinkFi
 		argNum++
 	}
 
+	if filters.UserID != nil {
+		conditions = append(conditions, fmt.Sprintf("project_id IN (%s)", accessibleProjects(argNum)))
+		args = append(args, *filters.UserID)
+		argNum++
+	}
+
 	if filters.Status != nil {
 		conditions = append(conditions, fmt.Sprintf("status = $%d", argNum))
 		args = append(args, *filters.Status)
@@ -96,22 +186,132 @@ func (r *BacklinkRepository) List(ctx context.Context, filters *model.BacklinkFi
 		argNum++
 	}
 
+	if filters.SourceURLCanonical != nil {
+		conditions = append(conditions, fmt.Sprintf("source_url_canonical = ANY($%d)", argNum))
+		args = append(args, filters.SourceURLCanonical)
+		argNum++
+	}
+
+	if filters.TargetURLCanonical != nil {
+		conditions = append(conditions, fmt.Sprintf("target_url_canonical = ANY($%d)", argNum))
+		args = append(args, filters.TargetURLCanonical)
+		argNum++
+	}
+
+	// Substring filters are served by the trigram indexes
 	if filters.SourceURL != nil {
 		conditions = append(conditions, fmt.Sprintf("source_url ILIKE $%d", argNum))
-		args = append(args, "%"+*filters.SourceURL+"%")
+		args = append(args, containsPattern(*filters.SourceURL))
 		argNum++
 	}
 
 	if filters.TargetURL != nil {
 		conditions = append(conditions, fmt.Sprintf("target_url ILIKE $%d", argNum))
-		args = append(args, "%"+*filters.TargetURL+"%")
+		args = append(args, containsPattern(*filters.TargetURL))
+		argNum++
+	}
+
+	if filters.Vendor != nil {
+		conditions = append(conditions, fmt.Sprintf("vendor ILIKE $%d", argNum))
+		args = append(args, containsPattern(*filters.Vendor))
+		argNum++
+	}
+
+	if filters.Search != nil {
+		conditions = append(conditions, fmt.Sprintf(
+			"(source_url ILIKE $%[1]d OR target_url ILIKE $%[1]d OR anchor_text ILIKE $%[1]d OR vendor ILIKE $%[1]d)", argNum))
+		args = append(args, containsPattern(strings.TrimSpace(*filters.Search)))
+		argNum++
+	}
+
+	if filters.CreatedAfter != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", argNum))
+		args = append(args, *filters.CreatedAfter)
+		argNum++
+	}
+
+	if filters.CreatedBefore != nil {
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", argNum))
+		args = append(args, *filters.CreatedBefore)
+		argNum++
+	}
+
+	if filters.CheckedAfter != nil {
+		conditions = append(conditions, fmt.Sprintf("last_checked_at >= $%d", argNum))
+		args = append(args, *filters.CheckedAfter)
+		argNum++
+	}
+
+	if filters.CheckedBefore != nil {
+		conditions = append(conditions, fmt.Sprintf("last_checked_at < $%d", argNum))
+		args = append(args, *filters.CheckedBefore)
+		argNum++
+	}
+
+	if filters.DonorIndexable != nil {
+		conditions = append(conditions, fmt.Sprintf("donor_indexable = $%d", argNum))
+		args = append(args, *filters.DonorIndexable)
+		argNum++
+	}
+
+	if filters.DonorCanonicalOK != nil {
+		conditions = append(conditions, fmt.Sprintf("donor_canonical_ok = $%d", argNum))
+		args = append(args, *filters.DonorCanonicalOK)
+		argNum++
+	}
+
+	if filters.DonorRobotsAllowed != nil {
+		if *filters.DonorRobotsAllowed {
+			conditions = append(conditions, "donor_robots_googlebot AND donor_robots_yandex")
+		} else {
+			conditions = append(conditions, "(NOT donor_robots_googlebot OR NOT donor_robots_yandex)")
+		}
+	}
+
+	if filters.DonorInSitemap != nil {
+		conditions = append(conditions, fmt.Sprintf("donor_in_sitemap = $%d", argNum))
+		args = append(args, *filters.DonorInSitemap)
+		argNum++
+	}
+
+	if filters.MaxOutboundLinks != nil {
+		conditions = append(conditions, fmt.Sprintf("donor_outbound_links <= $%d", argNum))
+		args = append(args, *filters.MaxOutboundLinks)
+		argNum++
+	}
+
+	if filters.PlacementRegion != nil {
+		conditions = append(conditions, fmt.Sprintf("placement_region = $%d", argNum))
+		args = append(args, *filters.PlacementRegion)
+		argNum++
+	}
+
+	if filters.PlacementHidden != nil {
+		conditions = append(conditions, fmt.Sprintf("placement_hidden = $%d", argNum))
+		args = append(args, *filters.PlacementHidden)
+		argNum++
+	}
+
+	if filters.AnchorMatch != nil {
+		conditions = append(conditions, fmt.Sprintf("placement_anchor_match = $%d", argNum))
+		args = append(args, *filters.AnchorMatch)
 		argNum++
 	}
 
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	return conditions, args
+}
+
+func whereOf(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
 	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+func (r *BacklinkRepository) List(ctx context.Context, filters *model.BacklinkFilters) ([]*model.Backlink, int64, error) {
+	conditions, args := listConditions(filters)
+	whereClause := whereOf(conditions)
+	argNum := len(args) + 1
 
 	// Count total
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM backlinks %s", whereClause)
@@ -124,12 +324,12 @@ func (r *BacklinkRepository) List(ctx context.Context, filters *model.BacklinkFi
 	// Get paginated results
 	offset := (filters.Page - 1) * filters.PerPage
 	query := fmt.Sprintf(`
-		SELECT id, project_id, source_url, target_url, anchor_text, status, link_type, http_status, last_checked_at, created_at
+		SELECT %s
 		FROM backlinks
 		%s
-		ORDER BY created_at DESC
+		ORDER BY %s
 		LIMIT $%d OFFSET $%d
-	`, whereClause, argNum, argNum+1)
+	`, backlinkColumns, whereClause, orderBy(filters.SortBy, filters.Order), argNum, argNum+1)
 
 	args = append(args, filters.PerPage, offset)
 
@@ -141,19 +341,7 @@ func (r *BacklinkRepository) List(ctx context.Context, filters *model.BacklinkFi
 
 	var backlinks []*model.Backlink
 	for rows.Next() {
-		backlink := &model.Backlink{}
-		err := rows.Scan(
-			&backlink.ID,
-			&backlink.ProjectID,
-			&backlink.SourceURL,
-			&backlink.TargetURL,
-			&backlink.AnchorText,
-			&backlink.Status,
-			&backlink.LinkType,
-			&backlink.HTTPStatus,
-			&backlink.LastCheckedAt,
-			&backlink.CreatedAt,
-		)
+		backlink, err := scanBacklink(rows)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -163,22 +351,146 @@ func (r *BacklinkRepository) List(ctx context.Context, filters *model.BacklinkFi
 	return backlinks, total, rows.Err()
 }
 
-func (r *BacklinkRepository) Update(ctx context.Context, backlink *model.Backlink) error {
-	query := `
-		UPDATE backlinks
-		SET source_url = $1, target_url = $2, anchor_text = $3, status = $4, link_type = $5
-		WHERE id = $6
-	`
+// ListAfter returns up to PerPage links following filters.After, or the
+// first page when it is nil, and whether more links follow. It does not
+// count the matching links.
+func (r *BacklinkRepository) ListAfter(ctx context.Context, filters *model.BacklinkFilters) ([]*model.Backlink, bool, error) {
+	conditions, args := listConditions(filters)
+	argNum := len(args) + 1
 
-	result, err := r.db.Exec(ctx, query,
-		backlink.SourceURL,
-		backlink.TargetURL,
-		backlink.AnchorText,
-		backlink.Status,
-		backlink.LinkType,
-		backlink.ID,
-	)
+	if c := filters.After; c != nil {
+		column, ok := sortColumns[c.SortBy]
+		if !ok {
+			column = "created_at"
+		}
+		op := "<"
+		if c.Order == model.OrderAsc {
+			op = ">"
+		}
+		// Links without a sort value come last in either direction.
+		if c.Value != nil {
+			conditions = append(conditions, fmt.Sprintf(
+				"(%[1]s %[2]s $%[3]d::%[4]s OR (%[1]s = $%[3]d::%[4]s AND id %[2]s $%[5]d) OR %[1]s IS NULL)",
+				column, op, argNum, sortTypes[column], argNum+1))
+			args = append(args, *c.Value, c.ID)
+			argNum += 2
+		} else {
+			conditions = append(conditions, fmt.Sprintf("(%s IS NULL AND id %s $%d)", column, op, argNum))
+			args = append(args, c.ID)
+			argNum++
+		}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM backlinks
+		%s
+		ORDER BY %s
+		LIMIT $%d
+	`, backlinkColumns, whereOf(conditions), orderBy(filters.SortBy, filters.Order), argNum)
+
+	args = append(args, filters.PerPage+1)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var backlinks []*model.Backlink
+	for rows.Next() {
+		backlink, err := scanBacklink(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		backlinks = append(backlinks, backlink)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if len(backlinks) > filters.PerPage {
+		return backlinks[:filters.PerPage], true, nil
+	}
+	return backlinks, false, nil
+}
+
+// Count returns the number of links matching filters.
+func (r *BacklinkRepository) Count(ctx context.Context, filters *model.BacklinkFilters) (int64, error) {
+	conditions, args := listConditions(filters)
+	var total int64
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM backlinks "+whereOf(conditions), args...).Scan(&total)
+	return total, err
+}
 
+// EstimateCount returns the planner's estimate of the number of links
+// matching filters. It is cheap on large projects but may be far off for
+// selective filters.
+func (r *BacklinkRepository) EstimateCount(ctx context.Context, filters *model.BacklinkFilters) (int64, error) {
+	conditions, args := listConditions(filters)
+	var plan []byte
+	err := r.db.QueryRow(ctx, "EXPLAIN (FORMAT JSON) SELECT 1 FROM backlinks "+whereOf(conditions), args...).Scan(&plan)
+	if err != nil {
+		return 0, err
+	}
+
+	var explain []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explain); err != nil {
+		return 0, err
+	}
+	if len(explain) == 0 {
+		return 0, fmt.Errorf("empty query plan")
+	}
+	return int64(explain[0].Plan.Rows), nil
+}
+
+// sortColumns maps sort fields to columns.
+var sortColumns = map[model.BacklinkSort]string{
+	model.SortCreatedAt:     "created_at",
+	model.SortLastCheckedAt: "last_checked_at",
+	model.SortStatus:        "status",
+	model.SortDomain:        "source_domain",
+}
+
+// sortTypes are the SQL types cursor values are cast to, per sort column.
+var sortTypes = map[string]string{
+	"created_at":      "timestamptz",
+	"last_checked_at": "timestamptz",
+	"status":          "link_status",
+	"source_domain":   "text",
+}
+
+// orderBy builds a stable ORDER BY clause; unknown fields sort by creation
+// time. Links never checked sort last either way.
+func orderBy(sort model.BacklinkSort, order model.SortOrder) string {
+	column, ok := sortColumns[sort]
+	if !ok {
+		column = "created_at"
+	}
+	dir := "DESC"
+	if order == model.OrderAsc {
+		dir = "ASC"
+	}
+	return fmt.Sprintf("%s %s NULLS LAST, id %s", column, dir, dir)
+}
+
+// containsPattern is an ILIKE pattern matching s anywhere, with the
+// wildcards in s taken literally.
+func containsPattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
+func (r *BacklinkRepository) Update(ctx context.Context, backlink *model.Backlink) error {
+	result, err := r.db.Exec(ctx, updateBacklinkQuery, updateArgs(backlink)...)
+
+	if isUniqueViolation(err) {
+		return ErrDuplicateBacklink
+	}
 	if err != nil {
 		return err
 	}
@@ -190,6 +502,88 @@ func (r *BacklinkRepository) Update(ctx context.Context, backlink *model.Backlin
 	return nil
 }
 
+// BulkWrite stores links in one transaction: links with a zero ID are
+// inserted, the others updated. Inserts whose key is already taken are left
+// out and their indexes returned in conflicts, unless failOnConflict is set.
+// A failing statement rolls everything back and is reported as *BatchError.
+// IDs of inserted links are only set once the transaction commits. A non-nil
+// hook runs first in the same transaction.
+func (r *BacklinkRepository) BulkWrite(ctx context.Context, backlinks []*model.Backlink, failOnConflict bool, hook TxHook) (conflicts []int, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if hook != nil {
+		if err := hook(ctx, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]int64, len(backlinks))
+	createdAt := make([]time.Time, len(backlinks))
+
+	for start := 0; start < len(backlinks); start += bulkBatchSize {
+		end := min(start+bulkBatchSize, len(backlinks))
+
+		batch := &pgx.Batch{}
+		for _, b := range backlinks[start:end] {
+			if b.ID == 0 {
+				batch.Queue(insertBacklinkQuery+` ON CONFLICT (project_id, link_key) DO NOTHING RETURNING id, created_at`, insertArgs(b)...)
+			} else {
+				batch.Queue(updateBacklinkQuery, updateArgs(b)...)
+			}
+		}
+
+		results := tx.SendBatch(ctx, batch)
+		for i := start; i < end; i++ {
+			if backlinks[i].ID == 0 {
+				err := results.QueryRow().Scan(&ids[i], &createdAt[i])
+				if errors.Is(err, pgx.ErrNoRows) {
+					if !failOnConflict {
+						conflicts = append(conflicts, i)
+						continue
+					}
+					err = ErrDuplicateBacklink
+				}
+				if err != nil {
+					results.Close()
+					return nil, &BatchError{Index: i, Err: err}
+				}
+				continue
+			}
+
+			result, err := results.Exec()
+			if err == nil && result.RowsAffected() == 0 {
+				err = ErrBacklinkNotFound
+			}
+			if isUniqueViolation(err) {
+				err = ErrDuplicateBacklink
+			}
+			if err != nil {
+				results.Close()
+				return nil, &BatchError{Index: i, Err: err}
+			}
+		}
+		if err := results.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	for i, b := range backlinks {
+		if b.ID == 0 && ids[i] != 0 {
+			b.ID = ids[i]
+			b.CreatedAt = createdAt[i]
+		}
+	}
+	return conflicts, nil
+}
+
 func (r *BacklinkRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM backlinks WHERE id = $1`
 	result, err := r.db.Exec(ctx, query, id)
@@ -204,21 +598,6 @@ func (r *BacklinkRepository) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (r *BacklinkRepository) BulkCreate(ctx context.Context, backlinks []*model.Backlink) (int, []error) {
-	var errs []error
-	success := 0
-
-	for _, backlink := range backlinks {
-		if err := r.Create(ctx, backlink); err != nil {
-			errs = append(errs, err)
-		} else {
-			success++
-		}
-	}
-
-	return success, errs
-}
-
 func (r *BacklinkRepository) BulkDelete(ctx context.Context, ids []int64) (int, error) {
 	if len(ids) == 0 {
 		return 0, nil
@@ -233,15 +612,874 @@ func (r *BacklinkRepository) BulkDelete(ctx context.Context, ids []int64) (int,
 	return int(result.RowsAffected()), nil
 }
 
-func (r *BacklinkRepository) GetProjectID(ctx context.Context, backlinkID int64) (int64, error) {
-	query := `SELECT project_id FROM backlinks WHERE id = $1`
-	var projectID int64
-	err := r.db.QueryRow(ctx, query, backlinkID).Scan(&projectID)
+// ListByProject returns all links of a project in the order they were
+// added.
+func (r *BacklinkRepository) ListByProject(ctx context.Context, projectID int64) ([]*model.Backlink, error) {
+	query := `SELECT ` + backlinkColumns + ` FROM backlinks WHERE project_id = $1 ORDER BY id`
+
+	rows, err := r.db.Query(ctx, query, projectID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return 0, ErrBacklinkNotFound
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backlinks []*model.Backlink
+	for rows.Next() {
+		backlink, err := scanBacklink(rows)
+		if err != nil {
+			return nil, err
 		}
-		return 0, err
+		backlinks = append(backlinks, backlink)
 	}
-	return projectID, nil
+
+	return backlinks, rows.Err()
+}
+
+// UpdateCheckResult stores the outcome of a link check together with the
+// donor page audit.
+func (r *BacklinkRepository) UpdateCheckResult(ctx context.Context, backlink *model.Backlink) error {
+	query := `
+		UPDATE backlinks
+		SET status = $1, link_type = $2, http_status = $3, last_checked_at = $4,
+		    donor_indexable = $5, donor_has_noindex = $6, donor_canonical_url = $7, donor_canonical_ok = $8,
+		    donor_robots_googlebot = $9, donor_robots_yandex = $10, donor_outbound_links = $11,
+		    donor_in_sitemap = $12, donor_audited_at = $13, lost_at = $14,
+		    placement_region = $15, placement_context = $16, placement_position = $17,
+		    placement_total_links = $18, placement_position_pct = $19, placement_is_image = $20,
+		    placement_image_alt = $21, placement_hidden = $22, placement_hidden_by = $23,
+		    placement_anchor = $24, placement_anchor_match = $25
+		WHERE id = $26
+	`
+
+	audit := backlink.DonorAudit
+	if audit == nil {
+		audit = &model.DonorAudit{}
+	}
+	var auditedAt *time.Time
+	if backlink.DonorAudit != nil {
+		auditedAt = &backlink.DonorAudit.AuditedAt
+	}
+
+	// A nil placement clears the columns; region marks a stored placement.
+	placement := backlink.Placement
+	if placement == nil {
+		placement = &model.Placement{}
+	}
+	var region *model.Region
+	if backlink.Placement != nil {
+		region = &backlink.Placement.Region
+	}
+
+	result, err := r.db.Exec(ctx, query,
+		backlink.Status,
+		backlink.LinkType,
+		backlink.HTTPStatus,
+		backlink.LastCheckedAt,
+		audit.Indexable,
+		audit.HasNoindex,
+		audit.CanonicalURL,
+		audit.CanonicalOK,
+		audit.RobotsGooglebot,
+		audit.RobotsYandex,
+		audit.OutboundLinks,
+		audit.InSitemap,
+		auditedAt,
+		backlink.LostAt,
+		region,
+		nullIfEmpty(placement.Context),
+		placement.Position,
+		placement.TotalLinks,
+		placement.PositionPercent,
+		placement.IsImage,
+		placement.ImageAlt,
+		placement.Hidden,
+		placement.HiddenBy,
+		nullIfEmpty(placement.Anchor),
+		placement.AnchorMatch,
+		backlink.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrBacklinkNotFound
+	}
+
+	return nil
+}
+
+// lostPaidWhere selects the paid links of the project given as $1 that were
+// lost before their paid-until date.
+const lostPaidWhere = `
+		WHERE project_id = $1 AND price IS NOT NULL AND paid_until IS NOT NULL
+		  AND status IN ('removed', 'broken') AND lost_at IS NOT NULL AND lost_at::date < paid_until
+	`
+
+// ListLostPaid returns paid links of a project that were lost before their
+// paid-until date, most recently lost first.
+func (r *BacklinkRepository) ListLostPaid(ctx context.Context, projectID int64, page, perPage int) ([]*model.Backlink, int64, error) {
+	whereClause := lostPaidWhere
+
+	var total int64
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM backlinks"+whereClause, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * perPage
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM backlinks
+		%s
+		ORDER BY lost_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`, backlinkColumns, whereClause)
+
+	rows, err := r.db.Query(ctx, query, projectID, perPage, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var backlinks []*model.Backlink
+	for rows.Next() {
+		backlink, err := scanBacklink(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		backlinks = append(backlinks, backlink)
+	}
+
+	return backlinks, total, rows.Err()
+}
+
+// ListLostPaidAfter returns up to limit lost paid links of a project after
+// the given position, most recently lost first, and whether more follow.
+func (r *BacklinkRepository) ListLostPaidAfter(ctx context.Context, projectID int64, after *model.TimeCursor, limit int) ([]*model.Backlink, bool, error) {
+	whereClause := lostPaidWhere
+	args := []interface{}{projectID}
+	if after != nil {
+		whereClause += " AND (lost_at, id) < ($2, $3)"
+		args = append(args, after.At, after.ID)
+	}
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM backlinks
+		%s
+		ORDER BY lost_at DESC, id DESC
+		LIMIT $%d
+	`, backlinkColumns, whereClause, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var backlinks []*model.Backlink
+	for rows.Next() {
+		backlink, err := scanBacklink(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		backlinks = append(backlinks, backlink)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if len(backlinks) > limit {
+		return backlinks[:limit], true, nil
+	}
+	return backlinks, false, nil
+}
+
+// GetSpend sums prices of a project's paid links grouped by currency, vendor
+// and placement month. Links without placed_at count by creation date.
+func (r *BacklinkRepository) GetSpend(ctx context.Context, projectID int64, from, to *time.Time) (*model.SpendReport, error) {
+	conditions := []string{"project_id = $1", "price IS NOT NULL"}
+	args := []interface{}{projectID}
+	argNum := 2
+
+	if from != nil {
+		conditions = append(conditions, fmt.Sprintf("COALESCE(placed_at, created_at::date) >= $%d", argNum))
+		args = append(args, *from)
+		argNum++
+	}
+	if to != nil {
+		conditions = append(conditions, fmt.Sprintf("COALESCE(placed_at, created_at::date) <= $%d", argNum))
+		args = append(args, *to)
+		argNum++
+	}
+
+	whereClause := strings.Join(conditions, " AND ")
+	report := &model.SpendReport{
+		ProjectID: projectID,
+		Totals:    []model.SpendTotal{},
+		ByVendor:  []model.VendorSpend{},
+		ByMonth:   []model.MonthlySpend{},
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COALESCE(currency, ''), SUM(price)::float8, COUNT(*)
+		FROM backlinks WHERE %s
+		GROUP BY 1 ORDER BY 1
+	`, whereClause)
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var t model.SpendTotal
+		if err := rows.Scan(&t.Currency, &t.Amount, &t.Links); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.Totals = append(report.Totals, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf(`
+		SELECT COALESCE(vendor, ''), COALESCE(currency, ''), SUM(price)::float8, COUNT(*)
+		FROM backlinks WHERE %s
+		GROUP BY 1, 2 ORDER BY 3 DESC
+	`, whereClause)
+	rows, err = r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var v model.VendorSpend
+		if err := rows.Scan(&v.Vendor, &v.Currency, &v.Amount, &v.Links); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.ByVendor = append(report.ByVendor, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf(`
+		SELECT to_char(COALESCE(placed_at, created_at::date), 'YYYY-MM'), COALESCE(currency, ''), SUM(price)::float8, COUNT(*)
+		FROM backlinks WHERE %s
+		GROUP BY 1, 2 ORDER BY 1, 2
+	`, whereClause)
+	rows, err = r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m model.MonthlySpend
+		if err := rows.Scan(&m.Month, &m.Currency, &m.Amount, &m.Links); err != nil {
+			return nil, err
+		}
+		report.ByMonth = append(report.ByMonth, m)
+	}
+
+	return report, rows.Err()
+}
+
+// hostExpr extracts the host without "www." from a URL column, preferring
+// its canonical form, which has IDN hosts in punycode.
+func hostExpr(column string) string {
+	return fmt.Sprintf(`substring(COALESCE(%[1]s_canonical, lower(%[1]s)) from '^(?:[a-z][a-z0-9+.-]*:)?//(?:www\.)?([^/:?#]+)')`, column)
+}
+
+// GetStats counts the links of a project by status, type and referring
+// domain, and the links gained and lost in each of the last weeks,
+// including the current one.
+func (r *BacklinkRepository) GetStats(ctx context.Context, projectID int64, weeks int) (*model.ProjectStats, error) {
+	stats := &model.ProjectStats{
+		ProjectID: projectID,
+		ByStatus: map[model.LinkStatus]int{
+			model.LinkStatusPending: 0, model.LinkStatusActive: 0, model.LinkStatusBroken: 0,
+			model.LinkStatusRemoved: 0, model.LinkStatusNoFollow: 0,
+		},
+		ByLinkType: map[model.LinkType]int{
+			model.LinkTypeDoFollow: 0, model.LinkTypeNoFollow: 0, model.LinkTypeSponsored: 0, model.LinkTypeUGC: 0,
+		},
+		Weekly: []model.WeeklyLinks{},
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT status, link_type, COUNT(*)
+		FROM backlinks WHERE project_id = $1
+		GROUP BY 1, 2
+	`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var status model.LinkStatus
+		var linkType model.LinkType
+		var n int
+		if err := rows.Scan(&status, &linkType, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats.ByStatus[status] += n
+		stats.ByLinkType[linkType] += n
+		stats.Total += n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = r.db.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT source_domain),
+		       COUNT(DISTINCT source_domain) FILTER (WHERE status IN ('active', 'nofollow'))
+		FROM backlinks WHERE project_id = $1
+	`, projectID).Scan(&stats.ReferringDomains, &stats.ActiveReferringDomains)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.Query(ctx, `
+		WITH weeks AS (
+			SELECT generate_series(
+				date_trunc('week', NOW()) - ($2::int - 1) * INTERVAL '1 week',
+				date_trunc('week', NOW()),
+				INTERVAL '1 week'
+			) AS week
+		)
+		SELECT to_char(w.week, 'YYYY-MM-DD'),
+		       (SELECT COUNT(*) FROM backlinks b
+		        WHERE b.project_id = $1
+		          AND COALESCE(b.placed_at, b.created_at::date) >= w.week::date
+		          AND COALESCE(b.placed_at, b.created_at::date) < (w.week + INTERVAL '1 week')::date),
+		       (SELECT COUNT(*) FROM backlinks b
+		        WHERE b.project_id = $1
+		          AND b.lost_at >= w.week AND b.lost_at < w.week + INTERVAL '1 week')
+		FROM weeks w
+		ORDER BY w.week
+	`, projectID, weeks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var w model.WeeklyLinks
+		if err := rows.Scan(&w.Week, &w.Gained, &w.Lost); err != nil {
+			return nil, err
+		}
+		stats.Weekly = append(stats.Weekly, w)
+	}
+
+	return stats, rows.Err()
+}
+
+// ListAnchorCounts counts links that are not lost by anchor text and target
+// domain. Links without an expected anchor use the one found on the page.
+func (r *BacklinkRepository) ListAnchorCounts(ctx context.Context, projectID int64) ([]model.AnchorCount, error) {
+	query := fmt.Sprintf(`
+		SELECT COALESCE(NULLIF(anchor_text, ''), placement_anchor, ''), COALESCE(%s, ''), COUNT(*)
+		FROM backlinks
+		WHERE project_id = $1 AND status NOT IN ('removed', 'broken')
+		GROUP BY 1, 2
+	`, hostExpr("target_url"))
+	rows, err := r.db.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []model.AnchorCount
+	for rows.Next() {
+		var c model.AnchorCount
+		if err := rows.Scan(&c.Anchor, &c.TargetDomain, &c.Links); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// GetProjectURLs returns the distinct source and target URLs of a project.
+func (r *BacklinkRepository) GetProjectURLs(ctx context.Context, projectID int64) (sources, targets []string, err error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT DISTINCT source_url, 's' FROM backlinks WHERE project_id = $1
+		UNION
+		SELECT DISTINCT target_url, 't' FROM backlinks WHERE project_id = $1
+	`, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u, kind string
+		if err := rows.Scan(&u, &kind); err != nil {
+			return nil, nil, err
+		}
+		if kind == "s" {
+			sources = append(sources, u)
+		} else {
+			targets = append(targets, u)
+		}
+	}
+
+	return sources, targets, rows.Err()
+}
+
+// GetLinkPairs returns the source and target URL of every link in a project.
+func (r *BacklinkRepository) GetLinkPairs(ctx context.Context, projectID int64) ([][2]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT source_url, target_url FROM backlinks WHERE project_id = $1`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs [][2]string
+	for rows.Next() {
+		var p [2]string
+		if err := rows.Scan(&p[0], &p[1]); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, p)
+	}
+
+	return pairs, rows.Err()
+}
+
+// ListWithoutKey returns up to limit links with id above afterID that lack
+// canonical URLs or a link key, optionally only for one project.
+func (r *BacklinkRepository) ListWithoutKey(ctx context.Context, projectID *int64, afterID int64, limit int) ([]*model.Backlink, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, project_id, source_url, target_url
+		FROM backlinks
+		WHERE (source_url_canonical IS NULL OR target_url_canonical IS NULL OR link_key IS NULL)
+		  AND ($1::bigint IS NULL OR project_id = $1)
+		  AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`, projectID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backlinks []*model.Backlink
+	for rows.Next() {
+		b := &model.Backlink{}
+		if err := rows.Scan(&b.ID, &b.ProjectID, &b.SourceURL, &b.TargetURL); err != nil {
+			return nil, err
+		}
+		backlinks = append(backlinks, b)
+	}
+
+	return backlinks, rows.Err()
+}
+
+// SetCanonical stores computed canonical URLs for a batch of links. A link
+// gets its key only if no other link of the project holds it; the rest are
+// duplicates left for the duplicates report.
+func (r *BacklinkRepository) SetCanonical(ctx context.Context, backlinks []*model.Backlink) error {
+	batch := &pgx.Batch{}
+	for _, b := range backlinks {
+		batch.Queue(`
+			UPDATE backlinks
+			SET source_url_canonical = $1, target_url_canonical = $2,
+			    link_key = CASE WHEN EXISTS (
+			        SELECT 1 FROM backlinks o
+			        WHERE o.project_id = backlinks.project_id AND o.link_key = $3 AND o.id <> backlinks.id
+			    ) THEN NULL ELSE $3 END
+			WHERE id = $4
+		`, b.SourceURLCanonical, b.TargetURLCanonical, b.LinkKey, b.ID)
+	}
+	return r.db.SendBatch(ctx, batch).Close()
+}
+
+// RecomputeCanonical recomputes the canonical URLs and keys of every link
+// and discovered link of a project with canonicalize, which fills them from
+// the source and target URL. Everything runs in one transaction, after hook
+// when it is not nil, so the project never has links without a key. Of
+// links sharing a key the oldest keeps it; the rest are duplicates left for
+// the duplicates report.
+func (r *BacklinkRepository) RecomputeCanonical(ctx context.Context, projectID int64, canonicalize func(*model.Backlink), hook TxHook) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if hook != nil {
+		if err := hook(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	// Keys are freed first so a link can take over the key of another
+	if _, err := tx.Exec(ctx, `UPDATE backlinks SET link_key = NULL WHERE project_id = $1`, projectID); err != nil {
+		return err
+	}
+
+	backlinks, err := listLinkURLs(ctx, tx, `SELECT id, source_url, target_url FROM backlinks WHERE project_id = $1 ORDER BY id`, projectID)
+	if err != nil {
+		return err
+	}
+	batch := &pgx.Batch{}
+	for _, b := range backlinks {
+		canonicalize(b)
+		batch.Queue(`
+			UPDATE backlinks
+			SET source_url_canonical = $1, target_url_canonical = $2,
+			    link_key = CASE WHEN EXISTS (
+			        SELECT 1 FROM backlinks o WHERE o.project_id = $3 AND o.link_key = $4
+			    ) THEN NULL ELSE $4 END
+			WHERE id = $5
+		`, b.SourceURLCanonical, b.TargetURLCanonical, projectID, b.LinkKey, b.ID)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+
+	discovered, err := listLinkURLs(ctx, tx, `SELECT id, source_url, target_url FROM discovered_links WHERE project_id = $1`, projectID)
+	if err != nil {
+		return err
+	}
+	batch = &pgx.Batch{}
+	for _, d := range discovered {
+		canonicalize(d)
+		batch.Queue(`UPDATE discovered_links SET source_url_canonical = $1, target_url_canonical = $2 WHERE id = $3`,
+			d.SourceURLCanonical, d.TargetURLCanonical, d.ID)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// listLinkURLs reads the id, source and target URL of links or discovered
+// links.
+func listLinkURLs(ctx context.Context, tx pgx.Tx, query string, args ...any) ([]*model.Backlink, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*model.Backlink
+	for rows.Next() {
+		b := &model.Backlink{}
+		if err := rows.Scan(&b.ID, &b.SourceURL, &b.TargetURL); err != nil {
+			return nil, err
+		}
+		links = append(links, b)
+	}
+
+	return links, rows.Err()
+}
+
+// duplicateGroups selects the groups of links of the project given as $1
+// that share canonical URLs, with the ids of their links oldest first.
+const duplicateGroups = `
+		SELECT source_url_canonical, target_url_canonical, COUNT(*) AS cnt,
+		       MIN(id) FILTER (WHERE link_key IS NOT NULL) AS primary_id,
+		       array_agg(id ORDER BY id) AS ids
+		FROM backlinks
+		WHERE project_id = $1 AND source_url_canonical IS NOT NULL AND target_url_canonical IS NOT NULL
+		GROUP BY source_url_canonical, target_url_canonical
+		HAVING COUNT(*) > 1
+	`
+
+// ListDuplicateGroups returns groups of links sharing canonical URLs, largest
+// first, with the ids of their links oldest first.
+func (r *BacklinkRepository) ListDuplicateGroups(ctx context.Context, projectID int64, page, perPage int) ([]model.DuplicateGroup, [][]int64, int64, error) {
+	groupQuery := duplicateGroups
+
+	var total int64
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM ("+groupQuery+") g", projectID).Scan(&total); err != nil {
+		return nil, nil, 0, err
+	}
+
+	offset := (page - 1) * perPage
+	rows, err := r.db.Query(ctx, groupQuery+`
+		ORDER BY cnt DESC, MIN(id)
+		LIMIT $2 OFFSET $3
+	`, projectID, perPage, offset)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	groups, ids, err := scanDuplicateGroups(rows)
+	return groups, ids, total, err
+}
+
+// ListDuplicateGroupsAfter returns up to limit duplicate groups of a project
+// after the given position, in the order of ListDuplicateGroups, and whether
+// more follow. Groups are still aggregated in full, but neither counted nor
+// skipped with OFFSET.
+func (r *BacklinkRepository) ListDuplicateGroupsAfter(ctx context.Context, projectID int64, after *model.DuplicateCursor, limit int) ([]model.DuplicateGroup, [][]int64, bool, error) {
+	var condition string
+	args := []interface{}{projectID}
+	if after != nil {
+		condition = "WHERE cnt < $2 OR (cnt = $2 AND ids[1] > $3)"
+		args = append(args, after.Count, after.ID)
+	}
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT source_url_canonical, target_url_canonical, cnt, primary_id, ids
+		FROM (%s) g
+		%s
+		ORDER BY cnt DESC, ids[1]
+		LIMIT $%d
+	`, duplicateGroups, condition, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	groups, ids, err := scanDuplicateGroups(rows)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if len(groups) > limit {
+		return groups[:limit], ids[:limit], true, nil
+	}
+	return groups, ids, false, nil
+}
+
+// scanDuplicateGroups scans the rows of duplicateGroups and closes them.
+func scanDuplicateGroups(rows pgx.Rows) ([]model.DuplicateGroup, [][]int64, error) {
+	defer rows.Close()
+
+	var groups []model.DuplicateGroup
+	var ids [][]int64
+	for rows.Next() {
+		var g model.DuplicateGroup
+		var groupIDs []int64
+		if err := rows.Scan(&g.SourceURLCanonical, &g.TargetURLCanonical, &g.Count, &g.PrimaryID, &groupIDs); err != nil {
+			return nil, nil, err
+		}
+		groups = append(groups, g)
+		ids = append(ids, groupIDs)
+	}
+
+	return groups, ids, rows.Err()
+}
+
+// GetByIDs returns the links with the given ids in no particular order.
+func (r *BacklinkRepository) GetByIDs(ctx context.Context, ids []int64) ([]*model.Backlink, error) {
+	query := `
+		SELECT ` + backlinkColumns + `
+		FROM backlinks
+		WHERE id = ANY($1)
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backlinks []*model.Backlink
+	for rows.Next() {
+		backlink, err := scanBacklink(rows)
+		if err != nil {
+			return nil, err
+		}
+		backlinks = append(backlinks, backlink)
+	}
+
+	return backlinks, rows.Err()
+}
+
+func (r *BacklinkRepository) GetProjectID(ctx context.Context, backlinkID int64) (int64, error) {
+	query := `SELECT project_id FROM backlinks WHERE id = $1`
+	var projectID int64
+	err := r.db.QueryRow(ctx, query, backlinkID).Scan(&projectID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrBacklinkNotFound
+		}
+		return 0, err
+	}
+	return projectID, nil
+}
+
+func scanBacklink(row pgx.Row) (*model.Backlink, error) {
+	backlink := &model.Backlink{}
+	var (
+		indexable, hasNoindex, canonicalOK  *bool
+		robotsGooglebot, robotsYandex       *bool
+		inSitemap                           *bool
+		canonicalURL, linkKey               *string
+		sourceCanonical, targetCanonical    *string
+		placementRegion                     *model.Region
+		placementContext, placementHiddenBy *string
+		placementImageAlt                   *string
+		placementPosition, placementTotal   *int
+		placementPct                        *int
+		placementIsImage, placementHidden   *bool
+		placementAnchor                     *string
+		placementAnchorMatch                *bool
+		sourceDomain                        *string
+		outboundLinks                       *int
+		auditedAt                           *time.Time
+	)
+
+	err := row.Scan(
+		&backlink.ID,
+		&backlink.ProjectID,
+		&backlink.SourceURL,
+		&backlink.TargetURL,
+		&backlink.AnchorText,
+		&backlink.Status,
+		&backlink.LinkType,
+		&backlink.HTTPStatus,
+		&backlink.LastCheckedAt,
+		&indexable,
+		&hasNoindex,
+		&canonicalURL,
+		&canonicalOK,
+		&robotsGooglebot,
+		&robotsYandex,
+		&outboundLinks,
+		&inSitemap,
+		&auditedAt,
+		&backlink.CreatedAt,
+		&backlink.Vendor,
+		&backlink.Price,
+		&backlink.Currency,
+		&backlink.PlacedAt,
+		&backlink.PaidUntil,
+		&backlink.LostAt,
+		&sourceCanonical,
+		&targetCanonical,
+		&linkKey,
+		&placementRegion,
+		&placementContext,
+		&placementPosition,
+		&placementTotal,
+		&placementPct,
+		&placementIsImage,
+		&placementImageAlt,
+		&placementHidden,
+		&placementHiddenBy,
+		&placementAnchor,
+		&placementAnchorMatch,
+		&sourceDomain,
+	)
+	if err != nil {
+		return nil, err
+	}
+	backlink.SourceURLCanonical = derefString(sourceCanonical)
+	backlink.TargetURLCanonical = derefString(targetCanonical)
+	backlink.LinkKey = derefString(linkKey)
+	backlink.SourceDomain = derefString(sourceDomain)
+
+	if placementRegion != nil {
+		backlink.Placement = &model.Placement{
+			Region:          *placementRegion,
+			Context:         derefString(placementContext),
+			Position:        derefInt(placementPosition),
+			TotalLinks:      derefInt(placementTotal),
+			PositionPercent: derefInt(placementPct),
+			IsImage:         derefBool(placementIsImage),
+			ImageAlt:        placementImageAlt,
+			Hidden:          derefBool(placementHidden),
+			HiddenBy:        placementHiddenBy,
+			Anchor:          derefString(placementAnchor),
+			AnchorMatch:     placementAnchorMatch,
+		}
+	}
+
+	if auditedAt != nil {
+		backlink.DonorAudit = &model.DonorAudit{
+			Indexable:       derefBool(indexable),
+			HasNoindex:      derefBool(hasNoindex),
+			CanonicalURL:    canonicalURL,
+			CanonicalOK:     derefBool(canonicalOK),
+			RobotsGooglebot: robotsGooglebot,
+			RobotsYandex:    robotsYandex,
+			InSitemap:       inSitemap,
+			AuditedAt:       *auditedAt,
+		}
+		if outboundLinks != nil {
+			backlink.DonorAudit.OutboundLinks = *outboundLinks
+		}
+	}
+
+	return backlink, nil
+}
+
+func derefBool(b *bool) bool {
+	return b != nil && *b
+}
+
+func insertArgs(b *model.Backlink) []interface{} {
+	return []interface{}{
+		b.ProjectID,
+		b.SourceURL,
+		b.TargetURL,
+		b.AnchorText,
+		b.Status,
+		b.LinkType,
+		b.Vendor,
+		b.Price,
+		b.Currency,
+		b.PlacedAt,
+		b.PaidUntil,
+		nullIfEmpty(b.SourceURLCanonical),
+		nullIfEmpty(b.TargetURLCanonical),
+		nullIfEmpty(b.LinkKey),
+	}
+}
+
+func updateArgs(b *model.Backlink) []interface{} {
+	return []interface{}{
+		b.SourceURL,
+		b.TargetURL,
+		b.AnchorText,
+		b.Status,
+		b.LinkType,
+		b.Vendor,
+		b.Price,
+		b.Currency,
+		b.PlacedAt,
+		b.PaidUntil,
+		b.LostAt,
+		nullIfEmpty(b.SourceURLCanonical),
+		nullIfEmpty(b.TargetURLCanonical),
+		nullIfEmpty(b.LinkKey),
+		b.ID,
+	}
+}
+
+func derefInt(n *int) int {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
@@ -0,0 +1,380 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/backlink-service/internal/model"
+)
+
+var (
+	ErrDiscoveryRunNotFound   = errors.New("discovery run not found")
+	ErrDiscoveredLinkNotFound = errors.New("discovered link not found")
+	ErrDiscoveredLinkAdopted  = errors.New("discovered link already adopted")
+)
+
+const discoveryRunColumns = `id, project_id, status, target_domains, donor_domains, max_depth, max_pages,
+	pages_crawled, links_found, error, started_at, finished_at`
+
+type DiscoveryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDiscoveryRepository(db *pgxpool.Pool) *DiscoveryRepository {
+	return &DiscoveryRepository{db: db}
+}
+
+func (r *DiscoveryRepository) CreateRun(ctx context.Context, run *model.DiscoveryRun) error {
+	query := `
+		INSERT INTO discovery_runs (project_id, status, target_domains, donor_domains, max_depth, max_pages)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, started_at
+	`
+
+	return r.db.QueryRow(ctx, query,
+		run.ProjectID,
+		run.Status,
+		run.TargetDomains,
+		run.DonorDomains,
+		run.MaxDepth,
+		run.MaxPages,
+	).Scan(&run.ID, &run.StartedAt)
+}
+
+func (r *DiscoveryRepository) FinishRun(ctx context.Context, run *model.DiscoveryRun) error {
+	query := `
+		UPDATE discovery_runs
+		SET status = $1, pages_crawled = $2, links_found = $3, error = $4, finished_at = NOW()
+		WHERE id = $5
+		RETURNING finished_at
+	`
+
+	return r.db.QueryRow(ctx, query,
+		run.Status,
+		run.PagesCrawled,
+		run.LinksFound,
+		run.Error,
+		run.ID,
+	).Scan(&run.FinishedAt)
+}
+
+// FailInterrupted marks runs left running by a previous process as failed.
+func (r *DiscoveryRepository) FailInterrupted(ctx context.Context) (int64, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE discovery_runs
+		SET status = 'failed', error = 'interrupted by service restart', finished_at = NOW()
+		WHERE status = 'running'
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *DiscoveryRepository) GetRun(ctx context.Context, id int64) (*model.DiscoveryRun, error) {
+	query := `SELECT ` + discoveryRunColumns + ` FROM discovery_runs WHERE id = $1`
+
+	run := &model.DiscoveryRun{}
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&run.ID,
+		&run.ProjectID,
+		&run.Status,
+		&run.TargetDomains,
+		&run.DonorDomains,
+		&run.MaxDepth,
+		&run.MaxPages,
+		&run.PagesCrawled,
+		&run.LinksFound,
+		&run.Error,
+		&run.StartedAt,
+		&run.FinishedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDiscoveryRunNotFound
+		}
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// ListRuns returns the discovery runs of a project, oldest first.
+func (r *DiscoveryRepository) ListRuns(ctx context.Context, projectID int64) ([]*model.DiscoveryRun, error) {
+	query := `SELECT ` + discoveryRunColumns + ` FROM discovery_runs WHERE project_id = $1 ORDER BY started_at`
+
+	rows, err := r.db.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*model.DiscoveryRun
+	for rows.Next() {
+		run := &model.DiscoveryRun{}
+		err := rows.Scan(
+			&run.ID,
+			&run.ProjectID,
+			&run.Status,
+			&run.TargetDomains,
+			&run.DonorDomains,
+			&run.MaxDepth,
+			&run.MaxPages,
+			&run.PagesCrawled,
+			&run.LinksFound,
+			&run.Error,
+			&run.StartedAt,
+			&run.FinishedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// SaveLinks stores links found by a run and returns how many were new.
+// Links already discovered earlier keep their original record.
+func (r *DiscoveryRepository) SaveLinks(ctx context.Context, links []model.DiscoveredLink) (int, error) {
+	if len(links) == 0 {
+		return 0, nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, l := range links {
+		batch.Queue(`
+			INSERT INTO discovered_links (project_id, run_id, source_url, target_url, anchor_text, link_type,
+				source_url_canonical, target_url_canonical)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (project_id, source_url, target_url) DO NOTHING
+		`, l.ProjectID, l.RunID, l.SourceURL, l.TargetURL, l.AnchorText, l.LinkType,
+			nullIfEmpty(l.SourceURLCanonical), nullIfEmpty(l.TargetURLCanonical))
+	}
+
+	results := r.db.SendBatch(ctx, batch)
+	inserted := 0
+	for range links {
+		result, err := results.Exec()
+		if err != nil {
+			results.Close()
+			return 0, err
+		}
+		inserted += int(result.RowsAffected())
+	}
+
+	return inserted, results.Close()
+}
+
+// untrackedWhere selects the discovered links d of the project given as $1
+// that are neither adopted nor already tracked as backlinks of the project,
+// either verbatim or as an equivalent URL variant.
+const untrackedWhere = `
+		WHERE d.project_id = $1 AND d.adopted_backlink_id IS NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM backlinks b
+			WHERE b.project_id = d.project_id
+			  AND ((b.source_url = d.source_url AND b.target_url = d.target_url)
+			    OR (b.source_url_canonical = d.source_url_canonical AND b.target_url_canonical = d.target_url_canonical))
+		  )
+	`
+
+// discoveredLinkColumns are the columns of discovered_links d scanned by
+// scanDiscoveredLink.
+const discoveredLinkColumns = `d.id, d.project_id, d.run_id, d.source_url, d.target_url, d.anchor_text, d.link_type,
+		       d.adopted_backlink_id, d.discovered_at`
+
+// ListUntracked returns discovered links that are neither adopted nor already
+// tracked as backlinks of the project, either verbatim or as an equivalent
+// URL variant.
+func (r *DiscoveryRepository) ListUntracked(ctx context.Context, projectID int64, page, perPage int) ([]*model.DiscoveredLink, int64, error) {
+	whereClause := untrackedWhere
+
+	var total int64
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM discovered_links d"+whereClause, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * perPage
+	query := `
+		SELECT ` + discoveredLinkColumns + `
+		FROM discovered_links d
+	` + whereClause + `
+		ORDER BY d.discovered_at DESC, d.id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.Query(ctx, query, projectID, perPage, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var links []*model.DiscoveredLink
+	for rows.Next() {
+		link, err := scanDiscoveredLink(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		links = append(links, link)
+	}
+
+	return links, total, rows.Err()
+}
+
+// ListUntrackedAfter returns up to limit untracked discovered links of a
+// project after the given position, newest first, and whether more follow.
+func (r *DiscoveryRepository) ListUntrackedAfter(ctx context.Context, projectID int64, after *model.TimeCursor, limit int) ([]*model.DiscoveredLink, bool, error) {
+	whereClause := untrackedWhere
+	args := []interface{}{projectID}
+	if after != nil {
+		whereClause += " AND (d.discovered_at, d.id) < ($2, $3)"
+		args = append(args, after.At, after.ID)
+	}
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM discovered_links d
+		%s
+		ORDER BY d.discovered_at DESC, d.id DESC
+		LIMIT $%d
+	`, discoveredLinkColumns, whereClause, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var links []*model.DiscoveredLink
+	for rows.Next() {
+		link, err := scanDiscoveredLink(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if len(links) > limit {
+		return links[:limit], true, nil
+	}
+	return links, false, nil
+}
+
+func (r *DiscoveryRepository) GetLink(ctx context.Context, id int64) (*model.DiscoveredLink, error) {
+	query := `
+		SELECT id, project_id, run_id, source_url, target_url, anchor_text, link_type,
+		       adopted_backlink_id, discovered_at
+		FROM discovered_links
+		WHERE id = $1
+	`
+
+	link, err := scanDiscoveredLink(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDiscoveredLinkNotFound
+		}
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// Adopt creates the backlink for a discovered link and marks the link
+// adopted in one transaction. It fails with ErrDiscoveredLinkAdopted when
+// another request adopted the link first, and with ErrDuplicateBacklink when
+// the project already tracks the link.
+func (r *DiscoveryRepository) Adopt(ctx context.Context, linkID int64, backlink *model.Backlink) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, insertBacklinkQuery+` RETURNING id, created_at`, insertArgs(backlink)...).
+		Scan(&backlink.ID, &backlink.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateBacklink
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE discovered_links SET adopted_backlink_id = $1
+		WHERE id = $2 AND adopted_backlink_id IS NULL
+	`, backlink.ID, linkID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrDiscoveredLinkAdopted
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListWithoutCanonical returns up to limit discovered links with id above
+// afterID that lack canonical URLs.
+func (r *DiscoveryRepository) ListWithoutCanonical(ctx context.Context, afterID int64, limit int) ([]*model.DiscoveredLink, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, project_id, source_url, target_url
+		FROM discovered_links
+		WHERE (source_url_canonical IS NULL OR target_url_canonical IS NULL) AND id > $1
+		ORDER BY id
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*model.DiscoveredLink
+	for rows.Next() {
+		l := &model.DiscoveredLink{}
+		if err := rows.Scan(&l.ID, &l.ProjectID, &l.SourceURL, &l.TargetURL); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+
+	return links, rows.Err()
+}
+
+// SetCanonical stores computed canonical URLs for a batch of discovered
+// links.
+func (r *DiscoveryRepository) SetCanonical(ctx context.Context, links []*model.DiscoveredLink) error {
+	batch := &pgx.Batch{}
+	for _, l := range links {
+		batch.Queue(`UPDATE discovered_links SET source_url_canonical = $1, target_url_canonical = $2 WHERE id = $3`,
+			nullIfEmpty(l.SourceURLCanonical), nullIfEmpty(l.TargetURLCanonical), l.ID)
+	}
+	return r.db.SendBatch(ctx, batch).Close()
+}
+
+func scanDiscoveredLink(row pgx.Row) (*model.DiscoveredLink, error) {
+	link := &model.DiscoveredLink{}
+	err := row.Scan(
+		&link.ID,
+		&link.ProjectID,
+		&link.RunID,
+		&link.SourceURL,
+		&link.TargetURL,
+		&link.AnchorText,
+		&link.LinkType,
+		&link.AdoptedBacklinkID,
+		&link.DiscoveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/backlink-service/internal/model"
+)
+
+var (
+	ErrImportNotFound  = errors.New("import not found")
+	ErrImportCommitted = errors.New("import already committed")
+)
+
+type ImportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewImportRepository(db *pgxpool.Pool) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+func (r *ImportRepository) Create(ctx context.Context, imp *model.BacklinkImport) error {
+	query := `
+		INSERT INTO backlink_imports (project_id, user_id, format, parsed_rows)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query,
+		imp.ProjectID,
+		imp.UserID,
+		imp.Format,
+		imp.Rows,
+	).Scan(&imp.ID, &imp.CreatedAt)
+}
+
+func (r *ImportRepository) GetByID(ctx context.Context, id int64) (*model.BacklinkImport, error) {
+	query := `
+		SELECT id, project_id, user_id, format, parsed_rows, created_at, committed_at
+		FROM backlink_imports
+		WHERE id = $1
+	`
+
+	imp := &model.BacklinkImport{}
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&imp.ID,
+		&imp.ProjectID,
+		&imp.UserID,
+		&imp.Format,
+		&imp.Rows,
+		&imp.CreatedAt,
+		&imp.CommittedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrImportNotFound
+		}
+		return nil, err
+	}
+
+	return imp, nil
+}
+
+// MarkCommitted returns a hook for the transaction that writes the links of
+// an import. It marks the import committed together with the writes, so the
+// import is applied only once and stays open for another attempt when they
+// roll back. A concurrent commit waits for the row lock and then fails with
+// ErrImportCommitted.
+func (r *ImportRepository) MarkCommitted(id int64) TxHook {
+	return func(ctx context.Context, tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE backlink_imports SET committed_at = NOW()
+			WHERE id = $1 AND committed_at IS NULL
+		`, id)
+		if err != nil {
+			return err
+		}
+
+		if result.RowsAffected() == 0 {
+			return ErrImportCommitted
+		}
+
+		return nil
+	}
+}
+
+// DeleteBefore drops imports created before cutoff, committed or not.
+func (r *ImportRepository) DeleteBefore(ctx context.Context, cutoff time.Time) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM backlink_imports WHERE created_at < $1`, cutoff)
+	return err
+}
@@ -3,16 +3,42 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/urlcanon"
 )
 
 var (
 	ErrProjectNotFound = errors.New("project not found")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrMemberNotFound  = errors.New("project member not found")
 )
 
+// projectColumns are the columns of projects p scanned by scanProject,
+// followed by the workspace and share roles of the user joined by
+// projectAccess.
+const projectColumns = `p.id, p.name, p.user_id, p.google_sheet_id, p.url_rules, p.anchor_settings,
+	p.workspace_id, p.created_at, wm.role, pm.role`
+
+// projectAccess joins the memberships of the user given as $1.
+const projectAccess = `
+	LEFT JOIN active_workspace_members wm ON wm.workspace_id = p.workspace_id AND wm.user_id = $1
+	LEFT JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $1`
+
+// accessibleProjects is a subquery of the ids of the projects the user given
+// as argument arg can see: their own projects outside workspaces, projects
+// of their workspaces and projects shared with them.
+func accessibleProjects(arg int) string {
+	return fmt.Sprintf(`SELECT p.id FROM projects p
+		WHERE (p.workspace_id IS NULL AND p.user_id = $%[1]d)
+			OR p.workspace_id IN (SELECT workspace_id FROM active_workspace_members WHERE user_id = $%[1]d)
+			OR p.id IN (SELECT project_id FROM project_members WHERE user_id = $%[1]d)`, arg)
+}
+
 type ProjectRepository struct {
 	db *pgxpool.Pool
 }
@@ -23,8 +49,8 @@ func NewProjectRepository(db *pgxpool.Pool) *ProjectRepository {
 
 func (r *ProjectRepository) Create(ctx context.Context, project *model.Project) error {
 	query := `
-		INSERT INTO projects (name, user_id, google_sheet_id)
-		VALUES ($1, $2, $3)
+		INSERT INTO projects (name, user_id, google_sheet_id, url_rules, anchor_settings, workspace_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at
 	`
 
@@ -32,27 +58,25 @@ func (r *ProjectRepository) Create(ctx context.Context, project *model.Project)
 		project.Name,
 		project.UserID,
 		project.GoogleSheetID,
+		project.URLRules,
+		project.AnchorSettings,
+		project.WorkspaceID,
 	).Scan(&project.ID, &project.CreatedAt)
 
 	return err
 }
 
-func (r *ProjectRepository) GetByID(ctx context.Context, id int64) (*model.Project, error) {
-	query := `
-		SELECT id, name, user_id, google_sheet_id, created_at
-		FROM projects
-		WHERE id = $1
-	`
-
-	project := &model.Project{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&project.ID,
-		&project.Name,
-		&project.UserID,
-		&project.GoogleSheetID,
-		&project.CreatedAt,
-	)
+// GetByID returns a project with the role of userID on it, which is empty
+// when the user has no access.
+func (r *ProjectRepository) GetByID(ctx context.Context, id, userID int64) (*model.Project, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM projects p
+		%s
+		WHERE p.id = $2
+	`, projectColumns, projectAccess)
 
+	project, err := scanProject(r.db.QueryRow(ctx, query, userID, id), userID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrProjectNotFound
@@ -63,15 +87,54 @@ func (r *ProjectRepository) GetByID(ctx context.Context, id int64) (*model.Proje
 	return project, nil
 }
 
+// GetByUserID returns the projects userID can see with their role.
 func (r *ProjectRepository) GetByUserID(ctx context.Context, userID int64) ([]*model.Project, error) {
-	query := `
-		SELECT id, name, user_id, google_sheet_id, created_at
-		FROM projects
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-	`
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM projects p
+		%s
+		WHERE p.id IN (%s)
+		ORDER BY p.created_at DESC, p.id DESC
+	`, projectColumns, projectAccess, accessibleProjects(1))
 
-	rows, err := r.db.Query(ctx, query, userID)
+	return r.queryProjects(ctx, userID, query, userID)
+}
+
+// ListAfter returns up to limit projects userID can see after the given
+// position, newest first, and whether more follow. A nil after starts at the
+// newest project.
+func (r *ProjectRepository) ListAfter(ctx context.Context, userID int64, after *model.TimeCursor, limit int) ([]*model.Project, bool, error) {
+	conditions := fmt.Sprintf("p.id IN (%s)", accessibleProjects(1))
+	args := []interface{}{userID}
+	if after != nil {
+		conditions += " AND (p.created_at, p.id) < ($2, $3)"
+		args = append(args, after.At, after.ID)
+	}
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM projects p
+		%s
+		WHERE %s
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $%d
+	`, projectColumns, projectAccess, conditions, len(args))
+
+	projects, err := r.queryProjects(ctx, userID, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(projects) > limit {
+		return projects[:limit], true, nil
+	}
+	return projects, false, nil
+}
+
+// queryProjects runs a query selecting projectColumns and scans the projects
+// with the role of userID.
+func (r *ProjectRepository) queryProjects(ctx context.Context, userID int64, query string, args ...interface{}) ([]*model.Project, error) {
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -79,14 +142,7 @@ func (r *ProjectRepository) GetByUserID(ctx context.Context, userID int64) ([]*m
 
 	var projects []*model.Project
 	for rows.Next() {
-		project := &model.Project{}
-		err := rows.Scan(
-			&project.ID,
-			&project.Name,
-			&project.UserID,
-			&project.GoogleSheetID,
-			&project.CreatedAt,
-		)
+		project, err := scanProject(rows, userID)
 		if err != nil {
 			return nil, err
 		}
@@ -97,15 +153,30 @@ func (r *ProjectRepository) GetByUserID(ctx context.Context, userID int64) ([]*m
 }
 
 func (r *ProjectRepository) Update(ctx context.Context, project *model.Project) error {
+	return updateProject(ctx, r.db, project)
+}
+
+// Updating returns a hook that updates a project inside another write
+// transaction.
+func (r *ProjectRepository) Updating(project *model.Project) TxHook {
+	return func(ctx context.Context, tx pgx.Tx) error {
+		return updateProject(ctx, tx, project)
+	}
+}
+
+func updateProject(ctx context.Context, db execer, project *model.Project) error {
 	query := `
 		UPDATE projects
-		SET name = $1, google_sheet_id = $2
-		WHERE id = $3
+		SET name = $1, google_sheet_id = $2, url_rules = $3, anchor_settings = $4, workspace_id = $5
+		WHERE id = $6
 	`
 
-	result, err := r.db.Exec(ctx, query,
+	result, err := db.Exec(ctx, query,
 		project.Name,
 		project.GoogleSheetID,
+		project.URLRules,
+		project.AnchorSettings,
+		project.WorkspaceID,
 		project.ID,
 	)
 
@@ -134,9 +205,253 @@ func (r *ProjectRepository) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func (r *ProjectRepository) IsOwner(ctx context.Context, projectID, userID int64) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM projects WHERE id = $1 AND user_id = $2)`
-	var exists bool
-	err := r.db.QueryRow(ctx, query, projectID, userID).Scan(&exists)
-	return exists, err
+// CountUsage returns the number of projects the user created and of the
+// backlinks in them.
+func (r *ProjectRepository) CountUsage(ctx context.Context, userID int64) (projects, backlinks int64, err error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM projects WHERE user_id = $1),
+			(SELECT COUNT(*) FROM backlinks b JOIN projects p ON p.id = b.project_id WHERE p.user_id = $1)
+	`
+	err = r.db.QueryRow(ctx, query, userID).Scan(&projects, &backlinks)
+	return projects, backlinks, err
+}
+
+// ListCreatedBy returns the projects the user created, including those in
+// workspaces.
+func (r *ProjectRepository) ListCreatedBy(ctx context.Context, userID int64) ([]*model.Project, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM projects p
+		%s
+		WHERE p.user_id = $1
+		ORDER BY p.created_at
+	`, projectColumns, projectAccess)
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []*model.Project
+	for rows.Next() {
+		project, err := scanProject(rows, userID)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+// personalProjects matches the projects of the user given as $1 that go
+// with their account: those outside workspaces and those of workspaces
+// nobody else is a member of. Projects of shared workspaces stay with the
+// team.
+const personalProjects = `p.user_id = $1 AND (p.workspace_id IS NULL OR NOT EXISTS (
+	SELECT 1 FROM workspace_members wm WHERE wm.workspace_id = p.workspace_id AND wm.user_id <> $1))`
+
+// workspaceHeirs lists, for every workspace with members other than the
+// user given as $1, the member who owns it once the user is gone: the
+// longest-standing other owner or, without one, the longest-standing
+// member, whom auth-service makes owner. Users who are not disabled come
+// first.
+const workspaceHeirs = `SELECT DISTINCT ON (wm.workspace_id) wm.workspace_id, wm.user_id
+	FROM workspace_members wm
+	JOIN users u ON u.id = wm.user_id
+	WHERE wm.user_id <> $1
+	ORDER BY wm.workspace_id, u.disabled_at IS NULL DESC, wm.role = 'owner' DESC, wm.created_at, wm.user_id`
+
+// DeleteUserData deletes the personal projects of a user with their links,
+// the projects shared with them and the imports they started. Projects the
+// user created in shared workspaces pass to the workspace's heir. It
+// returns the number of deleted projects and backlinks.
+func (r *ProjectRepository) DeleteUserData(ctx context.Context, userID int64) (projects, backlinks int64, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM backlinks b JOIN projects p ON p.id = b.project_id WHERE `+personalProjects,
+		userID).Scan(&backlinks)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM projects p WHERE `+personalProjects, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	projects = result.RowsAffected()
+
+	_, err = tx.Exec(ctx, `
+		UPDATE projects p
+		SET user_id = heir.user_id
+		FROM (`+workspaceHeirs+`) heir
+		WHERE p.user_id = $1 AND p.workspace_id = heir.workspace_id
+	`, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM project_members WHERE user_id = $1`, userID); err != nil {
+		return 0, 0, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM backlink_imports WHERE user_id = $1`, userID); err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+	return projects, backlinks, nil
+}
+
+// GetURLRules returns the URL equivalence rules of a project.
+func (r *ProjectRepository) GetURLRules(ctx context.Context, projectID int64) (urlcanon.Rules, error) {
+	var rules *urlcanon.Rules
+	err := r.db.QueryRow(ctx, `SELECT url_rules FROM projects WHERE id = $1`, projectID).Scan(&rules)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return urlcanon.Rules{}, ErrProjectNotFound
+		}
+		return urlcanon.Rules{}, err
+	}
+	if rules == nil {
+		return urlcanon.DefaultRules(), nil
+	}
+	return *rules, nil
+}
+
+// HasRole reports whether userID has at least role on the project. It
+// is false for projects that do not exist.
+func (r *ProjectRepository) HasRole(ctx context.Context, projectID, userID int64, role models.MemberRole) (bool, error) {
+	project, err := r.GetByID(ctx, projectID, userID)
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return project.Role.Includes(role), nil
+}
+
+// WorkspaceRole returns the role of userID in a workspace, or "" when the
+// user is not a member or lacks the MFA the workspace requires.
+func (r *ProjectRepository) WorkspaceRole(ctx context.Context, workspaceID, userID int64) (models.MemberRole, error) {
+	query := `SELECT role FROM active_workspace_members WHERE workspace_id = $1 AND user_id = $2`
+	var role models.MemberRole
+	err := r.db.QueryRow(ctx, query, workspaceID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// FindUserByEmail returns the id of the registered user with the email.
+// Disabled users are not found, so projects cannot be shared with them.
+func (r *ProjectRepository) FindUserByEmail(ctx context.Context, email string) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, `SELECT id FROM users WHERE lower(email) = lower($1) AND disabled_at IS NULL`, email).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	return id, err
+}
+
+func (r *ProjectRepository) ListMembers(ctx context.Context, projectID int64) ([]*model.ProjectMember, error) {
+	query := `
+		SELECT pm.project_id, pm.user_id, COALESCE(u.email, ''), COALESCE(u.name, ''), pm.role, pm.created_at
+		FROM project_members pm
+		LEFT JOIN users u ON u.id = pm.user_id
+		WHERE pm.project_id = $1
+		ORDER BY pm.created_at, pm.user_id
+	`
+
+	rows, err := r.db.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*model.ProjectMember
+	for rows.Next() {
+		member := &model.ProjectMember{}
+		err := rows.Scan(
+			&member.ProjectID,
+			&member.UserID,
+			&member.Email,
+			&member.Name,
+			&member.Role,
+			&member.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// SetMember shares the project with a user or changes their role.
+func (r *ProjectRepository) SetMember(ctx context.Context, member *model.ProjectMember) error {
+	query := `
+		INSERT INTO project_members (project_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (project_id, user_id) DO UPDATE SET role = EXCLUDED.role
+		RETURNING created_at
+	`
+	return r.db.QueryRow(ctx, query, member.ProjectID, member.UserID, member.Role).Scan(&member.CreatedAt)
+}
+
+func (r *ProjectRepository) RemoveMember(ctx context.Context, projectID, userID int64) error {
+	query := `DELETE FROM project_members WHERE project_id = $1 AND user_id = $2`
+	result, err := r.db.Exec(ctx, query, projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
+
+// scanProject scans projectColumns and resolves the role of userID.
+func scanProject(row pgx.Row, userID int64) (*model.Project, error) {
+	project := &model.Project{}
+	var workspaceRole, shareRole *models.MemberRole
+	err := row.Scan(
+		&project.ID,
+		&project.Name,
+		&project.UserID,
+		&project.GoogleSheetID,
+		&project.URLRules,
+		&project.AnchorSettings,
+		&project.WorkspaceID,
+		&project.CreatedAt,
+		&workspaceRole,
+		&shareRole,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.WorkspaceID == nil && project.UserID == userID {
+		project.Role = models.MemberOwner
+	}
+	if workspaceRole != nil {
+		project.Role = models.HigherRole(project.Role, *workspaceRole)
+	}
+	if shareRole != nil {
+		project.Role = models.HigherRole(project.Role, *shareRole)
+	}
+
+	return project, nil
 }
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/backlink-service/internal/repository"
+)
+
+// AccountService exports and deletes the data of a user account. It backs
+// the account export and deletion of auth-service and does not check
+// access; the routes do.
+type AccountService struct {
+	projectRepo   *repository.ProjectRepository
+	backlinkRepo  *repository.BacklinkRepository
+	discoveryRepo *repository.DiscoveryRepository
+}
+
+func NewAccountService(
+	projectRepo *repository.ProjectRepository,
+	backlinkRepo *repository.BacklinkRepository,
+	discoveryRepo *repository.DiscoveryRepository,
+) *AccountService {
+	return &AccountService{
+		projectRepo:   projectRepo,
+		backlinkRepo:  backlinkRepo,
+		discoveryRepo: discoveryRepo,
+	}
+}
+
+// Export returns the projects the user created with their links and
+// discovery runs.
+func (s *AccountService) Export(ctx context.Context, userID int64) (*model.AccountExport, error) {
+	projects, err := s.projectRepo.ListCreatedBy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &model.AccountExport{
+		UserID:     userID,
+		ExportedAt: time.Now().UTC(),
+		Projects:   make([]*model.ProjectExport, 0, len(projects)),
+	}
+	for _, project := range projects {
+		backlinks, err := s.backlinkRepo.ListByProject(ctx, project.ID)
+		if err != nil {
+			return nil, err
+		}
+		runs, err := s.discoveryRepo.ListRuns(ctx, project.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		export.Projects = append(export.Projects, &model.ProjectExport{
+			Project:       project,
+			Backlinks:     backlinks,
+			DiscoveryRuns: runs,
+		})
+	}
+
+	return export, nil
+}
+
+// DeleteData deletes the personal projects of the user and their shares of
+// other projects, and returns what was deleted. Projects of workspaces the
+// user shares with others are kept for the team and handed over to the
+// workspace's heir.
+func (s *AccountService) DeleteData(ctx context.Context, userID int64) (*model.UserUsageResponse, error) {
+	projects, backlinks, err := s.projectRepo.DeleteUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserUsageResponse{UserID: userID, Projects: projects, Backlinks: backlinks}, nil
+}
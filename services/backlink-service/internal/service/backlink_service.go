@@ -3,20 +3,26 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/link-tracker/backlink-service/internal/model"
 	"github.com/link-tracker/backlink-service/internal/repository"
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/response"
+	"github.com/link-tracker/shared/pkg/urlcanon"
 )
 
 var (
-	ErrUnauthorized    = errors.New("unauthorized access to resource")
-	ErrValidation      = errors.New("validation error")
-	ErrProjectRequired = errors.New("project_id is required")
+	ErrUnauthorized = errors.New("unauthorized access to resource")
+	ErrValidation   = errors.New("validation error")
 )
 
 type BacklinkService struct {
 	backlinkRepo *repository.BacklinkRepository
 	projectRepo  *repository.ProjectRepository
+	checker      *LinkChecker
 }
 
 func NewBacklinkService(
@@ -26,37 +32,43 @@ func NewBacklinkService(
 	return &BacklinkService{
 		backlinkRepo: backlinkRepo,
 		projectRepo:  projectRepo,
+		checker:      NewLinkChecker(),
 	}
 }
 
-func (s *BacklinkService) Create(ctx context.Context, userID int64, req *model.CreateBacklinkRequest) (*model.Backlink, error) {
-	// Verify project ownership
-	isOwner, err := s.projectRepo.IsOwner(ctx, req.ProjectID, userID)
+// Create adds a link to a project. created is false when an existing link
+// was skipped or updated according to req.OnConflict; that link is returned.
+func (s *BacklinkService) Create(ctx context.Context, userID int64, req *model.CreateBacklinkRequest) (backlink *model.Backlink, created bool, err error) {
+	if err := validateConflictMode(req.OnConflict); err != nil {
+		return nil, false, err
+	}
+
+	// Verify project access
+	allowed, err := s.projectRepo.HasRole(ctx, req.ProjectID, userID, models.MemberEditor)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	if !isOwner {
-		return nil, ErrUnauthorized
+	if !allowed {
+		return nil, false, ErrUnauthorized
 	}
 
-	backlink := &model.Backlink{
-		ProjectID:  req.ProjectID,
-		SourceURL:  req.SourceURL,
-		TargetURL:  req.TargetURL,
-		AnchorText: req.AnchorText,
-		Status:     model.LinkStatusPending,
-		LinkType:   req.LinkType,
+	backlink, err = newBacklink(req)
+	if err != nil {
+		return nil, false, err
 	}
 
-	if backlink.LinkType == "" {
-		backlink.LinkType = model.LinkTypeDoFollow
+	rules, err := s.projectRepo.GetURLRules(ctx, backlink.ProjectID)
+	if err != nil {
+		return nil, false, err
 	}
+	setCanonical(backlink, rules)
 
-	if err := s.backlinkRepo.Create(ctx, backlink); err != nil {
-		return nil, err
+	backlink, outcome, err := storeBacklink(ctx, s.backlinkRepo, backlink, req, req.OnConflict)
+	if err != nil {
+		return nil, false, err
 	}
 
-	return backlink, nil
+	return backlink, outcome == storeCreated, nil
 }
 
 func (s *BacklinkService) GetByID(ctx context.Context, userID, backlinkID int64) (*model.Backlink, error) {
@@ -65,50 +77,289 @@ func (s *BacklinkService) GetByID(ctx context.Context, userID, backlinkID int64)
 		return nil, err
 	}
 
-	// Verify project ownership
-	isOwner, err := s.projectRepo.IsOwner(ctx, backlink.ProjectID, userID)
+	// Verify project access
+	allowed, err := s.projectRepo.HasRole(ctx, backlink.ProjectID, userID, models.MemberViewer)
 	if err != nil {
 		return nil, err
 	}
-	if !isOwner {
+	if !allowed {
 		return nil, ErrUnauthorized
 	}
 
 	return backlink, nil
 }
 
+// List returns links of one project, or of all projects of the user when no
+// project is given.
 func (s *BacklinkService) List(ctx context.Context, userID int64, filters *model.BacklinkFilters) ([]*model.Backlink, int64, error) {
-	// If project_id is specified, verify ownership
+	found, err := s.prepareList(ctx, userID, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !found {
+		return []*model.Backlink{}, 0, nil
+	}
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+
+	return s.backlinkRepo.List(ctx, filters)
+}
+
+// ListCursor returns a page of links after filters.After. The cursor must
+// come from a list with the same sort. Counting the matching links is
+// optional as it dominates the cost of deep pages on large projects.
+func (s *BacklinkService) ListCursor(ctx context.Context, userID int64, filters *model.BacklinkFilters, totalMode model.TotalMode) (*model.BacklinkCursorPage, error) {
+	page := &model.BacklinkCursorPage{Backlinks: []*model.Backlink{}}
+
+	switch totalMode {
+	case "":
+		totalMode = model.TotalNone
+	case model.TotalNone, model.TotalEstimate, model.TotalExact:
+	default:
+		return nil, fmt.Errorf("%w: total must be one of: none, estimate, exact", ErrValidation)
+	}
+
+	found, err := s.prepareList(ctx, userID, filters)
+	if err != nil {
+		return nil, err
+	}
+	if c := filters.After; c != nil {
+		if c.SortBy != filters.SortBy || c.Order != filters.Order {
+			return nil, fmt.Errorf("%w: cursor belongs to a list with a different sort", ErrValidation)
+		}
+		if !validCursor(c) {
+			return nil, response.ErrInvalidCursor
+		}
+	}
+	if !found {
+		if totalMode != model.TotalNone {
+			var zero int64
+			page.Total = &zero
+		}
+		return page, nil
+	}
+
+	backlinks, hasMore, err := s.backlinkRepo.ListAfter(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	if backlinks != nil {
+		page.Backlinks = backlinks
+	}
+	if hasMore {
+		page.Next = cursorAfter(backlinks[len(backlinks)-1], filters)
+	}
+
+	switch totalMode {
+	case model.TotalExact:
+		total, err := s.backlinkRepo.Count(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	case model.TotalEstimate:
+		total, err := s.backlinkRepo.EstimateCount(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+		page.TotalEstimated = true
+	}
+
+	return page, nil
+}
+
+// cursorAfter returns the position after b in a list sorted as filters say.
+func cursorAfter(b *model.Backlink, filters *model.BacklinkFilters) *model.BacklinkCursor {
+	c := &model.BacklinkCursor{SortBy: filters.SortBy, Order: filters.Order, ID: b.ID}
+	var value string
+	switch filters.SortBy {
+	case model.SortLastCheckedAt:
+		if b.LastCheckedAt != nil {
+			value = b.LastCheckedAt.UTC().Format(time.RFC3339Nano)
+		}
+	case model.SortStatus:
+		value = string(b.Status)
+	case model.SortDomain:
+		value = b.SourceDomain
+	default:
+		value = b.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	if value != "" {
+		c.Value = &value
+	}
+	return c
+}
+
+// validTimeCursor reports whether c can be a position in a list sorted by a
+// timestamp and id.
+func validTimeCursor(c *model.TimeCursor) bool {
+	return c.ID > 0 && !c.At.IsZero()
+}
+
+// validDuplicateCursor reports whether c can be the position of a duplicate
+// group, which has at least two links.
+func validDuplicateCursor(c *model.DuplicateCursor) bool {
+	return c.ID > 0 && c.Count > 1
+}
+
+// validCursor reports whether the position in c can be compared with the
+// sort column. Cursors are not signed, so the value is checked here rather
+// than left to fail as a cast in the query.
+func validCursor(c *model.BacklinkCursor) bool {
+	if c.ID <= 0 {
+		return false
+	}
+	if c.Value == nil {
+		return true
+	}
+	switch c.SortBy {
+	case model.SortStatus:
+		switch model.LinkStatus(*c.Value) {
+		case model.LinkStatusPending, model.LinkStatusActive, model.LinkStatusBroken,
+			model.LinkStatusRemoved, model.LinkStatusNoFollow:
+			return true
+		}
+		return false
+	case model.SortDomain:
+		return true
+	default:
+		_, err := time.Parse(time.RFC3339Nano, *c.Value)
+		return err == nil
+	}
+}
+
+// prepareList checks access, applies defaults and resolves URL filters to
+// canonical keys. It reports false when the user has no projects to list.
+func (s *BacklinkService) prepareList(ctx context.Context, userID int64, filters *model.BacklinkFilters) (bool, error) {
+	var rules []urlcanon.Rules
 	if filters.ProjectID != nil {
-		isOwner, err := s.projectRepo.IsOwner(ctx, *filters.ProjectID, userID)
+		allowed, err := s.projectRepo.HasRole(ctx, *filters.ProjectID, userID, models.MemberViewer)
 		if err != nil {
-			return nil, 0, err
+			return false, err
 		}
-		if !isOwner {
-			return nil, 0, ErrUnauthorized
+		if !allowed {
+			return false, ErrUnauthorized
 		}
 	} else {
-		// Get all user's projects and filter by them
 		projects, err := s.projectRepo.GetByUserID(ctx, userID)
 		if err != nil {
-			return nil, 0, err
+			return false, err
 		}
-		if len(projects) == 0 {
-			return []*model.Backlink{}, 0, nil
+		filters.UserID = &userID
+		for _, p := range projects {
+			rules = append(rules, p.Rules())
 		}
-		// For simplicity, require project_id filter
-		return nil, 0, ErrProjectRequired
 	}
 
 	// Set defaults
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
 	if filters.PerPage < 1 || filters.PerPage > 100 {
 		filters.PerPage = 20
 	}
+	if err := normalizeListFilters(filters); err != nil {
+		return false, err
+	}
+	if filters.ProjectID == nil && len(rules) == 0 {
+		return false, nil
+	}
 
-	return s.backlinkRepo.List(ctx, filters)
+	// A full URL matches all its variants; anything else is a substring search
+	if (filters.SourceURL != nil && urlcanon.IsAbsolute(*filters.SourceURL)) ||
+		(filters.TargetURL != nil && urlcanon.IsAbsolute(*filters.TargetURL)) {
+		if filters.ProjectID != nil {
+			projectRules, err := s.projectRepo.GetURLRules(ctx, *filters.ProjectID)
+			if err != nil {
+				return false, err
+			}
+			rules = []urlcanon.Rules{projectRules}
+		}
+		if filters.SourceURL != nil && urlcanon.IsAbsolute(*filters.SourceURL) {
+			filters.SourceURLCanonical = canonicalKeys(*filters.SourceURL, rules)
+			filters.SourceURL = nil
+		}
+		if filters.TargetURL != nil && urlcanon.IsAbsolute(*filters.TargetURL) {
+			filters.TargetURLCanonical = canonicalKeys(*filters.TargetURL, rules)
+			filters.TargetURL = nil
+		}
+	}
+
+	return true, nil
+}
+
+// normalizeListFilters validates sorting and turns the date ranges into
+// time bounds.
+func normalizeListFilters(filters *model.BacklinkFilters) error {
+	switch filters.SortBy {
+	case "":
+		filters.SortBy = model.SortCreatedAt
+	case model.SortCreatedAt, model.SortLastCheckedAt, model.SortStatus, model.SortDomain:
+	default:
+		return fmt.Errorf("%w: sort_by must be one of: created_at, last_checked_at, status, domain", ErrValidation)
+	}
+	switch filters.Order {
+	case "":
+		// Newest first for dates, alphabetical otherwise
+		filters.Order = model.OrderDesc
+		if filters.SortBy == model.SortStatus || filters.SortBy == model.SortDomain {
+			filters.Order = model.OrderAsc
+		}
+	case model.OrderAsc, model.OrderDesc:
+	default:
+		return fmt.Errorf("%w: order must be asc or desc", ErrValidation)
+	}
+
+	var err error
+	if filters.CreatedAfter, filters.CreatedBefore, err = dateRange(filters.CreatedFrom, filters.CreatedTo, "created"); err != nil {
+		return err
+	}
+	if filters.CheckedAfter, filters.CheckedBefore, err = dateRange(filters.CheckedFrom, filters.CheckedTo, "checked"); err != nil {
+		return err
+	}
+
+	if filters.Search != nil && strings.TrimSpace(*filters.Search) == "" {
+		filters.Search = nil
+	}
+	return nil
+}
+
+// dateRange parses an inclusive range of YYYY-MM-DD dates into a start and
+// an exclusive end.
+func dateRange(from, to *string, field string) (after, before *time.Time, err error) {
+	if from != nil {
+		if after, err = parseDate(*from, field+"_from"); err != nil {
+			return nil, nil, err
+		}
+	}
+	if to != nil {
+		end, err := parseDate(*to, field+"_to")
+		if err != nil {
+			return nil, nil, err
+		}
+		if end != nil {
+			next := end.AddDate(0, 0, 1)
+			before = &next
+		}
+	}
+	if after != nil && before != nil && !after.Before(*before) {
+		return nil, nil, fmt.Errorf("%w: %s_from must not be after %s_to", ErrValidation, field, field)
+	}
+	return after, before, nil
+}
+
+// canonicalKeys returns the distinct canonical forms of rawURL under each of
+// rules.
+func canonicalKeys(rawURL string, rules []urlcanon.Rules) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, r := range rules {
+		k := urlcanon.Key(rawURL, r)
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
 }
 
 func (s *BacklinkService) Update(ctx context.Context, userID, backlinkID int64, req *model.UpdateBacklinkRequest) (*model.Backlink, error) {
@@ -117,15 +368,18 @@ func (s *BacklinkService) Update(ctx context.Context, userID, backlinkID int64,
 		return nil, err
 	}
 
-	// Verify project ownership
-	isOwner, err := s.projectRepo.IsOwner(ctx, backlink.ProjectID, userID)
+	// Verify project access
+	allowed, err := s.projectRepo.HasRole(ctx, backlink.ProjectID, userID, models.MemberEditor)
 	if err != nil {
 		return nil, err
 	}
-	if !isOwner {
+	if !allowed {
 		return nil, ErrUnauthorized
 	}
 
+	urlsChanged := (req.SourceURL != nil && *req.SourceURL != backlink.SourceURL) ||
+		(req.TargetURL != nil && *req.TargetURL != backlink.TargetURL)
+
 	// Apply updates
 	if req.SourceURL != nil {
 		backlink.SourceURL = *req.SourceURL
@@ -137,11 +391,24 @@ func (s *BacklinkService) Update(ctx context.Context, userID, backlinkID int64,
 		backlink.AnchorText = *req.AnchorText
 	}
 	if req.Status != nil {
-		backlink.Status = *req.Status
+		setStatus(backlink, *req.Status)
 	}
 	if req.LinkType != nil {
 		backlink.LinkType = *req.LinkType
 	}
+	if err := applyCommercialTerms(backlink, req.Vendor, req.Price, req.Currency, req.PlacedAt, req.PaidUntil); err != nil {
+		return nil, err
+	}
+
+	// Unchanged URLs keep their key, so editing a known duplicate does not
+	// collide with the link holding the key.
+	if urlsChanged {
+		rules, err := s.projectRepo.GetURLRules(ctx, backlink.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		setCanonical(backlink, rules)
+	}
 
 	if err := s.backlinkRepo.Update(ctx, backlink); err != nil {
 		return nil, err
@@ -156,68 +423,82 @@ func (s *BacklinkService) Delete(ctx context.Context, userID, backlinkID int64)
 		return err
 	}
 
-	// Verify project ownership
-	isOwner, err := s.projectRepo.IsOwner(ctx, projectID, userID)
+	// Verify project access
+	allowed, err := s.projectRepo.HasRole(ctx, projectID, userID, models.MemberEditor)
 	if err != nil {
 		return err
 	}
-	if !isOwner {
+	if !allowed {
 		return ErrUnauthorized
 	}
 
 	return s.backlinkRepo.Delete(ctx, backlinkID)
 }
 
+// Check verifies the link on the source page and records the donor audit.
+func (s *BacklinkService) Check(ctx context.Context, userID, backlinkID int64) (*model.Backlink, error) {
+	backlink, err := s.backlinkRepo.GetByID(ctx, backlinkID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checking records the result, so viewers may not trigger it
+	allowed, err := s.projectRepo.HasRole(ctx, backlink.ProjectID, userID, models.MemberEditor)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrUnauthorized
+	}
+
+	rules, err := s.projectRepo.GetURLRules(ctx, backlink.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := backlink.Status
+	s.checker.Check(ctx, backlink, rules)
+	newStatus := backlink.Status
+	backlink.Status = previous
+	setStatus(backlink, newStatus)
+
+	if err := s.backlinkRepo.UpdateCheckResult(ctx, backlink); err != nil {
+		return nil, err
+	}
+
+	return backlink, nil
+}
+
 func (s *BacklinkService) BulkCreate(ctx context.Context, userID int64, req *model.BulkCreateBacklinksRequest) (*model.BulkOperationResponse, error) {
 	if len(req.Backlinks) == 0 {
 		return &model.BulkOperationResponse{Success: 0, Failed: 0}, nil
 	}
+	if err := validateConflictMode(req.OnConflict); err != nil {
+		return nil, err
+	}
 
-	// Verify ownership for all projects
+	// Verify access to all projects
 	projectIDs := make(map[int64]bool)
 	for _, b := range req.Backlinks {
 		projectIDs[b.ProjectID] = true
 	}
 
 	for projectID := range projectIDs {
-		isOwner, err := s.projectRepo.IsOwner(ctx, projectID, userID)
+		allowed, err := s.projectRepo.HasRole(ctx, projectID, userID, models.MemberEditor)
 		if err != nil {
 			return nil, err
 		}
-		if !isOwner {
+		if !allowed {
 			return nil, ErrUnauthorized
 		}
 	}
 
-	// Create backlinks
-	backlinks := make([]*model.Backlink, len(req.Backlinks))
-	for i, b := range req.Backlinks {
-		linkType := b.LinkType
-		if linkType == "" {
-			linkType = model.LinkTypeDoFollow
-		}
-		backlinks[i] = &model.Backlink{
-			ProjectID:  b.ProjectID,
-			SourceURL:  b.SourceURL,
-			TargetURL:  b.TargetURL,
-			AnchorText: b.AnchorText,
-			Status:     model.LinkStatusPending,
-			LinkType:   linkType,
-		}
-	}
-
-	success, errs := s.backlinkRepo.BulkCreate(ctx, backlinks)
-
-	var errStrings []string
-	for _, e := range errs {
-		errStrings = append(errStrings, e.Error())
+	inputs := make([]bulkInput, len(req.Backlinks))
+	for i := range req.Backlinks {
+		inputs[i] = bulkInput{req: req.Backlinks[i]}
 	}
 
-	return &model.BulkOperationResponse{
-		Success: success,
-		Failed:  len(errs),
-		Errors:  errStrings,
-	}, nil
+	return bulkCreate(ctx, s.backlinkRepo, newRulesCache(s.projectRepo), inputs, req.OnConflict, req.Atomic, nil)
 }
 
 func (s *BacklinkService) BulkDelete(ctx context.Context, userID int64, req *model.BulkDeleteBacklinksRequest) (*model.BulkOperationResponse, error) {
@@ -225,7 +506,7 @@ func (s *BacklinkService) BulkDelete(ctx context.Context, userID int64, req *mod
 		return &model.BulkOperationResponse{Success: 0, Failed: 0}, nil
 	}
 
-	// Verify ownership for all backlinks
+	// Verify access to all backlinks
 	for _, id := range req.IDs {
 		projectID, err := s.backlinkRepo.GetProjectID(ctx, id)
 		if err != nil {
@@ -235,11 +516,11 @@ func (s *BacklinkService) BulkDelete(ctx context.Context, userID int64, req *mod
 			return nil, err
 		}
 
-		isOwner, err := s.projectRepo.IsOwner(ctx, projectID, userID)
+		allowed, err := s.projectRepo.HasRole(ctx, projectID, userID, models.MemberEditor)
 		if err != nil {
 			return nil, err
 		}
-		if !isOwner {
+		if !allowed {
 			return nil, ErrUnauthorized
 		}
 	}
@@ -254,3 +535,94 @@ func (s *BacklinkService) BulkDelete(ctx context.Context, userID int64, req *mod
 		Failed:  len(req.IDs) - deleted,
 	}, nil
 }
+
+// Duplicates lists groups of project links that share canonical URLs, e.g.
+// links added before uniqueness was enforced or merged by new URL rules.
+func (s *BacklinkService) Duplicates(ctx context.Context, userID, projectID int64, page, perPage int) ([]model.DuplicateGroup, int64, error) {
+	allowed, err := s.projectRepo.HasRole(ctx, projectID, userID, models.MemberViewer)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !allowed {
+		return nil, 0, ErrUnauthorized
+	}
+
+	groups, groupIDs, total, err := s.backlinkRepo.ListDuplicateGroups(ctx, projectID, page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.fillDuplicateGroups(ctx, groups, groupIDs); err != nil {
+		return nil, 0, err
+	}
+	if groups == nil {
+		groups = []model.DuplicateGroup{}
+	}
+
+	return groups, total, nil
+}
+
+// DuplicatesCursor lists duplicate groups after the given position, in the
+// order of Duplicates, and returns the cursor of the next page, nil on the
+// last one.
+func (s *BacklinkService) DuplicatesCursor(ctx context.Context, userID, projectID int64, after *model.DuplicateCursor, perPage int) ([]model.DuplicateGroup, *model.DuplicateCursor, error) {
+	if after != nil && !validDuplicateCursor(after) {
+		return nil, nil, response.ErrInvalidCursor
+	}
+	allowed, err := s.projectRepo.HasRole(ctx, projectID, userID, models.MemberViewer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !allowed {
+		return nil, nil, ErrUnauthorized
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	groups, groupIDs, hasMore, err := s.backlinkRepo.ListDuplicateGroupsAfter(ctx, projectID, after, perPage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.fillDuplicateGroups(ctx, groups, groupIDs); err != nil {
+		return nil, nil, err
+	}
+	if groups == nil {
+		groups = []model.DuplicateGroup{}
+	}
+	if !hasMore {
+		return groups, nil, nil
+	}
+	last := len(groups) - 1
+	return groups, &model.DuplicateCursor{Count: groups[last].Count, ID: groupIDs[last][0]}, nil
+}
+
+// fillDuplicateGroups loads the links of each group, given by groupIDs.
+func (s *BacklinkService) fillDuplicateGroups(ctx context.Context, groups []model.DuplicateGroup, groupIDs [][]int64) error {
+	var ids []int64
+	for _, g := range groupIDs {
+		ids = append(ids, g...)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	backlinks, err := s.backlinkRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int64]*model.Backlink, len(backlinks))
+	for _, b := range backlinks {
+		byID[b.ID] = b
+	}
+
+	for i := range groups {
+		groups[i].Backlinks = make([]model.BacklinkResponse, 0, len(groupIDs[i]))
+		for _, id := range groupIDs[i] {
+			// A link deleted between the two queries is left out.
+			if b, ok := byID[id]; ok {
+				groups[i].Backlinks = append(groups[i].Backlinks, model.BacklinkToResponse(b))
+			}
+		}
+	}
+
+	return nil
+}
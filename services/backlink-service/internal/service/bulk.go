@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/backlink-service/internal/repository"
+)
+
+// bulkInput is one item of a bulk create. err marks an item rejected before
+// the call, e.g. an unparsable import row.
+type bulkInput struct {
+	req  model.CreateBacklinkRequest
+	line int // file line for imports
+	err  error
+}
+
+// bulkKey is where an item with a given link key ends up: an existing link
+// or an earlier item of the same call.
+type bulkKey struct {
+	backlink *model.Backlink
+	op       int // index into ops, -1 while nothing is written
+	item     int // first item with the key, -1 for an existing link
+}
+
+// bulkCreate stores new links in a single transaction per attempt.
+// Duplicates of existing links and of earlier items are resolved by mode
+// before writing. When atomic is set any failed item fails the whole call;
+// otherwise a failing statement is dropped and the rest written again. A
+// non-nil hook runs in every attempt's transaction and commits only with
+// the attempt that succeeds, even when there is nothing to write.
+func bulkCreate(ctx context.Context, repo *repository.BacklinkRepository, rules *rulesCache, inputs []bulkInput, mode model.ConflictMode, atomic bool, hook repository.TxHook) (*model.BulkOperationResponse, error) {
+	n := len(inputs)
+	errs := make([]error, n)
+	outcomes := make([]storeOutcome, n)
+	opOf := make([]int, n)
+
+	backlinks := make([]*model.Backlink, n)
+	keysByProject := make(map[int64][]string)
+	for i := range inputs {
+		opOf[i] = -1
+		if inputs[i].err != nil {
+			errs[i] = inputs[i].err
+			continue
+		}
+		b, err := newBacklink(&inputs[i].req)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		projectRules, err := rules.get(ctx, b.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		setCanonical(b, projectRules)
+		backlinks[i] = b
+		keysByProject[b.ProjectID] = append(keysByProject[b.ProjectID], b.LinkKey)
+	}
+
+	seen := make(map[string]*bulkKey)
+	for projectID, keys := range keysByProject {
+		existing, err := repo.GetByLinkKeys(ctx, projectID, keys)
+		if err != nil {
+			return nil, err
+		}
+		for key, b := range existing {
+			seen[fmt.Sprintf("%d %s", projectID, key)] = &bulkKey{backlink: b, op: -1, item: -1}
+		}
+	}
+
+	var ops []*model.Backlink
+	for i, b := range backlinks {
+		if b == nil {
+			continue
+		}
+		key := fmt.Sprintf("%d %s", b.ProjectID, b.LinkKey)
+		k, dup := seen[key]
+		if !dup {
+			seen[key] = &bulkKey{backlink: b, op: len(ops), item: i}
+			opOf[i] = len(ops)
+			outcomes[i] = storeCreated
+			ops = append(ops, b)
+			continue
+		}
+
+		switch mode {
+		case model.ConflictSkip:
+			outcomes[i] = storeSkipped
+		case model.ConflictUpdate:
+			if err := mergeInto(k.backlink, &inputs[i].req); err != nil {
+				errs[i] = err
+				continue
+			}
+			if k.op < 0 {
+				k.op = len(ops)
+				ops = append(ops, k.backlink)
+			}
+			opOf[i] = k.op
+			outcomes[i] = storeUpdated
+		default:
+			if k.item >= 0 {
+				errs[i] = fmt.Errorf("%w: duplicate of item %d", repository.ErrDuplicateBacklink, k.item)
+			} else {
+				errs[i] = repository.ErrDuplicateBacklink
+			}
+		}
+	}
+
+	if atomic {
+		for _, err := range errs {
+			if err != nil {
+				return bulkFailed(inputs, errs), nil
+			}
+		}
+	}
+
+	// Links taken by a concurrent request are conflicts; under skip they
+	// are skipped, otherwise they fail.
+	opErrs := make([]error, len(ops))
+	pending := make([]int, len(ops))
+	for i := range pending {
+		pending[i] = i
+	}
+	for len(pending) > 0 || hook != nil {
+		batch := make([]*model.Backlink, len(pending))
+		for i, op := range pending {
+			batch[i] = ops[op]
+		}
+
+		conflicts, err := repo.BulkWrite(ctx, batch, atomic && mode != model.ConflictSkip, hook)
+		var batchErr *repository.BatchError
+		if errors.As(err, &batchErr) {
+			failed := pending[batchErr.Index]
+			opErrs[failed] = batchErr.Err
+			if atomic {
+				for i := range inputs {
+					if opOf[i] == failed {
+						errs[i] = batchErr.Err
+					}
+				}
+				return bulkFailed(inputs, errs), nil
+			}
+			pending = append(pending[:batchErr.Index], pending[batchErr.Index+1:]...)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range conflicts {
+			if mode == model.ConflictSkip {
+				opErrs[pending[c]] = errConflictSkipped
+			} else {
+				opErrs[pending[c]] = repository.ErrDuplicateBacklink
+			}
+		}
+		break
+	}
+
+	result := &model.BulkOperationResponse{}
+	for i := range inputs {
+		err := errs[i]
+		if err == nil && opOf[i] >= 0 {
+			err = opErrs[opOf[i]]
+		}
+		switch {
+		case errors.Is(err, errConflictSkipped):
+			result.Skipped++
+		case err != nil:
+			result.AddError(bulkError(i, &inputs[i], err))
+		case outcomes[i] == storeUpdated:
+			result.Success++
+			result.Updated++
+		case outcomes[i] == storeSkipped:
+			result.Skipped++
+		default:
+			result.Success++
+		}
+	}
+	result.Failed = len(result.Errors)
+
+	return result, nil
+}
+
+// errConflictSkipped marks a write left out under ConflictSkip.
+var errConflictSkipped = errors.New("skipped")
+
+// bulkFailed reports an all-or-nothing call that wrote nothing.
+func bulkFailed(inputs []bulkInput, errs []error) *model.BulkOperationResponse {
+	result := &model.BulkOperationResponse{Failed: len(inputs)}
+	for i, err := range errs {
+		if err != nil {
+			result.AddError(bulkError(i, &inputs[i], err))
+		}
+	}
+	return result
+}
+
+func bulkError(index int, in *bulkInput, err error) model.BulkError {
+	return model.BulkError{
+		Index:   index,
+		Line:    in.line,
+		URL:     in.req.SourceURL,
+		Message: err.Error(),
+	}
+}
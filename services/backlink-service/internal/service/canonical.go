@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/backlink-service/internal/repository"
+	"github.com/link-tracker/shared/pkg/urlcanon"
+)
+
+const canonicalBatchSize = 500
+
+// setCanonical fills the canonical URLs and the key of a link under its
+// project's rules.
+func setCanonical(b *model.Backlink, rules urlcanon.Rules) {
+	b.SourceURLCanonical = urlcanon.Key(b.SourceURL, rules)
+	b.TargetURLCanonical = urlcanon.Key(b.TargetURL, rules)
+	b.LinkKey = b.SourceURLCanonical + " " + b.TargetURLCanonical
+}
+
+// linkKey identifies a link for deduplication. It equals Backlink.LinkKey
+// as set by setCanonical.
+func linkKey(source, target string, rules urlcanon.Rules) string {
+	return urlcanon.Key(source, rules) + " " + urlcanon.Key(target, rules)
+}
+
+// rulesCache loads project URL rules once per operation.
+type rulesCache struct {
+	projectRepo *repository.ProjectRepository
+	rules       map[int64]urlcanon.Rules
+}
+
+func newRulesCache(projectRepo *repository.ProjectRepository) *rulesCache {
+	return &rulesCache{projectRepo: projectRepo, rules: make(map[int64]urlcanon.Rules)}
+}
+
+func (c *rulesCache) get(ctx context.Context, projectID int64) (urlcanon.Rules, error) {
+	if r, ok := c.rules[projectID]; ok {
+		return r, nil
+	}
+	r, err := c.projectRepo.GetURLRules(ctx, projectID)
+	if err != nil {
+		return urlcanon.Rules{}, err
+	}
+	c.rules[projectID] = r
+	return r, nil
+}
+
+// BackfillCanonical computes canonical URLs and keys for links that lack
+// them, such as links created before canonicalization existed. Duplicates
+// stay without a key; a key freed by deleting a duplicate is picked up by
+// the next run. A nil projectID processes all projects.
+func BackfillCanonical(ctx context.Context, backlinkRepo *repository.BacklinkRepository, projectRepo *repository.ProjectRepository, projectID *int64) (int, error) {
+	cache := newRulesCache(projectRepo)
+	total := 0
+	var afterID int64
+	for {
+		backlinks, err := backlinkRepo.ListWithoutKey(ctx, projectID, afterID, canonicalBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(backlinks) == 0 {
+			return total, nil
+		}
+
+		for _, b := range backlinks {
+			rules, err := cache.get(ctx, b.ProjectID)
+			if err != nil {
+				return total, err
+			}
+			setCanonical(b, rules)
+		}
+		if err := backlinkRepo.SetCanonical(ctx, backlinks); err != nil {
+			return total, err
+		}
+		total += len(backlinks)
+		afterID = backlinks[len(backlinks)-1].ID
+	}
+}
+
+// BackfillDiscoveredCanonical computes canonical URLs for discovered links
+// that lack them, so they are matched against tracked links.
+func BackfillDiscoveredCanonical(ctx context.Context, discoveryRepo *repository.DiscoveryRepository, projectRepo *repository.ProjectRepository) (int, error) {
+	cache := newRulesCache(projectRepo)
+	total := 0
+	var afterID int64
+	for {
+		links, err := discoveryRepo.ListWithoutCanonical(ctx, afterID, canonicalBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(links) == 0 {
+			return total, nil
+		}
+
+		for _, l := range links {
+			rules, err := cache.get(ctx, l.ProjectID)
+			if err != nil {
+				return total, err
+			}
+			l.SourceURLCanonical = urlcanon.Key(l.SourceURL, rules)
+			l.TargetURLCanonical = urlcanon.Key(l.TargetURL, rules)
+		}
+		if err := discoveryRepo.SetCanonical(ctx, links); err != nil {
+			return total, err
+		}
+		total += len(links)
+		afterID = links[len(links)-1].ID
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/shared/pkg/urlcanon"
+	"golang.org/x/net/html"
+)
+
+// skippedExtensions are not fetched during discovery crawls.
+var skippedExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".svg": true, ".ico": true,
+	".pdf": true, ".zip": true, ".rar": true, ".gz": true, ".mp3": true, ".mp4": true, ".avi": true,
+	".css": true, ".js": true, ".xml": true, ".json": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true,
+}
+
+// maxAnchorLength matches the anchor_text column size.
+const maxAnchorLength = 500
+
+// crawlLimits bounds a crawl of a single donor domain.
+type crawlLimits struct {
+	maxDepth int
+	maxPages int
+	delay    time.Duration
+}
+
+// crawlResult is what a crawl of one donor domain produced.
+type crawlResult struct {
+	pages int
+	links []model.DiscoveredLink
+}
+
+// crawlDomain walks a donor domain breadth-first from its home page, staying
+// on the same host and honouring robots.txt, and collects every link pointing
+// at one of the target domains.
+func (c *LinkChecker) crawlDomain(ctx context.Context, domain string, targets []string, limits crawlLimits) crawlResult {
+	var result crawlResult
+
+	start, err := url.Parse("https://" + domain + "/")
+	if err != nil {
+		return result
+	}
+
+	robots, err := c.fetchRobots(ctx, start)
+	if err != nil {
+		robots = &robotsTxt{}
+	}
+
+	type queued struct {
+		url   string
+		depth int
+	}
+	queue := []queued{{url: start.String()}}
+	seen := map[string]bool{urlcanon.Key(start.String(), urlcanon.DefaultRules()): true}
+	found := make(map[string]bool)
+
+	for len(queue) > 0 && result.pages < limits.maxPages {
+		if ctx.Err() != nil {
+			return result
+		}
+
+		item := queue[0]
+		queue = queue[1:]
+
+		if result.pages > 0 && limits.delay > 0 {
+			select {
+			case <-ctx.Done():
+				return result
+			case <-time.After(limits.delay):
+			}
+		}
+
+		page, err := c.fetch(ctx, item.url)
+		if err != nil && item.depth == 0 {
+			// Some donors still serve the home page over plain HTTP only.
+			page, err = c.fetch(ctx, "http://"+domain+"/")
+		}
+		result.pages++
+		if err != nil || page.doc == nil || !isHTML(page) {
+			continue
+		}
+
+		walkElements(page.doc, func(n *html.Node) bool {
+			if n.Data != "a" {
+				return true
+			}
+			href := resolveHref(page.url, attr(n, "href"))
+			if href == nil {
+				return true
+			}
+			href.Fragment = ""
+
+			if matchesDomain(href.Hostname(), targets) {
+				key := page.url.String() + " " + href.String()
+				if !found[key] {
+					found[key] = true
+					result.links = append(result.links, model.DiscoveredLink{
+						SourceURL:  page.url.String(),
+						TargetURL:  href.String(),
+						AnchorText: truncateRunes(strings.Join(strings.Fields(nodeText(n)), " "), maxAnchorLength),
+						LinkType:   linkTypeFromRel(strings.Fields(strings.ToLower(attr(n, "rel")))),
+					})
+				}
+				return true
+			}
+
+			if item.depth >= limits.maxDepth || !matchesDomain(href.Hostname(), []string{domain}) {
+				return true
+			}
+			if skippedExtensions[strings.ToLower(path.Ext(href.Path))] {
+				return true
+			}
+			key := urlcanon.Key(href.String(), urlcanon.DefaultRules())
+			if seen[key] {
+				return true
+			}
+			seen[key] = true
+
+			p := href.EscapedPath()
+			if href.RawQuery != "" {
+				p += "?" + href.RawQuery
+			}
+			if robots.allowed("linktracker", p) {
+				queue = append(queue, queued{url: href.String(), depth: item.depth + 1})
+			}
+			return true
+		})
+	}
+
+	return result
+}
+
+func isHTML(page *fetchedPage) bool {
+	ct := strings.ToLower(page.header.Get("Content-Type"))
+	return ct == "" || strings.Contains(ct, "html")
+}
+
+func linkTypeFromRel(rel []string) model.LinkType {
+	switch {
+	case hasRel(rel, "sponsored"):
+		return model.LinkTypeSponsored
+	case hasRel(rel, "ugc"):
+		return model.LinkTypeUGC
+	case hasRel(rel, "nofollow"):
+		return model.LinkTypeNoFollow
+	default:
+		return model.LinkTypeDoFollow
+	}
+}
+
+// normalizeDomain turns "https://www.Example.com/path" or "example.com" into
+// "example.com". It returns "" for input without a host.
+func normalizeDomain(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(urlcanon.Host(u.Hostname()), "www.")
+}
+
+// matchesDomain reports whether host is one of domains or a subdomain of one.
+func matchesDomain(host string, domains []string) bool {
+	host = strings.TrimPrefix(urlcanon.Host(host), "www.")
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
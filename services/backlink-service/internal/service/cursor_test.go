@@ -0,0 +1,84 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+)
+
+func TestValidCursor(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name   string
+		cursor model.BacklinkCursor
+		want   bool
+	}{
+		{"created_at", model.BacklinkCursor{SortBy: model.SortCreatedAt, Value: str("2026-10-19T08:30:00.123456Z"), ID: 1}, true},
+		{"created_at with offset", model.BacklinkCursor{SortBy: model.SortCreatedAt, Value: str("2026-10-19T11:30:00+03:00"), ID: 1}, true},
+		{"created_at not a time", model.BacklinkCursor{SortBy: model.SortCreatedAt, Value: str("'; DROP TABLE backlinks; --"), ID: 1}, false},
+		{"created_at date only", model.BacklinkCursor{SortBy: model.SortCreatedAt, Value: str("2026-10-19"), ID: 1}, false},
+		{"last_checked_at never checked", model.BacklinkCursor{SortBy: model.SortLastCheckedAt, ID: 1}, true},
+		{"status", model.BacklinkCursor{SortBy: model.SortStatus, Value: str("broken"), ID: 1}, true},
+		{"status unknown", model.BacklinkCursor{SortBy: model.SortStatus, Value: str("lost"), ID: 1}, false},
+		{"domain", model.BacklinkCursor{SortBy: model.SortDomain, Value: str("example.com"), ID: 1}, true},
+		{"unknown sort falls back to time", model.BacklinkCursor{SortBy: "price", Value: str("10"), ID: 1}, false},
+		{"zero id", model.BacklinkCursor{SortBy: model.SortDomain, Value: str("example.com")}, false},
+		{"negative id", model.BacklinkCursor{SortBy: model.SortCreatedAt, ID: -1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validCursor(&tt.cursor); got != tt.want {
+				t.Errorf("validCursor(%+v) = %v, want %v", tt.cursor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidTimeCursor(t *testing.T) {
+	at := time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor model.TimeCursor
+		want   bool
+	}{
+		{"valid", model.TimeCursor{At: at, ID: 7}, true},
+		{"zero time", model.TimeCursor{ID: 7}, false},
+		{"zero id", model.TimeCursor{At: at}, false},
+		{"negative id", model.TimeCursor{At: at, ID: -7}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validTimeCursor(&tt.cursor); got != tt.want {
+				t.Errorf("validTimeCursor(%+v) = %v, want %v", tt.cursor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidDuplicateCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor model.DuplicateCursor
+		want   bool
+	}{
+		{"pair", model.DuplicateCursor{Count: 2, ID: 7}, true},
+		{"large group", model.DuplicateCursor{Count: 40, ID: 7}, true},
+		{"single link", model.DuplicateCursor{Count: 1, ID: 7}, false},
+		{"zero count", model.DuplicateCursor{ID: 7}, false},
+		{"zero id", model.DuplicateCursor{Count: 2}, false},
+		{"negative id", model.DuplicateCursor{Count: 2, ID: -7}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validDuplicateCursor(&tt.cursor); got != tt.want {
+				t.Errorf("validDuplicateCursor(%+v) = %v, want %v", tt.cursor, got, tt.want)
+			}
+		})
+	}
+}
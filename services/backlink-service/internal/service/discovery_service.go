@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/link-tracker/backlink-service/internal/config"
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/backlink-service/internal/repository"
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/response"
+	"github.com/link-tracker/shared/pkg/urlcanon"
+)
+
+var (
+	ErrNoTargetDomains    = errors.New("project has no target domains")
+	ErrNoDonorDomains     = errors.New("project has no donor domains")
+	ErrTooManyDiscoveries = errors.New("too many discovery runs in progress")
+	ErrDiscoveryQueueFull = errors.New("discovery queue is full, try again later")
+)
+
+// discoveryJob is a run waiting for a crawl worker.
+type discoveryJob struct {
+	run    model.DiscoveryRun
+	userID int64
+}
+
+// DiscoveryService crawls donor domains on a fixed pool of workers. Runs
+// wait in a bounded queue, and each user may only have a few of them queued
+// or running at a time.
+type DiscoveryService struct {
+	discoveryRepo *repository.DiscoveryRepository
+	backlinkRepo  *repository.BacklinkRepository
+	projectRepo   *repository.ProjectRepository
+	checker       *LinkChecker
+	cfg           config.DiscoveryConfig
+	jobs          chan discoveryJob
+
+	mu       sync.Mutex
+	userRuns map[int64]int
+}
+
+func NewDiscoveryService(
+	discoveryRepo *repository.DiscoveryRepository,
+	backlinkRepo *repository.BacklinkRepository,
+	projectRepo *repository.ProjectRepository,
+	cfg config.DiscoveryConfig,
+) *DiscoveryService {
+	s := &DiscoveryService{
+		discoveryRepo: discoveryRepo,
+		backlinkRepo:  backlinkRepo,
+		projectRepo:   projectRepo,
+		checker:       NewLinkChecker(),
+		cfg:           cfg,
+		jobs:          make(chan discoveryJob, max(cfg.QueueSize, 0)),
+		userRuns:      make(map[int64]int),
+	}
+	for i := 0; i < max(cfg.Workers, 1); i++ {
+		go s.work()
+	}
+	return s
+}
+
+// Start records a discovery run and queues it for crawling. The returned
+// run is in the running state, also while it waits for a worker; poll
+// GetRun for the outcome.
+func (s *DiscoveryService) Start(ctx context.Context, userID, projectID int64, req *model.StartDiscoveryRequest) (*model.DiscoveryRun, error) {
+	if err := s.checkAccess(ctx, projectID, userID, models.MemberEditor); err != nil {
+		return nil, err
+	}
+
+	sources, targets, err := s.backlinkRepo.GetProjectURLs(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.TargetDomains) > 0 {
+		targets = req.TargetDomains
+	}
+	if len(req.DonorDomains) > 0 {
+		sources = req.DonorDomains
+	}
+
+	targetDomains := uniqueDomains(targets, 0)
+	if len(targetDomains) == 0 {
+		return nil, ErrNoTargetDomains
+	}
+	donorDomains := uniqueDomains(sources, s.cfg.MaxDomains)
+	if len(donorDomains) == 0 {
+		return nil, ErrNoDonorDomains
+	}
+
+	if !s.acquire(userID) {
+		return nil, ErrTooManyDiscoveries
+	}
+
+	run := &model.DiscoveryRun{
+		ProjectID:     projectID,
+		Status:        model.DiscoveryStatusRunning,
+		TargetDomains: targetDomains,
+		DonorDomains:  donorDomains,
+		MaxDepth:      clampLimit(req.MaxDepth, s.cfg.MaxDepth),
+		MaxPages:      clampLimit(req.MaxPages, s.cfg.MaxPages),
+	}
+	if err := s.discoveryRepo.CreateRun(ctx, run); err != nil {
+		s.release(userID)
+		return nil, err
+	}
+
+	select {
+	case s.jobs <- discoveryJob{run: *run, userID: userID}:
+	default:
+		s.release(userID)
+		run.Status = model.DiscoveryStatusFailed
+		msg := ErrDiscoveryQueueFull.Error()
+		run.Error = &msg
+		if err := s.discoveryRepo.FinishRun(ctx, run); err != nil {
+			log.Printf("Failed to finish discovery run %d: %v", run.ID, err)
+		}
+		return nil, ErrDiscoveryQueueFull
+	}
+
+	return run, nil
+}
+
+// acquire reserves one of the user's run slots.
+func (s *DiscoveryService) acquire(userID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg.MaxUserRuns > 0 && s.userRuns[userID] >= s.cfg.MaxUserRuns {
+		return false
+	}
+	s.userRuns[userID]++
+	return true
+}
+
+func (s *DiscoveryService) release(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.userRuns[userID]--; s.userRuns[userID] <= 0 {
+		delete(s.userRuns, userID)
+	}
+}
+
+func (s *DiscoveryService) work() {
+	for job := range s.jobs {
+		s.run(job.run)
+		s.release(job.userID)
+	}
+}
+
+func (s *DiscoveryService) run(run model.DiscoveryRun) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.RunTimeout)
+	defer cancel()
+
+	limits := crawlLimits{
+		maxDepth: run.MaxDepth,
+		maxPages: run.MaxPages,
+		delay:    s.cfg.RequestDelay,
+	}
+
+	rules, err := s.projectRepo.GetURLRules(ctx, run.ProjectID)
+	if err != nil {
+		log.Printf("Failed to load URL rules of project %d: %v", run.ProjectID, err)
+		rules = urlcanon.DefaultRules()
+	}
+
+	var saveErr error
+	for _, domain := range run.DonorDomains {
+		if ctx.Err() != nil {
+			break
+		}
+		// Never crawl our own sites as donors.
+		if matchesDomain(domain, run.TargetDomains) {
+			continue
+		}
+
+		result := s.checker.crawlDomain(ctx, domain, run.TargetDomains, limits)
+		run.PagesCrawled += result.pages
+
+		for i := range result.links {
+			result.links[i].ProjectID = run.ProjectID
+			result.links[i].RunID = run.ID
+			result.links[i].SourceURLCanonical = urlcanon.Key(result.links[i].SourceURL, rules)
+			result.links[i].TargetURLCanonical = urlcanon.Key(result.links[i].TargetURL, rules)
+		}
+		inserted, err := s.discoveryRepo.SaveLinks(ctx, result.links)
+		if err != nil {
+			saveErr = err
+			break
+		}
+		run.LinksFound += inserted
+	}
+
+	run.Status = model.DiscoveryStatusDone
+	switch {
+	case saveErr != nil:
+		run.Status = model.DiscoveryStatusFailed
+		msg := saveErr.Error()
+		run.Error = &msg
+	case ctx.Err() != nil:
+		run.Status = model.DiscoveryStatusFailed
+		msg := "run timed out"
+		run.Error = &msg
+	}
+
+	// The run context may be exhausted; finishing must still be recorded.
+	if err := s.discoveryRepo.FinishRun(context.Background(), &run); err != nil {
+		log.Printf("Failed to finish discovery run %d: %v", run.ID, err)
+	}
+}
+
+func (s *DiscoveryService) GetRun(ctx context.Context, userID, projectID, runID int64) (*model.DiscoveryRun, error) {
+	if err := s.checkAccess(ctx, projectID, userID, models.MemberViewer); err != nil {
+		return nil, err
+	}
+
+	run, err := s.discoveryRepo.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.ProjectID != projectID {
+		return nil, repository.ErrDiscoveryRunNotFound
+	}
+
+	return run, nil
+}
+
+func (s *DiscoveryService) ListUntracked(ctx context.Context, userID, projectID int64, page, perPage int) ([]*model.DiscoveredLink, int64, error) {
+	if err := s.checkAccess(ctx, projectID, userID, models.MemberViewer); err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	return s.discoveryRepo.ListUntracked(ctx, projectID, page, perPage)
+}
+
+// ListUntrackedCursor lists untracked discovered links after the given
+// position, newest first, and returns the cursor of the next page, nil on
+// the last one.
+func (s *DiscoveryService) ListUntrackedCursor(ctx context.Context, userID, projectID int64, after *model.TimeCursor, perPage int) ([]*model.DiscoveredLink, *model.TimeCursor, error) {
+	if after != nil && !validTimeCursor(after) {
+		return nil, nil, response.ErrInvalidCursor
+	}
+	if err := s.checkAccess(ctx, projectID, userID, models.MemberViewer); err != nil {
+		return nil, nil, err
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	links, hasMore, err := s.discoveryRepo.ListUntrackedAfter(ctx, projectID, after, perPage)
+	if err != nil || !hasMore {
+		return links, nil, err
+	}
+	last := links[len(links)-1]
+	return links, &model.TimeCursor{At: last.DiscoveredAt, ID: last.ID}, nil
+}
+
+// Adopt turns a discovered link into a tracked backlink of the project.
+func (s *DiscoveryService) Adopt(ctx context.Context, userID, projectID, linkID int64) (*model.Backlink, error) {
+	if err := s.checkAccess(ctx, projectID, userID, models.MemberEditor); err != nil {
+		return nil, err
+	}
+
+	link, err := s.discoveryRepo.GetLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if link.ProjectID != projectID {
+		return nil, repository.ErrDiscoveredLinkNotFound
+	}
+	if link.AdoptedBacklinkID != nil {
+		return nil, repository.ErrDiscoveredLinkAdopted
+	}
+
+	backlink := &model.Backlink{
+		ProjectID:  link.ProjectID,
+		SourceURL:  link.SourceURL,
+		TargetURL:  link.TargetURL,
+		AnchorText: link.AnchorText,
+		Status:     model.LinkStatusPending,
+		LinkType:   link.LinkType,
+	}
+	rules, err := s.projectRepo.GetURLRules(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	setCanonical(backlink, rules)
+	if err := s.discoveryRepo.Adopt(ctx, link.ID, backlink); err != nil {
+		return nil, err
+	}
+
+	return backlink, nil
+}
+
+func (s *DiscoveryService) checkAccess(ctx context.Context, projectID, userID int64, role models.MemberRole) error {
+	allowed, err := s.projectRepo.HasRole(ctx, projectID, userID, role)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// uniqueDomains normalizes and deduplicates domains; limit <= 0 means no limit.
+func uniqueDomains(values []string, limit int) []string {
+	seen := make(map[string]bool)
+	var domains []string
+	for _, v := range values {
+		d := normalizeDomain(v)
+		if d == "" || seen[d] {
+			continue
+		}
+		seen[d] = true
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+	if limit > 0 && len(domains) > limit {
+		domains = domains[:limit]
+	}
+	return domains
+}
+
+// clampLimit uses the requested value when it is positive and within the
+// configured maximum.
+func clampLimit(requested *int, max int) int {
+	if requested == nil || *requested < 0 || *requested > max {
+		return max
+	}
+	return *requested
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/shared/pkg/urlcanon"
+	"golang.org/x/net/html"
+)
+
+var errUnavailable = errors.New("resource unavailable")
+
+const (
+	maxRobotsSize   = 512 * 1024
+	maxSitemapSize  = 10 * 1024 * 1024
+	maxSitemapFiles = 5
+)
+
+// auditDonor evaluates the fetched source page as a link donor.
+func (c *LinkChecker) auditDonor(ctx context.Context, page *fetchedPage) *model.DonorAudit {
+	audit := &model.DonorAudit{
+		AuditedAt: time.Now(),
+	}
+
+	audit.HasNoindex = hasNoindex(page)
+	audit.OutboundLinks = countOutboundLinks(page)
+
+	audit.CanonicalOK = true
+	if canonical := findCanonical(page); canonical != nil {
+		s := canonical.String()
+		audit.CanonicalURL = &s
+		audit.CanonicalOK = sameURL(s, page.url.String())
+	}
+
+	audit.Indexable = !audit.HasNoindex && audit.CanonicalOK
+
+	robots, err := c.fetchRobots(ctx, page.url)
+	if err == nil {
+		path := page.url.EscapedPath()
+		if page.url.RawQuery != "" {
+			path += "?" + page.url.RawQuery
+		}
+		googlebot := robots.allowed("googlebot", path)
+		yandex := robots.allowed("yandex", path)
+		audit.RobotsGooglebot = &googlebot
+		audit.RobotsYandex = &yandex
+		audit.Indexable = audit.Indexable && googlebot && yandex
+	}
+
+	var sitemaps []string
+	if robots != nil {
+		sitemaps = robots.sitemaps
+	}
+	if len(sitemaps) == 0 {
+		sitemaps = []string{page.url.Scheme + "://" + page.url.Host + "/sitemap.xml"}
+	}
+	if inSitemap, ok := c.findInSitemaps(ctx, sitemaps, page.url.String()); ok {
+		audit.InSitemap = &inSitemap
+	}
+
+	return audit
+}
+
+// hasNoindex checks the robots meta tags and the X-Robots-Tag header.
+func hasNoindex(page *fetchedPage) bool {
+	for _, v := range page.header.Values("X-Robots-Tag") {
+		if robotsDirectiveNoindex(v) {
+			return true
+		}
+	}
+
+	noindex := false
+	walkElements(page.doc, func(n *html.Node) bool {
+		if n.Data != "meta" {
+			return true
+		}
+		name := strings.ToLower(attr(n, "name"))
+		if name != "robots" && name != "googlebot" && name != "yandex" {
+			return true
+		}
+		if robotsDirectiveNoindex(attr(n, "content")) {
+			noindex = true
+			return false
+		}
+		return true
+	})
+	return noindex
+}
+
+func robotsDirectiveNoindex(value string) bool {
+	// X-Robots-Tag may be prefixed with a user agent, e.g. "googlebot: noindex".
+	if i := strings.Index(value, ":"); i >= 0 {
+		value = value[i+1:]
+	}
+	for _, d := range strings.Split(strings.ToLower(value), ",") {
+		d = strings.TrimSpace(d)
+		if d == "noindex" || d == "none" {
+			return true
+		}
+	}
+	return false
+}
+
+func findCanonical(page *fetchedPage) *url.URL {
+	var canonical *url.URL
+	walkElements(page.doc, func(n *html.Node) bool {
+		if n.Data != "link" {
+			return true
+		}
+		for _, rel := range strings.Fields(strings.ToLower(attr(n, "rel"))) {
+			if rel == "canonical" {
+				canonical = resolveHref(page.url, attr(n, "href"))
+				return false
+			}
+		}
+		return true
+	})
+	return canonical
+}
+
+// countOutboundLinks counts links pointing to other hosts. www. is ignored so
+// links within the same site are not counted.
+func countOutboundLinks(page *fetchedPage) int {
+	host := strings.TrimPrefix(urlcanon.Host(page.url.Hostname()), "www.")
+	count := 0
+	walkElements(page.doc, func(n *html.Node) bool {
+		if n.Data != "a" {
+			return true
+		}
+		href := resolveHref(page.url, attr(n, "href"))
+		if href != nil && strings.TrimPrefix(urlcanon.Host(href.Hostname()), "www.") != host {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// robotsGroup is a set of rules applying to one or more user agents.
+type robotsGroup struct {
+	agents []string
+	rules  []robotsRule
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type robotsTxt struct {
+	groups   []robotsGroup
+	sitemaps []string
+}
+
+func (c *LinkChecker) fetchRobots(ctx context.Context, pageURL *url.URL) (*robotsTxt, error) {
+	robotsURL := pageURL.Scheme + "://" + pageURL.Host + "/robots.txt"
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// A missing robots.txt allows everything.
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return &robotsTxt{}, nil
+	}
+	if resp.StatusCode >= 500 {
+		return nil, errUnavailable
+	}
+
+	return parseRobots(io.LimitReader(resp.Body, maxRobotsSize)), nil
+}
+
+func parseRobots(r io.Reader) *robotsTxt {
+	robots := &robotsTxt{}
+	var current *robotsGroup
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || !lastWasAgent {
+				robots.groups = append(robots.groups, robotsGroup{})
+				current = &robots.groups[len(robots.groups)-1]
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			// An empty Disallow allows everything and adds no rule.
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "sitemap":
+			if value != "" {
+				robots.sitemaps = append(robots.sitemaps, value)
+			}
+		}
+		lastWasAgent = false
+	}
+
+	return robots
+}
+
+// allowed applies the group matching agent, falling back to the "*" group.
+// The longest matching rule wins; on a tie Allow wins.
+func (r *robotsTxt) allowed(agent, path string) bool {
+	group := r.groupFor(agent)
+	if group == nil {
+		return true
+	}
+
+	bestLen := -1
+	allow := true
+	for _, rule := range group.rules {
+		if !robotsPatternMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > bestLen || (len(rule.pattern) == bestLen && rule.allow) {
+			bestLen = len(rule.pattern)
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+func (r *robotsTxt) groupFor(agent string) *robotsGroup {
+	var fallback *robotsGroup
+	for i := range r.groups {
+		for _, a := range r.groups[i].agents {
+			if a == "*" {
+				if fallback == nil {
+					fallback = &r.groups[i]
+				}
+			} else if a == agent {
+				return &r.groups[i]
+			}
+		}
+	}
+	return fallback
+}
+
+// robotsPatternMatch matches a path against a robots.txt pattern where "*"
+// matches any sequence and a trailing "$" anchors the end.
+func robotsPatternMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for _, part := range parts[1:] {
+		i := strings.Index(path[pos:], part)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(part)
+	}
+
+	if !anchored {
+		return true
+	}
+	last := parts[len(parts)-1]
+	if len(parts) == 1 {
+		return pos == len(path)
+	}
+	return strings.HasSuffix(path, last)
+}
+
+type sitemapDoc struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// findInSitemaps looks for pageURL in the given sitemaps, following one level
+// of sitemap indexes. ok is false when no sitemap could be read.
+func (c *LinkChecker) findInSitemaps(ctx context.Context, sitemaps []string, pageURL string) (found, ok bool) {
+	queue := sitemaps
+	fetched := 0
+	nested := false
+
+	for len(queue) > 0 && fetched < maxSitemapFiles {
+		var next []string
+		for _, sitemapURL := range queue {
+			if fetched >= maxSitemapFiles {
+				break
+			}
+			fetched++
+
+			doc, err := c.fetchSitemap(ctx, sitemapURL)
+			if err != nil {
+				continue
+			}
+			ok = true
+			for _, u := range doc.URLs {
+				if sameURL(u.Loc, pageURL) {
+					return true, true
+				}
+			}
+			if !nested {
+				for _, s := range doc.Sitemaps {
+					next = append(next, strings.TrimSpace(s.Loc))
+				}
+			}
+		}
+		queue = next
+		nested = true
+	}
+
+	return false, ok
+}
+
+func (c *LinkChecker) fetchSitemap(ctx context.Context, sitemapURL string) (*sitemapDoc, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, errUnavailable
+	}
+
+	var body io.Reader = io.LimitReader(resp.Body, maxSitemapSize)
+	if strings.HasSuffix(req.URL.Path, ".gz") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = io.LimitReader(gz, maxSitemapSize)
+	}
+
+	var doc sitemapDoc
+	if err := xml.NewDecoder(body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/link-tracker/backlink-service/internal/importer"
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/backlink-service/internal/repository"
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/urlcanon"
+)
+
+const (
+	// importTTL is how long a previewed import can be committed.
+	importTTL   = 24 * time.Hour
+	previewRows = 100
+)
+
+var ErrImportExpired = errors.New("import expired, upload the file again")
+
+type ImportService struct {
+	importRepo   *repository.ImportRepository
+	backlinkRepo *repository.BacklinkRepository
+	projectRepo  *repository.ProjectRepository
+}
+
+func NewImportService(
+	importRepo *repository.ImportRepository,
+	backlinkRepo *repository.BacklinkRepository,
+	projectRepo *repository.ProjectRepository,
+) *ImportService {
+	return &ImportService{
+		importRepo:   importRepo,
+		backlinkRepo: backlinkRepo,
+		projectRepo:  projectRepo,
+	}
+}
+
+// Preview parses an export, marks each row as new, duplicate or invalid and
+// stores the result for a later Commit.
+func (s *ImportService) Preview(ctx context.Context, userID, projectID int64, format, defaultTarget string, file io.Reader) (*model.ImportPreview, error) {
+	allowed, err := s.projectRepo.HasRole(ctx, projectID, userID, models.MemberEditor)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrUnauthorized
+	}
+
+	var f importer.Format
+	if format != "" {
+		var ok bool
+		if f, ok = importer.ParseFormat(format); !ok {
+			return nil, fmt.Errorf("%w: unsupported format %q", ErrValidation, format)
+		}
+	}
+
+	parsed, detected, err := importer.Parse(file, f, defaultTarget)
+	if err != nil {
+		if errors.Is(err, importer.ErrUnknownFormat) || errors.Is(err, importer.ErrEmptyFile) || errors.Is(err, importer.ErrTooManyRows) {
+			return nil, fmt.Errorf("%w: %s", ErrValidation, err.Error())
+		}
+		return nil, err
+	}
+
+	rules, err := s.projectRepo.GetURLRules(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.existingKeys(ctx, projectID, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]model.ImportRow, len(parsed))
+	for i, p := range parsed {
+		rows[i] = model.ImportRow{
+			Line:       p.Line,
+			SourceURL:  p.SourceURL,
+			TargetURL:  p.TargetURL,
+			AnchorText: p.AnchorText,
+			LinkType:   p.LinkType,
+			Error:      p.Error,
+		}
+		if p.Error != "" {
+			rows[i].Status = model.ImportRowInvalid
+		}
+	}
+	markDuplicates(rows, existing, rules)
+
+	// Opportunistic cleanup keeps the table small without a separate job.
+	if err := s.importRepo.DeleteBefore(ctx, time.Now().Add(-importTTL)); err != nil {
+		log.Printf("Failed to delete expired imports: %v", err)
+	}
+
+	imp := &model.BacklinkImport{
+		ProjectID: projectID,
+		UserID:    userID,
+		Format:    string(detected),
+		Rows:      rows,
+	}
+	if err := s.importRepo.Create(ctx, imp); err != nil {
+		return nil, err
+	}
+
+	preview := &model.ImportPreview{
+		ImportID:  imp.ID,
+		ProjectID: projectID,
+		Format:    imp.Format,
+		TotalRows: len(rows),
+		ExpiresAt: imp.CreatedAt.Add(importTTL),
+	}
+	for _, r := range rows {
+		switch r.Status {
+		case model.ImportRowNew:
+			preview.NewRows++
+		case model.ImportRowDuplicate:
+			preview.Duplicates++
+		case model.ImportRowInvalid:
+			preview.Invalid++
+		}
+	}
+	preview.Rows = rows
+	if len(rows) > previewRows {
+		preview.Rows = rows[:previewRows]
+	}
+
+	return preview, nil
+}
+
+// Commit creates the valid rows of a previewed import. Rows matching a
+// tracked link, including links added since the preview or earlier in the
+// file, are handled according to req.OnConflict. The import is marked
+// committed in the transaction of the writes, so a commit that fails or is
+// rolled back, such as a failed atomic one, can be retried.
+func (s *ImportService) Commit(ctx context.Context, userID, importID int64, req *model.CommitImportRequest) (*model.BulkOperationResponse, error) {
+	if err := validateConflictMode(req.OnConflict); err != nil {
+		return nil, err
+	}
+
+	imp, err := s.importRepo.GetByID(ctx, importID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := s.projectRepo.HasRole(ctx, imp.ProjectID, userID, models.MemberEditor)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrUnauthorized
+	}
+	if imp.CommittedAt != nil {
+		return nil, repository.ErrImportCommitted
+	}
+	if time.Since(imp.CreatedAt) > importTTL {
+		return nil, ErrImportExpired
+	}
+
+	inputs := make([]bulkInput, len(imp.Rows))
+	for i, r := range imp.Rows {
+		inputs[i] = bulkInput{
+			req: model.CreateBacklinkRequest{
+				ProjectID:  imp.ProjectID,
+				SourceURL:  r.SourceURL,
+				TargetURL:  r.TargetURL,
+				AnchorText: r.AnchorText,
+				LinkType:   r.LinkType,
+			},
+			line: r.Line,
+		}
+		if r.Status == model.ImportRowInvalid {
+			inputs[i].err = errors.New(r.Error)
+		}
+	}
+
+	return bulkCreate(ctx, s.backlinkRepo, newRulesCache(s.projectRepo), inputs, req.OnConflict, req.Atomic, s.importRepo.MarkCommitted(imp.ID))
+}
+
+func (s *ImportService) existingKeys(ctx context.Context, projectID int64, rules urlcanon.Rules) (map[string]bool, error) {
+	pairs, err := s.backlinkRepo.GetLinkPairs(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(pairs))
+	for _, p := range pairs {
+		keys[linkKey(p[0], p[1], rules)] = true
+	}
+	return keys, nil
+}
+
+// markDuplicates sets the status of valid rows: duplicate when the link is
+// already tracked or appeared earlier in the file, new otherwise.
+func markDuplicates(rows []model.ImportRow, existing map[string]bool, rules urlcanon.Rules) {
+	seen := make(map[string]int)
+	for i := range rows {
+		r := &rows[i]
+		if r.Status == model.ImportRowInvalid {
+			continue
+		}
+		key := linkKey(r.SourceURL, r.TargetURL, rules)
+		switch {
+		case existing[key]:
+			r.Status = model.ImportRowDuplicate
+			r.Error = "already tracked in project"
+		case seen[key] > 0:
+			r.Status = model.ImportRowDuplicate
+			r.Error = fmt.Sprintf("duplicate of line %d", seen[key])
+		default:
+			r.Status = model.ImportRowNew
+			seen[key] = r.Line
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/shared/pkg/htmlcharset"
+	"github.com/link-tracker/shared/pkg/urlcanon"
+	"golang.org/x/net/html"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	userAgent       = "LinkTracker/1.0"
+	maxPageBodySize = 2 * 1024 * 1024
+
+	// maxRedirectCandidates bounds how many outbound links are followed when
+	// looking for one that redirects to the target.
+	maxRedirectCandidates = 10
+)
+
+// LinkChecker fetches donor pages and looks for the tracked link on them.
+// It only connects to public addresses.
+type LinkChecker struct {
+	httpClient *http.Client
+}
+
+func NewLinkChecker() *LinkChecker {
+	return &LinkChecker{
+		httpClient: &http.Client{
+			Timeout:   15 * time.Second,
+			Transport: newGuardedTransport(),
+		},
+	}
+}
+
+// fetchedPage is a donor page downloaded and parsed for checking.
+type fetchedPage struct {
+	url        *url.URL
+	statusCode int
+	header     http.Header
+	doc        *html.Node
+}
+
+// foundLink is an <a> element on the donor page pointing at the target.
+type foundLink struct {
+	node       *html.Node
+	anchorText string
+	rel        []string
+}
+
+// Check fetches the donor page, updates link status, type and placement and
+// audits the donor. The target is matched under the project's URL rules.
+// Network failures are reported as a broken link, not as an error.
+func (c *LinkChecker) Check(ctx context.Context, backlink *model.Backlink, rules urlcanon.Rules) {
+	now := time.Now()
+	backlink.LastCheckedAt = &now
+	backlink.Placement = nil
+
+	page, err := c.fetch(ctx, backlink.SourceURL)
+	if err != nil {
+		backlink.Status = model.LinkStatusBroken
+		backlink.HTTPStatus = nil
+		return
+	}
+
+	status := page.statusCode
+	backlink.HTTPStatus = &status
+	if status >= 400 || page.doc == nil {
+		backlink.Status = model.LinkStatusBroken
+		return
+	}
+
+	link := findLink(page, backlink.TargetURL, backlink.AnchorText, rules)
+	if link == nil && rules.FollowRedirects {
+		link = c.findRedirectingLink(ctx, page, backlink.TargetURL, rules)
+	}
+	switch {
+	case link == nil:
+		backlink.Status = model.LinkStatusRemoved
+	case hasRel(link.rel, "sponsored"):
+		backlink.Status = model.LinkStatusActive
+		backlink.LinkType = model.LinkTypeSponsored
+	case hasRel(link.rel, "ugc"):
+		backlink.Status = model.LinkStatusActive
+		backlink.LinkType = model.LinkTypeUGC
+	case hasRel(link.rel, "nofollow"):
+		backlink.Status = model.LinkStatusNoFollow
+		backlink.LinkType = model.LinkTypeNoFollow
+	default:
+		backlink.Status = model.LinkStatusActive
+		backlink.LinkType = model.LinkTypeDoFollow
+	}
+	if link != nil {
+		backlink.Placement = placementOf(page, link.node)
+		backlink.Placement.Anchor = link.anchorText
+		if strings.TrimSpace(backlink.AnchorText) != "" {
+			match := anchorsMatch(backlink.AnchorText, link.anchorText)
+			backlink.Placement.AnchorMatch = &match
+		}
+	}
+
+	backlink.DonorAudit = c.auditDonor(ctx, page)
+}
+
+func (c *LinkChecker) fetch(ctx context.Context, rawURL string) (*fetchedPage, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	page := &fetchedPage{
+		url:        resp.Request.URL,
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+	}
+
+	if resp.StatusCode >= 400 {
+		return page, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	// The parser assumes UTF-8, so legacy encodings are converted first.
+	body = htmlcharset.Decode(body, resp.Header.Get("Content-Type"))
+	page.doc, err = html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// findLink returns the link on the page whose href is equivalent to target
+// under rules. When several links qualify, the first one with the expected
+// anchor text wins, then the first one.
+func findLink(page *fetchedPage, target, anchor string, rules urlcanon.Rules) *foundLink {
+	var found *foundLink
+	walkElements(page.doc, func(n *html.Node) bool {
+		if n.Data != "a" {
+			return true
+		}
+		href := resolveHref(page.url, attr(n, "href"))
+		if href == nil || !urlcanon.Equal(href.String(), target, rules) {
+			return true
+		}
+		link := newFoundLink(n)
+		if found == nil {
+			found = link
+		}
+		if strings.TrimSpace(anchor) != "" && anchorsMatch(anchor, link.anchorText) {
+			found = link
+			return false
+		}
+		return strings.TrimSpace(anchor) != ""
+	})
+	return found
+}
+
+// findRedirectingLink follows outbound links of the page and returns the
+// first one that redirects to target, e.g. an old URL that 301s to the new
+// one or a shortener.
+func (c *LinkChecker) findRedirectingLink(ctx context.Context, page *fetchedPage, target string, rules urlcanon.Rules) *foundLink {
+	donorHost := strings.TrimPrefix(urlcanon.Host(page.url.Hostname()), "www.")
+	tried := make(map[string]bool)
+
+	var candidates []*html.Node
+	var hrefs []string
+	walkElements(page.doc, func(n *html.Node) bool {
+		if n.Data != "a" {
+			return true
+		}
+		href := resolveHref(page.url, attr(n, "href"))
+		if href == nil || strings.TrimPrefix(urlcanon.Host(href.Hostname()), "www.") == donorHost {
+			return true
+		}
+		href.Fragment = ""
+		if tried[href.String()] {
+			return true
+		}
+		tried[href.String()] = true
+		candidates = append(candidates, n)
+		hrefs = append(hrefs, href.String())
+		return len(candidates) < maxRedirectCandidates
+	})
+
+	for i, href := range hrefs {
+		final, err := c.resolveRedirects(ctx, href)
+		if err != nil || final == href {
+			continue
+		}
+		if urlcanon.Equal(final, target, rules) {
+			return newFoundLink(candidates[i])
+		}
+	}
+	return nil
+}
+
+// resolveRedirects returns the URL a link finally lands on. HEAD is tried
+// first; servers that reject it get a GET whose body is not read.
+func (c *LinkChecker) resolveRedirects(ctx context.Context, rawURL string) (string, error) {
+	for _, method := range []string{"HEAD", "GET"} {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("User-Agent", userAgent)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+
+		if method == "HEAD" && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+			continue
+		}
+		return resp.Request.URL.String(), nil
+	}
+	return rawURL, nil
+}
+
+func newFoundLink(n *html.Node) *foundLink {
+	return &foundLink{
+		node:       n,
+		anchorText: strings.Join(strings.Fields(nodeText(n)), " "),
+		rel:        strings.Fields(strings.ToLower(attr(n, "rel"))),
+	}
+}
+
+// anchorsMatch compares anchor texts ignoring case, surrounding punctuation,
+// whitespace, soft hyphens and the ё/е spelling, so Cyrillic anchors written differently
+// in the page and in the tracker still match.
+func anchorsMatch(expected, found string) bool {
+	return normalizeAnchor(expected) == normalizeAnchor(found)
+}
+
+func normalizeAnchor(s string) string {
+	s = strings.ToLower(norm.NFC.String(s))
+	s = strings.NewReplacer("ё", "е", "\u00ad", "").Replace(s)
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r)
+	})
+}
+
+// sameURL compares two URLs under the default equivalence rules.
+func sameURL(a, b string) bool {
+	return urlcanon.Equal(a, b, urlcanon.DefaultRules())
+}
+
+func resolveHref(base *url.URL, href string) *url.URL {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return nil
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return nil
+	}
+	abs := base.ResolveReference(ref)
+	if abs.Scheme != "http" && abs.Scheme != "https" {
+		return nil
+	}
+	return abs
+}
+
+func hasRel(rel []string, value string) bool {
+	for _, r := range rel {
+		if r == value {
+			return true
+		}
+	}
+	return false
+}
+
+// walkElements visits element nodes depth-first until visit returns false.
+func walkElements(n *html.Node, visit func(*html.Node) bool) bool {
+	if n.Type == html.ElementNode && !visit(n) {
+		return false
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if !walkElements(c, visit) {
+			return false
+		}
+	}
+	return true
+}
+
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
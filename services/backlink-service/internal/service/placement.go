@@ -0,0 +1,374 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"golang.org/x/net/html"
+)
+
+// contextRadius is how many characters of the sentence are kept on each side
+// of the anchor text.
+const contextRadius = 150
+
+var (
+	sentenceEndBefore = regexp.MustCompile(`[.!?…]\s|\x00`)
+	sentenceEndAfter  = regexp.MustCompile(`[.!?…](\s|$)|\x00`)
+	simpleSelector    = regexp.MustCompile(`^[a-z0-9_-]*([.#][a-z0-9_-]+)?$`)
+	whitespace        = regexp.MustCompile(`\s+`)
+)
+
+// hiddenClasses are utility classes of common CSS frameworks that hide an
+// element. External stylesheets are not fetched, so they are matched by name.
+var hiddenClasses = map[string]bool{
+	"hidden":             true,
+	"hide":               true,
+	"d-none":             true,
+	"invisible":          true,
+	"sr-only":            true,
+	"visually-hidden":    true,
+	"screen-reader-text": true,
+}
+
+// blockElements end the search for the block holding a link's sentence.
+var blockElements = map[string]bool{
+	"p": true, "li": true, "td": true, "th": true, "dd": true, "dt": true,
+	"blockquote": true, "figcaption": true, "caption": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"div": true, "section": true, "article": true, "main": true, "aside": true,
+	"header": true, "footer": true, "nav": true, "body": true,
+}
+
+// placementOf describes where the link element a sits on the page.
+func placementOf(page *fetchedPage, a *html.Node) *model.Placement {
+	p := &model.Placement{
+		Region:  regionOf(a),
+		Context: sentenceAround(a),
+	}
+	p.Position, p.TotalLinks, p.PositionPercent = linkPosition(page.doc, a)
+
+	walkElements(a, func(n *html.Node) bool {
+		if n.Data != "img" {
+			return true
+		}
+		p.IsImage = true
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			p.ImageAlt = &alt
+		}
+		return false
+	})
+
+	if reason := hiddenBy(a, parseHidingRules(page.doc)); reason != "" {
+		p.Hidden = true
+		p.HiddenBy = &reason
+	}
+
+	return p
+}
+
+// regionOf returns the region of the nearest ancestor that marks one by tag,
+// ARIA role, id or class. Links outside any marked region count as content.
+func regionOf(n *html.Node) model.Region {
+	for e := n.Parent; e != nil; e = e.Parent {
+		if e.Type != html.ElementNode {
+			continue
+		}
+		// Body classes describe the whole page, not a region of it.
+		if e.Data == "body" || e.Data == "html" {
+			break
+		}
+		if region, ok := elementRegion(e); ok {
+			return region
+		}
+	}
+	return model.RegionContent
+}
+
+func elementRegion(e *html.Node) (model.Region, bool) {
+	switch e.Data {
+	case "header":
+		return model.RegionHeader, true
+	case "footer":
+		return model.RegionFooter, true
+	case "nav":
+		return model.RegionNavigation, true
+	case "aside":
+		return model.RegionSidebar, true
+	case "main", "article":
+		return model.RegionContent, true
+	}
+
+	switch strings.ToLower(attr(e, "role")) {
+	case "banner":
+		return model.RegionHeader, true
+	case "contentinfo":
+		return model.RegionFooter, true
+	case "navigation":
+		return model.RegionNavigation, true
+	case "complementary":
+		return model.RegionSidebar, true
+	case "main", "article":
+		return model.RegionContent, true
+	}
+
+	tokens := make(map[string]bool)
+	for _, t := range strings.FieldsFunc(strings.ToLower(attr(e, "id")+" "+attr(e, "class")), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}) {
+		tokens[t] = true
+	}
+	// Comments sit inside content and footers inside posts, so the more
+	// specific regions are checked first.
+	switch {
+	case tokens["comment"] || tokens["comments"] || tokens["disqus"] || tokens["respond"]:
+		return model.RegionComments, true
+	case tokens["footer"]:
+		return model.RegionFooter, true
+	case tokens["header"] || tokens["masthead"]:
+		return model.RegionHeader, true
+	case tokens["sidebar"] || tokens["widget"] || tokens["aside"]:
+		return model.RegionSidebar, true
+	case tokens["nav"] || tokens["navbar"] || tokens["menu"] || tokens["breadcrumb"] || tokens["breadcrumbs"]:
+		return model.RegionNavigation, true
+	case tokens["content"] || tokens["entry"] || tokens["post"] || tokens["article"]:
+		return model.RegionContent, true
+	}
+	return "", false
+}
+
+// sentenceAround returns the sentence of the nearest block element that
+// contains the link, cut to contextRadius characters around the anchor.
+func sentenceAround(a *html.Node) string {
+	block := a.Parent
+	for block != nil && !(block.Type == html.ElementNode && blockElements[block.Data]) {
+		block = block.Parent
+	}
+	if block == nil {
+		return strings.Join(strings.Fields(nodeText(a)), " ")
+	}
+
+	var sb strings.Builder
+	start, end := -1, -1
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n == a {
+			start = sb.Len()
+		}
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+		case html.ElementNode:
+			if n.Data == "script" || n.Data == "style" {
+				return
+			}
+			if n.Data == "br" {
+				sb.WriteByte(' ')
+			}
+		}
+		// Nested blocks end sentences even without punctuation.
+		nested := n != block && n.Type == html.ElementNode && blockElements[n.Data]
+		if nested {
+			sb.WriteByte(0)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if nested {
+			sb.WriteByte(0)
+		}
+		if n == a {
+			end = sb.Len()
+		}
+	}
+	walk(block)
+	text := sb.String()
+
+	before := text[:start]
+	if m := sentenceEndBefore.FindAllStringIndex(before, -1); len(m) > 0 {
+		before = before[m[len(m)-1][1]:]
+	}
+	after := text[end:]
+	if m := sentenceEndAfter.FindStringIndex(after); m != nil {
+		// Keep the punctuation, which may be the multi-byte ellipsis.
+		cut := m[0]
+		if after[cut] != 0 {
+			_, size := utf8.DecodeRuneInString(after[cut:])
+			cut += size
+		}
+		after = after[:cut]
+	}
+
+	// Collapsing keeps a single space at the edges so words do not merge.
+	before = whitespace.ReplaceAllString(before, " ")
+	after = whitespace.ReplaceAllString(after, " ")
+	if r := []rune(before); len(r) > contextRadius {
+		before = string(r[len(r)-contextRadius:])
+	}
+	after = truncateRunes(after, contextRadius)
+
+	anchor := whitespace.ReplaceAllString(strings.ReplaceAll(text[start:end], "\x00", " "), " ")
+	return strings.TrimSpace(before + anchor + after)
+}
+
+// linkPosition returns the 1-based position of a among the page's links,
+// the number of links and the share of page text that precedes it.
+func linkPosition(doc, a *html.Node) (position, total, percent int) {
+	var textBefore, textTotal int
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			textTotal += len(strings.TrimSpace(n.Data))
+		case html.ElementNode:
+			if n.Data == "script" || n.Data == "style" {
+				return
+			}
+			if n.Data == "a" && attr(n, "href") != "" {
+				total++
+				if n == a {
+					position = total
+					textBefore = textTotal
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if textTotal > 0 {
+		percent = textBefore * 100 / textTotal
+	}
+	return position, total, percent
+}
+
+// parseHidingRules collects simple selectors (tag, .class, #id, tag.class,
+// tag#id) that the page's <style> blocks hide, with the hiding declaration.
+// Nested at-rules are flattened, which may over-report print-only rules.
+func parseHidingRules(doc *html.Node) map[string]string {
+	rules := make(map[string]string)
+	walkElements(doc, func(n *html.Node) bool {
+		if n.Data != "style" || n.FirstChild == nil {
+			return true
+		}
+		css := stripCSSComments(strings.ToLower(n.FirstChild.Data))
+		for _, block := range strings.Split(css, "}") {
+			i := strings.LastIndex(block, "{")
+			if i < 0 {
+				continue
+			}
+			decl := hidingDeclaration(block[i+1:])
+			if decl == "" {
+				continue
+			}
+			selectors := block[:i]
+			if j := strings.LastIndexAny(selectors, "{;"); j >= 0 {
+				selectors = selectors[j+1:]
+			}
+			for _, sel := range strings.Split(selectors, ",") {
+				sel = strings.TrimSpace(sel)
+				if sel != "" && simpleSelector.MatchString(sel) {
+					rules[sel] = decl
+				}
+			}
+		}
+		return true
+	})
+	return rules
+}
+
+func stripCSSComments(css string) string {
+	for {
+		i := strings.Index(css, "/*")
+		if i < 0 {
+			return css
+		}
+		j := strings.Index(css[i+2:], "*/")
+		if j < 0 {
+			return css[:i]
+		}
+		css = css[:i] + css[i+2+j+2:]
+	}
+}
+
+// hidingDeclaration returns the first declaration in decls that hides an
+// element, normalized as "property:value", or "".
+func hidingDeclaration(decls string) string {
+	for _, d := range strings.Split(decls, ";") {
+		prop, value, ok := strings.Cut(d, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		value = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important")))
+
+		hides := false
+		switch prop {
+		case "display":
+			hides = value == "none"
+		case "visibility":
+			hides = value == "hidden" || value == "collapse"
+		case "opacity", "font-size":
+			n, err := strconv.ParseFloat(strings.TrimRight(value, "pxemrt%"), 64)
+			hides = err == nil && n == 0
+		case "text-indent", "left", "top", "margin-left":
+			// Off-screen positioning, e.g. text-indent: -9999px
+			n, err := strconv.ParseFloat(strings.TrimSuffix(value, "px"), 64)
+			hides = err == nil && n <= -999
+		}
+		if hides {
+			return prop + ":" + value
+		}
+	}
+	return ""
+}
+
+// hiddenBy explains why the element or one of its ancestors is hidden, or
+// returns "" when it looks visible.
+func hiddenBy(n *html.Node, rules map[string]string) string {
+	for e := n; e != nil; e = e.Parent {
+		if e.Type != html.ElementNode {
+			continue
+		}
+		if hasAttr(e, "hidden") {
+			return "hidden attribute"
+		}
+		if decl := hidingDeclaration(attr(e, "style")); decl != "" {
+			return "style " + decl
+		}
+
+		id := strings.ToLower(attr(e, "id"))
+		classes := strings.Fields(strings.ToLower(attr(e, "class")))
+		selectors := []string{e.Data}
+		if id != "" {
+			selectors = append(selectors, "#"+id, e.Data+"#"+id)
+		}
+		for _, c := range classes {
+			selectors = append(selectors, "."+c, e.Data+"."+c)
+		}
+		for _, sel := range selectors {
+			if decl, ok := rules[sel]; ok {
+				return "stylesheet " + sel + " " + decl
+			}
+		}
+		for _, c := range classes {
+			if hiddenClasses[c] {
+				return "class " + c
+			}
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return true
+		}
+	}
+	return false
+}
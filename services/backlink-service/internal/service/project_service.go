@@ -2,58 +2,106 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/link-tracker/backlink-service/internal/model"
 	"github.com/link-tracker/backlink-service/internal/repository"
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/response"
 )
 
 type ProjectService struct {
-	projectRepo *repository.ProjectRepository
+	projectRepo  *repository.ProjectRepository
+	backlinkRepo *repository.BacklinkRepository
 }
 
-func NewProjectService(projectRepo *repository.ProjectRepository) *ProjectService {
-	return &ProjectService{projectRepo: projectRepo}
+func NewProjectService(projectRepo *repository.ProjectRepository, backlinkRepo *repository.BacklinkRepository) *ProjectService {
+	return &ProjectService{
+		projectRepo:  projectRepo,
+		backlinkRepo: backlinkRepo,
+	}
 }
 
 func (s *ProjectService) Create(ctx context.Context, userID int64, req *model.CreateProjectRequest) (*model.Project, error) {
+	if req.AnchorSettings != nil {
+		if err := validateAnchorSettings(req.AnchorSettings); err != nil {
+			return nil, err
+		}
+	}
+
+	role := models.MemberOwner
+	if req.WorkspaceID != nil {
+		var err error
+		if role, err = s.workspaceRole(ctx, *req.WorkspaceID, userID); err != nil {
+			return nil, err
+		}
+	}
+
 	project := &model.Project{
-		Name:          req.Name,
-		UserID:        userID,
-		GoogleSheetID: req.GoogleSheetID,
+		Name:           req.Name,
+		UserID:         userID,
+		GoogleSheetID:  req.GoogleSheetID,
+		URLRules:       req.URLRules,
+		AnchorSettings: req.AnchorSettings,
+		WorkspaceID:    req.WorkspaceID,
 	}
 
 	if err := s.projectRepo.Create(ctx, project); err != nil {
 		return nil, err
 	}
+	project.Role = role
 
 	return project, nil
 }
 
 func (s *ProjectService) GetByID(ctx context.Context, userID, projectID int64) (*model.Project, error) {
-	project, err := s.projectRepo.GetByID(ctx, projectID)
-	if err != nil {
-		return nil, err
-	}
-
-	if project.UserID != userID {
-		return nil, ErrUnauthorized
-	}
-
-	return project, nil
+	return s.authorize(ctx, userID, projectID, models.MemberViewer)
 }
 
 func (s *ProjectService) List(ctx context.Context, userID int64) ([]*model.Project, error) {
 	return s.projectRepo.GetByUserID(ctx, userID)
 }
 
+// ListCursor returns a page of the user's projects after the given position,
+// newest first, and the cursor of the next page, nil on the last one.
+func (s *ProjectService) ListCursor(ctx context.Context, userID int64, after *model.TimeCursor, perPage int) ([]*model.Project, *model.TimeCursor, error) {
+	if after != nil && !validTimeCursor(after) {
+		return nil, nil, response.ErrInvalidCursor
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	projects, hasMore, err := s.projectRepo.ListAfter(ctx, userID, after, perPage)
+	if err != nil || !hasMore {
+		return projects, nil, err
+	}
+	last := projects[len(projects)-1]
+	return projects, &model.TimeCursor{At: last.CreatedAt, ID: last.ID}, nil
+}
+
 func (s *ProjectService) Update(ctx context.Context, userID, projectID int64, req *model.UpdateProjectRequest) (*model.Project, error) {
-	project, err := s.projectRepo.GetByID(ctx, projectID)
+	project, err := s.authorize(ctx, userID, projectID, models.MemberEditor)
 	if err != nil {
 		return nil, err
 	}
 
-	if project.UserID != userID {
-		return nil, ErrUnauthorized
+	// Moving a project between workspaces changes who can see it, so it
+	// takes an owner, who must be able to add projects to the target.
+	if req.WorkspaceID != nil {
+		if project.Role != models.MemberOwner {
+			return nil, ErrUnauthorized
+		}
+		if *req.WorkspaceID == 0 {
+			project.WorkspaceID = nil
+		} else {
+			if _, err := s.workspaceRole(ctx, *req.WorkspaceID, userID); err != nil {
+				return nil, err
+			}
+			project.WorkspaceID = req.WorkspaceID
+		}
 	}
 
 	if req.Name != nil {
@@ -62,23 +110,133 @@ func (s *ProjectService) Update(ctx context.Context, userID, projectID int64, re
 	if req.GoogleSheetID != nil {
 		project.GoogleSheetID = req.GoogleSheetID
 	}
+	rulesChanged := req.URLRules != nil && !reflect.DeepEqual(*req.URLRules, project.Rules())
+	if req.URLRules != nil {
+		project.URLRules = req.URLRules
+	}
+	if req.AnchorSettings != nil {
+		if err := validateAnchorSettings(req.AnchorSettings); err != nil {
+			return nil, err
+		}
+		project.AnchorSettings = req.AnchorSettings
+	}
 
-	if err := s.projectRepo.Update(ctx, project); err != nil {
+	// Canonical URLs depend on the rules, so they are recomputed for the
+	// project together with the update
+	if rulesChanged {
+		rules := project.Rules()
+		canonicalize := func(b *model.Backlink) { setCanonical(b, rules) }
+		if err := s.backlinkRepo.RecomputeCanonical(ctx, project.ID, canonicalize, s.projectRepo.Updating(project)); err != nil {
+			return nil, err
+		}
+	} else if err := s.projectRepo.Update(ctx, project); err != nil {
 		return nil, err
 	}
 
+	if req.WorkspaceID != nil {
+		return s.projectRepo.GetByID(ctx, project.ID, userID)
+	}
+
 	return project, nil
 }
 
 func (s *ProjectService) Delete(ctx context.Context, userID, projectID int64) error {
-	project, err := s.projectRepo.GetByID(ctx, projectID)
-	if err != nil {
+	if _, err := s.authorize(ctx, userID, projectID, models.MemberOwner); err != nil {
 		return err
 	}
 
-	if project.UserID != userID {
-		return ErrUnauthorized
+	return s.projectRepo.Delete(ctx, projectID)
+}
+
+// Usage counts the projects and backlinks a user created. It is for admins
+// and does not check access.
+func (s *ProjectService) Usage(ctx context.Context, userID int64) (*model.UserUsageResponse, error) {
+	projects, backlinks, err := s.projectRepo.CountUsage(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserUsageResponse{UserID: userID, Projects: projects, Backlinks: backlinks}, nil
+}
+
+// Members lists the users the project is shared with directly. Workspace
+// members are managed in auth-service.
+func (s *ProjectService) Members(ctx context.Context, userID, projectID int64) ([]*model.ProjectMember, error) {
+	if _, err := s.authorize(ctx, userID, projectID, models.MemberEditor); err != nil {
+		return nil, err
 	}
 
-	return s.projectRepo.Delete(ctx, projectID)
+	return s.projectRepo.ListMembers(ctx, projectID)
+}
+
+// Share gives a registered user editor or viewer access to the project, or
+// changes the role of a user it is already shared with.
+func (s *ProjectService) Share(ctx context.Context, userID, projectID int64, req *model.ShareProjectRequest) (*model.ProjectMember, error) {
+	if req.Role != models.MemberEditor && req.Role != models.MemberViewer {
+		return nil, fmt.Errorf("%w: role must be editor or viewer", ErrValidation)
+	}
+	if _, err := s.authorize(ctx, userID, projectID, models.MemberOwner); err != nil {
+		return nil, err
+	}
+
+	memberID, err := s.projectRepo.FindUserByEmail(ctx, strings.TrimSpace(req.Email))
+	if err != nil {
+		return nil, err
+	}
+	if memberID == userID {
+		return nil, fmt.Errorf("%w: cannot share a project with yourself", ErrValidation)
+	}
+
+	member := &model.ProjectMember{
+		ProjectID: projectID,
+		UserID:    memberID,
+		Email:     strings.TrimSpace(req.Email),
+		Role:      req.Role,
+	}
+	if err := s.projectRepo.SetMember(ctx, member); err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// Unshare revokes the direct access of a user. Users may also remove
+// themselves from a project shared with them.
+func (s *ProjectService) Unshare(ctx context.Context, userID, projectID, memberID int64) error {
+	if memberID != userID {
+		if _, err := s.authorize(ctx, userID, projectID, models.MemberOwner); err != nil {
+			return err
+		}
+	}
+
+	return s.projectRepo.RemoveMember(ctx, projectID, memberID)
+}
+
+// authorize loads the project and checks that userID has at least role on
+// it.
+func (s *ProjectService) authorize(ctx context.Context, userID, projectID int64, role models.MemberRole) (*model.Project, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !project.Role.Includes(role) {
+		return nil, ErrUnauthorized
+	}
+
+	return project, nil
+}
+
+// workspaceRole returns the role of userID in a workspace they add a project
+// to, which takes at least an editor.
+func (s *ProjectService) workspaceRole(ctx context.Context, workspaceID, userID int64) (models.MemberRole, error) {
+	role, err := s.projectRepo.WorkspaceRole(ctx, workspaceID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if !role.Includes(models.MemberEditor) {
+		return "", ErrUnauthorized
+	}
+
+	return role, nil
 }
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/response"
+)
+
+const dateLayout = "2006-01-02"
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// applyCommercialTerms validates and copies the purchase terms from a create
+// or update request. Nil fields leave the current value untouched.
+func applyCommercialTerms(b *model.Backlink, vendor *string, price *float64, currency, placedAt, paidUntil *string) error {
+	if vendor != nil {
+		v := strings.TrimSpace(*vendor)
+		if v == "" {
+			b.Vendor = nil
+		} else {
+			b.Vendor = &v
+		}
+	}
+	if price != nil {
+		if *price < 0 {
+			return fmt.Errorf("%w: price must not be negative", ErrValidation)
+		}
+		b.Price = price
+	}
+	if currency != nil {
+		c := strings.ToUpper(strings.TrimSpace(*currency))
+		if !currencyPattern.MatchString(c) {
+			return fmt.Errorf("%w: currency must be an ISO 4217 code", ErrValidation)
+		}
+		b.Currency = &c
+	}
+	if placedAt != nil {
+		t, err := parseDate(*placedAt, "placed_at")
+		if err != nil {
+			return err
+		}
+		b.PlacedAt = t
+	}
+	if paidUntil != nil {
+		t, err := parseDate(*paidUntil, "paid_until")
+		if err != nil {
+			return err
+		}
+		b.PaidUntil = t
+	}
+
+	if b.Price != nil && b.Currency == nil {
+		return fmt.Errorf("%w: currency is required when price is set", ErrValidation)
+	}
+	if b.PlacedAt != nil && b.PaidUntil != nil && b.PaidUntil.Before(*b.PlacedAt) {
+		return fmt.Errorf("%w: paid_until must not be before placed_at", ErrValidation)
+	}
+	return nil
+}
+
+// parseDate parses a YYYY-MM-DD date; an empty string clears the value.
+func parseDate(value, field string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be YYYY-MM-DD", ErrValidation, field)
+	}
+	return &t, nil
+}
+
+// setStatus changes the link status and keeps lost_at in sync: it is set when
+// a link goes missing and cleared when it comes back.
+func setStatus(b *model.Backlink, status model.LinkStatus) {
+	switch {
+	case status.IsLost() && !b.Status.IsLost():
+		now := time.Now()
+		b.LostAt = &now
+	case !status.IsLost() && status != model.LinkStatusPending:
+		b.LostAt = nil
+	}
+	b.Status = status
+}
+
+func (s *BacklinkService) SpendReport(ctx context.Context, userID, projectID int64, filters *model.SpendFilters) (*model.SpendReport, error) {
+	allowed, err := s.projectRepo.HasRole(ctx, projectID, userID, models.MemberViewer)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrUnauthorized
+	}
+
+	var from, to *time.Time
+	if filters.From != nil {
+		if from, err = parseDate(*filters.From, "from"); err != nil {
+			return nil, err
+		}
+	}
+	if filters.To != nil {
+		if to, err = parseDate(*filters.To, "to"); err != nil {
+			return nil, err
+		}
+	}
+
+	report, err := s.backlinkRepo.GetSpend(ctx, projectID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	report.From = filters.From
+	report.To = filters.To
+	return report, nil
+}
+
+func (s *BacklinkService) LostPaidLinks(ctx context.Context, userID, projectID int64, page, perPage int) ([]model.LostPaidLinkResponse, int64, error) {
+	allowed, err := s.projectRepo.HasRole(ctx, projectID, userID, models.MemberViewer)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !allowed {
+		return nil, 0, ErrUnauthorized
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	backlinks, total, err := s.backlinkRepo.ListLostPaid(ctx, projectID, page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data := make([]model.LostPaidLinkResponse, len(backlinks))
+	for i, b := range backlinks {
+		data[i] = lostPaidLink(b)
+	}
+	return data, total, nil
+}
+
+// LostPaidLinksCursor lists lost paid links after the given position, most
+// recently lost first, and returns the cursor of the next page, nil on the
+// last one.
+func (s *BacklinkService) LostPaidLinksCursor(ctx context.Context, userID, projectID int64, after *model.TimeCursor, perPage int) ([]model.LostPaidLinkResponse, *model.TimeCursor, error) {
+	if after != nil && !validTimeCursor(after) {
+		return nil, nil, response.ErrInvalidCursor
+	}
+	allowed, err := s.projectRepo.HasRole(ctx, projectID, userID, models.MemberViewer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !allowed {
+		return nil, nil, ErrUnauthorized
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	backlinks, hasMore, err := s.backlinkRepo.ListLostPaidAfter(ctx, projectID, after, perPage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := make([]model.LostPaidLinkResponse, len(backlinks))
+	for i, b := range backlinks {
+		data[i] = lostPaidLink(b)
+	}
+	if !hasMore {
+		return data, nil, nil
+	}
+	last := backlinks[len(backlinks)-1]
+	return data, &model.TimeCursor{At: *last.LostAt, ID: last.ID}, nil
+}
+
+// lostPaidLink prorates the refund over the paid term by whole days.
+func lostPaidLink(b *model.Backlink) model.LostPaidLinkResponse {
+	resp := model.LostPaidLinkResponse{BacklinkResponse: model.BacklinkToResponse(b)}
+
+	lostDay := truncateDay(*b.LostAt)
+	resp.UnusedDays = daysBetween(lostDay, *b.PaidUntil)
+
+	if b.PlacedAt != nil {
+		term := daysBetween(*b.PlacedAt, *b.PaidUntil)
+		if term > 0 {
+			refund := math.Round(*b.Price*float64(resp.UnusedDays)/float64(term)*100) / 100
+			resp.RefundEstimate = &refund
+		}
+	}
+	return resp
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	days := int(truncateDay(to).Sub(truncateDay(from)).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/shared/pkg/models"
+	"golang.org/x/net/idna"
+)
+
+const (
+	defaultStatsWeeks = 12
+	maxStatsWeeks     = 104
+	topAnchorsLimit   = 10
+)
+
+// genericAnchors are anchor texts that say nothing about the target page.
+var genericAnchors = []string{
+	"здесь", "тут", "сюда", "там", "этот", "по ссылке", "ссылка", "ссылке",
+	"перейти", "перейти на сайт", "подробнее", "читать", "читать далее",
+	"читать полностью", "узнать больше", "источник", "сайт", "на сайте",
+	"официальный сайт", "смотреть", "click here", "here", "this", "link",
+	"read more", "more", "learn more", "website", "this website", "site",
+	"visit", "visit site", "source", "go", "see more",
+}
+
+var urlAnchor = regexp.MustCompile(`^(?:https?://|www\.)\S+$|^[\p{L}\d-]+(?:\.[\p{L}\d-]+)+(?:/\S*)?$`)
+
+// anchorClassifier sorts anchor texts into categories for one project.
+type anchorClassifier struct {
+	keywords []string
+	brands   []string
+	generic  map[string]bool
+}
+
+func newAnchorClassifier(settings model.AnchorSettings) *anchorClassifier {
+	c := &anchorClassifier{generic: make(map[string]bool)}
+	for _, k := range settings.Keywords {
+		if k = normalizeAnchor(k); k != "" {
+			c.keywords = append(c.keywords, k)
+		}
+	}
+	for _, b := range settings.Brands {
+		if b = normalizeAnchor(b); b != "" {
+			c.brands = append(c.brands, b)
+		}
+	}
+	for _, g := range append(append([]string(nil), genericAnchors...), settings.Generic...) {
+		c.generic[normalizeAnchor(g)] = true
+	}
+	return c
+}
+
+// classify returns the category of anchor for a link to targetDomain, whose
+// second level label counts as a brand name. Keywords win over brands only
+// for exact matches, so "buy windows at Acme" is a brand anchor.
+func (c *anchorClassifier) classify(anchor, targetDomain string) model.AnchorCategory {
+	a := normalizeAnchor(anchor)
+	switch {
+	case a == "" || c.generic[a]:
+		// Empty anchors are mostly image links without alt text.
+		return model.AnchorGeneric
+	case urlAnchor.MatchString(a):
+		return model.AnchorURL
+	}
+	for _, k := range c.keywords {
+		if a == k {
+			return model.AnchorExact
+		}
+	}
+	for _, b := range c.brands {
+		if containsBrand(a, b) {
+			return model.AnchorBrand
+		}
+	}
+	if b := domainBrand(targetDomain); b != "" && containsBrand(a, b) {
+		return model.AnchorBrand
+	}
+	for _, k := range c.keywords {
+		if containsKeyword(a, k) {
+			return model.AnchorPartial
+		}
+	}
+	return model.AnchorOther
+}
+
+// domainBrand returns the label before the public suffix, e.g. "acme" for
+// "shop.acme.co.uk", or "" when it is shorter than three characters. Second
+// level suffixes such as co.uk and com.ru are skipped.
+func domainBrand(host string) string {
+	// Hosts are stored in punycode; anchors are written in Unicode.
+	if u, err := idna.Lookup.ToUnicode(host); err == nil {
+		host = u
+	}
+	labels := strings.Split(strings.TrimPrefix(host, "www."), ".")
+	if len(labels) < 2 {
+		return ""
+	}
+	i := len(labels) - 2
+	if i > 0 && len(labels[i]) <= 3 && len(labels[len(labels)-1]) == 2 {
+		i--
+	}
+	if len([]rune(labels[i])) < 3 {
+		return ""
+	}
+	return labels[i]
+}
+
+// containsBrand reports whether brand occurs in anchor. Short brands must be
+// whole words; longer ones may be inflected, e.g. "яндекса".
+func containsBrand(anchor, brand string) bool {
+	if len([]rune(brand)) < 4 {
+		return strings.Contains(" "+anchor+" ", " "+brand+" ")
+	}
+	return strings.Contains(anchor, brand)
+}
+
+// containsKeyword reports whether every word of keyword occurs in anchor,
+// comparing word stems so that inflected forms ("пластиковые",
+// "пластиковых") match.
+func containsKeyword(anchor, keyword string) bool {
+	if strings.Contains(anchor, keyword) {
+		return true
+	}
+	words := strings.Fields(anchor)
+	for _, kw := range strings.Fields(keyword) {
+		stem := wordStem(kw)
+		found := false
+		for _, w := range words {
+			if strings.HasPrefix(w, stem) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// wordStem drops up to two trailing letters, keeping at least three.
+func wordStem(word string) string {
+	r := []rune(word)
+	n := max(len(r)-2, min(len(r), 3))
+	return string(r[:n])
+}
+
+// Stats counts the links of a project and reports the anchor distribution
+// of links that are not lost.
+func (s *ProjectService) Stats(ctx context.Context, userID, projectID int64, weeks int) (*model.ProjectStats, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !project.Role.Includes(models.MemberViewer) {
+		return nil, ErrUnauthorized
+	}
+
+	if weeks < 1 || weeks > maxStatsWeeks {
+		weeks = defaultStatsWeeks
+	}
+
+	stats, err := s.backlinkRepo.GetStats(ctx, projectID, weeks)
+	if err != nil {
+		return nil, err
+	}
+
+	anchors, err := s.backlinkRepo.ListAnchorCounts(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	stats.Anchors = anchorDistribution(anchors, project.Anchors())
+
+	return stats, nil
+}
+
+func anchorDistribution(counts []model.AnchorCount, settings model.AnchorSettings) model.AnchorDistribution {
+	dist := model.AnchorDistribution{
+		Groups:     make([]model.AnchorGroup, len(model.AnchorCategories)),
+		TopAnchors: []model.AnchorCount{},
+		Warnings:   []model.AnchorWarning{},
+		Thresholds: settings.Thresholds,
+	}
+	classifier := newAnchorClassifier(settings)
+
+	byCategory := make(map[model.AnchorCategory]int)
+	byAnchor := make(map[string]*model.AnchorCount)
+	for _, c := range counts {
+		category := classifier.classify(c.Anchor, c.TargetDomain)
+		byCategory[category] += c.Links
+		dist.Total += c.Links
+
+		key := normalizeAnchor(c.Anchor)
+		if a, ok := byAnchor[key]; ok {
+			a.Links += c.Links
+			continue
+		}
+		byAnchor[key] = &model.AnchorCount{Anchor: c.Anchor, Category: category, Links: c.Links}
+	}
+
+	for i, category := range model.AnchorCategories {
+		dist.Groups[i] = model.AnchorGroup{
+			Category: category,
+			Links:    byCategory[category],
+			Percent:  percentOf(byCategory[category], dist.Total),
+		}
+	}
+
+	for _, a := range byAnchor {
+		a.Percent = percentOf(a.Links, dist.Total)
+		dist.TopAnchors = append(dist.TopAnchors, *a)
+	}
+	sort.Slice(dist.TopAnchors, func(i, j int) bool {
+		if dist.TopAnchors[i].Links != dist.TopAnchors[j].Links {
+			return dist.TopAnchors[i].Links > dist.TopAnchors[j].Links
+		}
+		return dist.TopAnchors[i].Anchor < dist.TopAnchors[j].Anchor
+	})
+	if len(dist.TopAnchors) > topAnchorsLimit {
+		dist.TopAnchors = dist.TopAnchors[:topAnchorsLimit]
+	}
+
+	if dist.Total == 0 {
+		return dist
+	}
+	t := settings.Thresholds
+	if p := percentOf(byCategory[model.AnchorExact], dist.Total); p > t.ExactMaxPercent {
+		dist.Warnings = append(dist.Warnings, model.AnchorWarning{
+			Code:      "exact_match_share",
+			Message:   fmt.Sprintf("%.1f%% of links use exact-match anchors", p),
+			Percent:   p,
+			Threshold: t.ExactMaxPercent,
+		})
+	}
+	if p := percentOf(byCategory[model.AnchorPartial], dist.Total); p > t.PartialMaxPercent {
+		dist.Warnings = append(dist.Warnings, model.AnchorWarning{
+			Code:      "partial_match_share",
+			Message:   fmt.Sprintf("%.1f%% of links use partial-match anchors", p),
+			Percent:   p,
+			Threshold: t.PartialMaxPercent,
+		})
+	}
+	// Repeating a brand, URL or generic anchor is natural; repeating a
+	// keyword anchor is what gets penalized.
+	for _, a := range dist.TopAnchors {
+		if a.Category != model.AnchorExact && a.Category != model.AnchorPartial && a.Category != model.AnchorOther {
+			continue
+		}
+		if a.Percent > t.SingleAnchorMaxPercent {
+			dist.Warnings = append(dist.Warnings, model.AnchorWarning{
+				Code:      "single_anchor_share",
+				Message:   fmt.Sprintf("anchor %q is used by %.1f%% of links", a.Anchor, a.Percent),
+				Percent:   a.Percent,
+				Threshold: t.SingleAnchorMaxPercent,
+			})
+		}
+	}
+
+	return dist
+}
+
+// percentOf returns part as a percentage of total rounded to one decimal.
+func percentOf(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
+
+// validateAnchorSettings trims the configured phrases and checks that the
+// thresholds are percentages.
+func validateAnchorSettings(settings *model.AnchorSettings) error {
+	settings.Keywords = trimPhrases(settings.Keywords)
+	settings.Brands = trimPhrases(settings.Brands)
+	settings.Generic = trimPhrases(settings.Generic)
+
+	t := settings.Thresholds
+	for _, v := range []float64{t.ExactMaxPercent, t.PartialMaxPercent, t.SingleAnchorMaxPercent} {
+		if v < 0 || v > 100 {
+			return fmt.Errorf("%w: anchor thresholds must be between 0 and 100", ErrValidation)
+		}
+	}
+	return nil
+}
+
+func trimPhrases(phrases []string) []string {
+	out := []string{}
+	for _, p := range phrases {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/link-tracker/backlink-service/internal/model"
+	"github.com/link-tracker/backlink-service/internal/repository"
+)
+
+type storeOutcome int
+
+const (
+	storeCreated storeOutcome = iota
+	storeUpdated
+	storeSkipped
+)
+
+// storeBacklink creates b, whose canonical URLs must already be set. When
+// the project already has a link with the same key, mode decides: the
+// duplicate error is returned, the existing link is returned unchanged, or
+// the fields set in req are applied to the existing link.
+func storeBacklink(ctx context.Context, repo *repository.BacklinkRepository, b *model.Backlink, req *model.CreateBacklinkRequest, mode model.ConflictMode) (*model.Backlink, storeOutcome, error) {
+	// Inserting first lets the unique index arbitrate concurrent creates.
+	err := repo.Create(ctx, b)
+	if err == nil {
+		return b, storeCreated, nil
+	}
+	if !errors.Is(err, repository.ErrDuplicateBacklink) || mode == "" || mode == model.ConflictError {
+		return nil, storeCreated, err
+	}
+
+	existing, err := repo.GetByLinkKey(ctx, b.ProjectID, b.LinkKey)
+	if err != nil {
+		return nil, storeCreated, err
+	}
+	if mode == model.ConflictSkip {
+		return existing, storeSkipped, nil
+	}
+
+	if err := mergeInto(existing, req); err != nil {
+		return nil, storeCreated, err
+	}
+	if err := repo.Update(ctx, existing); err != nil {
+		return nil, storeCreated, err
+	}
+	return existing, storeUpdated, nil
+}
+
+// newBacklink builds a pending link from a create request. Canonical URLs
+// are left for the caller, which knows the project rules.
+func newBacklink(req *model.CreateBacklinkRequest) (*model.Backlink, error) {
+	backlink := &model.Backlink{
+		ProjectID:  req.ProjectID,
+		SourceURL:  req.SourceURL,
+		TargetURL:  req.TargetURL,
+		AnchorText: req.AnchorText,
+		Status:     model.LinkStatusPending,
+		LinkType:   req.LinkType,
+	}
+
+	if backlink.LinkType == "" {
+		backlink.LinkType = model.LinkTypeDoFollow
+	}
+	if !backlink.LinkType.IsValid() {
+		return nil, fmt.Errorf("%w: invalid link_type %q", ErrValidation, backlink.LinkType)
+	}
+
+	if err := applyCommercialTerms(backlink, req.Vendor, req.Price, req.Currency, req.PlacedAt, req.PaidUntil); err != nil {
+		return nil, err
+	}
+	return backlink, nil
+}
+
+// mergeInto applies the fields set in req to an existing link. On error the
+// link is left unchanged.
+func mergeInto(existing *model.Backlink, req *model.CreateBacklinkRequest) error {
+	merged := *existing
+	if strings.TrimSpace(req.AnchorText) != "" {
+		merged.AnchorText = req.AnchorText
+	}
+	if req.LinkType != "" {
+		if !req.LinkType.IsValid() {
+			return fmt.Errorf("%w: invalid link_type %q", ErrValidation, req.LinkType)
+		}
+		merged.LinkType = req.LinkType
+	}
+	if err := applyCommercialTerms(&merged, req.Vendor, req.Price, req.Currency, req.PlacedAt, req.PaidUntil); err != nil {
+		return err
+	}
+	*existing = merged
+	return nil
+}
+
+// validateConflictMode rejects unknown on_conflict values.
+func validateConflictMode(mode model.ConflictMode) error {
+	if !mode.IsValid() {
+		return fmt.Errorf("%w: on_conflict must be one of: error, skip, update", ErrValidation)
+	}
+	return nil
+}
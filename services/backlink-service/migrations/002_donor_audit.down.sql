@@ -0,0 +1,14 @@
+-- Donor page audit rollback
+
+DROP INDEX IF EXISTS idx_backlinks_donor_indexable;
+
+ALTER TABLE backlinks
+    DROP COLUMN IF EXISTS donor_indexable,
+    DROP COLUMN IF EXISTS donor_has_noindex,
+    DROP COLUMN IF EXISTS donor_canonical_url,
+    DROP COLUMN IF EXISTS donor_canonical_ok,
+    DROP COLUMN IF EXISTS donor_robots_googlebot,
+    DROP COLUMN IF EXISTS donor_robots_yandex,
+    DROP COLUMN IF EXISTS donor_outbound_links,
+    DROP COLUMN IF EXISTS donor_in_sitemap,
+    DROP COLUMN IF EXISTS donor_audited_at;
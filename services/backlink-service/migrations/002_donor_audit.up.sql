@@ -0,0 +1,13 @@
+-- Donor page audit recorded when a backlink is checked
+ALTER TABLE backlinks
+    ADD COLUMN IF NOT EXISTS donor_indexable BOOLEAN,
+    ADD COLUMN IF NOT EXISTS donor_has_noindex BOOLEAN,
+    ADD COLUMN IF NOT EXISTS donor_canonical_url TEXT,
+    ADD COLUMN IF NOT EXISTS donor_canonical_ok BOOLEAN,
+    ADD COLUMN IF NOT EXISTS donor_robots_googlebot BOOLEAN,
+    ADD COLUMN IF NOT EXISTS donor_robots_yandex BOOLEAN,
+    ADD COLUMN IF NOT EXISTS donor_outbound_links INTEGER,
+    ADD COLUMN IF NOT EXISTS donor_in_sitemap BOOLEAN,
+    ADD COLUMN IF NOT EXISTS donor_audited_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_backlinks_donor_indexable ON backlinks(project_id, donor_indexable);
@@ -0,0 +1,12 @@
+-- Commercial terms rollback
+
+DROP INDEX IF EXISTS idx_backlinks_lost_paid;
+DROP INDEX IF EXISTS idx_backlinks_vendor;
+
+ALTER TABLE backlinks
+    DROP COLUMN IF EXISTS vendor,
+    DROP COLUMN IF EXISTS price,
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS placed_at,
+    DROP COLUMN IF EXISTS paid_until,
+    DROP COLUMN IF EXISTS lost_at;
@@ -0,0 +1,12 @@
+-- Commercial terms of purchased links
+ALTER TABLE backlinks
+    ADD COLUMN IF NOT EXISTS vendor VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS price NUMERIC(12, 2),
+    ADD COLUMN IF NOT EXISTS currency CHAR(3),
+    ADD COLUMN IF NOT EXISTS placed_at DATE,
+    ADD COLUMN IF NOT EXISTS paid_until DATE,
+    ADD COLUMN IF NOT EXISTS lost_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_backlinks_vendor ON backlinks(project_id, vendor);
+CREATE INDEX IF NOT EXISTS idx_backlinks_lost_paid ON backlinks(project_id, lost_at)
+    WHERE price IS NOT NULL AND paid_until IS NOT NULL;
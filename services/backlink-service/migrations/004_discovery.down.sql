@@ -0,0 +1,4 @@
+-- Backlink discovery rollback
+
+DROP TABLE IF EXISTS discovered_links;
+DROP TABLE IF EXISTS discovery_runs;
@@ -0,0 +1,32 @@
+-- Backlink discovery by crawling donor domains
+CREATE TABLE IF NOT EXISTS discovery_runs (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    target_domains TEXT[] NOT NULL,
+    donor_domains TEXT[] NOT NULL,
+    max_depth INTEGER NOT NULL,
+    max_pages INTEGER NOT NULL,
+    pages_crawled INTEGER NOT NULL DEFAULT 0,
+    links_found INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_discovery_runs_project_id ON discovery_runs(project_id);
+
+CREATE TABLE IF NOT EXISTS discovered_links (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    run_id BIGINT NOT NULL REFERENCES discovery_runs(id) ON DELETE CASCADE,
+    source_url TEXT NOT NULL,
+    target_url TEXT NOT NULL,
+    anchor_text VARCHAR(500) NOT NULL DEFAULT '',
+    link_type link_type NOT NULL DEFAULT 'dofollow',
+    adopted_backlink_id BIGINT REFERENCES backlinks(id) ON DELETE SET NULL,
+    discovered_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (project_id, source_url, target_url)
+);
+
+CREATE INDEX IF NOT EXISTS idx_discovered_links_project_id ON discovered_links(project_id, discovered_at DESC);
@@ -0,0 +1,3 @@
+-- Backlink imports rollback
+
+DROP TABLE IF EXISTS backlink_imports;
@@ -0,0 +1,12 @@
+-- Parsed third-party exports awaiting commit
+CREATE TABLE IF NOT EXISTS backlink_imports (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    format VARCHAR(20) NOT NULL,
+    parsed_rows JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    committed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_backlink_imports_created_at ON backlink_imports(created_at);
@@ -0,0 +1,12 @@
+-- URL canonical rollback
+
+ALTER TABLE discovered_links DROP COLUMN IF EXISTS target_url_canonical;
+ALTER TABLE discovered_links DROP COLUMN IF EXISTS source_url_canonical;
+
+DROP INDEX IF EXISTS idx_backlinks_target_url_canonical;
+DROP INDEX IF EXISTS idx_backlinks_source_url_canonical;
+
+ALTER TABLE backlinks DROP COLUMN IF EXISTS target_url_canonical;
+ALTER TABLE backlinks DROP COLUMN IF EXISTS source_url_canonical;
+
+ALTER TABLE projects DROP COLUMN IF EXISTS url_rules;
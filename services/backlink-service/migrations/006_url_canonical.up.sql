@@ -0,0 +1,11 @@
+-- URL equivalence rules and canonical URLs
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS url_rules JSONB;
+
+ALTER TABLE backlinks ADD COLUMN IF NOT EXISTS source_url_canonical TEXT;
+ALTER TABLE backlinks ADD COLUMN IF NOT EXISTS target_url_canonical TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_backlinks_source_url_canonical ON backlinks(project_id, source_url_canonical);
+CREATE INDEX IF NOT EXISTS idx_backlinks_target_url_canonical ON backlinks(project_id, target_url_canonical);
+
+ALTER TABLE discovered_links ADD COLUMN IF NOT EXISTS source_url_canonical TEXT;
+ALTER TABLE discovered_links ADD COLUMN IF NOT EXISTS target_url_canonical TEXT;
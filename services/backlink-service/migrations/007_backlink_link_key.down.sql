@@ -0,0 +1,5 @@
+-- Backlink link key rollback
+
+DROP INDEX IF EXISTS idx_backlinks_project_link_key;
+
+ALTER TABLE backlinks DROP COLUMN IF EXISTS link_key;
@@ -0,0 +1,5 @@
+-- Unique key of a link within a project: canonical source and target URLs.
+-- Filled by the service; duplicates that existed before stay NULL.
+ALTER TABLE backlinks ADD COLUMN IF NOT EXISTS link_key TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_backlinks_project_link_key ON backlinks(project_id, link_key);
@@ -0,0 +1,14 @@
+-- Link placement rollback
+
+DROP INDEX IF EXISTS idx_backlinks_placement_region;
+
+ALTER TABLE backlinks
+    DROP COLUMN IF EXISTS placement_region,
+    DROP COLUMN IF EXISTS placement_context,
+    DROP COLUMN IF EXISTS placement_position,
+    DROP COLUMN IF EXISTS placement_total_links,
+    DROP COLUMN IF EXISTS placement_position_pct,
+    DROP COLUMN IF EXISTS placement_is_image,
+    DROP COLUMN IF EXISTS placement_image_alt,
+    DROP COLUMN IF EXISTS placement_hidden,
+    DROP COLUMN IF EXISTS placement_hidden_by;
@@ -0,0 +1,13 @@
+-- Placement of the link on the donor page, recorded when a backlink is checked
+ALTER TABLE backlinks
+    ADD COLUMN IF NOT EXISTS placement_region VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS placement_context TEXT,
+    ADD COLUMN IF NOT EXISTS placement_position INTEGER,
+    ADD COLUMN IF NOT EXISTS placement_total_links INTEGER,
+    ADD COLUMN IF NOT EXISTS placement_position_pct SMALLINT,
+    ADD COLUMN IF NOT EXISTS placement_is_image BOOLEAN,
+    ADD COLUMN IF NOT EXISTS placement_image_alt TEXT,
+    ADD COLUMN IF NOT EXISTS placement_hidden BOOLEAN,
+    ADD COLUMN IF NOT EXISTS placement_hidden_by TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_backlinks_placement_region ON backlinks(project_id, placement_region);
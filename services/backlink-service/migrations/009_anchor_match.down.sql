@@ -0,0 +1,5 @@
+-- Anchor match rollback
+
+ALTER TABLE backlinks
+    DROP COLUMN IF EXISTS placement_anchor,
+    DROP COLUMN IF EXISTS placement_anchor_match;
@@ -0,0 +1,4 @@
+-- Anchor text found on the donor page and whether it matches the expected one
+ALTER TABLE backlinks
+    ADD COLUMN IF NOT EXISTS placement_anchor TEXT,
+    ADD COLUMN IF NOT EXISTS placement_anchor_match BOOLEAN;
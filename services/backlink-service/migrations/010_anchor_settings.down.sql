@@ -0,0 +1,3 @@
+-- Anchor settings rollback
+
+ALTER TABLE projects DROP COLUMN IF EXISTS anchor_settings;
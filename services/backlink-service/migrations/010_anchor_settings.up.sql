@@ -0,0 +1,2 @@
+-- Keywords, brands and thresholds for the anchor distribution report
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS anchor_settings JSONB;
@@ -0,0 +1,13 @@
+-- Backlink search rollback
+
+DROP INDEX IF EXISTS idx_backlinks_created_at;
+DROP INDEX IF EXISTS idx_backlinks_source_domain;
+
+ALTER TABLE backlinks DROP COLUMN IF EXISTS source_domain;
+
+DROP INDEX IF EXISTS idx_backlinks_vendor_trgm;
+DROP INDEX IF EXISTS idx_backlinks_anchor_text_trgm;
+DROP INDEX IF EXISTS idx_backlinks_target_url_trgm;
+DROP INDEX IF EXISTS idx_backlinks_source_url_trgm;
+
+-- pg_trgm is left installed; other schemas may use it.
@@ -0,0 +1,15 @@
+-- Trigram indexes for substring search and the source domain for sorting
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_backlinks_source_url_trgm ON backlinks USING GIN (source_url gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_backlinks_target_url_trgm ON backlinks USING GIN (target_url gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_backlinks_anchor_text_trgm ON backlinks USING GIN (anchor_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_backlinks_vendor_trgm ON backlinks USING GIN (vendor gin_trgm_ops);
+
+-- Host without "www.", from the canonical URL when known so IDN hosts are in punycode
+ALTER TABLE backlinks ADD COLUMN IF NOT EXISTS source_domain TEXT GENERATED ALWAYS AS (
+    substring(COALESCE(source_url_canonical, lower(source_url)) from '^(?:[a-z][a-z0-9+.-]*:)?//(?:www\.)?([^/:?#]+)')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_backlinks_source_domain ON backlinks(project_id, source_domain);
+CREATE INDEX IF NOT EXISTS idx_backlinks_created_at ON backlinks(project_id, created_at DESC);
@@ -0,0 +1,6 @@
+-- Project sharing rollback
+
+DROP TABLE IF EXISTS project_members;
+
+DROP INDEX IF EXISTS idx_projects_workspace_id;
+ALTER TABLE projects DROP COLUMN IF EXISTS workspace_id;
@@ -0,0 +1,16 @@
+-- Workspace projects and per-user project shares. Requires the workspaces
+-- tables of auth-service (002_workspaces).
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS workspace_id BIGINT REFERENCES workspaces(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_projects_workspace_id ON projects(workspace_id);
+
+-- Users a single project is shared with, e.g. a client with read-only access
+CREATE TABLE IF NOT EXISTS project_members (
+    project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('editor', 'viewer')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (project_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_members_user_id ON project_members(user_id);
@@ -0,0 +1,2 @@
+-- IDN canonical URL recompute rollback
+-- Nothing to undo: the cleared values are recomputed by the service.
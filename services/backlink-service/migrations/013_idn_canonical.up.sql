@@ -0,0 +1,12 @@
+-- Canonical URLs computed before IDN hosts were converted to punycode still
+-- hold Unicode hosts. Canonical URLs are otherwise pure ASCII, so those rows
+-- are cleared here and recomputed by the service on startup.
+UPDATE backlinks
+SET source_url_canonical = NULL, target_url_canonical = NULL, link_key = NULL
+WHERE octet_length(source_url_canonical) <> char_length(source_url_canonical)
+   OR octet_length(target_url_canonical) <> char_length(target_url_canonical);
+
+UPDATE discovered_links
+SET source_url_canonical = NULL, target_url_canonical = NULL
+WHERE octet_length(source_url_canonical) <> char_length(source_url_canonical)
+   OR octet_length(target_url_canonical) <> char_length(target_url_canonical);
@@ -18,12 +18,19 @@ import (
 	"github.com/link-tracker/health-service/internal/handler"
 	"github.com/link-tracker/health-service/internal/repository"
 	"github.com/link-tracker/health-service/internal/service"
+	"github.com/link-tracker/shared/pkg/apikey"
+	"github.com/link-tracker/shared/pkg/jwks"
 	"github.com/link-tracker/shared/pkg/middleware"
 )
 
 func main() {
 	cfg := config.Load()
 
+	// Tokens signed with a public secret could be forged by anyone
+	if err := middleware.CheckLegacySecret(cfg.JWTLegacySecret); err != nil {
+		log.Fatalf("Invalid JWT configuration: %v", err)
+	}
+
 	// Database connection
 	dbPool, err := pgxpool.New(context.Background(), cfg.DatabaseURL)
 	if err != nil {
@@ -39,14 +46,36 @@ func main() {
 
 	// Initialize layers
 	siteRepo := repository.NewSiteRepository(dbPool)
-	siteService := service.NewSiteService(siteRepo)
+	contentRepo := repository.NewContentRepository(dbPool)
+	rollupRepo := repository.NewRollupRepository(dbPool)
+
+	// Domains of IDN hosts are stored in punycode; convert older rows
+	go func() {
+		n, err := siteRepo.NormalizeDomains(context.Background())
+		if err != nil {
+			log.Printf("Domain normalization failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Domain normalization: %d sites updated", n)
+		}
+	}()
+
+	retention := service.RetentionPolicy{
+		RawDays:    cfg.RawRetentionDays,
+		HourlyDays: cfg.HourlyRetentionDays,
+		DailyDays:  cfg.DailyRetentionDays,
+	}
+	siteService := service.NewSiteService(siteRepo, contentRepo, rollupRepo, retention)
 	siteHandler := handler.NewSiteHandler(siteService)
+	adminHandler := handler.NewAdminHandler(siteService)
+	accountHandler := handler.NewAccountHandler(siteService)
 	healthHandler := handler.NewHealthHandler(dbPool)
 
 	// JWT middleware config
 	jwtConfig := middleware.JWTConfig{
-		Secret:     cfg.JWTSecret,
-		ContextKey: "claims",
+		Keys:         jwks.NewCache(cfg.JWKSURL),
+		LegacySecret: cfg.JWTLegacySecret,
+		APIKeys:      apikey.NewStore(dbPool),
+		Resource:     apikey.ResourceSites,
 	}
 
 	// Router setup
@@ -81,9 +110,25 @@ func main() {
 			r.Delete("/{id}", siteHandler.Delete)
 			r.Post("/{id}/check", siteHandler.CheckHealth)
 			r.Get("/{id}/history", siteHandler.GetHistory)
+			r.Get("/{id}/stats", siteHandler.GetStats)
+			r.Get("/{id}/changes", siteHandler.GetContentChanges)
 		})
 	})
 
+	// Internal routes, called by auth-service for the account export and
+	// deletion and the admin usage. They take the user from the path and
+	// have no authentication, so they are served on a port that is neither
+	// published nor proxied.
+	internal := chi.NewRouter()
+	internal.Use(chimiddleware.Logger)
+	internal.Use(chimiddleware.Recoverer)
+	internal.Use(chimiddleware.RequestID)
+	internal.Route("/internal/v1/users/{id}", func(r chi.Router) {
+		r.Get("/usage", adminHandler.Usage)
+		r.Get("/export", accountHandler.Export)
+		r.Delete("/data", accountHandler.DeleteData)
+	})
+
 	// Server
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -92,6 +137,19 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	internalServer := &http.Server{
+		Addr:         ":" + cfg.InternalPort,
+		Handler:      internal,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Background history compaction
+	compactCtx, stopCompaction := context.WithCancel(context.Background())
+	defer stopCompaction()
+	compactor := service.NewHistoryCompactor(rollupRepo, retention)
+	go compactor.Run(compactCtx, time.Duration(cfg.CompactionIntervalMinutes)*time.Minute)
 
 	// Graceful shutdown
 	go func() {
@@ -100,16 +158,26 @@ func main() {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
+	go func() {
+		log.Printf("Internal server starting on port %s", cfg.InternalPort)
+		if err := internalServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Internal server error: %v", err)
+		}
+	}()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopCompaction()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if err := internalServer.Shutdown(ctx); err != nil {
+		log.Printf("Internal server forced to shutdown: %v", err)
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
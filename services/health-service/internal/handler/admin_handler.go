@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/link-tracker/health-service/internal/service"
+	"github.com/link-tracker/shared/pkg/response"
+)
+
+// AdminHandler serves the admin routes auth-service aggregates. Routes are
+// only served on the internal port.
+type AdminHandler struct {
+	service *service.SiteService
+}
+
+func NewAdminHandler(service *service.SiteService) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+// Usage handles GET /internal/v1/users/{id}/usage
+func (h *AdminHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid user id", "INVALID_ID")
+		return
+	}
+
+	usage, err := h.service.Usage(r.Context(), userID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "failed to count usage", "INTERNAL_ERROR")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, usage)
+}
@@ -4,11 +4,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/link-tracker/health-service/internal/model"
 	"github.com/link-tracker/health-service/internal/service"
-	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/middleware"
 	"github.com/link-tracker/shared/pkg/response"
 )
 
@@ -21,7 +22,11 @@ func NewSiteHandler(service *service.SiteService) *SiteHandler {
 }
 
 func (h *SiteHandler) List(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	filters := &model.SiteFilters{
 		Page:    1,
@@ -50,32 +55,80 @@ func (h *SiteHandler) List(w http.ResponseWriter, r *http.Request) {
 		filters.Domain = domain
 	}
 
-	sites, total, err := h.service.List(r.Context(), claims.UserID, filters)
+	// Any cursor parameter, even an empty one for the first page, switches
+	// to cursor pagination
+	if r.URL.Query().Has("cursor") {
+		h.listCursor(w, r, userID, filters)
+		return
+	}
+
+	sites, total, err := h.service.List(r.Context(), userID, filters)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
+		response.Error(w, http.StatusInternalServerError, "failed to list sites", "INTERNAL_ERROR")
 		return
 	}
 
 	response.Paginated(w, sites, filters.Page, filters.PerPage, total)
 }
 
+func (h *SiteHandler) listCursor(w http.ResponseWriter, r *http.Request, userID int64, filters *model.SiteFilters) {
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		var after model.SiteCursor
+		if err := response.DecodeCursor(v, &after); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		filters.After = &after
+	}
+
+	sites, next, err := h.service.ListCursor(r.Context(), userID, filters)
+	if err != nil {
+		if err == response.ErrInvalidCursor {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list sites", "INTERNAL_ERROR")
+		return
+	}
+
+	var nextCursor string
+	if next != nil {
+		if nextCursor, err = response.EncodeCursor(next); err != nil {
+			response.Error(w, http.StatusInternalServerError, "failed to list sites", "INTERNAL_ERROR")
+			return
+		}
+	}
+	if sites == nil {
+		sites = []model.MonitoredSite{}
+	}
+	response.CursorPaginated(w, sites, filters.PerPage, nextCursor, nil, false)
+}
+
 func (h *SiteHandler) Create(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	var req model.CreateSiteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid request body")
+		response.Error(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
 		return
 	}
 
 	if req.URL == "" {
-		response.Error(w, http.StatusBadRequest, "url is required")
+		response.Error(w, http.StatusBadRequest, "url is required", "VALIDATION_ERROR")
 		return
 	}
 
-	site, err := h.service.Create(r.Context(), claims.UserID, &req)
+	site, err := h.service.Create(r.Context(), userID, &req)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
+		if err == service.ErrAccessDenied {
+			response.Error(w, http.StatusForbidden, "workspace access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to create site", "INTERNAL_ERROR")
 		return
 	}
 
@@ -83,23 +136,27 @@ func (h *SiteHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SiteHandler) GetByID(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid site id")
+		response.Error(w, http.StatusBadRequest, "invalid site id", "INVALID_ID")
 		return
 	}
 
-	site, err := h.service.GetByID(r.Context(), claims.UserID, id)
+	site, err := h.service.GetByID(r.Context(), userID, id)
 	if err != nil {
 		switch err {
 		case service.ErrSiteNotFound:
-			response.Error(w, http.StatusNotFound, err.Error())
-		case service.ErrNotOwner:
-			response.Error(w, http.StatusForbidden, err.Error())
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
 		default:
-			response.Error(w, http.StatusInternalServerError, err.Error())
+			response.Error(w, http.StatusInternalServerError, "failed to get site", "INTERNAL_ERROR")
 		}
 		return
 	}
@@ -108,29 +165,33 @@ func (h *SiteHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SiteHandler) Update(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid site id")
+		response.Error(w, http.StatusBadRequest, "invalid site id", "INVALID_ID")
 		return
 	}
 
 	var req model.UpdateSiteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid request body")
+		response.Error(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
 		return
 	}
 
-	site, err := h.service.Update(r.Context(), claims.UserID, id, &req)
+	site, err := h.service.Update(r.Context(), userID, id, &req)
 	if err != nil {
 		switch err {
 		case service.ErrSiteNotFound:
-			response.Error(w, http.StatusNotFound, err.Error())
-		case service.ErrNotOwner:
-			response.Error(w, http.StatusForbidden, err.Error())
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
 		default:
-			response.Error(w, http.StatusInternalServerError, err.Error())
+			response.Error(w, http.StatusInternalServerError, "failed to update site", "INTERNAL_ERROR")
 		}
 		return
 	}
@@ -139,23 +200,27 @@ func (h *SiteHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SiteHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid site id")
+		response.Error(w, http.StatusBadRequest, "invalid site id", "INVALID_ID")
 		return
 	}
 
-	err = h.service.Delete(r.Context(), claims.UserID, id)
+	err = h.service.Delete(r.Context(), userID, id)
 	if err != nil {
 		switch err {
 		case service.ErrSiteNotFound:
-			response.Error(w, http.StatusNotFound, err.Error())
-		case service.ErrNotOwner:
-			response.Error(w, http.StatusForbidden, err.Error())
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
 		default:
-			response.Error(w, http.StatusInternalServerError, err.Error())
+			response.Error(w, http.StatusInternalServerError, "failed to delete site", "INTERNAL_ERROR")
 		}
 		return
 	}
@@ -164,23 +229,27 @@ func (h *SiteHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SiteHandler) CheckHealth(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid site id")
+		response.Error(w, http.StatusBadRequest, "invalid site id", "INVALID_ID")
 		return
 	}
 
-	result, err := h.service.CheckHealth(r.Context(), claims.UserID, id)
+	result, err := h.service.CheckHealth(r.Context(), userID, id)
 	if err != nil {
 		switch err {
 		case service.ErrSiteNotFound:
-			response.Error(w, http.StatusNotFound, err.Error())
-		case service.ErrNotOwner:
-			response.Error(w, http.StatusForbidden, err.Error())
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
 		default:
-			response.Error(w, http.StatusInternalServerError, err.Error())
+			response.Error(w, http.StatusInternalServerError, "failed to check site", "INTERNAL_ERROR")
 		}
 		return
 	}
@@ -189,11 +258,146 @@ func (h *SiteHandler) CheckHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SiteHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid site id", "INVALID_ID")
+		return
+	}
+
+	filters := &model.HistoryFilters{
+		Page:    1,
+		PerPage: 20,
+	}
+
+	if page := r.URL.Query().Get("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filters.Page = p
+		}
+	}
+	if perPage := r.URL.Query().Get("per_page"); perPage != "" {
+		if pp, err := strconv.Atoi(perPage); err == nil {
+			filters.PerPage = pp
+		}
+	}
+	if from := r.URL.Query().Get("from"); from != "" {
+		if t, err := time.Parse(time.RFC3339, from); err == nil {
+			filters.From = &t
+		}
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		if t, err := time.Parse(time.RFC3339, to); err == nil {
+			filters.To = &t
+		}
+	}
+	if resolution := r.URL.Query().Get("resolution"); resolution != "" {
+		filters.Resolution = model.Resolution(resolution)
+	}
+
+	cursorMode := r.URL.Query().Has("cursor")
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		var after model.HistoryCursor
+		if err := response.DecodeCursor(v, &after); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		filters.After = &after
+	}
+
+	var history *model.HistoryPage
+	if cursorMode {
+		history, err = h.service.GetHistoryCursor(r.Context(), userID, id, filters)
+	} else {
+		history, err = h.service.GetHistory(r.Context(), userID, id, filters)
+	}
+	if err != nil {
+		switch err {
+		case service.ErrSiteNotFound:
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
+		case response.ErrInvalidCursor:
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+		default:
+			response.Error(w, http.StatusInternalServerError, "failed to get history", "INTERNAL_ERROR")
+		}
+		return
+	}
+
+	w.Header().Set("X-History-Resolution", string(history.Resolution))
+	var data interface{} = history.Rollups
+	if history.Resolution == model.ResolutionRaw {
+		data = history.Checks
+	}
+	if !cursorMode {
+		response.Paginated(w, data, filters.Page, filters.PerPage, history.Total)
+		return
+	}
+
+	var nextCursor string
+	if history.Next != nil {
+		if nextCursor, err = response.EncodeCursor(history.Next); err != nil {
+			response.Error(w, http.StatusInternalServerError, "failed to get history", "INTERNAL_ERROR")
+			return
+		}
+	}
+	response.CursorPaginated(w, data, filters.PerPage, nextCursor, nil, false)
+}
+
+func (h *SiteHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "invalid site id", "INVALID_ID")
+		return
+	}
+
+	filters := &model.StatsFilters{
+		Days: 7,
+	}
+
+	if days := r.URL.Query().Get("days"); days != "" {
+		if d, err := strconv.Atoi(days); err == nil {
+			filters.Days = d
+		}
+	}
+
+	stats, err := h.service.GetStats(r.Context(), userID, id, filters)
+	if err != nil {
+		switch err {
+		case service.ErrSiteNotFound:
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
+		default:
+			response.Error(w, http.StatusInternalServerError, "failed to get stats", "INTERNAL_ERROR")
+		}
+		return
+	}
+
+	response.JSON(w, http.StatusOK, stats)
+}
+
+func (h *SiteHandler) GetContentChanges(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid site id")
+		response.Error(w, http.StatusBadRequest, "invalid site id", "INVALID_ID")
 		return
 	}
 
@@ -213,18 +417,18 @@ func (h *SiteHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	history, total, err := h.service.GetHistory(r.Context(), claims.UserID, id, filters)
+	events, total, err := h.service.GetContentChanges(r.Context(), userID, id, filters)
 	if err != nil {
 		switch err {
 		case service.ErrSiteNotFound:
-			response.Error(w, http.StatusNotFound, err.Error())
-		case service.ErrNotOwner:
-			response.Error(w, http.StatusForbidden, err.Error())
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
 		default:
-			response.Error(w, http.StatusInternalServerError, err.Error())
+			response.Error(w, http.StatusInternalServerError, "failed to get content changes", "INTERNAL_ERROR")
 		}
 		return
 	}
 
-	response.Paginated(w, history, filters.Page, filters.PerPage, total)
+	response.Paginated(w, events, filters.Page, filters.PerPage, total)
 }
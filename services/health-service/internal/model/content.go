@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// PageFingerprint is the snapshot of page content compared between checks.
+type PageFingerprint struct {
+	TextHash        string    `json:"text_hash"`
+	Title           string    `json:"title"`
+	MetaDescription string    `json:"meta_description"`
+	H1              string    `json:"h1"`
+	OutboundLinks   []string  `json:"outbound_links"`
+	CapturedAt      time.Time `json:"captured_at"`
+}
+
+type FieldChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type ContentDiff struct {
+	ChangedFields   []string     `json:"changed_fields"`
+	Title           *FieldChange `json:"title,omitempty"`
+	MetaDescription *FieldChange `json:"meta_description,omitempty"`
+	H1              *FieldChange `json:"h1,omitempty"`
+	LinksAdded      []string     `json:"links_added,omitempty"`
+	LinksRemoved    []string     `json:"links_removed,omitempty"`
+}
+
+type ContentChangeEvent struct {
+	ID         int64       `json:"id"`
+	SiteID     int64       `json:"site_id"`
+	Diff       ContentDiff `json:"diff"`
+	DetectedAt time.Time   `json:"detected_at"`
+}
@@ -1,12 +1,16 @@
 package model
 
+import "time"
+
 type CreateSiteRequest struct {
-	URL string `json:"url"`
+	URL         string `json:"url"`
+	WorkspaceID *int64 `json:"workspace_id,omitempty"`
 }
 
 type UpdateSiteRequest struct {
 	URL          *string `json:"url,omitempty"`
 	PagesIndexed *int    `json:"pages_indexed,omitempty"`
+	WorkspaceID  *int64  `json:"workspace_id,omitempty"` // 0 moves the site out of its workspace
 }
 
 type SiteFilters struct {
@@ -15,9 +19,61 @@ type SiteFilters struct {
 	Domain     string `json:"domain,omitempty"`
 	Page       int    `json:"page"`
 	PerPage    int    `json:"per_page"`
+
+	// After continues a cursor-paginated list after the given position.
+	After *SiteCursor `json:"-"`
+}
+
+// SiteCursor is the position after the last site of a page in cursor
+// pagination. Sites are listed newest first.
+type SiteCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
 }
 
 type HistoryFilters struct {
-	Page    int `json:"page"`
-	PerPage int `json:"per_page"`
+	From       *time.Time `json:"from,omitempty"`
+	To         *time.Time `json:"to,omitempty"`
+	Resolution Resolution `json:"resolution,omitempty"`
+	Page       int        `json:"page"`
+	PerPage    int        `json:"per_page"`
+
+	// After continues a cursor-paginated history after the given position.
+	After *HistoryCursor `json:"-"`
+}
+
+// HistoryCursor is the position after the last entry of a history page:
+// the check time and id of a raw check, or the bucket start of a rollup,
+// which is unique per resolution. History is listed newest first.
+type HistoryCursor struct {
+	Resolution Resolution `json:"r"`
+	Time       time.Time  `json:"t"`
+	ID         int64      `json:"id,omitempty"`
+}
+
+type StatsFilters struct {
+	Days int `json:"days"`
+}
+
+// UserUsageResponse counts what a user created, for admins.
+type UserUsageResponse struct {
+	UserID int64 `json:"user_id"`
+	Sites  int64 `json:"sites"`
+}
+
+// AccountExport is everything a user added to health-service, for the
+// account export.
+type AccountExport struct {
+	UserID     int64        `json:"user_id"`
+	ExportedAt time.Time    `json:"exported_at"`
+	Sites      []SiteExport `json:"sites"`
+}
+
+// SiteExport is a site with its check history: raw checks, the rollups
+// older checks were compacted into, and content changes.
+type SiteExport struct {
+	MonitoredSite
+	History        []SiteCheckHistory   `json:"history"`
+	Rollups        []CheckRollup        `json:"rollups"`
+	ContentChanges []ContentChangeEvent `json:"content_changes"`
 }
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+type Resolution string
+
+const (
+	ResolutionRaw  Resolution = "raw"
+	ResolutionHour Resolution = "hour"
+	ResolutionDay  Resolution = "day"
+)
+
+// CheckRollup is an aggregate of site checks over one hour or one day.
+// P95 values of daily rollups are the maximum of the hourly p95s they were
+// built from, so they are an upper bound rather than an exact percentile.
+type CheckRollup struct {
+	SiteID        int64      `json:"site_id"`
+	Resolution    Resolution `json:"resolution"`
+	BucketStart   time.Time  `json:"bucket_start"`
+	TotalChecks   int64      `json:"total_checks"`
+	AliveChecks   int64      `json:"alive_checks"`
+	UptimePercent float64    `json:"uptime_percent"`
+	StatusCounts
+	ResponseTime PhaseStats `json:"response_time"`
+	DNSLookup    PhaseStats `json:"dns_lookup"`
+	TCPConnect   PhaseStats `json:"tcp_connect"`
+	TLSHandshake PhaseStats `json:"tls_handshake"`
+	TTFB         PhaseStats `json:"ttfb"`
+	Download     PhaseStats `json:"download"`
+}
+
+// StatusCounts groups checks by HTTP status class. Failed counts checks that
+// got no HTTP response at all (DNS, connect or timeout errors).
+type StatusCounts struct {
+	Status2xx    int64 `json:"status_2xx"`
+	Status3xx    int64 `json:"status_3xx"`
+	Status4xx    int64 `json:"status_4xx"`
+	Status5xx    int64 `json:"status_5xx"`
+	StatusFailed int64 `json:"status_failed"`
+}
+
+// HistoryPage is a page of check history at the resolution that covers the
+// requested range: raw checks or hourly/daily rollups.
+type HistoryPage struct {
+	Resolution Resolution
+	Checks     []SiteCheckHistory
+	Rollups    []CheckRollup
+	Total      int64
+	Next       *HistoryCursor // cursor pagination only, nil on the last page
+}
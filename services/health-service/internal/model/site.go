@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/link-tracker/shared/pkg/models"
+)
 
 type MonitoredSite struct {
 	ID              int64      `json:"id"`
@@ -16,6 +20,11 @@ type MonitoredSite struct {
 	PagesIndexed    int        `json:"pages_indexed"`
 	LastCheckedAt   *time.Time `json:"last_checked_at"`
 	CreatedAt       time.Time  `json:"created_at"`
+	WorkspaceID     *int64     `json:"workspace_id,omitempty"`
+
+	// Role is the role of the requesting user, set when a single site is
+	// loaded for them
+	Role models.MemberRole `json:"role,omitempty"`
 }
 
 type SiteCheckHistory struct {
@@ -24,18 +33,66 @@ type SiteCheckHistory struct {
 	HTTPStatus     *int      `json:"http_status"`
 	IsAlive        bool      `json:"is_alive"`
 	ResponseTimeMs *int      `json:"response_time_ms"`
+	DNSLookupMs    *int      `json:"dns_lookup_ms"`
+	TCPConnectMs   *int      `json:"tcp_connect_ms"`
+	TLSHandshakeMs *int      `json:"tls_handshake_ms"`
+	TTFBMs         *int      `json:"ttfb_ms"`
+	DownloadMs     *int      `json:"download_ms"`
 	CheckedAt      time.Time `json:"checked_at"`
 }
 
+// CheckTiming is the per-phase breakdown of a single check request.
+// Phases that did not happen (e.g. TLS on plain HTTP) are left nil. After
+// redirects the phases describe the final hop.
+type CheckTiming struct {
+	DNSLookupMs    *int `json:"dns_lookup_ms"`
+	TCPConnectMs   *int `json:"tcp_connect_ms"`
+	TLSHandshakeMs *int `json:"tls_handshake_ms"`
+	TTFBMs         *int `json:"ttfb_ms"`
+	DownloadMs     *int `json:"download_ms"`
+}
+
+type PhaseStats struct {
+	AvgMs *float64 `json:"avg_ms"`
+	P95Ms *float64 `json:"p95_ms"`
+}
+
+// TimingStats summarizes check timings over a period. P95 values are exact
+// while the period lies within raw history retention. Older checks only
+// survive as rollups, and P95Approximate is set when the period includes
+// them: p95 is then taken over the per-bucket p95 values.
+type TimingStats struct {
+	DNSLookup      PhaseStats `json:"dns_lookup"`
+	TCPConnect     PhaseStats `json:"tcp_connect"`
+	TLSHandshake   PhaseStats `json:"tls_handshake"`
+	TTFB           PhaseStats `json:"ttfb"`
+	Download       PhaseStats `json:"download"`
+	ResponseTime   PhaseStats `json:"response_time"`
+	P95Approximate bool       `json:"p95_approximate"`
+}
+
+type SiteStats struct {
+	SiteID        int64     `json:"site_id"`
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	TotalChecks   int64     `json:"total_checks"`
+	AliveChecks   int64     `json:"alive_checks"`
+	UptimePercent float64   `json:"uptime_percent"`
+	StatusCounts
+	Timings TimingStats `json:"timings"`
+}
+
 type SiteHealthCheck struct {
-	SiteID          int64     `json:"site_id"`
-	URL             string    `json:"url"`
-	HTTPStatus      int       `json:"http_status"`
-	IsAlive         bool      `json:"is_alive"`
-	ResponseTimeMs  int       `json:"response_time_ms"`
-	AllowsIndexing  bool      `json:"allows_indexing"`
-	RobotsTxtStatus string    `json:"robots_txt_status"`
-	HasNoindex      bool      `json:"has_noindex"`
-	CheckedAt       time.Time `json:"checked_at"`
-	Error           string    `json:"error,omitempty"`
+	SiteID          int64        `json:"site_id"`
+	URL             string       `json:"url"`
+	HTTPStatus      int          `json:"http_status"`
+	IsAlive         bool         `json:"is_alive"`
+	ResponseTimeMs  int          `json:"response_time_ms"`
+	Timing          CheckTiming  `json:"timing"`
+	AllowsIndexing  bool         `json:"allows_indexing"`
+	RobotsTxtStatus string       `json:"robots_txt_status"`
+	HasNoindex      bool         `json:"has_noindex"`
+	ContentChange   *ContentDiff `json:"content_change,omitempty"`
+	CheckedAt       time.Time    `json:"checked_at"`
+	Error           string       `json:"error,omitempty"`
 }
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/health-service/internal/model"
+)
+
+type ContentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewContentRepository(db *pgxpool.Pool) *ContentRepository {
+	return &ContentRepository{db: db}
+}
+
+func (r *ContentRepository) GetFingerprint(ctx context.Context, siteID int64) (*model.PageFingerprint, error) {
+	var fp model.PageFingerprint
+	err := r.db.QueryRow(ctx, `
+		SELECT text_hash, title, meta_description, h1, outbound_links, captured_at
+		FROM site_content_snapshots WHERE site_id = $1
+	`, siteID).Scan(&fp.TextHash, &fp.Title, &fp.MetaDescription, &fp.H1, &fp.OutboundLinks, &fp.CapturedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fp, nil
+}
+
+func (r *ContentRepository) SaveFingerprint(ctx context.Context, siteID int64, fp *model.PageFingerprint) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO site_content_snapshots (site_id, text_hash, title, meta_description, h1, outbound_links, captured_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (site_id) DO UPDATE
+		SET text_hash = EXCLUDED.text_hash, title = EXCLUDED.title,
+		    meta_description = EXCLUDED.meta_description, h1 = EXCLUDED.h1,
+		    outbound_links = EXCLUDED.outbound_links, captured_at = EXCLUDED.captured_at
+	`, siteID, fp.TextHash, fp.Title, fp.MetaDescription, fp.H1, fp.OutboundLinks)
+	return err
+}
+
+func (r *ContentRepository) AddChangeEvent(ctx context.Context, siteID int64, diff *model.ContentDiff) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO site_content_changes (site_id, changed_fields, diff)
+		VALUES ($1, $2, $3)
+	`, siteID, diff.ChangedFields, diff)
+	return err
+}
+
+func (r *ContentRepository) GetChangeEvents(ctx context.Context, siteID int64, filters *model.HistoryFilters) ([]model.ContentChangeEvent, int64, error) {
+	// Count total
+	var total int64
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM site_content_changes WHERE site_id = $1", siteID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (filters.Page - 1) * filters.PerPage
+	rows, err := r.db.Query(ctx, `
+		SELECT id, site_id, diff, detected_at
+		FROM site_content_changes
+		WHERE site_id = $1
+		ORDER BY detected_at DESC
+		LIMIT $2 OFFSET $3
+	`, siteID, filters.PerPage, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var events []model.ContentChangeEvent
+	for rows.Next() {
+		var e model.ContentChangeEvent
+		if err := rows.Scan(&e.ID, &e.SiteID, &e.Diff, &e.DetectedAt); err != nil {
+			return nil, 0, err
+		}
+		events = append(events, e)
+	}
+
+	return events, total, nil
+}
+
+// ListChangeEvents returns every content change of a site, oldest first.
+func (r *ContentRepository) ListChangeEvents(ctx context.Context, siteID int64) ([]model.ContentChangeEvent, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, site_id, diff, detected_at
+		FROM site_content_changes
+		WHERE site_id = $1
+		ORDER BY detected_at
+	`, siteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []model.ContentChangeEvent
+	for rows.Next() {
+		var e model.ContentChangeEvent
+		if err := rows.Scan(&e.ID, &e.SiteID, &e.Diff, &e.DetectedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
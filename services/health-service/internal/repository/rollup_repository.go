@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/link-tracker/health-service/internal/model"
+)
+
+// rollupPhases are the latency columns aggregated into rollups. Raw history
+// stores them as <phase>_ms, rollups as avg_<phase>_ms and p95_<phase>_ms,
+// with the number of checks that had the phase in <phase>_samples.
+var rollupPhases = []string{"response_time", "dns_lookup", "tcp_connect", "tls_handshake", "ttfb", "download"}
+
+const rollupColumns = `site_id, resolution, bucket_start, total_checks, alive_checks,
+	status_2xx, status_3xx, status_4xx, status_5xx, status_failed,
+	avg_response_time_ms, p95_response_time_ms, avg_dns_lookup_ms, p95_dns_lookup_ms,
+	avg_tcp_connect_ms, p95_tcp_connect_ms, avg_tls_handshake_ms, p95_tls_handshake_ms,
+	avg_ttfb_ms, p95_ttfb_ms, avg_download_ms, p95_download_ms`
+
+// rollupWriteColumns are rollupColumns followed by the sample counts, which
+// are only needed to merge rollups.
+const rollupWriteColumns = rollupColumns + `,
+	response_time_samples, dns_lookup_samples, tcp_connect_samples,
+	tls_handshake_samples, ttfb_samples, download_samples`
+
+// compactionLockKey is the advisory lock that lets only one replica compact
+// at a time. Two concurrent runs would aggregate the same rows twice.
+const compactionLockKey = 0x6865616c7468 // "health"
+
+type RollupRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRollupRepository(db *pgxpool.Pool) *RollupRepository {
+	return &RollupRepository{db: db}
+}
+
+// CompactRaw rolls raw checks older than cutoff into hourly rollups and
+// deletes them. Both steps run in one transaction. It returns 0 without
+// doing anything while another replica is compacting.
+func (r *RollupRepository) CompactRaw(ctx context.Context, cutoff time.Time) (int64, error) {
+	var aggregates, samples []string
+	for _, p := range rollupPhases {
+		aggregates = append(aggregates, fmt.Sprintf(
+			"AVG(%[1]s_ms), PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY %[1]s_ms)", p))
+		samples = append(samples, fmt.Sprintf("COUNT(%s_ms)", p))
+	}
+
+	insert := fmt.Sprintf(`
+		INSERT INTO site_check_rollups AS r (%s)
+		SELECT site_id, 'hour', date_trunc('hour', checked_at),
+		       COUNT(*), COUNT(*) FILTER (WHERE is_alive),
+		       COUNT(*) FILTER (WHERE http_status BETWEEN 200 AND 299),
+		       COUNT(*) FILTER (WHERE http_status BETWEEN 300 AND 399),
+		       COUNT(*) FILTER (WHERE http_status BETWEEN 400 AND 499),
+		       COUNT(*) FILTER (WHERE http_status >= 500),
+		       COUNT(*) FILTER (WHERE http_status IS NULL OR http_status < 200),
+		       %s,
+		       %s
+		FROM site_check_history
+		WHERE checked_at < $1
+		GROUP BY site_id, date_trunc('hour', checked_at)
+		%s
+	`, rollupWriteColumns, strings.Join(aggregates, ",\n\t\t       "), strings.Join(samples, ", "), rollupConflictClause())
+
+	return r.compact(ctx, insert, "DELETE FROM site_check_history WHERE checked_at < $1", cutoff)
+}
+
+// CompactHourly rolls hourly rollups older than cutoff into daily rollups and
+// deletes them. Daily averages are weighted by the hourly sample counts,
+// daily p95 is the max of hourly p95s.
+func (r *RollupRepository) CompactHourly(ctx context.Context, cutoff time.Time) (int64, error) {
+	var aggregates, samples []string
+	for _, p := range rollupPhases {
+		aggregates = append(aggregates, fmt.Sprintf(
+			"SUM(avg_%[1]s_ms * %[1]s_samples) / NULLIF(SUM(%[1]s_samples), 0), MAX(p95_%[1]s_ms)", p))
+		samples = append(samples, fmt.Sprintf("SUM(%s_samples)", p))
+	}
+
+	insert := fmt.Sprintf(`
+		INSERT INTO site_check_rollups AS r (%s)
+		SELECT site_id, 'day', date_trunc('day', bucket_start),
+		       SUM(total_checks), SUM(alive_checks),
+		       SUM(status_2xx), SUM(status_3xx), SUM(status_4xx), SUM(status_5xx), SUM(status_failed),
+		       %s,
+		       %s
+		FROM site_check_rollups
+		WHERE resolution = 'hour' AND bucket_start < $1
+		GROUP BY site_id, date_trunc('day', bucket_start)
+		%s
+	`, rollupWriteColumns, strings.Join(aggregates, ",\n\t\t       "), strings.Join(samples, ", "), rollupConflictClause())
+
+	return r.compact(ctx, insert, "DELETE FROM site_check_rollups WHERE resolution = 'hour' AND bucket_start < $1", cutoff)
+}
+
+// DeleteDailyBefore drops daily rollups older than cutoff.
+func (r *RollupRepository) DeleteDailyBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, "DELETE FROM site_check_rollups WHERE resolution = 'day' AND bucket_start < $1", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *RollupRepository) compact(ctx context.Context, insert, del string, cutoff time.Time) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// The lock is released with the transaction
+	var locked bool
+	if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", compactionLockKey).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	if _, err := tx.Exec(ctx, insert, cutoff); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(ctx, del, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *RollupRepository) List(ctx context.Context, siteID int64, filters *model.HistoryFilters) ([]model.CheckRollup, int64, error) {
+	conditions, args := rollupConditions(siteID, filters)
+	argIndex := len(args) + 1
+	whereClause := strings.Join(conditions, " AND ")
+
+	// Count total
+	var total int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM site_check_rollups WHERE %s", whereClause)
+	if err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (filters.Page - 1) * filters.PerPage
+	args = append(args, filters.PerPage, offset)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM site_check_rollups
+		WHERE %s
+		ORDER BY bucket_start DESC
+		LIMIT $%d OFFSET $%d
+	`, rollupColumns, whereClause, argIndex, argIndex+1)
+
+	rollups, err := r.queryRollups(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rollups, total, nil
+}
+
+// ListAfter returns up to filters.PerPage rollups starting before
+// filters.After, newest first, and whether more follow.
+func (r *RollupRepository) ListAfter(ctx context.Context, siteID int64, filters *model.HistoryFilters) ([]model.CheckRollup, bool, error) {
+	conditions, args := rollupConditions(siteID, filters)
+	argIndex := len(args) + 1
+
+	if filters.After != nil {
+		conditions = append(conditions, fmt.Sprintf("bucket_start < $%d", argIndex))
+		args = append(args, filters.After.Time)
+		argIndex++
+	}
+	args = append(args, filters.PerPage+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM site_check_rollups
+		WHERE %s
+		ORDER BY bucket_start DESC
+		LIMIT $%d
+	`, rollupColumns, strings.Join(conditions, " AND "), argIndex)
+
+	rollups, err := r.queryRollups(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(rollups) > filters.PerPage {
+		return rollups[:filters.PerPage], true, nil
+	}
+	return rollups, false, nil
+}
+
+func rollupConditions(siteID int64, filters *model.HistoryFilters) ([]string, []interface{}) {
+	conditions := []string{"site_id = $1", "resolution = $2"}
+	args := []interface{}{siteID, filters.Resolution}
+	argIndex := 3
+
+	if filters.From != nil {
+		conditions = append(conditions, fmt.Sprintf("bucket_start >= $%d", argIndex))
+		args = append(args, *filters.From)
+		argIndex++
+	}
+	if filters.To != nil {
+		conditions = append(conditions, fmt.Sprintf("bucket_start < $%d", argIndex))
+		args = append(args, *filters.To)
+	}
+
+	return conditions, args
+}
+
+func (r *RollupRepository) queryRollups(ctx context.Context, query string, args ...interface{}) ([]model.CheckRollup, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rollups []model.CheckRollup
+	for rows.Next() {
+		rollup, err := scanRollup(rows)
+		if err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, *rollup)
+	}
+
+	return rollups, rows.Err()
+}
+
+// ListAll returns every hourly and daily rollup of a site, oldest first.
+func (r *RollupRepository) ListAll(ctx context.Context, siteID int64) ([]model.CheckRollup, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM site_check_rollups
+		WHERE site_id = $1
+		ORDER BY bucket_start, resolution
+	`, rollupColumns)
+
+	rows, err := r.db.Query(ctx, query, siteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rollups []model.CheckRollup
+	for rows.Next() {
+		rollup, err := scanRollup(rows)
+		if err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, *rollup)
+	}
+
+	return rollups, rows.Err()
+}
+
+func scanRollup(row pgx.Row) (*model.CheckRollup, error) {
+	var c model.CheckRollup
+	err := row.Scan(
+		&c.SiteID, &c.Resolution, &c.BucketStart, &c.TotalChecks, &c.AliveChecks,
+		&c.Status2xx, &c.Status3xx, &c.Status4xx, &c.Status5xx, &c.StatusFailed,
+		&c.ResponseTime.AvgMs, &c.ResponseTime.P95Ms, &c.DNSLookup.AvgMs, &c.DNSLookup.P95Ms,
+		&c.TCPConnect.AvgMs, &c.TCPConnect.P95Ms, &c.TLSHandshake.AvgMs, &c.TLSHandshake.P95Ms,
+		&c.TTFB.AvgMs, &c.TTFB.P95Ms, &c.Download.AvgMs, &c.Download.P95Ms,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if c.TotalChecks > 0 {
+		c.UptimePercent = float64(c.AliveChecks) / float64(c.TotalChecks) * 100
+	}
+	return &c, nil
+}
+
+// rollupConflictClause merges a late batch into an existing bucket: counts
+// are summed, averages weighted by the number of checks that had the phase,
+// p95 takes the larger value.
+func rollupConflictClause() string {
+	sets := []string{
+		"total_checks = r.total_checks + EXCLUDED.total_checks",
+		"alive_checks = r.alive_checks + EXCLUDED.alive_checks",
+		"status_2xx = r.status_2xx + EXCLUDED.status_2xx",
+		"status_3xx = r.status_3xx + EXCLUDED.status_3xx",
+		"status_4xx = r.status_4xx + EXCLUDED.status_4xx",
+		"status_5xx = r.status_5xx + EXCLUDED.status_5xx",
+		"status_failed = r.status_failed + EXCLUDED.status_failed",
+	}
+	for _, p := range rollupPhases {
+		sets = append(sets,
+			fmt.Sprintf("avg_%[1]s_ms = COALESCE((r.avg_%[1]s_ms * r.%[1]s_samples + EXCLUDED.avg_%[1]s_ms * EXCLUDED.%[1]s_samples) / NULLIF(r.%[1]s_samples + EXCLUDED.%[1]s_samples, 0), r.avg_%[1]s_ms, EXCLUDED.avg_%[1]s_ms)", p),
+			fmt.Sprintf("p95_%[1]s_ms = GREATEST(r.p95_%[1]s_ms, EXCLUDED.p95_%[1]s_ms)", p),
+			fmt.Sprintf("%[1]s_samples = r.%[1]s_samples + EXCLUDED.%[1]s_samples", p),
+		)
+	}
+	return "ON CONFLICT (site_id, resolution, bucket_start) DO UPDATE SET " + strings.Join(sets, ", ")
+}
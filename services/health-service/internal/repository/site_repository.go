@@ -3,12 +3,16 @@ package repository
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/link-tracker/health-service/internal/model"
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/urlcanon"
 )
 
 type SiteRepository struct {
@@ -24,14 +28,14 @@ func (r *SiteRepository) Create(ctx context.Context, userID int64, req *model.Cr
 
 	var site model.MonitoredSite
 	err := r.db.QueryRow(ctx, `
-		INSERT INTO monitored_sites (user_id, url, domain)
-		VALUES ($1, $2, $3)
+		INSERT INTO monitored_sites (user_id, url, domain, workspace_id)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, user_id, url, domain, http_status, is_alive, response_time_ms,
-		          allows_indexing, robots_txt_status, has_noindex, pages_indexed, last_checked_at, created_at
-	`, userID, req.URL, domain).Scan(
+		          allows_indexing, robots_txt_status, has_noindex, pages_indexed, last_checked_at, created_at, workspace_id
+	`, userID, req.URL, domain, req.WorkspaceID).Scan(
 		&site.ID, &site.UserID, &site.URL, &site.Domain, &site.HTTPStatus, &site.IsAlive,
 		&site.ResponseTimeMs, &site.AllowsIndexing, &site.RobotsTxtStatus, &site.HasNoindex,
-		&site.PagesIndexed, &site.LastCheckedAt, &site.CreatedAt,
+		&site.PagesIndexed, &site.LastCheckedAt, &site.CreatedAt, &site.WorkspaceID,
 	)
 	if err != nil {
 		return nil, err
@@ -43,12 +47,12 @@ func (r *SiteRepository) GetByID(ctx context.Context, id int64) (*model.Monitore
 	var site model.MonitoredSite
 	err := r.db.QueryRow(ctx, `
 		SELECT id, user_id, url, domain, http_status, is_alive, response_time_ms,
-		       allows_indexing, robots_txt_status, has_noindex, pages_indexed, last_checked_at, created_at
+		       allows_indexing, robots_txt_status, has_noindex, pages_indexed, last_checked_at, created_at, workspace_id
 		FROM monitored_sites WHERE id = $1
 	`, id).Scan(
 		&site.ID, &site.UserID, &site.URL, &site.Domain, &site.HTTPStatus, &site.IsAlive,
 		&site.ResponseTimeMs, &site.AllowsIndexing, &site.RobotsTxtStatus, &site.HasNoindex,
-		&site.PagesIndexed, &site.LastCheckedAt, &site.CreatedAt,
+		&site.PagesIndexed, &site.LastCheckedAt, &site.CreatedAt, &site.WorkspaceID,
 	)
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -60,11 +64,83 @@ func (r *SiteRepository) GetByID(ctx context.Context, id int64) (*model.Monitore
 }
 
 func (r *SiteRepository) List(ctx context.Context, userID int64, filters *model.SiteFilters) ([]model.MonitoredSite, int64, error) {
+	conditions, args := siteConditions(userID, filters)
+	argIndex := len(args) + 1
+	whereClause := strings.Join(conditions, " AND ")
+
+	// Count total
+	var total int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM monitored_sites WHERE %s", whereClause)
+	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (filters.Page - 1) * filters.PerPage
+	args = append(args, filters.PerPage, offset)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM monitored_sites
+		WHERE %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, siteColumns, whereClause, argIndex, argIndex+1)
+
+	sites, err := r.querySites(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return sites, total, nil
+}
+
+// ListAfter returns up to filters.PerPage sites after filters.After, newest
+// first, and whether more follow. Sites created in the same instant are
+// ordered by id so no site is skipped or repeated between pages.
+func (r *SiteRepository) ListAfter(ctx context.Context, userID int64, filters *model.SiteFilters) ([]model.MonitoredSite, bool, error) {
+	conditions, args := siteConditions(userID, filters)
+	argIndex := len(args) + 1
+
+	if filters.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < ($%d, $%d)", argIndex, argIndex+1))
+		args = append(args, filters.After.CreatedAt, filters.After.ID)
+		argIndex += 2
+	}
+	args = append(args, filters.PerPage+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM monitored_sites
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, siteColumns, strings.Join(conditions, " AND "), argIndex)
+
+	sites, err := r.querySites(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(sites) > filters.PerPage {
+		return sites[:filters.PerPage], true, nil
+	}
+	return sites, false, nil
+}
+
+const siteColumns = `id, user_id, url, domain, http_status, is_alive, response_time_ms,
+		       allows_indexing, robots_txt_status, has_noindex, pages_indexed, last_checked_at, created_at, workspace_id`
+
+// siteConditions returns the WHERE conditions and arguments selecting the
+// sites a user can see that match filters.
+func siteConditions(userID int64, filters *model.SiteFilters) ([]string, []interface{}) {
 	var conditions []string
 	var args []interface{}
 	argIndex := 1
 
-	conditions = append(conditions, fmt.Sprintf("user_id = $%d", argIndex))
+	// Own sites outside workspaces and sites of the user's workspaces
+	conditions = append(conditions, fmt.Sprintf(
+		"((workspace_id IS NULL AND user_id = $%[1]d) OR workspace_id IN (SELECT workspace_id FROM active_workspace_members WHERE user_id = $%[1]d))",
+		argIndex))
 	args = append(args, userID)
 	argIndex++
 
@@ -83,35 +159,15 @@ func (r *SiteRepository) List(ctx context.Context, userID int64, filters *model.
 	if filters.Domain != "" {
 		conditions = append(conditions, fmt.Sprintf("domain ILIKE $%d", argIndex))
 		args = append(args, "%"+filters.Domain+"%")
-		argIndex++
-	}
-
-	whereClause := strings.Join(conditions, " AND ")
-
-	// Count total
-	var total int64
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM monitored_sites WHERE %s", whereClause)
-	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, err
 	}
 
-	// Get paginated results
-	offset := (filters.Page - 1) * filters.PerPage
-	args = append(args, filters.PerPage, offset)
-
-	query := fmt.Sprintf(`
-		SELECT id, user_id, url, domain, http_status, is_alive, response_time_ms,
-		       allows_indexing, robots_txt_status, has_noindex, pages_indexed, last_checked_at, created_at
-		FROM monitored_sites
-		WHERE %s
-		ORDER BY created_at DESC
-		LIMIT $%d OFFSET $%d
-	`, whereClause, argIndex, argIndex+1)
+	return conditions, args
+}
 
+func (r *SiteRepository) querySites(ctx context.Context, query string, args ...interface{}) ([]model.MonitoredSite, error) {
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -121,15 +177,15 @@ func (r *SiteRepository) List(ctx context.Context, userID int64, filters *model.
 		err := rows.Scan(
 			&s.ID, &s.UserID, &s.URL, &s.Domain, &s.HTTPStatus, &s.IsAlive,
 			&s.ResponseTimeMs, &s.AllowsIndexing, &s.RobotsTxtStatus, &s.HasNoindex,
-			&s.PagesIndexed, &s.LastCheckedAt, &s.CreatedAt,
+			&s.PagesIndexed, &s.LastCheckedAt, &s.CreatedAt, &s.WorkspaceID,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 		sites = append(sites, s)
 	}
 
-	return sites, total, nil
+	return sites, rows.Err()
 }
 
 func (r *SiteRepository) Update(ctx context.Context, id int64, req *model.UpdateSiteRequest) (*model.MonitoredSite, error) {
@@ -152,6 +208,16 @@ func (r *SiteRepository) Update(ctx context.Context, id int64, req *model.Update
 		argIndex++
 	}
 
+	if req.WorkspaceID != nil {
+		setClauses = append(setClauses, fmt.Sprintf("workspace_id = $%d", argIndex))
+		if *req.WorkspaceID == 0 {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.WorkspaceID)
+		}
+		argIndex++
+	}
+
 	if len(setClauses) == 0 {
 		return r.GetByID(ctx, id)
 	}
@@ -161,14 +227,14 @@ func (r *SiteRepository) Update(ctx context.Context, id int64, req *model.Update
 		UPDATE monitored_sites SET %s
 		WHERE id = $%d
 		RETURNING id, user_id, url, domain, http_status, is_alive, response_time_ms,
-		          allows_indexing, robots_txt_status, has_noindex, pages_indexed, last_checked_at, created_at
+		          allows_indexing, robots_txt_status, has_noindex, pages_indexed, last_checked_at, created_at, workspace_id
 	`, strings.Join(setClauses, ", "), argIndex)
 
 	var site model.MonitoredSite
 	err := r.db.QueryRow(ctx, query, args...).Scan(
 		&site.ID, &site.UserID, &site.URL, &site.Domain, &site.HTTPStatus, &site.IsAlive,
 		&site.ResponseTimeMs, &site.AllowsIndexing, &site.RobotsTxtStatus, &site.HasNoindex,
-		&site.PagesIndexed, &site.LastCheckedAt, &site.CreatedAt,
+		&site.PagesIndexed, &site.LastCheckedAt, &site.CreatedAt, &site.WorkspaceID,
 	)
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -179,6 +245,115 @@ func (r *SiteRepository) Update(ctx context.Context, id int64, req *model.Update
 	return &site, nil
 }
 
+// Role returns the role of userID on a site: owner of their own sites
+// outside workspaces, otherwise their workspace role. It is empty when the
+// user has no access.
+func (r *SiteRepository) Role(ctx context.Context, site *model.MonitoredSite, userID int64) (models.MemberRole, error) {
+	if site.WorkspaceID == nil {
+		if site.UserID == userID {
+			return models.MemberOwner, nil
+		}
+		return "", nil
+	}
+	return r.WorkspaceRole(ctx, *site.WorkspaceID, userID)
+}
+
+// WorkspaceRole returns the role of userID in a workspace, or "" when the
+// user is not a member or lacks the MFA the workspace requires.
+func (r *SiteRepository) WorkspaceRole(ctx context.Context, workspaceID, userID int64) (models.MemberRole, error) {
+	var role models.MemberRole
+	err := r.db.QueryRow(ctx, `
+		SELECT role FROM active_workspace_members WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID).Scan(&role)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// CountByUser returns the number of sites the user added.
+func (r *SiteRepository) CountByUser(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM monitored_sites WHERE user_id = $1", userID).Scan(&count)
+	return count, err
+}
+
+// ListCreatedBy returns the sites the user added, including those in
+// workspaces.
+func (r *SiteRepository) ListCreatedBy(ctx context.Context, userID int64) ([]model.MonitoredSite, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, url, domain, http_status, is_alive, response_time_ms,
+		       allows_indexing, robots_txt_status, has_noindex, pages_indexed, last_checked_at, created_at, workspace_id
+		FROM monitored_sites
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sites []model.MonitoredSite
+	for rows.Next() {
+		var site model.MonitoredSite
+		err := rows.Scan(
+			&site.ID, &site.UserID, &site.URL, &site.Domain, &site.HTTPStatus, &site.IsAlive,
+			&site.ResponseTimeMs, &site.AllowsIndexing, &site.RobotsTxtStatus, &site.HasNoindex,
+			&site.PagesIndexed, &site.LastCheckedAt, &site.CreatedAt, &site.WorkspaceID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sites = append(sites, site)
+	}
+
+	return sites, rows.Err()
+}
+
+// DeleteUserData deletes the sites of a user outside workspaces and in
+// workspaces nobody else is a member of, with their history. Sites of
+// shared workspaces stay with the team: they pass to the longest-standing
+// other owner of the workspace or, without one, to the longest-standing
+// member, whom auth-service makes owner, preferring users who are not
+// disabled. It returns the number of deleted sites.
+func (r *SiteRepository) DeleteUserData(ctx context.Context, userID int64) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		DELETE FROM monitored_sites s
+		WHERE s.user_id = $1 AND (s.workspace_id IS NULL OR NOT EXISTS (
+			SELECT 1 FROM workspace_members wm WHERE wm.workspace_id = s.workspace_id AND wm.user_id <> $1))
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE monitored_sites s
+		SET user_id = heir.user_id
+		FROM (
+			SELECT DISTINCT ON (wm.workspace_id) wm.workspace_id, wm.user_id
+			FROM workspace_members wm
+			JOIN users u ON u.id = wm.user_id
+			WHERE wm.user_id <> $1
+			ORDER BY wm.workspace_id, u.disabled_at IS NULL DESC, wm.role = 'owner' DESC, wm.created_at, wm.user_id
+		) heir
+		WHERE s.user_id = $1 AND s.workspace_id = heir.workspace_id
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 func (r *SiteRepository) Delete(ctx context.Context, id int64) error {
 	_, err := r.db.Exec(ctx, "DELETE FROM monitored_sites WHERE id = $1", id)
 	return err
@@ -197,45 +372,255 @@ func (r *SiteRepository) UpdateHealthCheck(ctx context.Context, id int64, check
 
 func (r *SiteRepository) AddCheckHistory(ctx context.Context, siteID int64, check *model.SiteHealthCheck) error {
 	_, err := r.db.Exec(ctx, `
-		INSERT INTO site_check_history (site_id, http_status, is_alive, response_time_ms)
-		VALUES ($1, $2, $3, $4)
-	`, siteID, check.HTTPStatus, check.IsAlive, check.ResponseTimeMs)
+		INSERT INTO site_check_history (site_id, http_status, is_alive, response_time_ms,
+		                                dns_lookup_ms, tcp_connect_ms, tls_handshake_ms, ttfb_ms, download_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, siteID, check.HTTPStatus, check.IsAlive, check.ResponseTimeMs,
+		check.Timing.DNSLookupMs, check.Timing.TCPConnectMs, check.Timing.TLSHandshakeMs,
+		check.Timing.TTFBMs, check.Timing.DownloadMs)
 	return err
 }
 
 func (r *SiteRepository) GetHistory(ctx context.Context, siteID int64, filters *model.HistoryFilters) ([]model.SiteCheckHistory, int64, error) {
+	conditions, args := historyConditions(siteID, filters)
+	argIndex := len(args) + 1
+	whereClause := strings.Join(conditions, " AND ")
+
 	// Count total
 	var total int64
-	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM site_check_history WHERE site_id = $1", siteID).Scan(&total)
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM site_check_history WHERE %s", whereClause)
+	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated results
 	offset := (filters.Page - 1) * filters.PerPage
-	rows, err := r.db.Query(ctx, `
-		SELECT id, site_id, http_status, is_alive, response_time_ms, checked_at
+	args = append(args, filters.PerPage, offset)
+	query := fmt.Sprintf(`
+		SELECT %s
 		FROM site_check_history
-		WHERE site_id = $1
+		WHERE %s
 		ORDER BY checked_at DESC
-		LIMIT $2 OFFSET $3
-	`, siteID, filters.PerPage, offset)
+		LIMIT $%d OFFSET $%d
+	`, historyColumns, whereClause, argIndex, argIndex+1)
+
+	history, err := r.queryHistory(ctx, query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
+	return history, total, nil
+}
+
+// GetHistoryAfter returns up to filters.PerPage raw checks after
+// filters.After, newest first, and whether more follow.
+func (r *SiteRepository) GetHistoryAfter(ctx context.Context, siteID int64, filters *model.HistoryFilters) ([]model.SiteCheckHistory, bool, error) {
+	conditions, args := historyConditions(siteID, filters)
+	argIndex := len(args) + 1
+
+	if filters.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(checked_at, id) < ($%d, $%d)", argIndex, argIndex+1))
+		args = append(args, filters.After.Time, filters.After.ID)
+		argIndex += 2
+	}
+	args = append(args, filters.PerPage+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM site_check_history
+		WHERE %s
+		ORDER BY checked_at DESC, id DESC
+		LIMIT $%d
+	`, historyColumns, strings.Join(conditions, " AND "), argIndex)
+
+	history, err := r.queryHistory(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(history) > filters.PerPage {
+		return history[:filters.PerPage], true, nil
+	}
+	return history, false, nil
+}
+
+const historyColumns = `id, site_id, http_status, is_alive, response_time_ms,
+		       dns_lookup_ms, tcp_connect_ms, tls_handshake_ms, ttfb_ms, download_ms, checked_at`
+
+// historyConditions returns the WHERE conditions and arguments selecting a
+// site's raw checks within the filters' range.
+func historyConditions(siteID int64, filters *model.HistoryFilters) ([]string, []interface{}) {
+	conditions := []string{"site_id = $1"}
+	args := []interface{}{siteID}
+	argIndex := 2
+
+	if filters.From != nil {
+		conditions = append(conditions, fmt.Sprintf("checked_at >= $%d", argIndex))
+		args = append(args, *filters.From)
+		argIndex++
+	}
+	if filters.To != nil {
+		conditions = append(conditions, fmt.Sprintf("checked_at < $%d", argIndex))
+		args = append(args, *filters.To)
+	}
+
+	return conditions, args
+}
+
+func (r *SiteRepository) queryHistory(ctx context.Context, query string, args ...interface{}) ([]model.SiteCheckHistory, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var history []model.SiteCheckHistory
 	for rows.Next() {
 		var h model.SiteCheckHistory
-		err := rows.Scan(&h.ID, &h.SiteID, &h.HTTPStatus, &h.IsAlive, &h.ResponseTimeMs, &h.CheckedAt)
+		err := rows.Scan(
+			&h.ID, &h.SiteID, &h.HTTPStatus, &h.IsAlive, &h.ResponseTimeMs,
+			&h.DNSLookupMs, &h.TCPConnectMs, &h.TLSHandshakeMs, &h.TTFBMs, &h.DownloadMs, &h.CheckedAt,
+		)
 		if err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 		history = append(history, h)
 	}
 
-	return history, total, nil
+	return history, rows.Err()
+}
+
+// ListAllHistory returns every raw check of a site, oldest first.
+func (r *SiteRepository) ListAllHistory(ctx context.Context, siteID int64) ([]model.SiteCheckHistory, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, site_id, http_status, is_alive, response_time_ms,
+		       dns_lookup_ms, tcp_connect_ms, tls_handshake_ms, ttfb_ms, download_ms, checked_at
+		FROM site_check_history
+		WHERE site_id = $1
+		ORDER BY checked_at
+	`, siteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []model.SiteCheckHistory
+	for rows.Next() {
+		var h model.SiteCheckHistory
+		err := rows.Scan(
+			&h.ID, &h.SiteID, &h.HTTPStatus, &h.IsAlive, &h.ResponseTimeMs,
+			&h.DNSLookupMs, &h.TCPConnectMs, &h.TLSHandshakeMs, &h.TTFBMs, &h.DownloadMs, &h.CheckedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+
+	return history, rows.Err()
+}
+
+// GetStats aggregates checks in [from, to) across raw history and rollups.
+// Every check lives in exactly one of them, so the union never double counts.
+// Raw rows act as single-check buckets, so p95 is exact over raw history
+// only; when rollups are included it is marked approximate.
+func (r *SiteRepository) GetStats(ctx context.Context, siteID int64, from, to time.Time) (*model.SiteStats, error) {
+	stats := &model.SiteStats{
+		SiteID: siteID,
+		From:   from,
+		To:     to,
+	}
+	t := &stats.Timings
+
+	var rawPhases, rollupPhaseCols, aggregates []string
+	for _, p := range rollupPhases {
+		rawPhases = append(rawPhases, fmt.Sprintf("%[1]s_ms, %[1]s_ms, (%[1]s_ms IS NOT NULL)::int", p))
+		rollupPhaseCols = append(rollupPhaseCols, fmt.Sprintf("avg_%[1]s_ms, p95_%[1]s_ms, %[1]s_samples", p))
+		aggregates = append(aggregates, fmt.Sprintf(
+			"SUM(avg_%[1]s * samples_%[1]s) / NULLIF(SUM(samples_%[1]s), 0), "+
+				"PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY p95_%[1]s)", p))
+	}
+
+	var bucketCols []string
+	for _, p := range rollupPhases {
+		bucketCols = append(bucketCols, "avg_"+p, "p95_"+p, "samples_"+p)
+	}
+
+	query := fmt.Sprintf(`
+		WITH buckets (rollup, total, alive, s2xx, s3xx, s4xx, s5xx, failed, %s) AS (
+			SELECT FALSE, 1, is_alive::int,
+			       (http_status BETWEEN 200 AND 299)::int, (http_status BETWEEN 300 AND 399)::int,
+			       (http_status BETWEEN 400 AND 499)::int, (http_status >= 500)::int,
+			       (http_status IS NULL OR http_status < 200)::int,
+			       %s
+			FROM site_check_history
+			WHERE site_id = $1 AND checked_at >= $2 AND checked_at < $3
+			UNION ALL
+			SELECT TRUE, total_checks, alive_checks, status_2xx, status_3xx, status_4xx, status_5xx, status_failed,
+			       %s
+			FROM site_check_rollups
+			WHERE site_id = $1 AND bucket_start >= $2 AND bucket_start < $3
+		)
+		SELECT COALESCE(SUM(total), 0), COALESCE(SUM(alive), 0),
+		       COALESCE(SUM(s2xx), 0), COALESCE(SUM(s3xx), 0), COALESCE(SUM(s4xx), 0),
+		       COALESCE(SUM(s5xx), 0), COALESCE(SUM(failed), 0),
+		       COALESCE(BOOL_OR(rollup), FALSE),
+		       %s
+		FROM buckets
+	`, strings.Join(bucketCols, ", "), strings.Join(rawPhases, ", "),
+		strings.Join(rollupPhaseCols, ", "), strings.Join(aggregates, ",\n\t\t       "))
+
+	err := r.db.QueryRow(ctx, query, siteID, from, to).Scan(
+		&stats.TotalChecks, &stats.AliveChecks,
+		&stats.Status2xx, &stats.Status3xx, &stats.Status4xx, &stats.Status5xx, &stats.StatusFailed,
+		&t.P95Approximate,
+		&t.ResponseTime.AvgMs, &t.ResponseTime.P95Ms,
+		&t.DNSLookup.AvgMs, &t.DNSLookup.P95Ms,
+		&t.TCPConnect.AvgMs, &t.TCPConnect.P95Ms,
+		&t.TLSHandshake.AvgMs, &t.TLSHandshake.P95Ms,
+		&t.TTFB.AvgMs, &t.TTFB.P95Ms,
+		&t.Download.AvgMs, &t.Download.P95Ms,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.TotalChecks > 0 {
+		stats.UptimePercent = float64(stats.AliveChecks) / float64(stats.TotalChecks) * 100
+	}
+	return stats, nil
+}
+
+// NormalizeDomains recomputes domains still stored with Unicode hosts,
+// from before they were converted to punycode, and returns how many sites
+// were updated. Converted domains are pure ASCII, so later runs find
+// nothing to do.
+func (r *SiteRepository) NormalizeDomains(ctx context.Context) (int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, url FROM monitored_sites
+		WHERE octet_length(domain) <> char_length(domain)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	batch := &pgx.Batch{}
+	for rows.Next() {
+		var id int64
+		var rawURL string
+		if err := rows.Scan(&id, &rawURL); err != nil {
+			return 0, err
+		}
+		batch.Queue(`UPDATE monitored_sites SET domain = $1 WHERE id = $2`, extractDomain(rawURL), id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+
+	return batch.Len(), r.db.SendBatch(ctx, batch).Close()
 }
 
 func extractDomain(rawURL string) string {
@@ -246,5 +631,11 @@ func extractDomain(rawURL string) string {
 	if err != nil {
 		return ""
 	}
-	return parsed.Host
+	// Internationalized domains are stored in punycode, e.g. "сайт.рф" as
+	// "xn--80aswg.xn--p1ai", so both spellings find the same row.
+	host := urlcanon.Host(parsed.Hostname())
+	if port := parsed.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	return host
 }
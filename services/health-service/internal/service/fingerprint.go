@@ -0,0 +1,173 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/link-tracker/health-service/internal/model"
+	"golang.org/x/net/html"
+)
+
+const maxOutboundLinks = 1000
+
+// fingerprintPage extracts the parts of a page we watch for changes.
+// pageURL is used to resolve relative hrefs and tell outbound links apart.
+func fingerprintPage(body []byte, pageURL string) (*model.PageFingerprint, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := url.Parse(pageURL)
+
+	fp := &model.PageFingerprint{}
+	var text strings.Builder
+	links := make(map[string]bool)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "noscript", "template":
+				return
+			case "title":
+				if fp.Title == "" {
+					fp.Title = normalizeSpace(nodeText(n))
+				}
+			case "meta":
+				if strings.EqualFold(attr(n, "name"), "description") && fp.MetaDescription == "" {
+					fp.MetaDescription = normalizeSpace(attr(n, "content"))
+				}
+			case "h1":
+				if fp.H1 == "" {
+					fp.H1 = normalizeSpace(nodeText(n))
+				}
+			case "a":
+				if link := outboundLink(base, attr(n, "href")); link != "" {
+					links[link] = true
+				}
+			}
+		}
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+			text.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	hash := sha256.Sum256([]byte(strings.ToLower(normalizeSpace(text.String()))))
+	fp.TextHash = hex.EncodeToString(hash[:])
+
+	fp.OutboundLinks = make([]string, 0, len(links))
+	for link := range links {
+		fp.OutboundLinks = append(fp.OutboundLinks, link)
+	}
+	sort.Strings(fp.OutboundLinks)
+	if len(fp.OutboundLinks) > maxOutboundLinks {
+		fp.OutboundLinks = fp.OutboundLinks[:maxOutboundLinks]
+	}
+
+	return fp, nil
+}
+
+// diffFingerprints compares two fingerprints and returns nil when nothing changed.
+func diffFingerprints(prev, curr *model.PageFingerprint) *model.ContentDiff {
+	diff := &model.ContentDiff{}
+
+	if prev.TextHash != curr.TextHash {
+		diff.ChangedFields = append(diff.ChangedFields, "text")
+	}
+	if prev.Title != curr.Title {
+		diff.ChangedFields = append(diff.ChangedFields, "title")
+		diff.Title = &model.FieldChange{Before: prev.Title, After: curr.Title}
+	}
+	if prev.MetaDescription != curr.MetaDescription {
+		diff.ChangedFields = append(diff.ChangedFields, "meta_description")
+		diff.MetaDescription = &model.FieldChange{Before: prev.MetaDescription, After: curr.MetaDescription}
+	}
+	if prev.H1 != curr.H1 {
+		diff.ChangedFields = append(diff.ChangedFields, "h1")
+		diff.H1 = &model.FieldChange{Before: prev.H1, After: curr.H1}
+	}
+
+	diff.LinksAdded = setDifference(curr.OutboundLinks, prev.OutboundLinks)
+	diff.LinksRemoved = setDifference(prev.OutboundLinks, curr.OutboundLinks)
+	if len(diff.LinksAdded) > 0 || len(diff.LinksRemoved) > 0 {
+		diff.ChangedFields = append(diff.ChangedFields, "outbound_links")
+	}
+
+	if len(diff.ChangedFields) == 0 {
+		return nil
+	}
+	return diff
+}
+
+func outboundLink(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || base == nil {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	abs := base.ResolveReference(ref)
+	if abs.Scheme != "http" && abs.Scheme != "https" {
+		return ""
+	}
+	if strings.EqualFold(strings.TrimPrefix(abs.Hostname(), "www."), strings.TrimPrefix(base.Hostname(), "www.")) {
+		return ""
+	}
+	abs.Fragment = ""
+	return abs.String()
+}
+
+func setDifference(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, v := range b {
+		inB[v] = true
+	}
+	var out []string
+	for _, v := range a {
+		if !inB[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
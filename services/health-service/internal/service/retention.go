@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/link-tracker/health-service/internal/model"
+	"github.com/link-tracker/health-service/internal/repository"
+)
+
+// RetentionPolicy says how long check history is kept at each resolution.
+type RetentionPolicy struct {
+	RawDays    int
+	HourlyDays int
+	DailyDays  int // 0 keeps daily rollups forever
+}
+
+// rawCutoff is aligned to the hour so a bucket is never split between raw
+// history and its rollup.
+func (p RetentionPolicy) rawCutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -p.RawDays).Truncate(time.Hour)
+}
+
+// hourlyCutoff is aligned to the day for the same reason.
+func (p RetentionPolicy) hourlyCutoff(now time.Time) time.Time {
+	t := now.AddDate(0, 0, -p.HourlyDays)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// resolutionFor picks the finest resolution that still holds data from the
+// start of the requested range.
+func (p RetentionPolicy) resolutionFor(from *time.Time, now time.Time) model.Resolution {
+	if from == nil || !from.Before(p.rawCutoff(now)) {
+		return model.ResolutionRaw
+	}
+	if !from.Before(p.hourlyCutoff(now)) {
+		return model.ResolutionHour
+	}
+	return model.ResolutionDay
+}
+
+// HistoryCompactor periodically rolls raw check history into hourly and daily
+// aggregates and drops data past its retention. Every replica runs one; the
+// repository skips a step while another replica holds the compaction lock.
+type HistoryCompactor struct {
+	rollupRepo *repository.RollupRepository
+	policy     RetentionPolicy
+}
+
+func NewHistoryCompactor(rollupRepo *repository.RollupRepository, policy RetentionPolicy) *HistoryCompactor {
+	return &HistoryCompactor{
+		rollupRepo: rollupRepo,
+		policy:     policy,
+	}
+}
+
+// Run compacts once immediately and then on every tick until ctx is done.
+func (c *HistoryCompactor) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Compact(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("History compaction failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *HistoryCompactor) Compact(ctx context.Context) error {
+	now := time.Now()
+
+	raw, err := c.rollupRepo.CompactRaw(ctx, c.policy.rawCutoff(now))
+	if err != nil {
+		return err
+	}
+
+	hourly, err := c.rollupRepo.CompactHourly(ctx, c.policy.hourlyCutoff(now))
+	if err != nil {
+		return err
+	}
+
+	var daily int64
+	if c.policy.DailyDays > 0 {
+		daily, err = c.rollupRepo.DeleteDailyBefore(ctx, now.AddDate(0, 0, -c.policy.DailyDays))
+		if err != nil {
+			return err
+		}
+	}
+
+	if raw > 0 || hourly > 0 || daily > 0 {
+		log.Printf("History compaction: %d raw checks rolled up, %d hourly buckets rolled up, %d daily buckets dropped", raw, hourly, daily)
+	}
+	return nil
+}
@@ -5,48 +5,59 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"time"
 
 	"github.com/link-tracker/health-service/internal/model"
 	"github.com/link-tracker/health-service/internal/repository"
+	"github.com/link-tracker/shared/pkg/htmlcharset"
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/response"
 )
 
 var (
 	ErrSiteNotFound = errors.New("site not found")
-	ErrNotOwner     = errors.New("not site owner")
+	ErrAccessDenied = errors.New("access to site denied")
 )
 
 type SiteService struct {
-	repo       *repository.SiteRepository
-	httpClient *http.Client
+	repo        *repository.SiteRepository
+	contentRepo *repository.ContentRepository
+	rollupRepo  *repository.RollupRepository
+	retention   RetentionPolicy
+	httpClient  *http.Client
 }
 
-func NewSiteService(repo *repository.SiteRepository) *SiteService {
+func NewSiteService(
+	repo *repository.SiteRepository,
+	contentRepo *repository.ContentRepository,
+	rollupRepo *repository.RollupRepository,
+	retention RetentionPolicy,
+) *SiteService {
 	return &SiteService{
-		repo: repo,
+		repo:        repo,
+		contentRepo: contentRepo,
+		rollupRepo:  rollupRepo,
+		retention:   retention,
 		httpClient: &http.Client{
-			Timeout: 15 * time.Second,
+			Timeout:   15 * time.Second,
+			Transport: newCheckTransport(),
 		},
 	}
 }
 
 func (s *SiteService) Create(ctx context.Context, userID int64, req *model.CreateSiteRequest) (*model.MonitoredSite, error) {
+	if req.WorkspaceID != nil {
+		if err := s.checkWorkspace(ctx, *req.WorkspaceID, userID); err != nil {
+			return nil, err
+		}
+	}
 	return s.repo.Create(ctx, userID, req)
 }
 
 func (s *SiteService) GetByID(ctx context.Context, userID, siteID int64) (*model.MonitoredSite, error) {
-	site, err := s.repo.GetByID(ctx, siteID)
-	if err != nil {
-		return nil, err
-	}
-	if site == nil {
-		return nil, ErrSiteNotFound
-	}
-	if site.UserID != userID {
-		return nil, ErrNotOwner
-	}
-	return site, nil
+	return s.authorize(ctx, userID, siteID, models.MemberViewer)
 }
 
 func (s *SiteService) List(ctx context.Context, userID int64, filters *model.SiteFilters) ([]model.MonitoredSite, int64, error) {
@@ -59,44 +70,115 @@ func (s *SiteService) List(ctx context.Context, userID int64, filters *model.Sit
 	return s.repo.List(ctx, userID, filters)
 }
 
+// ListCursor returns a page of sites after filters.After and the cursor of
+// the next page, nil on the last one.
+func (s *SiteService) ListCursor(ctx context.Context, userID int64, filters *model.SiteFilters) ([]model.MonitoredSite, *model.SiteCursor, error) {
+	if filters.PerPage < 1 || filters.PerPage > 100 {
+		filters.PerPage = 20
+	}
+	if c := filters.After; c != nil && (c.ID <= 0 || c.CreatedAt.IsZero()) {
+		return nil, nil, response.ErrInvalidCursor
+	}
+
+	sites, hasMore, err := s.repo.ListAfter(ctx, userID, filters)
+	if err != nil || !hasMore {
+		return sites, nil, err
+	}
+	last := sites[len(sites)-1]
+	return sites, &model.SiteCursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
+
 func (s *SiteService) Update(ctx context.Context, userID, siteID int64, req *model.UpdateSiteRequest) (*model.MonitoredSite, error) {
-	site, err := s.repo.GetByID(ctx, siteID)
+	site, err := s.authorize(ctx, userID, siteID, models.MemberEditor)
 	if err != nil {
 		return nil, err
 	}
-	if site == nil {
-		return nil, ErrSiteNotFound
-	}
-	if site.UserID != userID {
-		return nil, ErrNotOwner
+
+	// Moving a site between workspaces changes who can see it
+	if req.WorkspaceID != nil {
+		if site.Role != models.MemberOwner {
+			return nil, ErrAccessDenied
+		}
+		if *req.WorkspaceID != 0 {
+			if err := s.checkWorkspace(ctx, *req.WorkspaceID, userID); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return s.repo.Update(ctx, siteID, req)
 }
 
 func (s *SiteService) Delete(ctx context.Context, userID, siteID int64) error {
-	site, err := s.repo.GetByID(ctx, siteID)
-	if err != nil {
+	if _, err := s.authorize(ctx, userID, siteID, models.MemberOwner); err != nil {
 		return err
 	}
-	if site == nil {
-		return ErrSiteNotFound
-	}
-	if site.UserID != userID {
-		return ErrNotOwner
-	}
 	return s.repo.Delete(ctx, siteID)
 }
 
-func (s *SiteService) CheckHealth(ctx context.Context, userID, siteID int64) (*model.SiteHealthCheck, error) {
-	site, err := s.repo.GetByID(ctx, siteID)
+// Usage counts the sites a user added. It is for admins and does not check
+// access.
+func (s *SiteService) Usage(ctx context.Context, userID int64) (*model.UserUsageResponse, error) {
+	sites, err := s.repo.CountByUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	if site == nil {
-		return nil, ErrSiteNotFound
+	return &model.UserUsageResponse{UserID: userID, Sites: sites}, nil
+}
+
+// Export returns the sites the user added with their history, for the
+// account export. It does not check access.
+func (s *SiteService) Export(ctx context.Context, userID int64) (*model.AccountExport, error) {
+	sites, err := s.repo.ListCreatedBy(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
-	if site.UserID != userID {
-		return nil, ErrNotOwner
+
+	export := &model.AccountExport{
+		UserID:     userID,
+		ExportedAt: time.Now().UTC(),
+		Sites:      make([]model.SiteExport, 0, len(sites)),
+	}
+	for _, site := range sites {
+		history, err := s.repo.ListAllHistory(ctx, site.ID)
+		if err != nil {
+			return nil, err
+		}
+		rollups, err := s.rollupRepo.ListAll(ctx, site.ID)
+		if err != nil {
+			return nil, err
+		}
+		changes, err := s.contentRepo.ListChangeEvents(ctx, site.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		export.Sites = append(export.Sites, model.SiteExport{
+			MonitoredSite:  site,
+			History:        history,
+			Rollups:        rollups,
+			ContentChanges: changes,
+		})
+	}
+
+	return export, nil
+}
+
+// DeleteData deletes the personal sites of the user, for the account
+// deletion, and returns what was deleted. Sites of workspaces the user
+// shares with others are kept for the team and handed over to the
+// workspace's heir.
+func (s *SiteService) DeleteData(ctx context.Context, userID int64) (*model.UserUsageResponse, error) {
+	sites, err := s.repo.DeleteUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserUsageResponse{UserID: userID, Sites: sites}, nil
+}
+
+func (s *SiteService) CheckHealth(ctx context.Context, userID, siteID int64) (*model.SiteHealthCheck, error) {
+	site, err := s.authorize(ctx, userID, siteID, models.MemberEditor)
+	if err != nil {
+		return nil, err
 	}
 
 	result := &model.SiteHealthCheck{
@@ -106,8 +188,9 @@ func (s *SiteService) CheckHealth(ctx context.Context, userID, siteID int64) (*m
 	}
 
 	// 1. HTTP GET request to main URL
-	startTime := time.Now()
-	req, err := http.NewRequestWithContext(ctx, "GET", site.URL, nil)
+	timer := newCheckTimer()
+	traceCtx := httptrace.WithClientTrace(ctx, timer.trace())
+	req, err := http.NewRequestWithContext(traceCtx, "GET", site.URL, nil)
 	if err != nil {
 		result.Error = err.Error()
 		_ = s.repo.UpdateHealthCheck(ctx, siteID, result)
@@ -125,15 +208,25 @@ func (s *SiteService) CheckHealth(ctx context.Context, userID, siteID int64) (*m
 	}
 	defer resp.Body.Close()
 
-	result.ResponseTimeMs = int(time.Since(startTime).Milliseconds())
+	result.ResponseTimeMs = int(time.Since(timer.start).Milliseconds())
 	result.HTTPStatus = resp.StatusCode
 	result.IsAlive = resp.StatusCode >= 200 && resp.StatusCode < 400
 
 	// 2. Read HTML to check for noindex
 	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024)) // Max 1MB
+	timer.markBodyRead()
+	result.Timing = timer.timing()
+	// Legacy encodings such as windows-1251 are converted to UTF-8 before
+	// the page is searched or fingerprinted.
+	body = htmlcharset.Decode(body, resp.Header.Get("Content-Type"))
 	bodyStr := strings.ToLower(string(body))
 	result.HasNoindex = strings.Contains(bodyStr, `name="robots"`) && strings.Contains(bodyStr, "noindex")
 
+	// Fingerprint page content and compare with the previous check
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		result.ContentChange = s.trackContent(ctx, siteID, resp.Request.URL.String(), body)
+	}
+
 	// 3. Check robots.txt
 	robotsURL := s.buildRobotsURL(site.URL)
 	robotsReq, _ := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
@@ -171,16 +264,113 @@ func (s *SiteService) CheckHealth(ctx context.Context, userID, siteID int64) (*m
 	return result, nil
 }
 
-func (s *SiteService) GetHistory(ctx context.Context, userID, siteID int64, filters *model.HistoryFilters) ([]model.SiteCheckHistory, int64, error) {
-	site, err := s.repo.GetByID(ctx, siteID)
+// GetHistory returns raw checks when the range is still within raw retention
+// and hourly or daily rollups for older ranges, unless a resolution is forced.
+func (s *SiteService) GetHistory(ctx context.Context, userID, siteID int64, filters *model.HistoryFilters) (*model.HistoryPage, error) {
+	_, err := s.authorize(ctx, userID, siteID, models.MemberViewer)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	if site == nil {
-		return nil, 0, ErrSiteNotFound
+
+	if filters.Page < 1 {
+		filters.Page = 1
 	}
-	if site.UserID != userID {
-		return nil, 0, ErrNotOwner
+	s.historyDefaults(filters)
+
+	page := &model.HistoryPage{Resolution: filters.Resolution}
+	if filters.Resolution == model.ResolutionRaw {
+		page.Checks, page.Total, err = s.repo.GetHistory(ctx, siteID, filters)
+	} else {
+		page.Rollups, page.Total, err = s.rollupRepo.List(ctx, siteID, filters)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// GetHistoryCursor is GetHistory with cursor pagination. A cursor keeps the
+// resolution of the page it came from, so the range can't switch between
+// raw checks and rollups halfway through a listing.
+func (s *SiteService) GetHistoryCursor(ctx context.Context, userID, siteID int64, filters *model.HistoryFilters) (*model.HistoryPage, error) {
+	_, err := s.authorize(ctx, userID, siteID, models.MemberViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if c := filters.After; c != nil {
+		if filters.Resolution == "" {
+			filters.Resolution = c.Resolution
+		}
+		if c.Resolution != filters.Resolution || c.Time.IsZero() ||
+			(c.Resolution == model.ResolutionRaw && c.ID <= 0) {
+			return nil, response.ErrInvalidCursor
+		}
+	}
+	s.historyDefaults(filters)
+	if c := filters.After; c != nil && c.Resolution != filters.Resolution {
+		return nil, response.ErrInvalidCursor
+	}
+
+	page := &model.HistoryPage{Resolution: filters.Resolution}
+	var hasMore bool
+	if filters.Resolution == model.ResolutionRaw {
+		page.Checks, hasMore, err = s.repo.GetHistoryAfter(ctx, siteID, filters)
+		if err == nil && hasMore {
+			last := page.Checks[len(page.Checks)-1]
+			page.Next = &model.HistoryCursor{Resolution: filters.Resolution, Time: last.CheckedAt, ID: last.ID}
+		}
+	} else {
+		page.Rollups, hasMore, err = s.rollupRepo.ListAfter(ctx, siteID, filters)
+		if err == nil && hasMore {
+			last := page.Rollups[len(page.Rollups)-1]
+			page.Next = &model.HistoryCursor{Resolution: filters.Resolution, Time: last.BucketStart}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if page.Checks == nil {
+		page.Checks = []model.SiteCheckHistory{}
+	}
+	if page.Rollups == nil {
+		page.Rollups = []model.CheckRollup{}
+	}
+	return page, nil
+}
+
+// historyDefaults bounds the page size and picks the resolution covering the
+// range when none was forced.
+func (s *SiteService) historyDefaults(filters *model.HistoryFilters) {
+	if filters.PerPage < 1 || filters.PerPage > 100 {
+		filters.PerPage = 20
+	}
+	switch filters.Resolution {
+	case model.ResolutionRaw, model.ResolutionHour, model.ResolutionDay:
+	default:
+		filters.Resolution = s.retention.resolutionFor(filters.From, time.Now())
+	}
+}
+
+func (s *SiteService) GetStats(ctx context.Context, userID, siteID int64, filters *model.StatsFilters) (*model.SiteStats, error) {
+	_, err := s.authorize(ctx, userID, siteID, models.MemberViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if filters.Days < 1 || filters.Days > 365 {
+		filters.Days = 7
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -filters.Days)
+	return s.repo.GetStats(ctx, siteID, from, to)
+}
+
+func (s *SiteService) GetContentChanges(ctx context.Context, userID, siteID int64, filters *model.HistoryFilters) ([]model.ContentChangeEvent, int64, error) {
+	_, err := s.authorize(ctx, userID, siteID, models.MemberViewer)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	if filters.Page < 1 {
@@ -190,7 +380,68 @@ func (s *SiteService) GetHistory(ctx context.Context, userID, siteID int64, filt
 		filters.PerPage = 20
 	}
 
-	return s.repo.GetHistory(ctx, siteID, filters)
+	return s.contentRepo.GetChangeEvents(ctx, siteID, filters)
+}
+
+// authorize loads a site and checks that userID has at least role on it.
+func (s *SiteService) authorize(ctx context.Context, userID, siteID int64, role models.MemberRole) (*model.MonitoredSite, error) {
+	site, err := s.repo.GetByID(ctx, siteID)
+	if err != nil {
+		return nil, err
+	}
+	if site == nil {
+		return nil, ErrSiteNotFound
+	}
+	site.Role, err = s.repo.Role(ctx, site, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !site.Role.Includes(role) {
+		return nil, ErrAccessDenied
+	}
+	return site, nil
+}
+
+// checkWorkspace checks that userID may add sites to a workspace, which
+// takes at least an editor.
+func (s *SiteService) checkWorkspace(ctx context.Context, workspaceID, userID int64) error {
+	role, err := s.repo.WorkspaceRole(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if !role.Includes(models.MemberEditor) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// trackContent stores the page fingerprint and records a change event when it
+// differs from the previous one. The first fingerprint of a site is a baseline
+// and never produces an event.
+func (s *SiteService) trackContent(ctx context.Context, siteID int64, pageURL string, body []byte) *model.ContentDiff {
+	curr, err := fingerprintPage(body, pageURL)
+	if err != nil {
+		return nil
+	}
+
+	prev, err := s.contentRepo.GetFingerprint(ctx, siteID)
+	if err != nil {
+		return nil
+	}
+
+	var diff *model.ContentDiff
+	if prev != nil {
+		diff = diffFingerprints(prev, curr)
+		if diff != nil {
+			_ = s.contentRepo.AddChangeEvent(ctx, siteID, diff)
+		}
+	}
+
+	if prev == nil || diff != nil {
+		_ = s.contentRepo.SaveFingerprint(ctx, siteID, curr)
+	}
+
+	return diff
 }
 
 func (s *SiteService) buildRobotsURL(siteURL string) string {
@@ -205,3 +456,11 @@ func (s *SiteService) buildRobotsURL(siteURL string) string {
 	}
 	return siteURL[:8+idx] + "/robots.txt"
 }
+
+// newCheckTransport returns a transport that opens a fresh connection per
+// request, so DNS, connect and TLS timings are measured on every check.
+func newCheckTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	return transport
+}
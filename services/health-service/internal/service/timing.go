@@ -0,0 +1,96 @@
+package service
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/link-tracker/health-service/internal/model"
+)
+
+// checkTimer records connection phase timestamps via httptrace. The trace
+// callbacks may run concurrently, as happy-eyeballs dials race each other,
+// so every field is guarded by mu. Phases describe the final redirect hop:
+// each new hop resets them.
+type checkTimer struct {
+	mu           sync.Mutex
+	start        time.Time
+	hopStart     time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	bodyDone     time.Time
+}
+
+func newCheckTimer() *checkTimer {
+	now := time.Now()
+	return &checkTimer{start: now, hopStart: now}
+}
+
+// record sets *field to the current time unless it is already set, so only
+// the first of several parallel dials counts.
+func (t *checkTimer) record(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if field.IsZero() {
+		*field = time.Now()
+	}
+}
+
+func (t *checkTimer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		// GetConn starts every request, including each redirect hop
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.hopStart = time.Now()
+			t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+			t.connectStart, t.connectDone = time.Time{}, time.Time{}
+			t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+			t.firstByte = time.Time{}
+		},
+		DNSStart:     func(httptrace.DNSStartInfo) { t.record(&t.dnsStart) },
+		DNSDone:      func(httptrace.DNSDoneInfo) { t.record(&t.dnsDone) },
+		ConnectStart: func(string, string) { t.record(&t.connectStart) },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.record(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart:    func() { t.record(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.record(&t.tlsDone) },
+		GotFirstResponseByte: func() { t.record(&t.firstByte) },
+	}
+}
+
+// markBodyRead records the moment the response body finished downloading.
+func (t *checkTimer) markBodyRead() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bodyDone = time.Now()
+}
+
+func (t *checkTimer) timing() model.CheckTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return model.CheckTiming{
+		DNSLookupMs:    phaseMs(t.dnsStart, t.dnsDone),
+		TCPConnectMs:   phaseMs(t.connectStart, t.connectDone),
+		TLSHandshakeMs: phaseMs(t.tlsStart, t.tlsDone),
+		TTFBMs:         phaseMs(t.hopStart, t.firstByte),
+		DownloadMs:     phaseMs(t.firstByte, t.bodyDone),
+	}
+}
+
+func phaseMs(from, to time.Time) *int {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return nil
+	}
+	ms := int(to.Sub(from).Milliseconds())
+	return &ms
+}
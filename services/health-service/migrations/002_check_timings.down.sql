@@ -0,0 +1,10 @@
+-- Per-phase timing breakdown rollback
+
+DROP INDEX IF EXISTS idx_history_site_checked_at;
+
+ALTER TABLE site_check_history
+    DROP COLUMN IF EXISTS dns_lookup_ms,
+    DROP COLUMN IF EXISTS tcp_connect_ms,
+    DROP COLUMN IF EXISTS tls_handshake_ms,
+    DROP COLUMN IF EXISTS ttfb_ms,
+    DROP COLUMN IF EXISTS download_ms;
@@ -0,0 +1,10 @@
+-- Per-phase timing breakdown for site checks
+
+ALTER TABLE site_check_history
+    ADD COLUMN dns_lookup_ms INTEGER,
+    ADD COLUMN tcp_connect_ms INTEGER,
+    ADD COLUMN tls_handshake_ms INTEGER,
+    ADD COLUMN ttfb_ms INTEGER,
+    ADD COLUMN download_ms INTEGER;
+
+CREATE INDEX idx_history_site_checked_at ON site_check_history(site_id, checked_at);
@@ -0,0 +1,4 @@
+-- Page content fingerprints rollback
+
+DROP TABLE IF EXISTS site_content_changes;
+DROP TABLE IF EXISTS site_content_snapshots;
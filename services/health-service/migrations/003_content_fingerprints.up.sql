@@ -0,0 +1,21 @@
+-- Page content fingerprints and change events
+
+CREATE TABLE site_content_snapshots (
+    site_id BIGINT PRIMARY KEY REFERENCES monitored_sites(id) ON DELETE CASCADE,
+    text_hash VARCHAR(64) NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    meta_description TEXT NOT NULL DEFAULT '',
+    h1 TEXT NOT NULL DEFAULT '',
+    outbound_links TEXT[] NOT NULL DEFAULT '{}',
+    captured_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE site_content_changes (
+    id BIGSERIAL PRIMARY KEY,
+    site_id BIGINT REFERENCES monitored_sites(id) ON DELETE CASCADE,
+    changed_fields TEXT[] NOT NULL,
+    diff JSONB NOT NULL,
+    detected_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_content_changes_site_detected_at ON site_content_changes(site_id, detected_at);
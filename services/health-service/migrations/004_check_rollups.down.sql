@@ -0,0 +1,3 @@
+-- Check history rollups rollback
+
+DROP TABLE IF EXISTS site_check_rollups;
@@ -0,0 +1,29 @@
+-- Hourly and daily rollups of site check history
+
+CREATE TABLE site_check_rollups (
+    site_id BIGINT NOT NULL REFERENCES monitored_sites(id) ON DELETE CASCADE,
+    resolution VARCHAR(10) NOT NULL CHECK (resolution IN ('hour', 'day')),
+    bucket_start TIMESTAMP NOT NULL,
+    total_checks INTEGER NOT NULL DEFAULT 0,
+    alive_checks INTEGER NOT NULL DEFAULT 0,
+    status_2xx INTEGER NOT NULL DEFAULT 0,
+    status_3xx INTEGER NOT NULL DEFAULT 0,
+    status_4xx INTEGER NOT NULL DEFAULT 0,
+    status_5xx INTEGER NOT NULL DEFAULT 0,
+    status_failed INTEGER NOT NULL DEFAULT 0,
+    avg_response_time_ms DOUBLE PRECISION,
+    p95_response_time_ms DOUBLE PRECISION,
+    avg_dns_lookup_ms DOUBLE PRECISION,
+    p95_dns_lookup_ms DOUBLE PRECISION,
+    avg_tcp_connect_ms DOUBLE PRECISION,
+    p95_tcp_connect_ms DOUBLE PRECISION,
+    avg_tls_handshake_ms DOUBLE PRECISION,
+    p95_tls_handshake_ms DOUBLE PRECISION,
+    avg_ttfb_ms DOUBLE PRECISION,
+    p95_ttfb_ms DOUBLE PRECISION,
+    avg_download_ms DOUBLE PRECISION,
+    p95_download_ms DOUBLE PRECISION,
+    PRIMARY KEY (site_id, resolution, bucket_start)
+);
+
+CREATE INDEX idx_rollups_resolution_bucket ON site_check_rollups(resolution, bucket_start);
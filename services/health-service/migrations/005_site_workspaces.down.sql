@@ -0,0 +1,4 @@
+-- Site workspaces rollback
+
+DROP INDEX IF EXISTS idx_monitored_sites_workspace_id;
+ALTER TABLE monitored_sites DROP COLUMN IF EXISTS workspace_id;
@@ -0,0 +1,7 @@
+-- Sites shared through a workspace. Requires the workspaces tables of
+-- auth-service (002_workspaces); deleting a workspace returns its sites to
+-- the users who added them.
+
+ALTER TABLE monitored_sites ADD COLUMN IF NOT EXISTS workspace_id BIGINT REFERENCES workspaces(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_monitored_sites_workspace_id ON monitored_sites(workspace_id);
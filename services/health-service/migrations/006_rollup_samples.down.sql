@@ -0,0 +1,9 @@
+-- Rollup sample counts rollback
+
+ALTER TABLE site_check_rollups
+    DROP COLUMN IF EXISTS response_time_samples,
+    DROP COLUMN IF EXISTS dns_lookup_samples,
+    DROP COLUMN IF EXISTS tcp_connect_samples,
+    DROP COLUMN IF EXISTS tls_handshake_samples,
+    DROP COLUMN IF EXISTS ttfb_samples,
+    DROP COLUMN IF EXISTS download_samples;
@@ -0,0 +1,20 @@
+-- Number of checks behind each phase average of a rollup. Phases such as
+-- TLS are missing from some checks, so averages are merged weighted by
+-- these counts rather than by total_checks. Existing rollups only know
+-- total_checks, which is the best estimate for them.
+
+ALTER TABLE site_check_rollups
+    ADD COLUMN IF NOT EXISTS response_time_samples INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS dns_lookup_samples INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tcp_connect_samples INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tls_handshake_samples INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS ttfb_samples INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS download_samples INTEGER NOT NULL DEFAULT 0;
+
+UPDATE site_check_rollups SET
+    response_time_samples = CASE WHEN avg_response_time_ms IS NULL THEN 0 ELSE total_checks END,
+    dns_lookup_samples = CASE WHEN avg_dns_lookup_ms IS NULL THEN 0 ELSE total_checks END,
+    tcp_connect_samples = CASE WHEN avg_tcp_connect_ms IS NULL THEN 0 ELSE total_checks END,
+    tls_handshake_samples = CASE WHEN avg_tls_handshake_ms IS NULL THEN 0 ELSE total_checks END,
+    ttfb_samples = CASE WHEN avg_ttfb_ms IS NULL THEN 0 ELSE total_checks END,
+    download_samples = CASE WHEN avg_download_ms IS NULL THEN 0 ELSE total_checks END;
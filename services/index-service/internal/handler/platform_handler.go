@@ -8,7 +8,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/link-tracker/index-service/internal/model"
 	"github.com/link-tracker/index-service/internal/service"
-	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/middleware"
 	"github.com/link-tracker/shared/pkg/response"
 )
 
@@ -21,7 +21,11 @@ func NewPlatformHandler(service *service.PlatformService) *PlatformHandler {
 }
 
 func (h *PlatformHandler) List(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	filters := &model.PlatformFilters{
 		Page:    1,
@@ -54,32 +58,80 @@ func (h *PlatformHandler) List(w http.ResponseWriter, r *http.Request) {
 		filters.Domain = domain
 	}
 
-	platforms, total, err := h.service.List(r.Context(), claims.UserID, filters)
+	// Any cursor parameter, even an empty one for the first page, switches
+	// to cursor pagination
+	if r.URL.Query().Has("cursor") {
+		h.listCursor(w, r, userID, filters)
+		return
+	}
+
+	platforms, total, err := h.service.List(r.Context(), userID, filters)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
+		response.Error(w, http.StatusInternalServerError, "failed to list platforms", "INTERNAL_ERROR")
 		return
 	}
 
 	response.Paginated(w, platforms, filters.Page, filters.PerPage, total)
 }
 
+func (h *PlatformHandler) listCursor(w http.ResponseWriter, r *http.Request, userID int64, filters *model.PlatformFilters) {
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		var after model.PlatformCursor
+		if err := response.DecodeCursor(v, &after); err != nil {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		filters.After = &after
+	}
+
+	platforms, next, err := h.service.ListCursor(r.Context(), userID, filters)
+	if err != nil {
+		if err == response.ErrInvalidCursor {
+			response.Error(w, http.StatusBadRequest, "invalid cursor", "INVALID_CURSOR")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to list platforms", "INTERNAL_ERROR")
+		return
+	}
+
+	var nextCursor string
+	if next != nil {
+		if nextCursor, err = response.EncodeCursor(next); err != nil {
+			response.Error(w, http.StatusInternalServerError, "failed to list platforms", "INTERNAL_ERROR")
+			return
+		}
+	}
+	if platforms == nil {
+		platforms = []model.Platform{}
+	}
+	response.CursorPaginated(w, platforms, filters.PerPage, nextCursor, nil, false)
+}
+
 func (h *PlatformHandler) Create(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	var req model.CreatePlatformRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid request body")
+		response.Error(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
 		return
 	}
 
 	if req.URL == "" {
-		response.Error(w, http.StatusBadRequest, "url is required")
+		response.Error(w, http.StatusBadRequest, "url is required", "VALIDATION_ERROR")
 		return
 	}
 
-	platform, err := h.service.Create(r.Context(), claims.UserID, &req)
+	platform, err := h.service.Create(r.Context(), userID, &req)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
+		if err == service.ErrAccessDenied {
+			response.Error(w, http.StatusForbidden, "workspace access denied", "FORBIDDEN")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "failed to create platform", "INTERNAL_ERROR")
 		return
 	}
 
@@ -87,23 +139,27 @@ func (h *PlatformHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PlatformHandler) GetByID(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid platform id")
+		response.Error(w, http.StatusBadRequest, "invalid platform id", "INVALID_ID")
 		return
 	}
 
-	platform, err := h.service.GetByID(r.Context(), claims.UserID, id)
+	platform, err := h.service.GetByID(r.Context(), userID, id)
 	if err != nil {
 		switch err {
 		case service.ErrPlatformNotFound:
-			response.Error(w, http.StatusNotFound, err.Error())
-		case service.ErrNotOwner:
-			response.Error(w, http.StatusForbidden, err.Error())
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
 		default:
-			response.Error(w, http.StatusInternalServerError, err.Error())
+			response.Error(w, http.StatusInternalServerError, "failed to get platform", "INTERNAL_ERROR")
 		}
 		return
 	}
@@ -112,29 +168,33 @@ func (h *PlatformHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PlatformHandler) Update(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid platform id")
+		response.Error(w, http.StatusBadRequest, "invalid platform id", "INVALID_ID")
 		return
 	}
 
 	var req model.UpdatePlatformRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid request body")
+		response.Error(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
 		return
 	}
 
-	platform, err := h.service.Update(r.Context(), claims.UserID, id, &req)
+	platform, err := h.service.Update(r.Context(), userID, id, &req)
 	if err != nil {
 		switch err {
 		case service.ErrPlatformNotFound:
-			response.Error(w, http.StatusNotFound, err.Error())
-		case service.ErrNotOwner:
-			response.Error(w, http.StatusForbidden, err.Error())
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
 		default:
-			response.Error(w, http.StatusInternalServerError, err.Error())
+			response.Error(w, http.StatusInternalServerError, "failed to update platform", "INTERNAL_ERROR")
 		}
 		return
 	}
@@ -143,23 +203,27 @@ func (h *PlatformHandler) Update(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PlatformHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid platform id")
+		response.Error(w, http.StatusBadRequest, "invalid platform id", "INVALID_ID")
 		return
 	}
 
-	err = h.service.Delete(r.Context(), claims.UserID, id)
+	err = h.service.Delete(r.Context(), userID, id)
 	if err != nil {
 		switch err {
 		case service.ErrPlatformNotFound:
-			response.Error(w, http.StatusNotFound, err.Error())
-		case service.ErrNotOwner:
-			response.Error(w, http.StatusForbidden, err.Error())
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
 		default:
-			response.Error(w, http.StatusInternalServerError, err.Error())
+			response.Error(w, http.StatusInternalServerError, "failed to delete platform", "INTERNAL_ERROR")
 		}
 		return
 	}
@@ -168,41 +232,49 @@ func (h *PlatformHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PlatformHandler) BulkCreate(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	var req model.BulkCreatePlatformsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid request body")
+		response.Error(w, http.StatusBadRequest, "invalid request body", "INVALID_REQUEST")
 		return
 	}
 
 	if len(req.Platforms) == 0 {
-		response.Error(w, http.StatusBadRequest, "platforms array is required")
+		response.Error(w, http.StatusBadRequest, "platforms array is required", "VALIDATION_ERROR")
 		return
 	}
 
-	result := h.service.BulkCreate(r.Context(), claims.UserID, &req)
+	result := h.service.BulkCreate(r.Context(), userID, &req)
 	response.JSON(w, http.StatusOK, result)
 }
 
 func (h *PlatformHandler) CheckIndex(w http.ResponseWriter, r *http.Request) {
-	claims := r.Context().Value("claims").(*models.Claims)
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		response.Error(w, http.StatusUnauthorized, "unauthorized", "UNAUTHORIZED")
+		return
+	}
 
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, "invalid platform id")
+		response.Error(w, http.StatusBadRequest, "invalid platform id", "INVALID_ID")
 		return
 	}
 
-	result, err := h.service.CheckIndex(r.Context(), claims.UserID, id)
+	result, err := h.service.CheckIndex(r.Context(), userID, id)
 	if err != nil {
 		switch err {
 		case service.ErrPlatformNotFound:
-			response.Error(w, http.StatusNotFound, err.Error())
-		case service.ErrNotOwner:
-			response.Error(w, http.StatusForbidden, err.Error())
+			response.Error(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+		case service.ErrAccessDenied:
+			response.Error(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
 		default:
-			response.Error(w, http.StatusInternalServerError, err.Error())
+			response.Error(w, http.StatusInternalServerError, "failed to check indexing", "INTERNAL_ERROR")
 		}
 		return
 	}
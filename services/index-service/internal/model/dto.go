@@ -1,10 +1,13 @@
 package model
 
+import "time"
+
 type CreatePlatformRequest struct {
 	URL            string `json:"url"`
 	PotentialScore int    `json:"potential_score,omitempty"`
 	IsMustHave     bool   `json:"is_must_have,omitempty"`
 	Notes          string `json:"notes,omitempty"`
+	WorkspaceID    *int64 `json:"workspace_id,omitempty"`
 }
 
 type UpdatePlatformRequest struct {
@@ -12,6 +15,7 @@ type UpdatePlatformRequest struct {
 	PotentialScore *int    `json:"potential_score,omitempty"`
 	IsMustHave     *bool   `json:"is_must_have,omitempty"`
 	Notes          *string `json:"notes,omitempty"`
+	WorkspaceID    *int64  `json:"workspace_id,omitempty"` // 0 moves the platform out of its workspace
 }
 
 type BulkCreatePlatformsRequest struct {
@@ -19,10 +23,10 @@ type BulkCreatePlatformsRequest struct {
 }
 
 type BulkOperationResponse struct {
-	Success int           `json:"success"`
-	Failed  int           `json:"failed"`
-	Errors  []BulkError   `json:"errors"`
-	Created []Platform    `json:"created,omitempty"`
+	Success int         `json:"success"`
+	Failed  int         `json:"failed"`
+	Errors  []BulkError `json:"errors"`
+	Created []Platform  `json:"created,omitempty"`
 }
 
 type BulkError struct {
@@ -31,6 +35,12 @@ type BulkError struct {
 	Message string `json:"message"`
 }
 
+// UserUsageResponse counts what a user created, for admins.
+type UserUsageResponse struct {
+	UserID    int64 `json:"user_id"`
+	Platforms int64 `json:"platforms"`
+}
+
 type PlatformFilters struct {
 	IndexStatus *IndexStatus `json:"index_status,omitempty"`
 	IsIndexed   *bool        `json:"is_indexed,omitempty"`
@@ -38,4 +48,22 @@ type PlatformFilters struct {
 	Domain      string       `json:"domain,omitempty"`
 	Page        int          `json:"page"`
 	PerPage     int          `json:"per_page"`
+
+	// After continues a cursor-paginated list after the given position.
+	After *PlatformCursor `json:"-"`
+}
+
+// PlatformCursor is the position after the last platform of a page in
+// cursor pagination. Platforms are listed newest first.
+type PlatformCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
+}
+
+// AccountExport is everything a user added to index-service, for the
+// account export.
+type AccountExport struct {
+	UserID     int64      `json:"user_id"`
+	ExportedAt time.Time  `json:"exported_at"`
+	Platforms  []Platform `json:"platforms"`
 }
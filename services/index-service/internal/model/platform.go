@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/link-tracker/shared/pkg/models"
+)
 
 type IndexStatus string
 
@@ -26,6 +30,11 @@ type Platform struct {
 	Notes          string      `json:"notes"`
 	CreatedAt      time.Time   `json:"created_at"`
 	UpdatedAt      time.Time   `json:"updated_at"`
+	WorkspaceID    *int64      `json:"workspace_id,omitempty"`
+
+	// Role is the role of the requesting user, set when a single platform
+	// is loaded for them
+	Role models.MemberRole `json:"role,omitempty"`
 }
 
 type IndexCheckResult struct {
@@ -3,12 +3,15 @@ package repository
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/link-tracker/index-service/internal/model"
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/urlcanon"
 )
 
 type PlatformRepository struct {
@@ -24,14 +27,14 @@ func (r *PlatformRepository) Create(ctx context.Context, userID int64, req *mode
 
 	var platform model.Platform
 	err := r.db.QueryRow(ctx, `
-		INSERT INTO platforms (user_id, url, domain, potential_score, is_must_have, notes)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO platforms (user_id, url, domain, potential_score, is_must_have, notes, workspace_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id, user_id, url, domain, index_status, is_indexed, first_indexed_at,
-		          last_checked_at, check_count, potential_score, is_must_have, notes, created_at, updated_at
-	`, userID, req.URL, domain, req.PotentialScore, req.IsMustHave, req.Notes).Scan(
+		          last_checked_at, check_count, potential_score, is_must_have, notes, created_at, updated_at, workspace_id
+	`, userID, req.URL, domain, req.PotentialScore, req.IsMustHave, req.Notes, req.WorkspaceID).Scan(
 		&platform.ID, &platform.UserID, &platform.URL, &platform.Domain, &platform.IndexStatus,
 		&platform.IsIndexed, &platform.FirstIndexedAt, &platform.LastCheckedAt, &platform.CheckCount,
-		&platform.PotentialScore, &platform.IsMustHave, &platform.Notes, &platform.CreatedAt, &platform.UpdatedAt,
+		&platform.PotentialScore, &platform.IsMustHave, &platform.Notes, &platform.CreatedAt, &platform.UpdatedAt, &platform.WorkspaceID,
 	)
 	if err != nil {
 		return nil, err
@@ -43,12 +46,12 @@ func (r *PlatformRepository) GetByID(ctx context.Context, id int64) (*model.Plat
 	var platform model.Platform
 	err := r.db.QueryRow(ctx, `
 		SELECT id, user_id, url, domain, index_status, is_indexed, first_indexed_at,
-		       last_checked_at, check_count, potential_score, is_must_have, notes, created_at, updated_at
+		       last_checked_at, check_count, potential_score, is_must_have, notes, created_at, updated_at, workspace_id
 		FROM platforms WHERE id = $1
 	`, id).Scan(
 		&platform.ID, &platform.UserID, &platform.URL, &platform.Domain, &platform.IndexStatus,
 		&platform.IsIndexed, &platform.FirstIndexedAt, &platform.LastCheckedAt, &platform.CheckCount,
-		&platform.PotentialScore, &platform.IsMustHave, &platform.Notes, &platform.CreatedAt, &platform.UpdatedAt,
+		&platform.PotentialScore, &platform.IsMustHave, &platform.Notes, &platform.CreatedAt, &platform.UpdatedAt, &platform.WorkspaceID,
 	)
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -60,11 +63,83 @@ func (r *PlatformRepository) GetByID(ctx context.Context, id int64) (*model.Plat
 }
 
 func (r *PlatformRepository) List(ctx context.Context, userID int64, filters *model.PlatformFilters) ([]model.Platform, int64, error) {
+	conditions, args := platformConditions(userID, filters)
+	argIndex := len(args) + 1
+	whereClause := strings.Join(conditions, " AND ")
+
+	// Count total
+	var total int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM platforms WHERE %s", whereClause)
+	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Get paginated results
+	offset := (filters.Page - 1) * filters.PerPage
+	args = append(args, filters.PerPage, offset)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM platforms
+		WHERE %s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, platformColumns, whereClause, argIndex, argIndex+1)
+
+	platforms, err := r.queryPlatforms(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return platforms, total, nil
+}
+
+// ListAfter returns up to filters.PerPage platforms after filters.After,
+// newest first, and whether more follow. Platforms created in the same
+// instant are ordered by id so none is skipped or repeated between pages.
+func (r *PlatformRepository) ListAfter(ctx context.Context, userID int64, filters *model.PlatformFilters) ([]model.Platform, bool, error) {
+	conditions, args := platformConditions(userID, filters)
+	argIndex := len(args) + 1
+
+	if filters.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < ($%d, $%d)", argIndex, argIndex+1))
+		args = append(args, filters.After.CreatedAt, filters.After.ID)
+		argIndex += 2
+	}
+	args = append(args, filters.PerPage+1)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM platforms
+		WHERE %s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, platformColumns, strings.Join(conditions, " AND "), argIndex)
+
+	platforms, err := r.queryPlatforms(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(platforms) > filters.PerPage {
+		return platforms[:filters.PerPage], true, nil
+	}
+	return platforms, false, nil
+}
+
+const platformColumns = `id, user_id, url, domain, index_status, is_indexed, first_indexed_at,
+		       last_checked_at, check_count, potential_score, is_must_have, notes, created_at, updated_at, workspace_id`
+
+// platformConditions returns the WHERE conditions and arguments selecting
+// the platforms a user can see that match filters.
+func platformConditions(userID int64, filters *model.PlatformFilters) ([]string, []interface{}) {
 	var conditions []string
 	var args []interface{}
 	argIndex := 1
 
-	conditions = append(conditions, fmt.Sprintf("user_id = $%d", argIndex))
+	// Own platforms outside workspaces and platforms of the user's workspaces
+	conditions = append(conditions, fmt.Sprintf(
+		"((workspace_id IS NULL AND user_id = $%[1]d) OR workspace_id IN (SELECT workspace_id FROM active_workspace_members WHERE user_id = $%[1]d))",
+		argIndex))
 	args = append(args, userID)
 	argIndex++
 
@@ -89,35 +164,15 @@ func (r *PlatformRepository) List(ctx context.Context, userID int64, filters *mo
 	if filters.Domain != "" {
 		conditions = append(conditions, fmt.Sprintf("domain ILIKE $%d", argIndex))
 		args = append(args, "%"+filters.Domain+"%")
-		argIndex++
-	}
-
-	whereClause := strings.Join(conditions, " AND ")
-
-	// Count total
-	var total int64
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM platforms WHERE %s", whereClause)
-	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, err
 	}
 
-	// Get paginated results
-	offset := (filters.Page - 1) * filters.PerPage
-	args = append(args, filters.PerPage, offset)
-
-	query := fmt.Sprintf(`
-		SELECT id, user_id, url, domain, index_status, is_indexed, first_indexed_at,
-		       last_checked_at, check_count, potential_score, is_must_have, notes, created_at, updated_at
-		FROM platforms
-		WHERE %s
-		ORDER BY created_at DESC
-		LIMIT $%d OFFSET $%d
-	`, whereClause, argIndex, argIndex+1)
+	return conditions, args
+}
 
+func (r *PlatformRepository) queryPlatforms(ctx context.Context, query string, args ...interface{}) ([]model.Platform, error) {
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -127,15 +182,15 @@ func (r *PlatformRepository) List(ctx context.Context, userID int64, filters *mo
 		err := rows.Scan(
 			&p.ID, &p.UserID, &p.URL, &p.Domain, &p.IndexStatus,
 			&p.IsIndexed, &p.FirstIndexedAt, &p.LastCheckedAt, &p.CheckCount,
-			&p.PotentialScore, &p.IsMustHave, &p.Notes, &p.CreatedAt, &p.UpdatedAt,
+			&p.PotentialScore, &p.IsMustHave, &p.Notes, &p.CreatedAt, &p.UpdatedAt, &p.WorkspaceID,
 		)
 		if err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 		platforms = append(platforms, p)
 	}
 
-	return platforms, total, nil
+	return platforms, rows.Err()
 }
 
 func (r *PlatformRepository) Update(ctx context.Context, id int64, req *model.UpdatePlatformRequest) (*model.Platform, error) {
@@ -170,6 +225,16 @@ func (r *PlatformRepository) Update(ctx context.Context, id int64, req *model.Up
 		argIndex++
 	}
 
+	if req.WorkspaceID != nil {
+		setClauses = append(setClauses, fmt.Sprintf("workspace_id = $%d", argIndex))
+		if *req.WorkspaceID == 0 {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.WorkspaceID)
+		}
+		argIndex++
+	}
+
 	if len(setClauses) == 0 {
 		return r.GetByID(ctx, id)
 	}
@@ -179,14 +244,14 @@ func (r *PlatformRepository) Update(ctx context.Context, id int64, req *model.Up
 		UPDATE platforms SET %s
 		WHERE id = $%d
 		RETURNING id, user_id, url, domain, index_status, is_indexed, first_indexed_at,
-		          last_checked_at, check_count, potential_score, is_must_have, notes, created_at, updated_at
+		          last_checked_at, check_count, potential_score, is_must_have, notes, created_at, updated_at, workspace_id
 	`, strings.Join(setClauses, ", "), argIndex)
 
 	var platform model.Platform
 	err := r.db.QueryRow(ctx, query, args...).Scan(
 		&platform.ID, &platform.UserID, &platform.URL, &platform.Domain, &platform.IndexStatus,
 		&platform.IsIndexed, &platform.FirstIndexedAt, &platform.LastCheckedAt, &platform.CheckCount,
-		&platform.PotentialScore, &platform.IsMustHave, &platform.Notes, &platform.CreatedAt, &platform.UpdatedAt,
+		&platform.PotentialScore, &platform.IsMustHave, &platform.Notes, &platform.CreatedAt, &platform.UpdatedAt, &platform.WorkspaceID,
 	)
 	if err == pgx.ErrNoRows {
 		return nil, nil
@@ -197,6 +262,115 @@ func (r *PlatformRepository) Update(ctx context.Context, id int64, req *model.Up
 	return &platform, nil
 }
 
+// Role returns the role of userID on a platform: owner of their own
+// platforms outside workspaces, otherwise their workspace role. It is empty
+// when the user has no access.
+func (r *PlatformRepository) Role(ctx context.Context, platform *model.Platform, userID int64) (models.MemberRole, error) {
+	if platform.WorkspaceID == nil {
+		if platform.UserID == userID {
+			return models.MemberOwner, nil
+		}
+		return "", nil
+	}
+	return r.WorkspaceRole(ctx, *platform.WorkspaceID, userID)
+}
+
+// WorkspaceRole returns the role of userID in a workspace, or "" when the
+// user is not a member or lacks the MFA the workspace requires.
+func (r *PlatformRepository) WorkspaceRole(ctx context.Context, workspaceID, userID int64) (models.MemberRole, error) {
+	var role models.MemberRole
+	err := r.db.QueryRow(ctx, `
+		SELECT role FROM active_workspace_members WHERE workspace_id = $1 AND user_id = $2
+	`, workspaceID, userID).Scan(&role)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// CountByUser returns the number of platforms the user added.
+func (r *PlatformRepository) CountByUser(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM platforms WHERE user_id = $1", userID).Scan(&count)
+	return count, err
+}
+
+// ListCreatedBy returns the platforms the user added, including those in
+// workspaces.
+func (r *PlatformRepository) ListCreatedBy(ctx context.Context, userID int64) ([]model.Platform, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, url, domain, index_status, is_indexed, first_indexed_at,
+		       last_checked_at, check_count, potential_score, is_must_have, notes, created_at, updated_at, workspace_id
+		FROM platforms
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var platforms []model.Platform
+	for rows.Next() {
+		var platform model.Platform
+		err := rows.Scan(
+			&platform.ID, &platform.UserID, &platform.URL, &platform.Domain, &platform.IndexStatus,
+			&platform.IsIndexed, &platform.FirstIndexedAt, &platform.LastCheckedAt, &platform.CheckCount,
+			&platform.PotentialScore, &platform.IsMustHave, &platform.Notes, &platform.CreatedAt, &platform.UpdatedAt, &platform.WorkspaceID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, platform)
+	}
+
+	return platforms, rows.Err()
+}
+
+// DeleteUserData deletes the platforms of a user outside workspaces and in
+// workspaces nobody else is a member of. Platforms of shared workspaces
+// stay with the team: they pass to the longest-standing other owner of the
+// workspace or, without one, to the longest-standing member, whom
+// auth-service makes owner, preferring users who are not disabled. It
+// returns the number of deleted platforms.
+func (r *PlatformRepository) DeleteUserData(ctx context.Context, userID int64) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		DELETE FROM platforms p
+		WHERE p.user_id = $1 AND (p.workspace_id IS NULL OR NOT EXISTS (
+			SELECT 1 FROM workspace_members wm WHERE wm.workspace_id = p.workspace_id AND wm.user_id <> $1))
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE platforms p
+		SET user_id = heir.user_id
+		FROM (
+			SELECT DISTINCT ON (wm.workspace_id) wm.workspace_id, wm.user_id
+			FROM workspace_members wm
+			JOIN users u ON u.id = wm.user_id
+			WHERE wm.user_id <> $1
+			ORDER BY wm.workspace_id, u.disabled_at IS NULL DESC, wm.role = 'owner' DESC, wm.created_at, wm.user_id
+		) heir
+		WHERE p.user_id = $1 AND p.workspace_id = heir.workspace_id
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 func (r *PlatformRepository) Delete(ctx context.Context, id int64) error {
 	_, err := r.db.Exec(ctx, "DELETE FROM platforms WHERE id = $1", id)
 	return err
@@ -220,6 +394,39 @@ func (r *PlatformRepository) UpdateIndexStatus(ctx context.Context, id int64, st
 	return err
 }
 
+// NormalizeDomains recomputes domains still stored with Unicode hosts,
+// from before they were converted to punycode, and returns how many platforms
+// were updated. Converted domains are pure ASCII, so later runs find
+// nothing to do.
+func (r *PlatformRepository) NormalizeDomains(ctx context.Context) (int, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, url FROM platforms
+		WHERE octet_length(domain) <> char_length(domain)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	batch := &pgx.Batch{}
+	for rows.Next() {
+		var id int64
+		var rawURL string
+		if err := rows.Scan(&id, &rawURL); err != nil {
+			return 0, err
+		}
+		batch.Queue(`UPDATE platforms SET domain = $1 WHERE id = $2`, extractDomain(rawURL), id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+
+	return batch.Len(), r.db.SendBatch(ctx, batch).Close()
+}
+
 func extractDomain(rawURL string) string {
 	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
 		rawURL = "https://" + rawURL
@@ -228,5 +435,11 @@ func extractDomain(rawURL string) string {
 	if err != nil {
 		return ""
 	}
-	return parsed.Host
+	// Internationalized domains are stored in punycode, e.g. "сайт.рф" as
+	// "xn--80aswg.xn--p1ai", so both spellings find the same row.
+	host := urlcanon.Host(parsed.Hostname())
+	if port := parsed.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	return host
 }
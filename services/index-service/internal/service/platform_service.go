@@ -8,11 +8,13 @@ import (
 
 	"github.com/link-tracker/index-service/internal/model"
 	"github.com/link-tracker/index-service/internal/repository"
+	"github.com/link-tracker/shared/pkg/models"
+	"github.com/link-tracker/shared/pkg/response"
 )
 
 var (
-	ErrPlatformNotFound = errors.New("platform not found")
-	ErrNotOwner         = errors.New("not platform owner")
+	ErrPlatformNotFound  = errors.New("platform not found")
+	ErrAccessDenied      = errors.New("access to platform denied")
 	ErrBulkLimitExceeded = errors.New("bulk operation limit exceeded (max 100)")
 )
 
@@ -31,21 +33,16 @@ func NewPlatformService(repo *repository.PlatformRepository) *PlatformService {
 }
 
 func (s *PlatformService) Create(ctx context.Context, userID int64, req *model.CreatePlatformRequest) (*model.Platform, error) {
+	if req.WorkspaceID != nil {
+		if err := s.checkWorkspace(ctx, *req.WorkspaceID, userID); err != nil {
+			return nil, err
+		}
+	}
 	return s.repo.Create(ctx, userID, req)
 }
 
 func (s *PlatformService) GetByID(ctx context.Context, userID, platformID int64) (*model.Platform, error) {
-	platform, err := s.repo.GetByID(ctx, platformID)
-	if err != nil {
-		return nil, err
-	}
-	if platform == nil {
-		return nil, ErrPlatformNotFound
-	}
-	if platform.UserID != userID {
-		return nil, ErrNotOwner
-	}
-	return platform, nil
+	return s.authorize(ctx, userID, platformID, models.MemberViewer)
 }
 
 func (s *PlatformService) List(ctx context.Context, userID int64, filters *model.PlatformFilters) ([]model.Platform, int64, error) {
@@ -58,32 +55,89 @@ func (s *PlatformService) List(ctx context.Context, userID int64, filters *model
 	return s.repo.List(ctx, userID, filters)
 }
 
+// ListCursor returns a page of platforms after filters.After and the cursor
+// of the next page, nil on the last one.
+func (s *PlatformService) ListCursor(ctx context.Context, userID int64, filters *model.PlatformFilters) ([]model.Platform, *model.PlatformCursor, error) {
+	if filters.PerPage < 1 || filters.PerPage > 100 {
+		filters.PerPage = 20
+	}
+	if c := filters.After; c != nil && (c.ID <= 0 || c.CreatedAt.IsZero()) {
+		return nil, nil, response.ErrInvalidCursor
+	}
+
+	platforms, hasMore, err := s.repo.ListAfter(ctx, userID, filters)
+	if err != nil || !hasMore {
+		return platforms, nil, err
+	}
+	last := platforms[len(platforms)-1]
+	return platforms, &model.PlatformCursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
+}
+
 func (s *PlatformService) Update(ctx context.Context, userID, platformID int64, req *model.UpdatePlatformRequest) (*model.Platform, error) {
-	platform, err := s.repo.GetByID(ctx, platformID)
+	platform, err := s.authorize(ctx, userID, platformID, models.MemberEditor)
 	if err != nil {
 		return nil, err
 	}
-	if platform == nil {
-		return nil, ErrPlatformNotFound
-	}
-	if platform.UserID != userID {
-		return nil, ErrNotOwner
+
+	// Moving a platform between workspaces changes who can see it
+	if req.WorkspaceID != nil {
+		if platform.Role != models.MemberOwner {
+			return nil, ErrAccessDenied
+		}
+		if *req.WorkspaceID != 0 {
+			if err := s.checkWorkspace(ctx, *req.WorkspaceID, userID); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return s.repo.Update(ctx, platformID, req)
 }
 
 func (s *PlatformService) Delete(ctx context.Context, userID, platformID int64) error {
-	platform, err := s.repo.GetByID(ctx, platformID)
-	if err != nil {
+	if _, err := s.authorize(ctx, userID, platformID, models.MemberOwner); err != nil {
 		return err
 	}
-	if platform == nil {
-		return ErrPlatformNotFound
+	return s.repo.Delete(ctx, platformID)
+}
+
+// Usage counts the platforms a user added. It is for admins and does not
+// check access.
+func (s *PlatformService) Usage(ctx context.Context, userID int64) (*model.UserUsageResponse, error) {
+	platforms, err := s.repo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
-	if platform.UserID != userID {
-		return ErrNotOwner
+	return &model.UserUsageResponse{UserID: userID, Platforms: platforms}, nil
+}
+
+// Export returns the platforms the user added, for the account export. It
+// does not check access.
+func (s *PlatformService) Export(ctx context.Context, userID int64) (*model.AccountExport, error) {
+	platforms, err := s.repo.ListCreatedBy(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
-	return s.repo.Delete(ctx, platformID)
+	if platforms == nil {
+		platforms = []model.Platform{}
+	}
+
+	return &model.AccountExport{
+		UserID:     userID,
+		ExportedAt: time.Now().UTC(),
+		Platforms:  platforms,
+	}, nil
+}
+
+// DeleteData deletes the personal platforms of the user, for the account
+// deletion, and returns what was deleted. Platforms of workspaces the user
+// shares with others are kept for the team and handed over to the
+// workspace's heir.
+func (s *PlatformService) DeleteData(ctx context.Context, userID int64) (*model.UserUsageResponse, error) {
+	platforms, err := s.repo.DeleteUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserUsageResponse{UserID: userID, Platforms: platforms}, nil
 }
 
 func (s *PlatformService) BulkCreate(ctx context.Context, userID int64, req *model.BulkCreatePlatformsRequest) *model.BulkOperationResponse {
@@ -102,7 +156,7 @@ func (s *PlatformService) BulkCreate(ctx context.Context, userID int64, req *mod
 	}
 
 	for i, createReq := range req.Platforms {
-		platform, err := s.repo.Create(ctx, userID, &createReq)
+		platform, err := s.Create(ctx, userID, &createReq)
 		if err != nil {
 			response.Failed++
 			response.Errors = append(response.Errors, model.BulkError{
@@ -120,16 +174,10 @@ func (s *PlatformService) BulkCreate(ctx context.Context, userID int64, req *mod
 }
 
 func (s *PlatformService) CheckIndex(ctx context.Context, userID, platformID int64) (*model.IndexCheckResult, error) {
-	platform, err := s.repo.GetByID(ctx, platformID)
+	platform, err := s.authorize(ctx, userID, platformID, models.MemberEditor)
 	if err != nil {
 		return nil, err
 	}
-	if platform == nil {
-		return nil, ErrPlatformNotFound
-	}
-	if platform.UserID != userID {
-		return nil, ErrNotOwner
-	}
 
 	result := &model.IndexCheckResult{
 		PlatformID: platformID,
@@ -176,3 +224,35 @@ func (s *PlatformService) CheckIndex(ctx context.Context, userID, platformID int
 
 	return result, nil
 }
+
+// authorize loads a platform and checks that userID has at least role on it.
+func (s *PlatformService) authorize(ctx context.Context, userID, platformID int64, role models.MemberRole) (*model.Platform, error) {
+	platform, err := s.repo.GetByID(ctx, platformID)
+	if err != nil {
+		return nil, err
+	}
+	if platform == nil {
+		return nil, ErrPlatformNotFound
+	}
+	platform.Role, err = s.repo.Role(ctx, platform, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !platform.Role.Includes(role) {
+		return nil, ErrAccessDenied
+	}
+	return platform, nil
+}
+
+// checkWorkspace checks that userID may add platforms to a workspace, which
+// takes at least an editor.
+func (s *PlatformService) checkWorkspace(ctx context.Context, workspaceID, userID int64) error {
+	role, err := s.repo.WorkspaceRole(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if !role.Includes(models.MemberEditor) {
+		return ErrAccessDenied
+	}
+	return nil
+}
@@ -0,0 +1,4 @@
+-- Platform workspaces rollback
+
+DROP INDEX IF EXISTS idx_platforms_workspace_id;
+ALTER TABLE platforms DROP COLUMN IF EXISTS workspace_id;
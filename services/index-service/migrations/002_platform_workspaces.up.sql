@@ -0,0 +1,7 @@
+-- Platforms shared through a workspace. Requires the workspaces tables of
+-- auth-service (002_workspaces); deleting a workspace returns its platforms
+-- to the users who added them.
+
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS workspace_id BIGINT REFERENCES workspaces(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_platforms_workspace_id ON platforms(workspace_id);
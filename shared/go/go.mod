@@ -2,4 +2,20 @@ module github.com/link-tracker/shared
 
 go 1.22
 
-require github.com/golang-jwt/jwt/v5 v5.2.1
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.1
+	github.com/jackc/pgx/v5 v5.5.3
+)
+
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
+	github.com/jackc/puddle/v2 v2.2.1 // indirect
+	golang.org/x/crypto v0.19.0 // indirect
+	golang.org/x/sync v0.1.0 // indirect
+)
+
+require (
+	golang.org/x/net v0.21.0
+	golang.org/x/text v0.14.0
+)
@@ -0,0 +1,64 @@
+// Package apikey validates the personal API keys issued by auth-service.
+package apikey
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/link-tracker/shared/pkg/models"
+)
+
+// Prefix starts every API key, which tells keys apart from JWTs in the
+// Authorization header.
+const Prefix = "lt_"
+
+var (
+	ErrInvalidKey = errors.New("invalid api key")
+	ErrKeyExpired = errors.New("api key expired")
+)
+
+// Resources a key can be scoped to. A scope is "<resource>:read" or
+// "<resource>:write"; write includes read.
+const (
+	ResourceBacklinks = "backlinks" // projects, backlinks, imports and discovery
+	ResourceSites     = "sites"
+	ResourcePlatforms = "platforms"
+)
+
+// Key is a validated API key together with the claims of its owner.
+type Key struct {
+	ID        int64
+	Scopes    []string
+	ExpiresAt *time.Time
+	Claims    models.Claims
+}
+
+// Allows reports whether the key grants access to resource. Write access is
+// needed for requests that change data.
+func (k *Key) Allows(resource string, write bool) bool {
+	for _, scope := range k.Scopes {
+		name, access, ok := strings.Cut(scope, ":")
+		if !ok || name != resource {
+			continue
+		}
+		if access == "write" || (access == "read" && !write) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKey reports whether token looks like an API key rather than a JWT.
+func IsKey(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
+
+// Hash returns the stored form of a key, the same SHA-256 hex digest
+// auth-service uses for refresh tokens.
+func Hash(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,68 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lastUsedInterval limits how often last_used_at is written for a key that
+// is used in a loop.
+const lastUsedInterval = time.Minute
+
+// Store looks keys up in the api_keys table of auth-service.
+type Store struct {
+	db *pgxpool.Pool
+}
+
+func NewStore(db *pgxpool.Pool) *Store {
+	return &Store{db: db}
+}
+
+// Validate resolves a raw key to its owner and records that it was used.
+// Keys of disabled accounts are invalid.
+func (s *Store) Validate(ctx context.Context, raw string) (*Key, error) {
+	if !IsKey(raw) {
+		return nil, ErrInvalidKey
+	}
+
+	query := `
+		SELECT k.id, k.scopes, k.expires_at, k.last_used_at, u.id, u.email, u.role
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND u.disabled_at IS NULL
+	`
+
+	key := &Key{}
+	var lastUsedAt *time.Time
+	err := s.db.QueryRow(ctx, query, Hash(raw)).Scan(
+		&key.ID,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&lastUsedAt,
+		&key.Claims.UserID,
+		&key.Claims.Email,
+		&key.Claims.Role,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, ErrKeyExpired
+	}
+
+	if lastUsedAt == nil || now.Sub(*lastUsedAt) > lastUsedInterval {
+		// Best effort: a failed write must not reject a valid key
+		_, _ = s.db.Exec(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, key.ID)
+	}
+
+	return key, nil
+}
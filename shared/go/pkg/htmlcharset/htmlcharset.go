@@ -0,0 +1,70 @@
+// Package htmlcharset converts HTML pages in legacy encodings to UTF-8.
+package htmlcharset
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// charsetPrescanSize is how much of the page is searched for a <meta>
+// charset declaration.
+const charsetPrescanSize = 4096
+
+// metaCharset matches both <meta charset="..."> and the http-equiv form.
+var metaCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_.:-]+)`)
+
+// Decode converts a page body to UTF-8. The charset comes from a BOM,
+// the Content-Type header or a <meta> tag. Undeclared bodies that are not
+// valid UTF-8 are read as windows-1251, the usual legacy encoding of
+// Russian-language sites.
+func Decode(body []byte, contentType string) []byte {
+	enc, name := htmlEncoding(body, contentType)
+	if name == "utf-8" {
+		return body
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+func htmlEncoding(body []byte, contentType string) (encoding.Encoding, string) {
+	// Only a BOM or the header make DetermineEncoding certain.
+	if enc, name, certain := charset.DetermineEncoding(body, contentType); certain {
+		return enc, name
+	}
+
+	head := body
+	if len(head) > charsetPrescanSize {
+		head = head[:charsetPrescanSize]
+	}
+	if m := metaCharset.FindSubmatch(head); m != nil {
+		if enc, name := charset.Lookup(string(m[1])); enc != nil {
+			return enc, name
+		}
+	}
+
+	if validUTF8(body) {
+		return encoding.Nop, "utf-8"
+	}
+	return charmap.Windows1251, "windows-1251"
+}
+
+// validUTF8 is utf8.Valid allowing a rune cut off at the end of a body
+// truncated by the size limit.
+func validUTF8(b []byte) bool {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				b = b[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(b)
+}
@@ -0,0 +1,196 @@
+// Package jwks fetches and caches the public keys auth-service signs access
+// tokens with, published as a JSON Web Key Set.
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long a fetched key set is used before it is
+	// fetched again
+	DefaultTTL = 10 * time.Minute
+
+	// minRefreshInterval limits refetching when tokens carry unknown key
+	// IDs, so forged tokens cannot flood auth-service
+	minRefreshInterval = 10 * time.Second
+)
+
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// JWK is a public key in JSON Web Key format. Only RSA and Ed25519 keys are
+// supported.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// Set is a JSON Web Key Set.
+type Set struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKey decodes the key.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: invalid modulus: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: invalid exponent: %w", k.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("key %s: exponent too large", k.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("key %s: unsupported curve %q", k.Kid, k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %s: invalid public key", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported key type %q", k.Kid, k.Kty)
+	}
+}
+
+// Cache fetches the key set from URL and keeps it in memory. Unknown key
+// IDs trigger a refetch, so keys added during rotation are picked up
+// before the cache expires. If auth-service is unreachable, the keys
+// fetched last stay in use.
+type Cache struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+
+	refreshMu sync.Mutex
+}
+
+func NewCache(url string) *Cache {
+	return &Cache{
+		url:    url,
+		ttl:    DefaultTTL,
+		client: &http.Client{Timeout: 5 * time.Second},
+		keys:   map[string]crypto.PublicKey{},
+	}
+}
+
+// Key returns the public key with the given ID.
+func (c *Cache) Key(kid string) (crypto.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	fresh := time.Since(c.fetchedAt) < c.ttl
+	c.mu.RUnlock()
+
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := c.refresh(); err != nil && !ok {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// refresh refetches the key set unless another caller just did.
+func (c *Cache) refresh() error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	recent := time.Since(c.lastAttempt) < minRefreshInterval
+	c.mu.RUnlock()
+	if recent {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.lastAttempt = time.Now()
+	c.mu.Unlock()
+
+	keys, err := c.fetch()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Cache) fetch() (map[string]crypto.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.client.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set Set
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// Skip keys of unsupported types instead of failing the set
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
@@ -2,11 +2,14 @@ package middleware
 
 import (
 	"context"
+	"crypto"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/link-tracker/shared/pkg/apikey"
 	"github.com/link-tracker/shared/pkg/models"
 )
 
@@ -16,6 +19,7 @@ const (
 	UserIDKey contextKey = "user_id"
 	EmailKey  contextKey = "email"
 	RoleKey   contextKey = "role"
+	APIKeyKey contextKey = "api_key_id"
 )
 
 var (
@@ -23,12 +27,43 @@ var (
 	ErrTokenExpired = errors.New("token expired")
 )
 
+// defaultSecrets are the HS256 secrets that shipped as config defaults.
+// They are public, so a service must not accept tokens signed with them.
+var defaultSecrets = map[string]bool{
+	"your-secret-key":                      true,
+	"your-secret-key-change-in-production": true,
+	"dev-secret-change-in-production":      true,
+}
+
+// KeySource returns the public key auth-service signed a token with, by
+// key ID. *jwks.Cache implements it.
+type KeySource interface {
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// APIKeyValidator resolves an API key to its owner.
+type APIKeyValidator interface {
+	Validate(ctx context.Context, raw string) (*apikey.Key, error)
+}
+
 // JWTConfig holds configuration for JWT middleware
 type JWTConfig struct {
-	Secret string
+	// Keys verifies RS256 and EdDSA tokens signed by auth-service
+	Keys KeySource
+
+	// LegacySecret additionally accepts HS256 tokens signed with the shared
+	// secret used before asymmetric signing. Set it only while such tokens
+	// can still be in circulation; see CheckLegacySecret.
+	LegacySecret string
+
+	// APIKeys enables API keys in the Authorization header next to JWTs.
+	// Keys are only accepted when they have a scope for Resource.
+	APIKeys  APIKeyValidator
+	Resource string
 }
 
-// JWTAuth creates a middleware that validates JWT tokens
+// JWTAuth creates a middleware that validates JWT tokens, and API keys
+// when cfg.APIKeys is set
 func JWTAuth(cfg JWTConfig) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -44,7 +79,12 @@ func JWTAuth(cfg JWTConfig) func(http.Handler) http.Handler {
 				return
 			}
 
-			claims, err := ValidateToken(parts[1], cfg.Secret)
+			if cfg.APIKeys != nil && apikey.IsKey(parts[1]) {
+				authenticateAPIKey(w, r, next, cfg, parts[1])
+				return
+			}
+
+			claims, err := ValidateToken(parts[1], cfg)
 			if err != nil {
 				if errors.Is(err, ErrTokenExpired) {
 					http.Error(w, `{"error":"token expired","code":"TOKEN_EXPIRED"}`, http.StatusUnauthorized)
@@ -63,14 +103,55 @@ func JWTAuth(cfg JWTConfig) func(http.Handler) http.Handler {
 	}
 }
 
-// ValidateToken validates a JWT token string and returns claims
-func ValidateToken(tokenString, secret string) (*models.Claims, error) {
+// authenticateAPIKey serves the request as the owner of an API key if the
+// key is valid and scoped for the request.
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, cfg JWTConfig, raw string) {
+	key, err := cfg.APIKeys.Validate(r.Context(), raw)
+	if err != nil {
+		switch {
+		case errors.Is(err, apikey.ErrKeyExpired):
+			http.Error(w, `{"error":"api key expired","code":"API_KEY_EXPIRED"}`, http.StatusUnauthorized)
+		case errors.Is(err, apikey.ErrInvalidKey):
+			http.Error(w, `{"error":"invalid api key","code":"UNAUTHORIZED"}`, http.StatusUnauthorized)
+		default:
+			http.Error(w, `{"error":"failed to validate api key","code":"INTERNAL_ERROR"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	write := r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions
+	if !key.Allows(cfg.Resource, write) {
+		http.Error(w, `{"error":"api key scope does not allow this request","code":"INSUFFICIENT_SCOPE"}`, http.StatusForbidden)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), UserIDKey, key.Claims.UserID)
+	ctx = context.WithValue(ctx, EmailKey, key.Claims.Email)
+	ctx = context.WithValue(ctx, RoleKey, key.Claims.Role)
+	ctx = context.WithValue(ctx, APIKeyKey, key.ID)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// ValidateToken validates a JWT token string against the keys of cfg and
+// returns claims
+func ValidateToken(tokenString string, cfg JWTConfig) (*models.Claims, error) {
+	methods := []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+	if cfg.LegacySecret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &models.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return []byte(cfg.LegacySecret), nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" || cfg.Keys == nil {
 			return nil, ErrInvalidToken
 		}
-		return []byte(secret), nil
-	})
+		return cfg.Keys.Key(kid)
+	}, jwt.WithValidMethods(methods))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -87,6 +168,21 @@ func ValidateToken(tokenString, secret string) (*models.Claims, error) {
 	return claims, nil
 }
 
+// CheckLegacySecret rejects HS256 secrets that are public defaults or too
+// short to resist guessing. An empty secret is fine: HS256 is then off.
+func CheckLegacySecret(secret string) error {
+	if secret == "" {
+		return nil
+	}
+	if defaultSecrets[secret] {
+		return errors.New("JWT_SECRET is set to a published default; remove it or set a real secret")
+	}
+	if len(secret) < 32 {
+		return fmt.Errorf("JWT_SECRET must be at least 32 bytes, got %d", len(secret))
+	}
+	return nil
+}
+
 // GetUserID extracts user ID from context
 func GetUserID(ctx context.Context) (int64, bool) {
 	userID, ok := ctx.Value(UserIDKey).(int64)
@@ -104,3 +200,10 @@ func GetRole(ctx context.Context) (models.Role, bool) {
 	role, ok := ctx.Value(RoleKey).(models.Role)
 	return role, ok
 }
+
+// GetAPIKeyID returns the id of the API key the request was authenticated
+// with. It is false for requests authenticated with a JWT.
+func GetAPIKeyID(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(APIKeyKey).(int64)
+	return id, ok
+}
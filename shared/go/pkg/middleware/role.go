@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/link-tracker/shared/pkg/models"
+)
+
+// RequireRole allows requests from users with one of roles. Use it after
+// JWTAuth. Requests authenticated with an API key are rejected, so a leaked
+// key of an admin cannot be used for admin routes.
+func RequireRole(roles ...models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := GetAPIKeyID(r.Context()); ok {
+				http.Error(w, `{"error":"api keys cannot be used for this request","code":"FORBIDDEN"}`, http.StatusForbidden)
+				return
+			}
+
+			role, _ := GetRole(r.Context())
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			http.Error(w, `{"error":"forbidden","code":"FORBIDDEN"}`, http.StatusForbidden)
+		})
+	}
+}
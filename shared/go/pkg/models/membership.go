@@ -0,0 +1,37 @@
+package models
+
+// MemberRole is a user's role in a workspace, or on a single resource shared
+// with them. Roles are ordered: owners can do everything editors can, and
+// editors everything viewers can.
+type MemberRole string
+
+const (
+	MemberOwner  MemberRole = "owner"  // manages members and deletes
+	MemberEditor MemberRole = "editor" // changes data
+	MemberViewer MemberRole = "viewer" // read-only
+)
+
+var memberRank = map[MemberRole]int{
+	MemberViewer: 1,
+	MemberEditor: 2,
+	MemberOwner:  3,
+}
+
+// Valid reports whether r is a known role.
+func (r MemberRole) Valid() bool {
+	return memberRank[r] > 0
+}
+
+// Includes reports whether r grants at least the permissions of min. The
+// empty role, meaning no access, includes nothing.
+func (r MemberRole) Includes(min MemberRole) bool {
+	return r.Valid() && memberRank[r] >= memberRank[min]
+}
+
+// HigherRole returns the more permissive of a and b.
+func HigherRole(a, b MemberRole) MemberRole {
+	if memberRank[b] > memberRank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,60 @@
+package response
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrInvalidCursor is returned for cursors that cannot be decoded. Cursors
+// are opaque but not signed, so a client can still forge a well-formed one:
+// callers must validate the decoded position before using it in a query.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorPaginatedResponse represents a page of a keyset-paginated list.
+// NextCursor is empty on the last page. Total is only present when the
+// caller asked for it and may be an estimate.
+type CursorPaginatedResponse struct {
+	Data           interface{} `json:"data"`
+	PerPage        int         `json:"per_page"`
+	NextCursor     string      `json:"next_cursor,omitempty"`
+	HasMore        bool        `json:"has_more"`
+	Total          *int64      `json:"total,omitempty"`
+	TotalEstimated bool        `json:"total_estimated,omitempty"`
+}
+
+// EncodeCursor turns a position in a list, typically the sort key and id of
+// the last row of a page, into an opaque URL-safe string.
+func EncodeCursor(position interface{}) (string, error) {
+	b, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor reads a cursor produced by EncodeCursor into position. It
+// only checks the encoding; the values in position are untrusted.
+func DecodeCursor(cursor string, position interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, position); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// CursorPaginated sends a keyset-paginated response
+func CursorPaginated(w http.ResponseWriter, data interface{}, perPage int, nextCursor string, total *int64, estimated bool) {
+	JSON(w, http.StatusOK, CursorPaginatedResponse{
+		Data:           data,
+		PerPage:        perPage,
+		NextCursor:     nextCursor,
+		HasMore:        nextCursor != "",
+		Total:          total,
+		TotalEstimated: total != nil && estimated,
+	})
+}
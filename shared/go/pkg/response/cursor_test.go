@@ -0,0 +1,105 @@
+package response
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type position struct {
+	At time.Time `json:"t"`
+	ID int64     `json:"id"`
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := position{At: time.Date(2026, 10, 19, 8, 30, 0, 123456789, time.UTC), ID: 42}
+
+	cursor, err := EncodeCursor(want)
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+	if strings.ContainsAny(cursor, "+/=") {
+		t.Errorf("EncodeCursor() = %q, want URL-safe unpadded base64", cursor)
+	}
+
+	var got position
+	if err := DecodeCursor(cursor, &got); err != nil {
+		t.Fatalf("DecodeCursor(%q) error = %v", cursor, err)
+	}
+	if !got.At.Equal(want.At) || got.ID != want.ID {
+		t.Errorf("DecodeCursor(%q) = %+v, want %+v", cursor, got, want)
+	}
+}
+
+func TestDecodeCursorRejectsMalformed(t *testing.T) {
+	raw := base64.RawURLEncoding.EncodeToString
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not a cursor!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"id":1}`))},
+		{"standard alphabet", base64.StdEncoding.EncodeToString([]byte(`{"id":1,"t":"?>?"}`))},
+		{"not JSON", raw([]byte("id=1"))},
+		{"truncated JSON", raw([]byte(`{"id":1`))},
+		{"wrong type", raw([]byte(`{"id":"1"}`))},
+		{"bad time", raw([]byte(`{"t":"yesterday","id":1}`))},
+		{"array", raw([]byte(`[1]`))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p position
+			if err := DecodeCursor(tt.cursor, &p); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", tt.cursor, err)
+			}
+		})
+	}
+}
+
+// DecodeCursor only checks the encoding: a well-formed cursor with values no
+// list would produce still decodes and must be validated by the caller.
+func TestDecodeCursorLeavesValuesToCaller(t *testing.T) {
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(`{"id":-5}`))
+
+	var p position
+	if err := DecodeCursor(cursor, &p); err != nil {
+		t.Fatalf("DecodeCursor(%q) error = %v", cursor, err)
+	}
+	if p.ID != -5 || !p.At.IsZero() {
+		t.Errorf("DecodeCursor(%q) = %+v, want id -5 and zero time", cursor, p)
+	}
+}
+
+func TestCursorPaginated(t *testing.T) {
+	total := int64(7)
+
+	tests := []struct {
+		name  string
+		next  string
+		total *int64
+		est   bool
+		want  string
+	}{
+		{"last page", "", nil, false, `{"data":[1],"per_page":20,"has_more":false}`},
+		{"more pages", "abc", nil, false, `{"data":[1],"per_page":20,"next_cursor":"abc","has_more":true}`},
+		{"exact total", "", &total, false, `{"data":[1],"per_page":20,"has_more":false,"total":7}`},
+		{"estimated total", "", &total, true, `{"data":[1],"per_page":20,"has_more":false,"total":7,"total_estimated":true}`},
+		{"estimate without total", "", nil, true, `{"data":[1],"per_page":20,"has_more":false}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			CursorPaginated(rec, []int{1}, 20, tt.next, tt.total, tt.est)
+
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Package urlcanon reduces URLs to a canonical form so that variants of the
+// same page compare equal.
+package urlcanon
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var ErrInvalidURL = errors.New("invalid URL")
+
+// Rules says which differences between two URLs are ignored. Host case,
+// default ports, fragments and query parameter order are always ignored.
+type Rules struct {
+	IgnoreScheme        bool `json:"ignore_scheme"`
+	IgnoreWWW           bool `json:"ignore_www"`
+	IgnoreTrailingSlash bool `json:"ignore_trailing_slash"`
+	IgnoreIndexFile     bool `json:"ignore_index_file"` // /index.html, /index.php
+	IgnorePathCase      bool `json:"ignore_path_case"`
+	IgnoreQuery         bool `json:"ignore_query"`
+	StripTracking       bool `json:"strip_tracking_params"` // utm_*, gclid, yclid, ...
+	// StripParams lists extra query parameters to drop. A trailing "*"
+	// matches by prefix, e.g. "ref_*".
+	StripParams []string `json:"strip_params,omitempty"`
+	// FollowRedirects lets link checkers count a link whose href redirects
+	// to the target as present.
+	FollowRedirects bool `json:"follow_redirects"`
+}
+
+// DefaultRules are used when a project has not configured its own.
+func DefaultRules() Rules {
+	return Rules{
+		IgnoreScheme:        true,
+		IgnoreWWW:           true,
+		IgnoreTrailingSlash: true,
+		IgnoreIndexFile:     true,
+		StripTracking:       true,
+		FollowRedirects:     true,
+	}
+}
+
+// trackingParams are dropped when Rules.StripTracking is set.
+var trackingParams = []string{
+	"utm_*", "gclid", "yclid", "ysclid", "fbclid", "msclkid", "dclid",
+	"_openstat", "mc_cid", "mc_eid", "_ga", "_gl",
+}
+
+var indexFiles = []string{"index.html", "index.htm", "index.php", "default.aspx", "default.asp"}
+
+// Canonicalize returns the canonical form of an absolute http(s) URL. With
+// IgnoreScheme the result is scheme-relative ("//host/path"). Internationalized
+// hosts are reduced to punycode, so "сайт.рф" and "xn--80aswg.xn--p1ai" match.
+func Canonicalize(raw string, rules Rules) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if (scheme != "http" && scheme != "https") || u.Host == "" {
+		return "", ErrInvalidURL
+	}
+
+	host := Host(u.Hostname())
+	if rules.IgnoreWWW {
+		host = strings.TrimPrefix(host, "www.")
+	}
+	if port := u.Port(); port != "" && !isDefaultPort(scheme, port) {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if rules.IgnoreIndexFile {
+		for _, f := range indexFiles {
+			if strings.HasSuffix(strings.ToLower(path), "/"+f) {
+				path = path[:len(path)-len(f)]
+				break
+			}
+		}
+	}
+	if rules.IgnoreTrailingSlash && len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+	}
+	if rules.IgnorePathCase {
+		path = strings.ToLower(path)
+	}
+
+	var query string
+	if !rules.IgnoreQuery {
+		query = canonicalQuery(u.Query(), rules)
+	}
+
+	var sb strings.Builder
+	if !rules.IgnoreScheme {
+		sb.WriteString(scheme)
+		sb.WriteByte(':')
+	}
+	sb.WriteString("//")
+	sb.WriteString(host)
+	sb.WriteString(path)
+	if query != "" {
+		sb.WriteByte('?')
+		sb.WriteString(query)
+	}
+	return sb.String(), nil
+}
+
+// Equal reports whether a and b are the same page under rules. URLs that
+// cannot be canonicalized are compared as given.
+func Equal(a, b string, rules Rules) bool {
+	ca, errA := Canonicalize(a, rules)
+	cb, errB := Canonicalize(b, rules)
+	if errA != nil || errB != nil {
+		return strings.TrimSpace(a) == strings.TrimSpace(b)
+	}
+	return ca == cb
+}
+
+// Key is Canonicalize falling back to the trimmed input, for use as a map or
+// database key.
+func Key(raw string, rules Rules) string {
+	if c, err := Canonicalize(raw, rules); err == nil {
+		return c
+	}
+	return strings.TrimSpace(raw)
+}
+
+// Host returns host in lower case without a trailing dot, with
+// internationalized labels converted to punycode. Hosts that are not valid
+// domain names, such as IP addresses, are only lowercased.
+func Host(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// IsAbsolute reports whether s is an absolute http(s) URL.
+func IsAbsolute(s string) bool {
+	u, err := url.Parse(strings.TrimSpace(s))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func canonicalQuery(values url.Values, rules Rules) string {
+	var keys []string
+	for k := range values {
+		if rules.StripTracking && matchParam(k, trackingParams) {
+			continue
+		}
+		if matchParam(k, rules.StripParams) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func matchParam(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == p {
+			return true
+		}
+	}
+	return false
+}
+
+func isDefaultPort(scheme, port string) bool {
+	return (scheme == "http" && port == "80") || (scheme == "https" && port == "443")
+}
@@ -0,0 +1,136 @@
+package urlcanon
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	defaults := DefaultRules()
+	strict := Rules{}
+
+	tests := []struct {
+		name  string
+		raw   string
+		rules Rules
+		want  string
+	}{
+		{"scheme, www and trailing slash", "https://www.Example.com/blog/", defaults, "//example.com/blog"},
+		{"root path", "http://example.com", defaults, "//example.com/"},
+		{"index file", "https://example.com/docs/index.html", defaults, "//example.com/docs"},
+		{"index file case", "https://example.com/INDEX.PHP", defaults, "//example.com/"},
+		{"default port", "https://example.com:443/a", defaults, "//example.com/a"},
+		{"other port", "https://example.com:8443/a", defaults, "//example.com:8443/a"},
+		{"fragment", "https://example.com/a#top", defaults, "//example.com/a"},
+		{"query order", "https://example.com/?b=2&a=1&a=0", defaults, "//example.com/?a=0&a=1&b=2"},
+		{"tracking params", "https://example.com/?utm_source=x&gclid=y&id=7", defaults, "//example.com/?id=7"},
+		{"trailing dot", "https://example.com./a", defaults, "//example.com/a"},
+		{"IDN host", "https://сайт.рф/путь", defaults, "//xn--80aswg.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{"IDN host uppercase", "https://САЙТ.РФ/", defaults, "//xn--80aswg.xn--p1ai/"},
+		{"IPv6 host", "http://[::1]:80/a", defaults, "//[::1]/a"},
+		{"strict keeps scheme", "HTTPS://Example.com/A/", strict, "https://example.com/A/"},
+		{"strict keeps www", "https://www.example.com/", strict, "https://www.example.com/"},
+		{"strict keeps tracking", "https://example.com/?utm_source=x", strict, "https://example.com/?utm_source=x"},
+		{"path case", "https://example.com/A/B", Rules{IgnorePathCase: true}, "https://example.com/a/b"},
+		{"ignore query", "https://example.com/a?x=1", Rules{IgnoreQuery: true}, "https://example.com/a"},
+		{"strip params prefix", "https://example.com/?ref_a=1&ref_b=2&x=1", Rules{StripParams: []string{"ref_*"}}, "https://example.com/?x=1"},
+		{"strip params exact", "https://example.com/?Session=1&sessions=2", Rules{StripParams: []string{"session"}}, "https://example.com/?sessions=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonicalize(tt.raw, tt.rules)
+			if err != nil {
+				t.Fatalf("Canonicalize(%q) error = %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("Canonicalize(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeRejectsInvalidURLs(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"example.com/a",
+		"/relative/path",
+		"ftp://example.com/file",
+		"mailto:info@example.com",
+		"https://",
+		"http://[::1",
+	} {
+		if got, err := Canonicalize(raw, DefaultRules()); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("Canonicalize(%q) = %q, %v; want ErrInvalidURL", raw, got, err)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"http://www.example.com/page/", "https://example.com/page", true},
+		{"https://сайт.рф/", "https://xn--80aswg.xn--p1ai", true},
+		{"https://example.com/a?utm_medium=email", "https://example.com/a", true},
+		{"https://example.com/a", "https://example.com/b", false},
+		{"https://example.com/a?id=1", "https://example.com/a?id=2", false},
+		{" not a url ", "not a url", true},
+		{"not a url", "https://example.com/", false},
+	}
+
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b, DefaultRules()); got != tt.want {
+			t.Errorf("Equal(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestKeyFallsBackToTrimmedInput(t *testing.T) {
+	if got := Key("  not a url ", DefaultRules()); got != "not a url" {
+		t.Errorf("Key() = %q, want %q", got, "not a url")
+	}
+	if got := Key("https://www.example.com/", DefaultRules()); got != "//example.com/" {
+		t.Errorf("Key() = %q, want %q", got, "//example.com/")
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		host, want string
+	}{
+		{"Example.COM", "example.com"},
+		{"example.com.", "example.com"},
+		{"Пример.РФ", "xn--e1afmkfd.xn--p1ai"},
+		{"xn--e1afmkfd.xn--p1ai", "xn--e1afmkfd.xn--p1ai"},
+		{"192.168.0.1", "192.168.0.1"},
+		{" host_with_underscore.example ", "host_with_underscore.example"},
+	}
+
+	for _, tt := range tests {
+		if got := Host(tt.host); got != tt.want {
+			t.Errorf("Host(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestIsAbsolute(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"https://example.com", true},
+		{" http://example.com/a ", true},
+		{"//example.com/a", false},
+		{"/a", false},
+		{"ftp://example.com", false},
+		{"https://", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsAbsolute(tt.s); got != tt.want {
+			t.Errorf("IsAbsolute(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}